@@ -1,6 +1,7 @@
 package action
 
 import (
+	"bytes"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -18,7 +19,7 @@ func GetAllApplications(namespace string) ([]map[string]interface{}, error) {
 
 	logging.Info("Getting applications...")
 
-	requestURL := fmt.Sprintf("http://%s:%s/api/application", host, port)
+	requestURL := fmt.Sprintf("https://%s:%s/api/application", host, port)
 
 	logging.Debug("Sending GET request to client...")
 	logging.Trace(fmt.Sprintf("Sending request to %s", requestURL))
@@ -28,7 +29,10 @@ func GetAllApplications(namespace string) ([]map[string]interface{}, error) {
 		return []map[string]interface{}{}, err
 	}
 
-	httpClient := &http.Client{}
+	httpClient, err := config.HTTPClient()
+	if err != nil {
+		return []map[string]interface{}{}, err
+	}
 	req, _ := http.NewRequest("GET", requestURL, nil)
 	req.Header.Set("Authorization", fmt.Sprintf("X-Stormfront-API %s", apiToken))
 	resp, err := httpClient.Do(req)
@@ -71,7 +75,7 @@ func GetApplicationById(id string) ([]map[string]interface{}, error) {
 
 	logging.Info(fmt.Sprintf("Getting application %s...", id))
 
-	requestURL := fmt.Sprintf("http://%s:%s/api/application/%s", host, port, id)
+	requestURL := fmt.Sprintf("https://%s:%s/api/application/%s", host, port, id)
 
 	logging.Debug("Sending GET request to client...")
 	logging.Trace(fmt.Sprintf("Sending request to %s", requestURL))
@@ -81,7 +85,10 @@ func GetApplicationById(id string) ([]map[string]interface{}, error) {
 		return []map[string]interface{}{}, err
 	}
 
-	httpClient := &http.Client{}
+	httpClient, err := config.HTTPClient()
+	if err != nil {
+		return []map[string]interface{}{}, err
+	}
 	req, _ := http.NewRequest("GET", requestURL, nil)
 	req.Header.Set("Authorization", fmt.Sprintf("X-Stormfront-API %s", apiToken))
 	resp, err := httpClient.Do(req)
@@ -120,7 +127,7 @@ func GetApplicationByNameNamespace(name, namespace string) ([]map[string]interfa
 
 	logging.Info("Getting applications...")
 
-	requestURL := fmt.Sprintf("http://%s:%s/api/application", host, port)
+	requestURL := fmt.Sprintf("https://%s:%s/api/application", host, port)
 
 	logging.Debug("Sending GET request to client...")
 	logging.Trace(fmt.Sprintf("Sending request to %s", requestURL))
@@ -130,7 +137,10 @@ func GetApplicationByNameNamespace(name, namespace string) ([]map[string]interfa
 		return []map[string]interface{}{}, err
 	}
 
-	httpClient := &http.Client{}
+	httpClient, err := config.HTTPClient()
+	if err != nil {
+		return []map[string]interface{}{}, err
+	}
 	req, _ := http.NewRequest("GET", requestURL, nil)
 	req.Header.Set("Authorization", fmt.Sprintf("X-Stormfront-API %s", apiToken))
 	resp, err := httpClient.Do(req)
@@ -178,7 +188,7 @@ func DeleteApplicationById(id string) error {
 
 	logging.Info(fmt.Sprintf("Deleting application %s...", id))
 
-	requestURL := fmt.Sprintf("http://%s:%s/api/application/%s", host, port, id)
+	requestURL := fmt.Sprintf("https://%s:%s/api/application/%s", host, port, id)
 
 	logging.Debug("Sending DELETE request to client...")
 	logging.Trace(fmt.Sprintf("Sending request to %s", requestURL))
@@ -188,7 +198,10 @@ func DeleteApplicationById(id string) error {
 		return err
 	}
 
-	httpClient := &http.Client{}
+	httpClient, err := config.HTTPClient()
+	if err != nil {
+		return err
+	}
 	req, _ := http.NewRequest("DELETE", requestURL, nil)
 	req.Header.Set("Authorization", fmt.Sprintf("X-Stormfront-API %s", apiToken))
 	resp, err := httpClient.Do(req)
@@ -235,3 +248,78 @@ func DeleteApplicationByNameNamespace(name, namespace string) error {
 	err = DeleteApplicationById(id)
 	return err
 }
+
+func UpdateApplicationById(id string, definition map[string]interface{}) ([]string, error) {
+	host, port, err := GetConnectionDetails()
+	if err != nil {
+		return []string{}, err
+	}
+
+	logging.Info(fmt.Sprintf("Updating application %s...", id))
+
+	requestURL := fmt.Sprintf("https://%s:%s/api/application/%s", host, port, id)
+
+	logging.Debug("Sending PATCH request to client...")
+	logging.Trace(fmt.Sprintf("Sending request to %s", requestURL))
+
+	apiToken, err := config.GetAPIToken()
+	if err != nil {
+		return []string{}, err
+	}
+
+	patchBody, _ := json.Marshal(definition)
+	patchBodyBuffer := bytes.NewBuffer(patchBody)
+
+	httpClient, err := config.HTTPClient()
+	if err != nil {
+		return []string{}, err
+	}
+	req, _ := http.NewRequest("PATCH", requestURL, patchBodyBuffer)
+	req.Header.Set("Authorization", fmt.Sprintf("X-Stormfront-API %s", apiToken))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return []string{}, err
+	}
+
+	logging.Debug("Done!")
+
+	defer resp.Body.Close()
+	//Read the response body
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return []string{}, err
+	}
+	responseBody := string(body)
+
+	logging.Debug(fmt.Sprintf("Status code: %v", resp.StatusCode))
+	logging.Debug(fmt.Sprintf("Response body: %s", responseBody))
+
+	if resp.StatusCode == http.StatusOK {
+		var data struct {
+			Changed []string `json:"changed"`
+		}
+		if err := json.Unmarshal(body, &data); err != nil {
+			return []string{}, err
+		}
+		return data.Changed, nil
+	}
+	var data map[string]string
+	if err := json.Unmarshal([]byte(responseBody), &data); err == nil {
+		if errMessage, ok := data["error"]; ok {
+			return []string{}, errors.New(errMessage)
+		}
+	}
+	return []string{}, fmt.Errorf("client has returned error with status code %v", resp.StatusCode)
+}
+
+func UpdateApplicationByNameNamespace(name, namespace string, definition map[string]interface{}) ([]string, error) {
+	applications, err := GetApplicationByNameNamespace(name, namespace)
+	if err != nil {
+		return []string{}, err
+	}
+
+	id := applications[0]["id"].(string)
+
+	return UpdateApplicationById(id, definition)
+}
@@ -0,0 +1,295 @@
+package action
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"stormfront-cli/config"
+	"stormfront-cli/logging"
+	"strings"
+	"time"
+)
+
+const BOLT_FOLLOW_DELAY = 1
+
+func GetAllBolts() ([]map[string]interface{}, error) {
+	host, port, err := GetConnectionDetails()
+	if err != nil {
+		return []map[string]interface{}{}, err
+	}
+
+	logging.Info("Getting bolts...")
+
+	requestURL := fmt.Sprintf("https://%s:%s/lightning", host, port)
+
+	logging.Debug("Sending GET request to client...")
+	logging.Trace(fmt.Sprintf("Sending request to %s", requestURL))
+
+	apiToken, err := config.GetAPIToken()
+	if err != nil {
+		return []map[string]interface{}{}, err
+	}
+
+	httpClient, err := config.HTTPClient()
+	if err != nil {
+		return []map[string]interface{}{}, err
+	}
+	req, _ := http.NewRequest("GET", requestURL, nil)
+	req.Header.Set("Authorization", fmt.Sprintf("X-Stormfront-API %s", apiToken))
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return []map[string]interface{}{}, err
+	}
+
+	logging.Debug("Done!")
+
+	defer resp.Body.Close()
+	//Read the response body
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return []map[string]interface{}{}, err
+	}
+	responseBody := string(body)
+
+	logging.Debug(fmt.Sprintf("Status code: %v", resp.StatusCode))
+	logging.Debug(fmt.Sprintf("Response body: %s", responseBody))
+
+	if resp.StatusCode == http.StatusOK {
+		return ParseJSON(responseBody)
+	}
+	return []map[string]interface{}{}, fmt.Errorf("request failed with status code %d", resp.StatusCode)
+}
+
+func GetBoltById(id string) ([]map[string]interface{}, error) {
+	host, port, err := GetConnectionDetails()
+	if err != nil {
+		return []map[string]interface{}{}, err
+	}
+
+	logging.Info(fmt.Sprintf("Getting bolt %s...", id))
+
+	requestURL := fmt.Sprintf("https://%s:%s/lightning/%s", host, port, id)
+
+	logging.Debug("Sending GET request to client...")
+	logging.Trace(fmt.Sprintf("Sending request to %s", requestURL))
+
+	apiToken, err := config.GetAPIToken()
+	if err != nil {
+		return []map[string]interface{}{}, err
+	}
+
+	httpClient, err := config.HTTPClient()
+	if err != nil {
+		return []map[string]interface{}{}, err
+	}
+	req, _ := http.NewRequest("GET", requestURL, nil)
+	req.Header.Set("Authorization", fmt.Sprintf("X-Stormfront-API %s", apiToken))
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return []map[string]interface{}{}, err
+	}
+
+	logging.Debug("Done!")
+
+	defer resp.Body.Close()
+	//Read the response body
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return []map[string]interface{}{}, err
+	}
+	responseBody := string(body)
+
+	logging.Debug(fmt.Sprintf("Status code: %v", resp.StatusCode))
+	logging.Debug(fmt.Sprintf("Response body: %s", responseBody))
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return ParseJSON(responseBody)
+	case http.StatusNotFound:
+		return []map[string]interface{}{}, fmt.Errorf("no bolt with id %s exists", id)
+	}
+	return []map[string]interface{}{}, fmt.Errorf("request failed with status code %d", resp.StatusCode)
+}
+
+// CreateBolt submits a bolt to the cluster and returns its id
+func CreateBolt(definition map[string]interface{}) (string, error) {
+	host, port, err := GetConnectionDetails()
+	if err != nil {
+		return "", err
+	}
+
+	logging.Info("Creating bolt...")
+
+	requestURL := fmt.Sprintf("https://%s:%s/lightning/", host, port)
+
+	logging.Debug("Sending POST request to client...")
+	logging.Trace(fmt.Sprintf("Sending request to %s", requestURL))
+
+	apiToken, err := config.GetAPIToken()
+	if err != nil {
+		return "", err
+	}
+
+	postBody, _ := json.Marshal(definition)
+	postBodyBuffer := bytes.NewBuffer(postBody)
+
+	httpClient, err := config.HTTPClient()
+	if err != nil {
+		return "", err
+	}
+	req, _ := http.NewRequest("POST", requestURL, postBodyBuffer)
+	req.Header.Set("Authorization", fmt.Sprintf("X-Stormfront-API %s", apiToken))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+
+	logging.Debug("Done!")
+
+	defer resp.Body.Close()
+	//Read the response body
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+	responseBody := string(body)
+
+	logging.Debug(fmt.Sprintf("Status code: %v", resp.StatusCode))
+	logging.Debug(fmt.Sprintf("Response body: %s", responseBody))
+
+	var data map[string]interface{}
+	if err := json.Unmarshal(body, &data); err != nil {
+		return "", fmt.Errorf("client has returned error with status code %v", resp.StatusCode)
+	}
+	if resp.StatusCode == http.StatusCreated {
+		logging.Success("Done!")
+		return data["id"].(string), nil
+	}
+	if errMessage, ok := data["error"].(string); ok {
+		return "", errors.New(errMessage)
+	}
+	return "", fmt.Errorf("client has returned error with status code %v", resp.StatusCode)
+}
+
+func CancelBolt(id string) error {
+	host, port, err := GetConnectionDetails()
+	if err != nil {
+		return err
+	}
+
+	logging.Info(fmt.Sprintf("Cancelling bolt %s...", id))
+
+	requestURL := fmt.Sprintf("https://%s:%s/lightning/%s/cancel", host, port, id)
+
+	logging.Debug("Sending POST request to client...")
+	logging.Trace(fmt.Sprintf("Sending request to %s", requestURL))
+
+	apiToken, err := config.GetAPIToken()
+	if err != nil {
+		return err
+	}
+
+	httpClient, err := config.HTTPClient()
+	if err != nil {
+		return err
+	}
+	req, _ := http.NewRequest("POST", requestURL, nil)
+	req.Header.Set("Authorization", fmt.Sprintf("X-Stormfront-API %s", apiToken))
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+
+	logging.Debug("Done!")
+
+	defer resp.Body.Close()
+	//Read the response body
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	responseBody := string(body)
+
+	logging.Debug(fmt.Sprintf("Status code: %v", resp.StatusCode))
+	logging.Debug(fmt.Sprintf("Response body: %s", responseBody))
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		logging.Success("Done!")
+		return nil
+	case http.StatusNotFound:
+		return fmt.Errorf("no bolt with id %s exists", id)
+	}
+	var data map[string]string
+	if err := json.Unmarshal(body, &data); err == nil {
+		if errMessage, ok := data["error"]; ok {
+			return errors.New(errMessage)
+		}
+	}
+	return fmt.Errorf("client has returned error with status code %v", resp.StatusCode)
+}
+
+// FollowBolt prints the output of every run of a bolt as it arrives, each
+// line prefixed with the node it came from, and returns the bolt's final
+// status once all of its runs are done
+func FollowBolt(id string, stdout, stderr io.Writer) (string, error) {
+	printed := map[string]int{}
+	for {
+		bolts, err := GetBoltById(id)
+		if err != nil {
+			return "", err
+		}
+		bolt := bolts[0]
+		status, _ := bolt["status"].(string)
+
+		runs, _ := bolt["runs"].([]interface{})
+		for _, item := range runs {
+			run, ok := item.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			node, _ := run["node"].(string)
+			for stream, writer := range map[string]io.Writer{"stdout": stdout, "stderr": stderr} {
+				output, _ := run[stream].(string)
+				key := fmt.Sprintf("%s/%s", run["id"], stream)
+				if printed[key] > len(output) {
+					continue
+				}
+				chunk := output[printed[key]:]
+				// Partial lines are held back until they are finished
+				if status == "Running" {
+					chunk = chunk[:strings.LastIndex(chunk, "\n")+1]
+				}
+				for _, line := range splitLines(chunk) {
+					fmt.Fprintf(writer, "[%s] %s", node, line)
+				}
+				printed[key] += len(chunk)
+			}
+		}
+
+		if status != "Running" {
+			return status, nil
+		}
+		time.Sleep(BOLT_FOLLOW_DELAY * time.Second)
+	}
+}
+
+func splitLines(output string) []string {
+	lines := []string{}
+	start := 0
+	for idx, char := range output {
+		if char == '\n' {
+			lines = append(lines, output[start:idx+1])
+			start = idx + 1
+		}
+	}
+	if start < len(output) {
+		lines = append(lines, output[start:]+"\n")
+	}
+	return lines
+}
@@ -0,0 +1,77 @@
+package action
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"stormfront-cli/logging"
+)
+
+// Event is something that happened to an object in the cluster, such as an
+// application being scheduled or a node becoming unreachable
+type Event struct {
+	ID         string `json:"id" yaml:"id"`
+	Type       string `json:"type" yaml:"type"`
+	ObjectKind string `json:"object_kind" yaml:"object_kind"`
+	ObjectID   string `json:"object_id" yaml:"object_id"`
+	ObjectName string `json:"object_name" yaml:"object_name"`
+	Namespace  string `json:"namespace" yaml:"namespace"`
+	Node       string `json:"node" yaml:"node"`
+	Message    string `json:"message" yaml:"message"`
+	Time       string `json:"time" yaml:"time"`
+}
+
+// Object names the event's object as kind/name
+func (e Event) Object() string {
+	return fmt.Sprintf("%s/%s", e.ObjectKind, e.ObjectName)
+}
+
+// EventOptions mirror the query parameters of the event endpoint
+type EventOptions struct {
+	Since  string
+	Object string
+	Type   string
+	Watch  bool
+}
+
+func (options EventOptions) query() string {
+	query := url.Values{}
+	if options.Since != "" {
+		query.Set("since", options.Since)
+	}
+	if options.Object != "" {
+		query.Set("object", options.Object)
+	}
+	if options.Type != "" {
+		query.Set("type", options.Type)
+	}
+	if options.Watch {
+		query.Set("watch", "true")
+	}
+	return query.Encode()
+}
+
+func GetEvents(options EventOptions) ([]Event, error) {
+	logging.Info("Getting events...")
+
+	options.Watch = false
+	var events []Event
+	err := getJSON(fmt.Sprintf("api/event?%s", options.query()), &events)
+	return events, err
+}
+
+// WatchEvents calls handle with each matching event as the client sends it,
+// starting with those already recorded, and only returns once the connection
+// is closed or handle returns an error
+func WatchEvents(options EventOptions, handle func(Event) error) error {
+	logging.Info("Watching events...")
+
+	options.Watch = true
+	return readStream(fmt.Sprintf("api/event?%s", options.query()), func(name, data string) error {
+		var event Event
+		if err := json.Unmarshal([]byte(data), &event); err != nil {
+			return err
+		}
+		return handle(event)
+	})
+}
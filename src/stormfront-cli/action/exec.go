@@ -0,0 +1,208 @@
+package action
+
+import (
+	"bufio"
+	"crypto/tls"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"stormfront-cli/config"
+	"stormfront-cli/logging"
+	"strconv"
+	"sync"
+)
+
+// Exec sessions are multiplexed over a single upgraded connection, each frame
+// being a one byte stream id, a big endian uint32 length, and the payload
+const STREAM_STDIN byte = 0
+const STREAM_STDOUT byte = 1
+const STREAM_STDERR byte = 2
+const STREAM_EXIT byte = 3
+
+const EXEC_PROTOCOL = "stormfront-exec"
+
+const MAX_EXEC_REDIRECTS = 3
+
+// ExecOptions describe the command to run and how it is attached to the
+// caller's terminal
+type ExecOptions struct {
+	Command     []string
+	Instance    string
+	Interactive bool
+	TTY         bool
+	Rows        int
+	Cols        int
+}
+
+func (options ExecOptions) query() string {
+	query := url.Values{}
+	query.Set("interactive", strconv.FormatBool(options.Interactive))
+	query.Set("tty", strconv.FormatBool(options.TTY))
+	query.Set("rows", strconv.Itoa(options.Rows))
+	query.Set("cols", strconv.Itoa(options.Cols))
+	if options.Instance != "" {
+		query.Set("instance", options.Instance)
+	}
+	query["command"] = options.Command
+	return query.Encode()
+}
+
+func writeFrame(w io.Writer, stream byte, data []byte) error {
+	header := make([]byte, 5)
+	header[0] = stream
+	binary.BigEndian.PutUint32(header[1:], uint32(len(data)))
+	_, err := w.Write(append(header, data...))
+	return err
+}
+
+func readFrame(r io.Reader) (byte, []byte, error) {
+	header := make([]byte, 5)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return 0, nil, err
+	}
+	data := make([]byte, binary.BigEndian.Uint32(header[1:]))
+	if _, err := io.ReadFull(r, data); err != nil {
+		return 0, nil, err
+	}
+	return header[0], data, nil
+}
+
+// upgradeExec opens an exec session, following redirects from followers to
+// the leader since the connection is taken over once it is accepted
+func upgradeExec(requestURL, apiToken string) (net.Conn, *bufio.Reader, error) {
+	tlsConfig, err := config.TLSConfig()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for redirects := 0; redirects <= MAX_EXEC_REDIRECTS; redirects++ {
+		logging.Trace(fmt.Sprintf("Sending request to %s", requestURL))
+
+		req, _ := http.NewRequest("GET", requestURL, nil)
+		req.Header.Set("Authorization", fmt.Sprintf("X-Stormfront-API %s", apiToken))
+		req.Header.Set("Connection", "Upgrade")
+		req.Header.Set("Upgrade", EXEC_PROTOCOL)
+
+		conn, err := tls.Dial("tcp", req.URL.Host, tlsConfig)
+		if err != nil {
+			return nil, nil, err
+		}
+		if err := req.Write(conn); err != nil {
+			conn.Close()
+			return nil, nil, err
+		}
+		reader := bufio.NewReader(conn)
+		resp, err := http.ReadResponse(reader, req)
+		if err != nil {
+			conn.Close()
+			return nil, nil, err
+		}
+
+		logging.Debug(fmt.Sprintf("Status code: %v", resp.StatusCode))
+
+		switch resp.StatusCode {
+		case http.StatusSwitchingProtocols:
+			return conn, reader, nil
+		case http.StatusTemporaryRedirect:
+			conn.Close()
+			requestURL = resp.Header.Get("Location")
+			continue
+		}
+
+		body, _ := ioutil.ReadAll(resp.Body)
+		conn.Close()
+		responseBody := string(body)
+
+		logging.Debug(fmt.Sprintf("Response body: %s", responseBody))
+
+		var data map[string]string
+		if err := json.Unmarshal(body, &data); err == nil {
+			if errMessage, ok := data["error"]; ok {
+				return nil, nil, errors.New(errMessage)
+			}
+		}
+		if resp.StatusCode == http.StatusNotFound {
+			return nil, nil, errors.New("application does not exist")
+		}
+		return nil, nil, fmt.Errorf("client has returned error with status code %v", resp.StatusCode)
+	}
+	return nil, nil, errors.New("too many redirects while starting exec session")
+}
+
+// ExecById runs a command inside an application, relaying stdin, stdout and
+// stderr until it exits. The command's exit code is returned.
+func ExecById(id string, options ExecOptions, stdin io.Reader, stdout, stderr io.Writer) (int, error) {
+	host, port, err := GetConnectionDetails()
+	if err != nil {
+		return -1, err
+	}
+
+	logging.Info(fmt.Sprintf("Starting exec session in application %s...", id))
+
+	requestURL := fmt.Sprintf("https://%s:%s/api/application/%s/exec?%s", host, port, id, options.query())
+
+	apiToken, err := config.GetAPIToken()
+	if err != nil {
+		return -1, err
+	}
+
+	conn, reader, err := upgradeExec(requestURL, apiToken)
+	if err != nil {
+		return -1, err
+	}
+	defer conn.Close()
+
+	logging.Debug("Done!")
+
+	if options.Interactive {
+		mutex := &sync.Mutex{}
+		go func() {
+			buffer := make([]byte, 32*1024)
+			for {
+				n, err := stdin.Read(buffer)
+				if n > 0 {
+					mutex.Lock()
+					writeErr := writeFrame(conn, STREAM_STDIN, buffer[:n])
+					mutex.Unlock()
+					if writeErr != nil {
+						return
+					}
+				}
+				if err != nil {
+					// An empty frame closes stdin on the other end
+					mutex.Lock()
+					writeFrame(conn, STREAM_STDIN, []byte{})
+					mutex.Unlock()
+					return
+				}
+			}
+		}()
+	} else {
+		writeFrame(conn, STREAM_STDIN, []byte{})
+	}
+
+	for {
+		stream, data, err := readFrame(reader)
+		if err != nil {
+			return -1, fmt.Errorf("exec session ended without an exit code: %v", err)
+		}
+		switch stream {
+		case STREAM_STDOUT:
+			stdout.Write(data)
+		case STREAM_STDERR:
+			stderr.Write(data)
+		case STREAM_EXIT:
+			exitCode, err := strconv.Atoi(string(data))
+			if err != nil {
+				return -1, fmt.Errorf("invalid exit code %s", data)
+			}
+			return exitCode, nil
+		}
+	}
+}
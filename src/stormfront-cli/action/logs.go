@@ -4,92 +4,123 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"net/http"
+	"net/url"
 	"stormfront-cli/config"
 	"stormfront-cli/logging"
+	"strconv"
 )
 
-func GetLogsById(id string) (string, error) {
+// LogOptions mirror the query parameters of the logs endpoint
+type LogOptions struct {
+	Follow     bool
+	Since      string
+	Tail       string
+	Timestamps bool
+}
+
+func (options LogOptions) query() string {
+	query := url.Values{}
+	query.Set("follow", strconv.FormatBool(options.Follow))
+	query.Set("timestamps", strconv.FormatBool(options.Timestamps))
+	if options.Since != "" {
+		query.Set("since", options.Since)
+	}
+	if options.Tail != "" {
+		query.Set("tail", options.Tail)
+	}
+	return query.Encode()
+}
+
+// GetLogsById copies an application's logs to out as the client streams them,
+// when following this only returns once the connection is closed
+func GetLogsById(id string, options LogOptions, out io.Writer) error {
 	host, port, err := GetConnectionDetails()
 	if err != nil {
-		return "", nil
+		return err
 	}
 	logging.Info("Getting logs...")
 
-	requestURL := fmt.Sprintf("http://%s:%s/api/application/%s/logs", host, port, id)
+	requestURL := fmt.Sprintf("https://%s:%s/api/application/%s/logs?%s", host, port, id, options.query())
 
 	logging.Debug("Sending GET request to client...")
 	logging.Trace(fmt.Sprintf("Sending request to %s", requestURL))
 
 	apiToken, err := config.GetAPIToken()
 	if err != nil {
-		return "", err
+		return err
 	}
 
-	httpClient := &http.Client{}
+	httpClient, err := config.HTTPClient()
+	if err != nil {
+		return err
+	}
 	req, _ := http.NewRequest("GET", requestURL, nil)
 	req.Header.Set("Authorization", fmt.Sprintf("X-Stormfront-API %s", apiToken))
 	resp, err := httpClient.Do(req)
 	if err != nil {
-		return "", err
+		return err
 	}
 
 	logging.Debug("Done!")
 
 	defer resp.Body.Close()
+
+	logging.Debug(fmt.Sprintf("Status code: %v", resp.StatusCode))
+
+	if resp.StatusCode == http.StatusOK {
+		_, err = io.Copy(out, resp.Body)
+		return err
+	}
+
 	//Read the response body
 	body, err := ioutil.ReadAll(resp.Body)
 	if err != nil {
-		return "", err
+		return err
 	}
 	responseBody := string(body)
 
-	logging.Debug(fmt.Sprintf("Status code: %v", resp.StatusCode))
 	logging.Debug(fmt.Sprintf("Response body: %s", responseBody))
 
-	if resp.StatusCode == http.StatusOK {
-		responseJSON := map[string]string{}
-		if err = json.Unmarshal(body, &responseJSON); err != nil {
-			return "", err
-		}
-		return responseJSON["logs"], nil
-	} else {
-		var data map[string]string
-		if err := json.Unmarshal([]byte(responseBody), &data); err == nil {
-			if errMessage, ok := data["error"]; ok {
-				logging.Error(errMessage)
-				return "", errors.New(errMessage)
-			}
+	var data map[string]string
+	if err := json.Unmarshal([]byte(responseBody), &data); err == nil {
+		if errMessage, ok := data["error"]; ok {
+			logging.Error(errMessage)
+			return errors.New(errMessage)
 		}
-		return "", fmt.Errorf("client has returned error with status code %v", resp.StatusCode)
 	}
+	return fmt.Errorf("client has returned error with status code %v", resp.StatusCode)
 }
 
-func GetLogsByNameNamespace(name, namespace string) (string, error) {
+func GetLogsByNameNamespace(name, namespace string, options LogOptions, out io.Writer) error {
 	host, port, err := GetConnectionDetails()
 	if err != nil {
-		return "", nil
+		return err
 	}
 
 	logging.Info("Getting applications...")
 
-	requestURL := fmt.Sprintf("http://%s:%s/api/application", host, port)
+	requestURL := fmt.Sprintf("https://%s:%s/api/application", host, port)
 
 	logging.Debug("Sending GET request to client...")
 	logging.Trace(fmt.Sprintf("Sending request to %s", requestURL))
 
 	apiToken, err := config.GetAPIToken()
 	if err != nil {
-		return "", err
+		return err
 	}
 
-	httpClient := &http.Client{}
+	httpClient, err := config.HTTPClient()
+	if err != nil {
+		return err
+	}
 	req, _ := http.NewRequest("GET", requestURL, nil)
 	req.Header.Set("Authorization", fmt.Sprintf("X-Stormfront-API %s", apiToken))
 	resp, err := httpClient.Do(req)
 	if err != nil {
-		return "", err
+		return err
 	}
 
 	logging.Debug("Done!")
@@ -98,7 +129,7 @@ func GetLogsByNameNamespace(name, namespace string) (string, error) {
 	//Read the response body
 	body, err := ioutil.ReadAll(resp.Body)
 	if err != nil {
-		return "", err
+		return err
 	}
 	responseBody := string(body)
 
@@ -108,20 +139,19 @@ func GetLogsByNameNamespace(name, namespace string) (string, error) {
 	if resp.StatusCode == http.StatusOK {
 		data, err := ParseJSON(responseBody)
 		if err != nil {
-			return "", err
+			return err
 		}
 		data, err = FilterNamespace(data, namespace)
 		if err != nil {
-			return "", err
+			return err
 		}
 		for _, application := range data {
 			if application["name"].(string) == name {
 				id := application["id"].(string)
-				output, err := GetLogsById(id)
-				return output, err
+				return GetLogsById(id, options, out)
 			}
 		}
-		return "", fmt.Errorf("no application with name %s in namespace %s exists", name, namespace)
+		return fmt.Errorf("no application with name %s in namespace %s exists", name, namespace)
 	}
-	return "", fmt.Errorf("request failed with status code %d", resp.StatusCode)
+	return fmt.Errorf("request failed with status code %d", resp.StatusCode)
 }
@@ -0,0 +1,216 @@
+package action
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"stormfront-cli/config"
+	"stormfront-cli/logging"
+)
+
+func GetAllNamespaces() ([]map[string]interface{}, error) {
+	host, port, err := GetConnectionDetails()
+	if err != nil {
+		return []map[string]interface{}{}, err
+	}
+
+	logging.Info("Getting namespaces...")
+
+	requestURL := fmt.Sprintf("https://%s:%s/api/namespace", host, port)
+
+	logging.Debug("Sending GET request to client...")
+	logging.Trace(fmt.Sprintf("Sending request to %s", requestURL))
+
+	apiToken, err := config.GetAPIToken()
+	if err != nil {
+		return []map[string]interface{}{}, err
+	}
+
+	httpClient, err := config.HTTPClient()
+	if err != nil {
+		return []map[string]interface{}{}, err
+	}
+	req, _ := http.NewRequest("GET", requestURL, nil)
+	req.Header.Set("Authorization", fmt.Sprintf("X-Stormfront-API %s", apiToken))
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return []map[string]interface{}{}, err
+	}
+
+	logging.Debug("Done!")
+
+	defer resp.Body.Close()
+	//Read the response body
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return []map[string]interface{}{}, err
+	}
+	responseBody := string(body)
+
+	logging.Debug(fmt.Sprintf("Status code: %v", resp.StatusCode))
+	logging.Debug(fmt.Sprintf("Response body: %s", responseBody))
+
+	if resp.StatusCode == http.StatusOK {
+		data, err := ParseJSON(responseBody)
+		if err != nil {
+			return []map[string]interface{}{}, err
+		}
+		return data, nil
+	}
+	return []map[string]interface{}{}, fmt.Errorf("request failed with status code %d", resp.StatusCode)
+}
+
+func NamespaceExists(name string) (bool, error) {
+	namespaces, err := GetAllNamespaces()
+	if err != nil {
+		return false, err
+	}
+	for _, namespace := range namespaces {
+		if namespace["name"].(string) == name {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func CreateNamespace(name string) error {
+	host, port, err := GetConnectionDetails()
+	if err != nil {
+		return err
+	}
+
+	logging.Info(fmt.Sprintf("Creating namespace %s...", name))
+
+	requestURL := fmt.Sprintf("https://%s:%s/api/namespace", host, port)
+
+	logging.Debug("Sending POST request to client...")
+	logging.Trace(fmt.Sprintf("Sending request to %s", requestURL))
+
+	apiToken, err := config.GetAPIToken()
+	if err != nil {
+		return err
+	}
+
+	postBody, _ := json.Marshal(map[string]string{"name": name})
+	postBodyBuffer := bytes.NewBuffer(postBody)
+
+	httpClient, err := config.HTTPClient()
+	if err != nil {
+		return err
+	}
+	req, _ := http.NewRequest("POST", requestURL, postBodyBuffer)
+	req.Header.Set("Authorization", fmt.Sprintf("X-Stormfront-API %s", apiToken))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+
+	logging.Debug("Done!")
+
+	return checkNamespaceResponse(resp)
+}
+
+func RenameNamespace(oldName, newName string) error {
+	host, port, err := GetConnectionDetails()
+	if err != nil {
+		return err
+	}
+
+	logging.Info(fmt.Sprintf("Renaming namespace %s to %s...", oldName, newName))
+
+	requestURL := fmt.Sprintf("https://%s:%s/api/namespace/%s", host, port, oldName)
+
+	logging.Debug("Sending PATCH request to client...")
+	logging.Trace(fmt.Sprintf("Sending request to %s", requestURL))
+
+	apiToken, err := config.GetAPIToken()
+	if err != nil {
+		return err
+	}
+
+	patchBody, _ := json.Marshal(map[string]string{"name": newName})
+	patchBodyBuffer := bytes.NewBuffer(patchBody)
+
+	httpClient, err := config.HTTPClient()
+	if err != nil {
+		return err
+	}
+	req, _ := http.NewRequest("PATCH", requestURL, patchBodyBuffer)
+	req.Header.Set("Authorization", fmt.Sprintf("X-Stormfront-API %s", apiToken))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+
+	logging.Debug("Done!")
+
+	return checkNamespaceResponse(resp)
+}
+
+func DeleteNamespace(name string) error {
+	host, port, err := GetConnectionDetails()
+	if err != nil {
+		return err
+	}
+
+	logging.Info(fmt.Sprintf("Deleting namespace %s...", name))
+
+	requestURL := fmt.Sprintf("https://%s:%s/api/namespace/%s", host, port, name)
+
+	logging.Debug("Sending DELETE request to client...")
+	logging.Trace(fmt.Sprintf("Sending request to %s", requestURL))
+
+	apiToken, err := config.GetAPIToken()
+	if err != nil {
+		return err
+	}
+
+	httpClient, err := config.HTTPClient()
+	if err != nil {
+		return err
+	}
+	req, _ := http.NewRequest("DELETE", requestURL, nil)
+	req.Header.Set("Authorization", fmt.Sprintf("X-Stormfront-API %s", apiToken))
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+
+	logging.Debug("Done!")
+
+	return checkNamespaceResponse(resp)
+}
+
+func checkNamespaceResponse(resp *http.Response) error {
+	defer resp.Body.Close()
+	//Read the response body
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	responseBody := string(body)
+
+	logging.Debug(fmt.Sprintf("Status code: %v", resp.StatusCode))
+	logging.Debug(fmt.Sprintf("Response body: %s", responseBody))
+
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusCreated, http.StatusNoContent:
+		logging.Success("Done!")
+		return nil
+	case http.StatusNotFound:
+		return errors.New("namespace does not exist")
+	}
+
+	var data map[string]string
+	if err := json.Unmarshal([]byte(responseBody), &data); err == nil {
+		if errMessage, ok := data["error"]; ok {
+			return errors.New(errMessage)
+		}
+	}
+	return fmt.Errorf("client has returned error with status code %v", resp.StatusCode)
+}
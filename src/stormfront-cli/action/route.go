@@ -1,6 +1,7 @@
 package action
 
 import (
+	"bytes"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -18,7 +19,7 @@ func GetAllRoutes(namespace string) ([]map[string]interface{}, error) {
 
 	logging.Info("Getting routes...")
 
-	requestURL := fmt.Sprintf("http://%s:%s/api/route", host, port)
+	requestURL := fmt.Sprintf("https://%s:%s/api/route", host, port)
 
 	logging.Debug("Sending GET request to client...")
 	logging.Trace(fmt.Sprintf("Sending request to %s", requestURL))
@@ -28,7 +29,10 @@ func GetAllRoutes(namespace string) ([]map[string]interface{}, error) {
 		return []map[string]interface{}{}, err
 	}
 
-	httpClient := &http.Client{}
+	httpClient, err := config.HTTPClient()
+	if err != nil {
+		return []map[string]interface{}{}, err
+	}
 	req, _ := http.NewRequest("GET", requestURL, nil)
 	req.Header.Set("Authorization", fmt.Sprintf("X-Stormfront-API %s", apiToken))
 	resp, err := httpClient.Do(req)
@@ -71,7 +75,7 @@ func GetRouteById(id string) ([]map[string]interface{}, error) {
 
 	logging.Info(fmt.Sprintf("Getting route %s...", id))
 
-	requestURL := fmt.Sprintf("http://%s:%s/api/route/%s", host, port, id)
+	requestURL := fmt.Sprintf("https://%s:%s/api/route/%s", host, port, id)
 
 	logging.Debug("Sending GET request to client...")
 	logging.Trace(fmt.Sprintf("Sending request to %s", requestURL))
@@ -81,7 +85,10 @@ func GetRouteById(id string) ([]map[string]interface{}, error) {
 		return []map[string]interface{}{}, err
 	}
 
-	httpClient := &http.Client{}
+	httpClient, err := config.HTTPClient()
+	if err != nil {
+		return []map[string]interface{}{}, err
+	}
 	req, _ := http.NewRequest("GET", requestURL, nil)
 	req.Header.Set("Authorization", fmt.Sprintf("X-Stormfront-API %s", apiToken))
 	resp, err := httpClient.Do(req)
@@ -120,7 +127,7 @@ func GetRouteByNameNamespace(name, namespace string) ([]map[string]interface{},
 
 	logging.Info("Getting routes...")
 
-	requestURL := fmt.Sprintf("http://%s:%s/api/route", host, port)
+	requestURL := fmt.Sprintf("https://%s:%s/api/route", host, port)
 
 	logging.Debug("Sending GET request to client...")
 	logging.Trace(fmt.Sprintf("Sending request to %s", requestURL))
@@ -130,7 +137,10 @@ func GetRouteByNameNamespace(name, namespace string) ([]map[string]interface{},
 		return []map[string]interface{}{}, err
 	}
 
-	httpClient := &http.Client{}
+	httpClient, err := config.HTTPClient()
+	if err != nil {
+		return []map[string]interface{}{}, err
+	}
 	req, _ := http.NewRequest("GET", requestURL, nil)
 	req.Header.Set("Authorization", fmt.Sprintf("X-Stormfront-API %s", apiToken))
 	resp, err := httpClient.Do(req)
@@ -161,7 +171,8 @@ func GetRouteByNameNamespace(name, namespace string) ([]map[string]interface{},
 			return []map[string]interface{}{}, err
 		}
 		for _, route := range data {
-			if route["name"].(string) == name {
+			// Routes created before they were named have no name to match
+			if routeName, ok := route["name"].(string); ok && routeName == name {
 				return []map[string]interface{}{route}, nil
 			}
 		}
@@ -178,7 +189,7 @@ func DeleteRouteById(id string) error {
 
 	logging.Info(fmt.Sprintf("Deleting route %s...", id))
 
-	requestURL := fmt.Sprintf("http://%s:%s/api/route/%s", host, port, id)
+	requestURL := fmt.Sprintf("https://%s:%s/api/route/%s", host, port, id)
 
 	logging.Debug("Sending DELETE request to client...")
 	logging.Trace(fmt.Sprintf("Sending request to %s", requestURL))
@@ -188,7 +199,10 @@ func DeleteRouteById(id string) error {
 		return err
 	}
 
-	httpClient := &http.Client{}
+	httpClient, err := config.HTTPClient()
+	if err != nil {
+		return err
+	}
 	req, _ := http.NewRequest("DELETE", requestURL, nil)
 	req.Header.Set("Authorization", fmt.Sprintf("X-Stormfront-API %s", apiToken))
 	resp, err := httpClient.Do(req)
@@ -235,3 +249,67 @@ func DeleteRouteByNameNamespace(name, namespace string) error {
 	err = DeleteRouteById(id)
 	return err
 }
+
+func UpdateRouteById(id string, definition map[string]interface{}) ([]string, error) {
+	host, port, err := GetConnectionDetails()
+	if err != nil {
+		return []string{}, err
+	}
+
+	logging.Info(fmt.Sprintf("Updating route %s...", id))
+
+	requestURL := fmt.Sprintf("https://%s:%s/api/route/%s", host, port, id)
+
+	logging.Debug("Sending PATCH request to client...")
+	logging.Trace(fmt.Sprintf("Sending request to %s", requestURL))
+
+	apiToken, err := config.GetAPIToken()
+	if err != nil {
+		return []string{}, err
+	}
+
+	patchBody, _ := json.Marshal(definition)
+	patchBodyBuffer := bytes.NewBuffer(patchBody)
+
+	httpClient, err := config.HTTPClient()
+	if err != nil {
+		return []string{}, err
+	}
+	req, _ := http.NewRequest("PATCH", requestURL, patchBodyBuffer)
+	req.Header.Set("Authorization", fmt.Sprintf("X-Stormfront-API %s", apiToken))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return []string{}, err
+	}
+
+	logging.Debug("Done!")
+
+	defer resp.Body.Close()
+	//Read the response body
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return []string{}, err
+	}
+	responseBody := string(body)
+
+	logging.Debug(fmt.Sprintf("Status code: %v", resp.StatusCode))
+	logging.Debug(fmt.Sprintf("Response body: %s", responseBody))
+
+	if resp.StatusCode == http.StatusOK {
+		var data struct {
+			Changed []string `json:"changed"`
+		}
+		if err := json.Unmarshal(body, &data); err != nil {
+			return []string{}, err
+		}
+		return data.Changed, nil
+	}
+	var data map[string]string
+	if err := json.Unmarshal([]byte(responseBody), &data); err == nil {
+		if errMessage, ok := data["error"]; ok {
+			return []string{}, errors.New(errMessage)
+		}
+	}
+	return []string{}, fmt.Errorf("client has returned error with status code %v", resp.StatusCode)
+}
@@ -0,0 +1,246 @@
+package action
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"stormfront-cli/config"
+	"stormfront-cli/logging"
+)
+
+func GetAllSecrets(namespace string) ([]map[string]interface{}, error) {
+	host, port, err := GetConnectionDetails()
+	if err != nil {
+		return []map[string]interface{}{}, err
+	}
+
+	logging.Info("Getting secrets...")
+
+	requestURL := fmt.Sprintf("https://%s:%s/api/secret", host, port)
+
+	logging.Debug("Sending GET request to client...")
+	logging.Trace(fmt.Sprintf("Sending request to %s", requestURL))
+
+	apiToken, err := config.GetAPIToken()
+	if err != nil {
+		return []map[string]interface{}{}, err
+	}
+
+	httpClient, err := config.HTTPClient()
+	if err != nil {
+		return []map[string]interface{}{}, err
+	}
+	req, _ := http.NewRequest("GET", requestURL, nil)
+	req.Header.Set("Authorization", fmt.Sprintf("X-Stormfront-API %s", apiToken))
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return []map[string]interface{}{}, err
+	}
+
+	logging.Debug("Done!")
+
+	defer resp.Body.Close()
+	//Read the response body
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return []map[string]interface{}{}, err
+	}
+	responseBody := string(body)
+
+	logging.Debug(fmt.Sprintf("Status code: %v", resp.StatusCode))
+	logging.Debug(fmt.Sprintf("Response body: %s", responseBody))
+
+	if resp.StatusCode == http.StatusOK {
+		data, err := ParseJSON(responseBody)
+		if err != nil {
+			return []map[string]interface{}{}, err
+		}
+		data, err = FilterNamespace(data, namespace)
+		if err != nil {
+			return []map[string]interface{}{}, err
+		}
+		return data, nil
+	}
+	return []map[string]interface{}{}, fmt.Errorf("request failed with status code %d", resp.StatusCode)
+}
+
+func GetSecretById(id string) ([]map[string]interface{}, error) {
+	host, port, err := GetConnectionDetails()
+	if err != nil {
+		return []map[string]interface{}{}, err
+	}
+
+	logging.Info(fmt.Sprintf("Getting secret %s...", id))
+
+	requestURL := fmt.Sprintf("https://%s:%s/api/secret/%s", host, port, id)
+
+	logging.Debug("Sending GET request to client...")
+	logging.Trace(fmt.Sprintf("Sending request to %s", requestURL))
+
+	apiToken, err := config.GetAPIToken()
+	if err != nil {
+		return []map[string]interface{}{}, err
+	}
+
+	httpClient, err := config.HTTPClient()
+	if err != nil {
+		return []map[string]interface{}{}, err
+	}
+	req, _ := http.NewRequest("GET", requestURL, nil)
+	req.Header.Set("Authorization", fmt.Sprintf("X-Stormfront-API %s", apiToken))
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return []map[string]interface{}{}, err
+	}
+
+	logging.Debug("Done!")
+
+	defer resp.Body.Close()
+	//Read the response body
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return []map[string]interface{}{}, err
+	}
+	responseBody := string(body)
+
+	logging.Debug(fmt.Sprintf("Status code: %v", resp.StatusCode))
+	logging.Debug(fmt.Sprintf("Response body: %s", responseBody))
+
+	if resp.StatusCode == http.StatusOK {
+		data, err := ParseJSON(responseBody)
+		if err != nil {
+			return []map[string]interface{}{}, err
+		}
+		return data, nil
+	}
+	return []map[string]interface{}{}, fmt.Errorf("request failed with status code %d", resp.StatusCode)
+}
+
+func GetSecretByNameNamespace(name, namespace string) ([]map[string]interface{}, error) {
+	secrets, err := GetAllSecrets(namespace)
+	if err != nil {
+		return []map[string]interface{}{}, err
+	}
+	for _, secret := range secrets {
+		if secret["name"].(string) == name {
+			return []map[string]interface{}{secret}, nil
+		}
+	}
+	return []map[string]interface{}{}, fmt.Errorf("no secret with name %s in namespace %s exists", name, namespace)
+}
+
+func CreateSecret(name, namespace string, data map[string]string) error {
+	host, port, err := GetConnectionDetails()
+	if err != nil {
+		return err
+	}
+
+	logging.Info(fmt.Sprintf("Creating secret %s...", name))
+
+	requestURL := fmt.Sprintf("https://%s:%s/api/secret", host, port)
+
+	logging.Debug("Sending POST request to client...")
+	logging.Trace(fmt.Sprintf("Sending request to %s", requestURL))
+
+	apiToken, err := config.GetAPIToken()
+	if err != nil {
+		return err
+	}
+
+	postBody, _ := json.Marshal(map[string]interface{}{"name": name, "namespace": namespace, "data": data})
+	postBodyBuffer := bytes.NewBuffer(postBody)
+
+	httpClient, err := config.HTTPClient()
+	if err != nil {
+		return err
+	}
+	req, _ := http.NewRequest("POST", requestURL, postBodyBuffer)
+	req.Header.Set("Authorization", fmt.Sprintf("X-Stormfront-API %s", apiToken))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+
+	logging.Debug("Done!")
+
+	return checkSecretResponse(resp)
+}
+
+func DeleteSecretById(id string) error {
+	host, port, err := GetConnectionDetails()
+	if err != nil {
+		return err
+	}
+
+	logging.Info(fmt.Sprintf("Deleting secret %s...", id))
+
+	requestURL := fmt.Sprintf("https://%s:%s/api/secret/%s", host, port, id)
+
+	logging.Debug("Sending DELETE request to client...")
+	logging.Trace(fmt.Sprintf("Sending request to %s", requestURL))
+
+	apiToken, err := config.GetAPIToken()
+	if err != nil {
+		return err
+	}
+
+	httpClient, err := config.HTTPClient()
+	if err != nil {
+		return err
+	}
+	req, _ := http.NewRequest("DELETE", requestURL, nil)
+	req.Header.Set("Authorization", fmt.Sprintf("X-Stormfront-API %s", apiToken))
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+
+	logging.Debug("Done!")
+
+	return checkSecretResponse(resp)
+}
+
+func DeleteSecretByNameNamespace(name, namespace string) error {
+	secrets, err := GetSecretByNameNamespace(name, namespace)
+	if err != nil {
+		return err
+	}
+
+	id := secrets[0]["id"].(string)
+
+	err = DeleteSecretById(id)
+	return err
+}
+
+func checkSecretResponse(resp *http.Response) error {
+	defer resp.Body.Close()
+	//Read the response body
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	responseBody := string(body)
+
+	logging.Debug(fmt.Sprintf("Status code: %v", resp.StatusCode))
+	logging.Debug(fmt.Sprintf("Response body: %s", responseBody))
+
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusCreated, http.StatusNoContent:
+		logging.Success("Done!")
+		return nil
+	case http.StatusNotFound:
+		return errors.New("secret does not exist")
+	}
+
+	var data map[string]string
+	if err := json.Unmarshal([]byte(responseBody), &data); err == nil {
+		if errMessage, ok := data["error"]; ok {
+			return errors.New(errMessage)
+		}
+	}
+	return fmt.Errorf("client has returned error with status code %v", resp.StatusCode)
+}
@@ -0,0 +1,140 @@
+package action
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"stormfront-cli/config"
+	"stormfront-cli/logging"
+	"stormfront-cli/utils"
+)
+
+const SPARKLINE_WIDTH = 30
+
+// StatsPoint is one sample of a node or application instance's resource usage
+// history, older points are averages over longer intervals
+type StatsPoint struct {
+	Time   string             `json:"time" yaml:"time"`
+	Values map[string]float64 `json:"values" yaml:"values"`
+}
+
+type NodeStats struct {
+	ID     string       `json:"id" yaml:"id"`
+	Range  string       `json:"range" yaml:"range"`
+	Points []StatsPoint `json:"points" yaml:"points"`
+}
+
+type InstanceStats struct {
+	Node   string       `json:"node" yaml:"node"`
+	Points []StatsPoint `json:"points" yaml:"points"`
+	Error  string       `json:"error,omitempty" yaml:"error,omitempty"`
+}
+
+type ApplicationStats struct {
+	ID        string                   `json:"id" yaml:"id"`
+	Name      string                   `json:"name" yaml:"name"`
+	Namespace string                   `json:"namespace" yaml:"namespace"`
+	Range     string                   `json:"range" yaml:"range"`
+	Instances map[string]InstanceStats `json:"instances" yaml:"instances"`
+}
+
+// Series returns the values of key across points, oldest first
+func Series(points []StatsPoint, key string) []float64 {
+	values := []float64{}
+	for _, point := range points {
+		if value, ok := point.Values[key]; ok {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+// Usage formats the latest value of a percentage key along with a sparkline of
+// its history for display in a table
+func Usage(points []StatsPoint, key string) (string, string) {
+	values := Series(points, key)
+	if len(values) == 0 {
+		return "-", ""
+	}
+	return fmt.Sprintf("%.1f%%", values[len(values)-1]), utils.Sparkline(values, 0, 100, SPARKLINE_WIDTH)
+}
+
+func getJSON(path string, out interface{}) error {
+	host, port, err := GetConnectionDetails()
+	if err != nil {
+		return err
+	}
+
+	requestURL := fmt.Sprintf("https://%s:%s/%s", host, port, path)
+
+	logging.Debug("Sending GET request to client...")
+	logging.Trace(fmt.Sprintf("Sending request to %s", requestURL))
+
+	apiToken, err := config.GetAPIToken()
+	if err != nil {
+		return err
+	}
+
+	httpClient, err := config.HTTPClient()
+	if err != nil {
+		return err
+	}
+	req, _ := http.NewRequest("GET", requestURL, nil)
+	req.Header.Set("Authorization", fmt.Sprintf("X-Stormfront-API %s", apiToken))
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+
+	logging.Debug("Done!")
+
+	defer resp.Body.Close()
+	//Read the response body
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	responseBody := string(body)
+
+	logging.Debug(fmt.Sprintf("Status code: %v", resp.StatusCode))
+	logging.Debug(fmt.Sprintf("Response body: %s", responseBody))
+
+	if resp.StatusCode != http.StatusOK {
+		var data map[string]string
+		if err := json.Unmarshal(body, &data); err == nil {
+			if errMessage, ok := data["error"]; ok {
+				return errors.New(errMessage)
+			}
+		}
+		return fmt.Errorf("client has returned error with status code %v", resp.StatusCode)
+	}
+
+	return json.Unmarshal(body, out)
+}
+
+func GetNodeStats(id, statsRange string) (NodeStats, error) {
+	logging.Info(fmt.Sprintf("Getting stats for node %s...", id))
+
+	var stats NodeStats
+	err := getJSON(fmt.Sprintf("api/node/%s/stats?%s", id, url.Values{"range": {statsRange}}.Encode()), &stats)
+	return stats, err
+}
+
+func GetApplicationStatsById(id, statsRange string) (ApplicationStats, error) {
+	logging.Info(fmt.Sprintf("Getting stats for application %s...", id))
+
+	var stats ApplicationStats
+	err := getJSON(fmt.Sprintf("api/application/%s/stats?%s", id, url.Values{"range": {statsRange}}.Encode()), &stats)
+	return stats, err
+}
+
+func GetAllNodes() ([]map[string]interface{}, error) {
+	logging.Info("Getting nodes...")
+
+	var nodes []map[string]interface{}
+	err := getJSON("api/node", &nodes)
+	return nodes, err
+}
@@ -0,0 +1,331 @@
+package action
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"stormfront-cli/config"
+	"stormfront-cli/logging"
+)
+
+func GetAllVolumes(namespace string) ([]map[string]interface{}, error) {
+	host, port, err := GetConnectionDetails()
+	if err != nil {
+		return []map[string]interface{}{}, err
+	}
+
+	logging.Info("Getting volumes...")
+
+	requestURL := fmt.Sprintf("https://%s:%s/api/volume", host, port)
+
+	logging.Debug("Sending GET request to client...")
+	logging.Trace(fmt.Sprintf("Sending request to %s", requestURL))
+
+	apiToken, err := config.GetAPIToken()
+	if err != nil {
+		return []map[string]interface{}{}, err
+	}
+
+	httpClient, err := config.HTTPClient()
+	if err != nil {
+		return []map[string]interface{}{}, err
+	}
+	req, _ := http.NewRequest("GET", requestURL, nil)
+	req.Header.Set("Authorization", fmt.Sprintf("X-Stormfront-API %s", apiToken))
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return []map[string]interface{}{}, err
+	}
+
+	logging.Debug("Done!")
+
+	defer resp.Body.Close()
+	//Read the response body
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return []map[string]interface{}{}, err
+	}
+	responseBody := string(body)
+
+	logging.Debug(fmt.Sprintf("Status code: %v", resp.StatusCode))
+	logging.Debug(fmt.Sprintf("Response body: %s", responseBody))
+
+	if resp.StatusCode == http.StatusOK {
+		data, err := ParseJSON(responseBody)
+		if err != nil {
+			return []map[string]interface{}{}, err
+		}
+		data, err = FilterNamespace(data, namespace)
+		if err != nil {
+			return []map[string]interface{}{}, err
+		}
+		return data, nil
+	}
+	return []map[string]interface{}{}, fmt.Errorf("request failed with status code %d", resp.StatusCode)
+}
+
+func GetVolumeById(id string) ([]map[string]interface{}, error) {
+	host, port, err := GetConnectionDetails()
+	if err != nil {
+		return []map[string]interface{}{}, err
+	}
+
+	logging.Info(fmt.Sprintf("Getting volume %s...", id))
+
+	requestURL := fmt.Sprintf("https://%s:%s/api/volume/%s", host, port, id)
+
+	logging.Debug("Sending GET request to client...")
+	logging.Trace(fmt.Sprintf("Sending request to %s", requestURL))
+
+	apiToken, err := config.GetAPIToken()
+	if err != nil {
+		return []map[string]interface{}{}, err
+	}
+
+	httpClient, err := config.HTTPClient()
+	if err != nil {
+		return []map[string]interface{}{}, err
+	}
+	req, _ := http.NewRequest("GET", requestURL, nil)
+	req.Header.Set("Authorization", fmt.Sprintf("X-Stormfront-API %s", apiToken))
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return []map[string]interface{}{}, err
+	}
+
+	logging.Debug("Done!")
+
+	defer resp.Body.Close()
+	//Read the response body
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return []map[string]interface{}{}, err
+	}
+	responseBody := string(body)
+
+	logging.Debug(fmt.Sprintf("Status code: %v", resp.StatusCode))
+	logging.Debug(fmt.Sprintf("Response body: %s", responseBody))
+
+	if resp.StatusCode == http.StatusOK {
+		data, err := ParseJSON(responseBody)
+		if err != nil {
+			return []map[string]interface{}{}, err
+		}
+		return data, nil
+	}
+	return []map[string]interface{}{}, fmt.Errorf("request failed with status code %d", resp.StatusCode)
+}
+
+func GetVolumeByNameNamespace(name, namespace string) ([]map[string]interface{}, error) {
+	volumes, err := GetAllVolumes(namespace)
+	if err != nil {
+		return []map[string]interface{}{}, err
+	}
+	for _, volume := range volumes {
+		if volume["name"].(string) == name {
+			return []map[string]interface{}{volume}, nil
+		}
+	}
+	return []map[string]interface{}{}, fmt.Errorf("no volume with name %s in namespace %s exists", name, namespace)
+}
+
+func CreateVolume(definition map[string]interface{}) error {
+	host, port, err := GetConnectionDetails()
+	if err != nil {
+		return err
+	}
+
+	logging.Info(fmt.Sprintf("Creating volume %s...", definition["name"]))
+
+	requestURL := fmt.Sprintf("https://%s:%s/api/volume", host, port)
+
+	logging.Debug("Sending POST request to client...")
+	logging.Trace(fmt.Sprintf("Sending request to %s", requestURL))
+
+	apiToken, err := config.GetAPIToken()
+	if err != nil {
+		return err
+	}
+
+	postBody, _ := json.Marshal(definition)
+	postBodyBuffer := bytes.NewBuffer(postBody)
+
+	httpClient, err := config.HTTPClient()
+	if err != nil {
+		return err
+	}
+	req, _ := http.NewRequest("POST", requestURL, postBodyBuffer)
+	req.Header.Set("Authorization", fmt.Sprintf("X-Stormfront-API %s", apiToken))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+
+	logging.Debug("Done!")
+
+	defer resp.Body.Close()
+	//Read the response body
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	responseBody := string(body)
+
+	logging.Debug(fmt.Sprintf("Status code: %v", resp.StatusCode))
+	logging.Debug(fmt.Sprintf("Response body: %s", responseBody))
+
+	if resp.StatusCode == http.StatusOK || resp.StatusCode == http.StatusCreated {
+		logging.Success("Done!")
+		return nil
+	}
+	var data map[string]string
+	if err := json.Unmarshal([]byte(responseBody), &data); err == nil {
+		if errMessage, ok := data["error"]; ok {
+			return errors.New(errMessage)
+		}
+	}
+	return fmt.Errorf("client has returned error with status code %v", resp.StatusCode)
+}
+
+func UpdateVolumeById(id string, definition map[string]interface{}) ([]string, error) {
+	host, port, err := GetConnectionDetails()
+	if err != nil {
+		return []string{}, err
+	}
+
+	logging.Info(fmt.Sprintf("Updating volume %s...", id))
+
+	requestURL := fmt.Sprintf("https://%s:%s/api/volume/%s", host, port, id)
+
+	logging.Debug("Sending PATCH request to client...")
+	logging.Trace(fmt.Sprintf("Sending request to %s", requestURL))
+
+	apiToken, err := config.GetAPIToken()
+	if err != nil {
+		return []string{}, err
+	}
+
+	patchBody, _ := json.Marshal(definition)
+	patchBodyBuffer := bytes.NewBuffer(patchBody)
+
+	httpClient, err := config.HTTPClient()
+	if err != nil {
+		return []string{}, err
+	}
+	req, _ := http.NewRequest("PATCH", requestURL, patchBodyBuffer)
+	req.Header.Set("Authorization", fmt.Sprintf("X-Stormfront-API %s", apiToken))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return []string{}, err
+	}
+
+	logging.Debug("Done!")
+
+	defer resp.Body.Close()
+	//Read the response body
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return []string{}, err
+	}
+	responseBody := string(body)
+
+	logging.Debug(fmt.Sprintf("Status code: %v", resp.StatusCode))
+	logging.Debug(fmt.Sprintf("Response body: %s", responseBody))
+
+	if resp.StatusCode == http.StatusOK {
+		var data struct {
+			Changed []string `json:"changed"`
+		}
+		if err := json.Unmarshal(body, &data); err != nil {
+			return []string{}, err
+		}
+		return data.Changed, nil
+	}
+	var data map[string]string
+	if err := json.Unmarshal([]byte(responseBody), &data); err == nil {
+		if errMessage, ok := data["error"]; ok {
+			return []string{}, errors.New(errMessage)
+		}
+	}
+	return []string{}, fmt.Errorf("client has returned error with status code %v", resp.StatusCode)
+}
+
+func DeleteVolumeById(id string) error {
+	host, port, err := GetConnectionDetails()
+	if err != nil {
+		return err
+	}
+
+	logging.Info(fmt.Sprintf("Deleting volume %s...", id))
+
+	requestURL := fmt.Sprintf("https://%s:%s/api/volume/%s", host, port, id)
+
+	logging.Debug("Sending DELETE request to client...")
+	logging.Trace(fmt.Sprintf("Sending request to %s", requestURL))
+
+	apiToken, err := config.GetAPIToken()
+	if err != nil {
+		return err
+	}
+
+	httpClient, err := config.HTTPClient()
+	if err != nil {
+		return err
+	}
+	req, _ := http.NewRequest("DELETE", requestURL, nil)
+	req.Header.Set("Authorization", fmt.Sprintf("X-Stormfront-API %s", apiToken))
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+
+	logging.Debug("Done!")
+
+	return checkVolumeResponse(resp)
+}
+
+func DeleteVolumeByNameNamespace(name, namespace string) error {
+	volumes, err := GetVolumeByNameNamespace(name, namespace)
+	if err != nil {
+		return err
+	}
+
+	id := volumes[0]["id"].(string)
+
+	err = DeleteVolumeById(id)
+	return err
+}
+
+func checkVolumeResponse(resp *http.Response) error {
+	defer resp.Body.Close()
+	//Read the response body
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	responseBody := string(body)
+
+	logging.Debug(fmt.Sprintf("Status code: %v", resp.StatusCode))
+	logging.Debug(fmt.Sprintf("Response body: %s", responseBody))
+
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusCreated, http.StatusNoContent:
+		logging.Success("Done!")
+		return nil
+	case http.StatusNotFound:
+		return errors.New("volume does not exist")
+	}
+
+	var data map[string]string
+	if err := json.Unmarshal([]byte(responseBody), &data); err == nil {
+		if errMessage, ok := data["error"]; ok {
+			return errors.New(errMessage)
+		}
+	}
+	return fmt.Errorf("client has returned error with status code %v", resp.StatusCode)
+}
@@ -0,0 +1,191 @@
+package action
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"stormfront-cli/config"
+	"stormfront-cli/logging"
+	"strings"
+)
+
+const WATCH_ADDED = "ADDED"
+const WATCH_MODIFIED = "MODIFIED"
+const WATCH_DELETED = "DELETED"
+const WATCH_BOOKMARK = "BOOKMARK"
+
+// WatchEvent is a change to a watched list, a bookmark is sent after every
+// batch of changes once the list is up to date
+type WatchEvent struct {
+	Type            string                 `json:"type" yaml:"type"`
+	ResourceVersion string                 `json:"resource_version" yaml:"resource_version"`
+	Object          map[string]interface{} `json:"object,omitempty" yaml:"object,omitempty"`
+}
+
+// errWatchExpired is returned by readStream when the client no longer holds
+// the changes after the resource version a watch resumed from
+var errWatchExpired = errors.New("watch resource version has expired")
+
+// WatchList calls handle with each change to the list at path as the client
+// sends it, starting with every object already in the list. When the stream
+// ends it is resumed from the last bookmark. If the client no longer holds
+// the changes since then the full list is sent again, and objects which were
+// removed in the meantime are handled as deleted at the bookmark after it.
+func WatchList(path string, handle func(WatchEvent) error) error {
+	known := map[string]map[string]interface{}{}
+	version := ""
+	for {
+		var relisted map[string]bool
+		query := "watch=true"
+		if version == "" {
+			relisted = map[string]bool{}
+		} else {
+			query = fmt.Sprintf("%s&resource_version=%s", query, url.QueryEscape(version))
+		}
+
+		var handleErr error
+		received := false
+		err := readStream(fmt.Sprintf("%s?%s", path, query), func(name, data string) error {
+			var event WatchEvent
+			if err := json.Unmarshal([]byte(data), &event); err != nil {
+				return err
+			}
+			received = true
+
+			id := fmt.Sprintf("%v", event.Object["id"])
+			switch event.Type {
+			case WATCH_BOOKMARK:
+				for knownID, object := range known {
+					if relisted == nil || relisted[knownID] {
+						continue
+					}
+					delete(known, knownID)
+					if handleErr = handle(WatchEvent{Type: WATCH_DELETED, ResourceVersion: event.ResourceVersion, Object: object}); handleErr != nil {
+						return handleErr
+					}
+				}
+				relisted = nil
+				version = event.ResourceVersion
+			case WATCH_DELETED:
+				delete(known, id)
+			default:
+				known[id] = event.Object
+				if relisted != nil {
+					relisted[id] = true
+				}
+			}
+			handleErr = handle(event)
+			return handleErr
+		})
+		if handleErr != nil {
+			return handleErr
+		}
+		if err == errWatchExpired && version != "" {
+			logging.Debug(fmt.Sprintf("Watch can not resume from %s, fetching the full list again", version))
+			version = ""
+			continue
+		}
+		if !received {
+			return err
+		}
+		logging.Debug(fmt.Sprintf("Watch stream ended, resuming from %s: %v", version, err))
+	}
+}
+
+// readStream reads the server-sent events returned by path, calling handle
+// with the name and data of each until the connection is closed or handle
+// returns an error
+func readStream(path string, handle func(string, string) error) error {
+	host, port, err := GetConnectionDetails()
+	if err != nil {
+		return err
+	}
+
+	requestURL := fmt.Sprintf("https://%s:%s/%s", host, port, path)
+
+	logging.Debug("Sending GET request to client...")
+	logging.Trace(fmt.Sprintf("Sending request to %s", requestURL))
+
+	apiToken, err := config.GetAPIToken()
+	if err != nil {
+		return err
+	}
+
+	httpClient, err := config.HTTPClient()
+	if err != nil {
+		return err
+	}
+	req, _ := http.NewRequest("GET", requestURL, nil)
+	req.Header.Set("Authorization", fmt.Sprintf("X-Stormfront-API %s", apiToken))
+	req.Header.Set("Accept", "text/event-stream")
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+
+	logging.Debug("Done!")
+
+	defer resp.Body.Close()
+
+	logging.Debug(fmt.Sprintf("Status code: %v", resp.StatusCode))
+
+	if resp.StatusCode != http.StatusOK {
+		body, err := ioutil.ReadAll(resp.Body)
+		if err != nil {
+			return err
+		}
+		logging.Debug(fmt.Sprintf("Response body: %s", string(body)))
+
+		if resp.StatusCode == http.StatusGone {
+			return errWatchExpired
+		}
+
+		var data map[string]string
+		if err := json.Unmarshal(body, &data); err == nil {
+			if errMessage, ok := data["error"]; ok {
+				return errors.New(errMessage)
+			}
+		}
+		return fmt.Errorf("client has returned error with status code %v", resp.StatusCode)
+	}
+
+	// Each server-sent event is a block of field lines ended by a blank line,
+	// lines starting with a colon are keepalive comments
+	name := ""
+	data := ""
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case line == "":
+			if data == "" {
+				continue
+			}
+			if name == "error" {
+				var body map[string]string
+				json.Unmarshal([]byte(data), &body)
+				return fmt.Errorf("client stopped streaming: %s", body["error"])
+			}
+			if err := handle(name, data); err != nil {
+				return err
+			}
+			name = ""
+			data = ""
+		case strings.HasPrefix(line, ":"):
+			continue
+		case strings.HasPrefix(line, "event:"):
+			name = strings.TrimSpace(strings.TrimPrefix(line, "event:"))
+		case strings.HasPrefix(line, "data:"):
+			if data != "" {
+				data += "\n"
+			}
+			data += strings.TrimPrefix(strings.TrimPrefix(line, "data:"), " ")
+		}
+	}
+	return scanner.Err()
+}
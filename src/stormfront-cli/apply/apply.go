@@ -10,9 +10,9 @@ import (
 	"net/http"
 	"os"
 	"path/filepath"
+	"stormfront-cli/action"
 	"stormfront-cli/config"
 	"stormfront-cli/logging"
-	"stormfront-cli/utils"
 	"strings"
 
 	"gopkg.in/yaml.v3"
@@ -122,57 +122,64 @@ func ExecuteApply(definition, namespace string) error {
 			if err := createRoute(host, port, namespace, apiToken, datum); err != nil {
 				return err
 			}
+		case "registry":
+			if err := createRegistry(name, datum); err != nil {
+				return err
+			}
+		case "config":
+			if err := createConfig(namespace, datum); err != nil {
+				return err
+			}
+		case "volume":
+			if err := createVolume(namespace, datum); err != nil {
+				return err
+			}
 		default:
-			return fmt.Errorf("invalid object type of '%s', allowed types are 'namespace' and 'application'", kind)
+			return fmt.Errorf("invalid object type of '%s', allowed types are 'namespace', 'application', 'route', 'registry', 'config', and 'volume'", kind)
 		}
 	}
 
-	logging.Success("All objects created")
+	logging.Success("All objects applied")
 
 	return nil
 }
 
 func createRoute(host, port, namespace, apiToken string, datum map[string]interface{}) error {
-	logging.Info("Creating route...")
-	requestURL := fmt.Sprintf("http://%s:%s/api/route", host, port)
+	logging.Info("Applying route...")
+	requestURL := fmt.Sprintf("https://%s:%s/api/route", host, port)
 
-	logging.Debug("Sending POST request to client...")
-	logging.Trace(fmt.Sprintf("Sending request to %s", requestURL))
+	// The leader rejects objects targeting a namespace which does not exist
+	if err := setNamespace(namespace, datum); err != nil {
+		return err
+	}
+	delete(datum, "kind")
 
-	if _, ok := datum["namespace"]; ok {
-		if namespace != "" {
-			namespaces, err := config.GetNamespaces()
-			if err != nil {
-				return err
-			}
-			if !utils.Contains(namespaces, namespace) {
-				return fmt.Errorf("route trying to be deployed to non-existent namespace: '%s'", namespace)
-			}
-			datum["namespace"] = namespace
+	// Routes which already exist are updated in place rather than re-created
+	existing, err := action.GetRouteByNameNamespace(datum["name"].(string), datum["namespace"].(string))
+	if err == nil && len(existing) > 0 {
+		changed, err := action.UpdateRouteById(existing[0]["id"].(string), datum)
+		if err != nil {
+			return err
 		}
-	} else {
-		if namespace != "" {
-			namespaces, err := config.GetNamespaces()
-			if err != nil {
-				return err
-			}
-			if !utils.Contains(namespaces, namespace) {
-				return fmt.Errorf("application trying to be deployed to non-existent namespace: '%s'", namespace)
-			}
-			datum["namespace"] = namespace
+		if len(changed) == 0 {
+			logging.Info("Route is already up to date")
 		} else {
-			configNamespace, err := config.GetNamespace()
-			if err != nil {
-				return err
-			}
-			datum["namespace"] = configNamespace
+			logging.Info(fmt.Sprintf("Updated fields: %s", strings.Join(changed, ", ")))
 		}
+		logging.Success("Done!")
+		return nil
 	}
 
+	logging.Debug("Sending POST request to client...")
+	logging.Trace(fmt.Sprintf("Sending request to %s", requestURL))
+
 	postBody, _ := json.Marshal(datum)
 	postBodyBuffer := bytes.NewBuffer(postBody)
 
-	httpClient := &http.Client{}
+	httpClient, err := config.HTTPClient()
+	if err != nil {
+		return err
+	}
 	req, _ := http.NewRequest("POST", requestURL, postBodyBuffer)
 	req.Header.Set("Authorization", fmt.Sprintf("X-Stormfront-API %s", apiToken))
 	req.Header.Set("Content-Type", "application/json")
@@ -213,55 +220,143 @@ func createRoute(host, port, namespace, apiToken string, datum map[string]interf
 }
 
 func createNamespace(name string) error {
-	logging.Info("Creating namespace...")
-	err := config.AddNamespace(name)
+	exists, err := action.NamespaceExists(name)
+	if err != nil {
+		return err
+	}
+	if exists {
+		logging.Info(fmt.Sprintf("Namespace %s already exists", name))
+		return nil
+	}
 
-	logging.Success("Done!")
+	return action.CreateNamespace(name)
+}
 
-	return err
+// createRegistry replaces any existing credentials with the same name as the
+// leader does not allow registries to be updated in place
+func createRegistry(name string, datum map[string]interface{}) error {
+	exists, err := action.RegistryExists(name)
+	if err != nil {
+		return err
+	}
+	if exists {
+		if err := action.DeleteRegistry(name); err != nil {
+			return err
+		}
+	}
+
+	delete(datum, "kind")
+	return action.CreateRegistry(datum)
 }
 
-func createApplication(host, port, namespace, apiToken string, datum map[string]interface{}) error {
-	logging.Info("Creating application...")
-	requestURL := fmt.Sprintf("http://%s:%s/api/application", host, port)
+func createConfig(namespace string, datum map[string]interface{}) error {
+	logging.Info("Applying config...")
 
-	logging.Debug("Sending POST request to client...")
-	logging.Trace(fmt.Sprintf("Sending request to %s", requestURL))
+	// The leader rejects objects targeting a namespace which does not exist
+	if err := setNamespace(namespace, datum); err != nil {
+		return err
+	}
+	delete(datum, "kind")
 
+	// Configs which already exist have their data replaced, which redeploys
+	// any applications mounting them
+	existing, err := action.GetConfigByNameNamespace(datum["name"].(string), datum["namespace"].(string))
+	if err == nil && len(existing) > 0 {
+		changed, err := action.UpdateConfigById(existing[0]["id"].(string), datum)
+		if err != nil {
+			return err
+		}
+		if len(changed) == 0 {
+			logging.Info("Config is already up to date")
+		} else {
+			logging.Info(fmt.Sprintf("Updated fields: %s", strings.Join(changed, ", ")))
+		}
+		logging.Success("Done!")
+		return nil
+	}
+
+	return action.CreateConfig(datum)
+}
+
+func createVolume(namespace string, datum map[string]interface{}) error {
+	logging.Info("Applying volume...")
+
+	if err := setNamespace(namespace, datum); err != nil {
+		return err
+	}
+	delete(datum, "kind")
+
+	// Volumes which already exist keep their data, only their policy and
+	// replication settings are updated
+	existing, err := action.GetVolumeByNameNamespace(datum["name"].(string), datum["namespace"].(string))
+	if err == nil && len(existing) > 0 {
+		changed, err := action.UpdateVolumeById(existing[0]["id"].(string), datum)
+		if err != nil {
+			return err
+		}
+		if len(changed) == 0 {
+			logging.Info("Volume is already up to date")
+		} else {
+			logging.Info(fmt.Sprintf("Updated fields: %s", strings.Join(changed, ", ")))
+		}
+		logging.Success("Done!")
+		return nil
+	}
+
+	return action.CreateVolume(datum)
+}
+
+func setNamespace(namespace string, datum map[string]interface{}) error {
+	if namespace != "" {
+		datum["namespace"] = namespace
+		return nil
+	}
 	if _, ok := datum["namespace"]; ok {
-		if namespace != "" {
-			namespaces, err := config.GetNamespaces()
-			if err != nil {
-				return err
-			}
-			if !utils.Contains(namespaces, namespace) {
-				return fmt.Errorf("application trying to be deployed to non-existent namespace: '%s'", namespace)
-			}
-			datum["namespace"] = namespace
+		return nil
+	}
+	configNamespace, err := config.GetNamespace()
+	if err != nil {
+		return err
+	}
+	datum["namespace"] = configNamespace
+	return nil
+}
+
+func createApplication(host, port, namespace, apiToken string, datum map[string]interface{}) error {
+	logging.Info("Applying application...")
+	requestURL := fmt.Sprintf("https://%s:%s/api/application", host, port)
+
+	// The leader rejects objects targeting a namespace which does not exist
+	if err := setNamespace(namespace, datum); err != nil {
+		return err
+	}
+
+	// Applications which already exist are updated in place rather than re-created
+	existing, err := action.GetApplicationByNameNamespace(datum["name"].(string), datum["namespace"].(string))
+	if err == nil && len(existing) > 0 {
+		changed, err := action.UpdateApplicationById(existing[0]["id"].(string), datum)
+		if err != nil {
+			return err
 		}
-	} else {
-		if namespace != "" {
-			namespaces, err := config.GetNamespaces()
-			if err != nil {
-				return err
-			}
-			if !utils.Contains(namespaces, namespace) {
-				return fmt.Errorf("application trying to be deployed to non-existent namespace: '%s'", namespace)
-			}
-			datum["namespace"] = namespace
+		if len(changed) == 0 {
+			logging.Info("Application is already up to date")
 		} else {
-			configNamespace, err := config.GetNamespace()
-			if err != nil {
-				return err
-			}
-			datum["namespace"] = configNamespace
+			logging.Info(fmt.Sprintf("Updated fields: %s", strings.Join(changed, ", ")))
 		}
+		logging.Success("Done!")
+		return nil
 	}
 
+	logging.Debug("Sending POST request to client...")
+	logging.Trace(fmt.Sprintf("Sending request to %s", requestURL))
+
 	postBody, _ := json.Marshal(datum)
 	postBodyBuffer := bytes.NewBuffer(postBody)
 
-	httpClient := &http.Client{}
+	httpClient, err := config.HTTPClient()
+	if err != nil {
+		return err
+	}
 	req, _ := http.NewRequest("POST", requestURL, postBodyBuffer)
 	req.Header.Set("Authorization", fmt.Sprintf("X-Stormfront-API %s", apiToken))
 	req.Header.Set("Content-Type", "application/json")
@@ -284,14 +379,20 @@ func createApplication(host, port, namespace, apiToken string, datum map[string]
 	if resp.StatusCode == http.StatusOK || resp.StatusCode == http.StatusCreated {
 		logging.Success("Done!")
 	} else {
-		var response_data map[string]string
+		var response_data map[string]interface{}
 		if err := json.Unmarshal([]byte(responseBody), &response_data); err == nil {
 			if _, ok := response_data["id"]; ok {
 				logging.Success("Done!")
 				return nil
-			} else if errMessage, ok := response_data["error"]; ok {
+			} else if errMessage, ok := response_data["error"].(string); ok {
 				logging.Error(errMessage)
 			}
+			// The scheduler explains why each node was passed over
+			if explanation, ok := response_data["explanation"].([]interface{}); ok {
+				for _, line := range explanation {
+					logging.Error(fmt.Sprintf("%v", line))
+				}
+			}
 		}
 		logging.Fatal(fmt.Sprintf("Client has returned error with status code %v", resp.StatusCode))
 	}
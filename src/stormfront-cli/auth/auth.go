@@ -1,6 +1,8 @@
 package auth
 
 import (
+	"crypto/tls"
+	"crypto/x509"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -43,12 +45,22 @@ func WriteClientInformation(clientInfo ClientInformation) error {
 	return err
 }
 
-func GetAPIToken(host, port string) (string, error) {
-	requestURL := fmt.Sprintf("http://%s:%s/auth/api", host, port)
+// ReadCA returns the cluster CA certificate written by the local daemon
+func ReadCA() ([]byte, error) {
+	return ioutil.ReadFile(fmt.Sprintf("%s/tls/ca.crt", getDataDirectory()))
+}
+
+func GetAPIToken(host, port string, ca []byte) (string, error) {
+	requestURL := fmt.Sprintf("https://%s:%s/auth/api", host, port)
 
 	clientInfo := ReadClientInformation()
 
-	httpClient := &http.Client{}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(ca) {
+		return "", errors.New("invalid cluster CA certificate")
+	}
+
+	httpClient := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: pool, MinVersion: tls.VersionTLS12}}}
 	req, _ := http.NewRequest("GET", requestURL, nil)
 	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", clientInfo.AccessToken))
 	resp, err := httpClient.Do(req)
@@ -65,11 +77,12 @@ func GetAPIToken(host, port string) (string, error) {
 	responseBody := string(body)
 
 	if resp.StatusCode == http.StatusOK {
-		responseJSON := map[string]string{}
+		responseJSON := map[string]interface{}{}
 		if err := json.Unmarshal(body, &responseJSON); err != nil {
 			return "", err
 		}
-		return responseJSON["token"], nil
+		token, _ := responseJSON["token"].(string)
+		return token, nil
 	} else {
 		var data map[string]string
 		if err := json.Unmarshal([]byte(responseBody), &data); err == nil {
@@ -0,0 +1,111 @@
+package bolt
+
+import (
+	"fmt"
+	"os"
+	"stormfront-cli/bolt/cancel"
+	"stormfront-cli/bolt/get"
+	"stormfront-cli/bolt/list"
+	"stormfront-cli/bolt/run"
+	"stormfront-cli/logging"
+	"stormfront-cli/utils"
+)
+
+var BoltHelpText = fmt.Sprintf(`usage: stormfront bolt <command> [-l|--log-level <log level>] [-h|--help]
+commands:
+	run               Run a shell command on one or more nodes
+	get               Get the status and output of a bolt
+	list              List the bolts in the cluster
+	cancel            Cancel a running bolt
+arguments:
+	-l|--log-level    Sets the log level of the CLI. valid levels are: %s, defaults to %s
+	-h|--help         Show this help message and exit`, logging.GetDefaults(), logging.ERROR_NAME)
+
+func ParseBoltArgs(args []string) {
+	envLogLevel, present := os.LookupEnv("STORMFRONT_LOG_LEVEL")
+	if present {
+		if err := logging.SetLevel(envLogLevel); err != nil {
+			fmt.Printf("Env logging level %s (from STORMFRONT_LOG_LEVEL) is invalid, skipping", envLogLevel)
+		}
+	}
+
+	if len(args) > 1 {
+		if args[1] == "-l" || args[1] == "--log-level" {
+			if len(args) == 2 {
+				logging.Fatal("No value passed after log-level flag")
+			}
+			err := logging.SetLevel(args[2])
+			if err != nil {
+				logging.Fatal(err.Error())
+			}
+			args = append(args[:0], args[2:]...)
+		}
+	}
+
+	if len(args) == 2 {
+		if utils.Contains(args, "-h") || utils.Contains(args, "--help") {
+			fmt.Println(BoltHelpText)
+			os.Exit(0)
+		}
+	}
+
+	if len(args) == 1 {
+		fmt.Println(BoltHelpText)
+		os.Exit(1)
+	}
+
+	switch args[1] {
+	case "run":
+		definition, wait, err := run.ParseRunArgs(args[2:])
+		if err != nil {
+			logging.Error(err.Error())
+			fmt.Println(run.RunHelpText)
+			os.Exit(1)
+		}
+		err = run.ExecuteRun(definition, wait)
+		if err != nil {
+			logging.Error(err.Error())
+			os.Exit(1)
+		}
+	case "get":
+		id, output, follow, err := get.ParseGetArgs(args[2:])
+		if err != nil {
+			logging.Error(err.Error())
+			fmt.Println(get.GetHelpText)
+			os.Exit(1)
+		}
+		err = get.ExecuteGet(id, output, follow)
+		if err != nil {
+			logging.Error(err.Error())
+			os.Exit(1)
+		}
+	case "list", "ls":
+		output, err := list.ParseListArgs(args[2:])
+		if err != nil {
+			logging.Error(err.Error())
+			fmt.Println(list.ListHelpText)
+			os.Exit(1)
+		}
+		err = list.ExecuteList(output)
+		if err != nil {
+			logging.Error(err.Error())
+			os.Exit(1)
+		}
+	case "cancel":
+		id, err := cancel.ParseCancelArgs(args[2:])
+		if err != nil {
+			logging.Error(err.Error())
+			fmt.Println(cancel.CancelHelpText)
+			os.Exit(1)
+		}
+		err = cancel.ExecuteCancel(id)
+		if err != nil {
+			logging.Error(err.Error())
+			os.Exit(1)
+		}
+	default:
+		fmt.Printf("Invalid argument: %s\n", args[1])
+		fmt.Println(BoltHelpText)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,123 @@
+package get
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"stormfront-cli/action"
+	"stormfront-cli/logging"
+	"stormfront-cli/utils"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+var GetHelpText = fmt.Sprintf(`usage: stormfront bolt get <bolt id> [-f|--follow] [-o|--output <output>] [-l|--log-level <log level>] [-h|--help]
+arguments:
+	-f|--follow       Stream the output of every node until the bolt finishes
+	-o|--output       Output format to print to console, valid options are "table", "yaml", and "json"
+	-l|--log-level    Sets the log level of the CLI. valid levels are: %s, defaults to %s
+	-h|--help         Show this help message and exit`, logging.GetDefaults(), logging.ERROR_NAME)
+
+func ParseGetArgs(args []string) (string, string, bool, error) {
+	id := ""
+	output := "table"
+	follow := false
+	envLogLevel, present := os.LookupEnv("STORMFRONT_LOG_LEVEL")
+	if present {
+		if err := logging.SetLevel(envLogLevel); err != nil {
+			fmt.Printf("Env logging level %s (from STORMFRONT_LOG_LEVEL) is invalid, skipping", envLogLevel)
+		}
+	}
+
+	for len(args) > 0 {
+		switch args[0] {
+		case "-f", "--follow":
+			follow = true
+			args = args[1:]
+		case "-o", "--output":
+			if len(args) > 1 {
+				switch args[1] {
+				case "table", "yaml", "json":
+					output = args[1]
+				default:
+					return "", "", false, fmt.Errorf("invalid output value %s, allowed values are 'table', 'yaml', and 'json", args[1])
+				}
+				args = args[2:]
+			} else {
+				return "", "", false, errors.New("no value passed after output flag")
+			}
+		case "-l", "--log-level":
+			if len(args) > 1 {
+				err := logging.SetLevel(args[1])
+				if err != nil {
+					return "", "", false, err
+				}
+				args = args[2:]
+			} else {
+				return "", "", false, errors.New("no value passed after log-level flag")
+			}
+		default:
+			if strings.HasPrefix(args[0], "-") || id != "" {
+				fmt.Printf("Invalid argument: %s\n", args[0])
+				fmt.Println(GetHelpText)
+				os.Exit(1)
+			} else {
+				id = args[0]
+				args = args[1:]
+			}
+		}
+	}
+
+	if id == "" {
+		return "", "", false, errors.New("id argument is required")
+	}
+
+	return id, output, follow, nil
+}
+
+func ExecuteGet(id, output string, follow bool) error {
+	if follow {
+		status, err := action.FollowBolt(id, os.Stdout, os.Stderr)
+		if err != nil {
+			return err
+		}
+		logging.Success(fmt.Sprintf("Bolt finished with status %s", status))
+		return nil
+	}
+
+	bolts, err := action.GetBoltById(id)
+	if err != nil {
+		return err
+	}
+
+	switch output {
+	case "table":
+		runs := []map[string]interface{}{}
+		items, _ := bolts[0]["runs"].([]interface{})
+		for _, item := range items {
+			if run, ok := item.(map[string]interface{}); ok {
+				runs = append(runs, run)
+			}
+		}
+		fmt.Printf("Command: %s\nStatus: %s\n\n", bolts[0]["command"], bolts[0]["status"])
+		utils.PrintTable(runs, []string{"node", "status", "exit_code", "started", "finished", "error"}, []string{"string", "string", "int", "string", "string", "string"})
+		for _, run := range runs {
+			for _, stream := range []string{"stdout", "stderr"} {
+				if text, _ := run[stream].(string); text != "" {
+					fmt.Printf("\n%s from %s:\n%s", stream, run["node"], text)
+				}
+			}
+		}
+	case "yaml":
+		contents, _ := yaml.Marshal(&bolts)
+		fmt.Println(string(contents))
+	case "json":
+		contents, _ := json.Marshal(&bolts)
+		fmt.Println(string(contents))
+	}
+	logging.Success("Done!")
+
+	return nil
+}
@@ -0,0 +1,123 @@
+package run
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"stormfront-cli/action"
+	"stormfront-cli/logging"
+	"strconv"
+	"strings"
+)
+
+var RunHelpText = fmt.Sprintf(`usage: stormfront bolt run [--node <node id>|--all|--selector <key>=<value>] [--timeout <seconds>] [-w|--wait] [-l|--log-level <log level>] [-h|--help] -- <command>
+arguments:
+	--node            Node to run the command on, defaults to the leader
+	--all             Run the command on every node
+	--selector        Run the command on nodes with a matching label, can be passed multiple times
+	--timeout         Seconds before the command is killed, defaults to 3600
+	-w|--wait         Stream the output and wait for every node to finish
+	-l|--log-level    Sets the log level of the CLI. valid levels are: %s, defaults to %s
+	-h|--help         Show this help message and exit`, logging.GetDefaults(), logging.ERROR_NAME)
+
+func ParseRunArgs(args []string) (map[string]interface{}, bool, error) {
+	definition := map[string]interface{}{}
+	selector := map[string]string{}
+	wait := false
+	envLogLevel, present := os.LookupEnv("STORMFRONT_LOG_LEVEL")
+	if present {
+		if err := logging.SetLevel(envLogLevel); err != nil {
+			fmt.Printf("Env logging level %s (from STORMFRONT_LOG_LEVEL) is invalid, skipping", envLogLevel)
+		}
+	}
+
+	for len(args) > 0 {
+		switch args[0] {
+		case "--":
+			definition["command"] = strings.Join(args[1:], " ")
+			args = []string{}
+		case "--node":
+			if len(args) > 1 {
+				definition["node"] = args[1]
+				args = args[2:]
+			} else {
+				return nil, false, errors.New("no value passed after node flag")
+			}
+		case "--all":
+			definition["all"] = true
+			args = args[1:]
+		case "--selector":
+			if len(args) > 1 {
+				parts := strings.SplitN(args[1], "=", 2)
+				if len(parts) != 2 {
+					return nil, false, fmt.Errorf("invalid selector %s, must be of the form <key>=<value>", args[1])
+				}
+				selector[parts[0]] = parts[1]
+				args = args[2:]
+			} else {
+				return nil, false, errors.New("no value passed after selector flag")
+			}
+		case "--timeout":
+			if len(args) > 1 {
+				timeout, err := strconv.Atoi(args[1])
+				if err != nil || timeout <= 0 {
+					return nil, false, fmt.Errorf("invalid timeout %s, must be a positive number of seconds", args[1])
+				}
+				definition["timeout"] = timeout
+				args = args[2:]
+			} else {
+				return nil, false, errors.New("no value passed after timeout flag")
+			}
+		case "-w", "--wait":
+			wait = true
+			args = args[1:]
+		case "-l", "--log-level":
+			if len(args) > 1 {
+				err := logging.SetLevel(args[1])
+				if err != nil {
+					return nil, false, err
+				}
+				args = args[2:]
+			} else {
+				return nil, false, errors.New("no value passed after log-level flag")
+			}
+		case "-h", "--help":
+			fmt.Println(RunHelpText)
+			os.Exit(0)
+		default:
+			fmt.Printf("Invalid argument: %s\n", args[0])
+			fmt.Println(RunHelpText)
+			os.Exit(1)
+		}
+	}
+
+	if command, ok := definition["command"].(string); !ok || command == "" {
+		return nil, false, errors.New("no command passed after '--'")
+	}
+	if len(selector) > 0 {
+		definition["selector"] = selector
+	}
+
+	return definition, wait, nil
+}
+
+func ExecuteRun(definition map[string]interface{}, wait bool) error {
+	id, err := action.CreateBolt(definition)
+	if err != nil {
+		return err
+	}
+
+	if !wait {
+		fmt.Println(id)
+		return nil
+	}
+
+	status, err := action.FollowBolt(id, os.Stdout, os.Stderr)
+	if err != nil {
+		return err
+	}
+	if status != "Success" {
+		return fmt.Errorf("bolt %s finished with status %s", id, status)
+	}
+	return nil
+}
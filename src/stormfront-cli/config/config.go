@@ -1,26 +1,33 @@
 package config
 
 import (
+	"crypto/tls"
+	"crypto/x509"
 	"fmt"
 	"io/ioutil"
+	"net/http"
 	"os"
 	"os/user"
 
 	"gopkg.in/yaml.v2"
 )
 
+// DEFAULT_NAMESPACE is created with every cluster and selected when no other
+// namespace is
+const DEFAULT_NAMESPACE = "default"
+
 type Config struct {
 	CurrentCluster string          `json:"current_cluster" yaml:"current_cluster"`
 	Clusters       []ClusterConfig `json:"clusters" yaml:"clusters"`
 }
 
 type ClusterConfig struct {
-	Token            string   `json:"token" yaml:"token"`                         // Token used for authentication
-	Name             string   `json:"name" yaml:"name"`                           // Name of the cluster to interact with
-	CurrentNamespace string   `json:"current_namespace" yaml:"current_namespace"` // Current namespace in cluster
-	Namespaces       []string `json:"namespaces" yaml:"namespaces"`               // All available namespaces within the cluster
-	Host             string   `json:"host" yaml:"host"`                           // Leader host
-	Port             string   `json:"port" yaml:"port"`                           // Leader port
+	Token            string `json:"token" yaml:"token"`                         // Token used for authentication
+	Name             string `json:"name" yaml:"name"`                           // Name of the cluster to interact with
+	CurrentNamespace string `json:"current_namespace" yaml:"current_namespace"` // Current namespace in cluster
+	Host             string `json:"host" yaml:"host"`                           // Leader host
+	Port             string `json:"port" yaml:"port"`                           // Leader port
+	CA               string `json:"ca" yaml:"ca"`                               // PEM encoded cluster CA certificate
 }
 
 func ReadConfig() (Config, error) {
@@ -178,103 +185,97 @@ func GetNamespace() (string, error) {
 	return "", fmt.Errorf("could not find entry for cluster %s", conf.CurrentCluster)
 }
 
-func GetNamespaces() ([]string, error) {
+func GetAPIToken() (string, error) {
 	conf, err := ReadConfig()
 	if err != nil {
-		return []string{}, err
+		return "", err
 	}
-
 	for _, cluster := range conf.Clusters {
 		if cluster.Name == conf.CurrentCluster {
-			return cluster.Namespaces, nil
+			return cluster.Token, nil
 		}
 	}
 
-	return []string{}, fmt.Errorf("could not find entry for cluster %s", conf.CurrentCluster)
+	return "", fmt.Errorf("could not find entry for cluster %s", conf.CurrentCluster)
 }
 
-func AddNamespace(name string) error {
+func GetHost() (string, error) {
 	conf, err := ReadConfig()
 	if err != nil {
-		return err
+		return "", err
 	}
 
-	for idx, clusterConfig := range conf.Clusters {
-		if conf.CurrentCluster == clusterConfig.Name {
-			conf.Clusters[idx].Namespaces = append(conf.Clusters[idx].Namespaces, name)
+	for _, cluster := range conf.Clusters {
+		if cluster.Name == conf.CurrentCluster {
+			return cluster.Host, nil
 		}
 	}
 
-	err = WriteConfig(conf)
-
-	return err
+	return "", fmt.Errorf("could not find entry for cluster %s", conf.CurrentCluster)
 }
 
-func RemoveNamespace(name string) error {
+func GetPort() (string, error) {
 	conf, err := ReadConfig()
 	if err != nil {
-		return err
+		return "", err
 	}
 
-	for idx, cluster := range conf.Clusters {
+	for _, cluster := range conf.Clusters {
 		if cluster.Name == conf.CurrentCluster {
-			for jdx, namespace := range cluster.Namespaces {
-				if namespace == name {
-					conf.Clusters[idx].Namespaces = append(conf.Clusters[idx].Namespaces[:jdx], conf.Clusters[idx].Namespaces[jdx+1:]...)
-					break
-				}
-			}
-			break
+			return cluster.Port, nil
 		}
 	}
 
-	err = WriteConfig(conf)
-
-	return err
+	return "", fmt.Errorf("could not find entry for cluster %s", conf.CurrentCluster)
 }
 
-func GetAPIToken() (string, error) {
+func GetCA() (string, error) {
 	conf, err := ReadConfig()
 	if err != nil {
 		return "", err
 	}
+
 	for _, cluster := range conf.Clusters {
 		if cluster.Name == conf.CurrentCluster {
-			return cluster.Token, nil
+			return cluster.CA, nil
 		}
 	}
 
 	return "", fmt.Errorf("could not find entry for cluster %s", conf.CurrentCluster)
 }
 
-func GetHost() (string, error) {
-	conf, err := ReadConfig()
+// TLSConfig trusts only the CA pinned for the current cluster. Clusters added
+// before the CA was recorded have to be added again, the system roots are
+// never trusted in its place.
+func TLSConfig() (*tls.Config, error) {
+	cluster, err := GetCluster()
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
-	for _, cluster := range conf.Clusters {
-		if cluster.Name == conf.CurrentCluster {
-			return cluster.Host, nil
-		}
+	ca, err := GetCA()
+	if err != nil {
+		return nil, err
 	}
 
-	return "", fmt.Errorf("could not find entry for cluster %s", conf.CurrentCluster)
+	if ca == "" {
+		return nil, fmt.Errorf("no CA certificate pinned for cluster %s, add the cluster again to pin it", cluster)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM([]byte(ca)) {
+		return nil, fmt.Errorf("invalid CA certificate for cluster %s", cluster)
+	}
+
+	return &tls.Config{MinVersion: tls.VersionTLS12, RootCAs: pool}, nil
 }
 
-func GetPort() (string, error) {
-	conf, err := ReadConfig()
+func HTTPClient() (*http.Client, error) {
+	tlsConfig, err := TLSConfig()
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
-	for _, cluster := range conf.Clusters {
-		if cluster.Name == conf.CurrentCluster {
-			return cluster.Port, nil
-		}
-	}
-
-	return "", fmt.Errorf("could not find entry for cluster %s", conf.CurrentCluster)
+	return &http.Client{Transport: &http.Transport{TLSClientConfig: tlsConfig}}, nil
 }
 
 func getEnvDefault(key, defaultValue string) string {
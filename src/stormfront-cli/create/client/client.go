@@ -107,7 +107,12 @@ func ExecuteClient(host, port, clientPort string) error {
 	logging.Debug(fmt.Sprintf("Response body: %s", responseBody))
 
 	if resp.StatusCode == http.StatusOK {
-		apiToken, err := auth.GetAPIToken(host, clientPort)
+		ca, err := auth.ReadCA()
+		if err != nil {
+			return err
+		}
+
+		apiToken, err := auth.GetAPIToken(host, clientPort, ca)
 		if err != nil {
 			return err
 		}
@@ -117,10 +122,10 @@ func ExecuteClient(host, port, clientPort string) error {
 		clusterData := config.ClusterConfig{
 			Name:             clusterName,
 			Token:            apiToken,
-			CurrentNamespace: "default",
-			Namespaces:       []string{"default"},
+			CurrentNamespace: config.DEFAULT_NAMESPACE,
 			Host:             host,
 			Port:             clientPort,
+			CA:               string(ca),
 		}
 
 		err = config.AddCluster(clusterData)
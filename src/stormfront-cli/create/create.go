@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"os"
 	"stormfront-cli/create/client"
+	"stormfront-cli/create/namespace"
+	"stormfront-cli/create/secret"
 	"stormfront-cli/logging"
 	"stormfront-cli/utils"
 )
@@ -11,6 +13,8 @@ import (
 var CreateHelpText = fmt.Sprintf(`usage: stormfront create <object> [-l|--log-level <log level>] [-h|--help]
 objects:
 	client            Create a new leader client 
+	namespace         Create a new namespace in the current cluster
+	secret            Create a new secret in the current namespace
 arguments:
 	-l|--log-level    Sets the log level of the CLI. valid levels are: %s, defaults to %s
 	-h|--help         Show this help message and exit`, logging.GetDefaults(), logging.ERROR_NAME)
@@ -61,6 +65,30 @@ func ParseCreateArgs(args []string) {
 			logging.Error(err.Error())
 			os.Exit(1)
 		}
+	case "namespace", "ns":
+		name, err := namespace.ParseNamespaceArgs(args[2:])
+		if err != nil {
+			logging.Error(err.Error())
+			fmt.Println(CreateHelpText)
+			os.Exit(1)
+		}
+		err = namespace.ExecuteNamespace(name)
+		if err != nil {
+			logging.Error(err.Error())
+			os.Exit(1)
+		}
+	case "secret", "sc":
+		name, namespace, data, err := secret.ParseSecretArgs(args[2:])
+		if err != nil {
+			logging.Error(err.Error())
+			fmt.Println(CreateHelpText)
+			os.Exit(1)
+		}
+		err = secret.ExecuteSecret(name, namespace, data)
+		if err != nil {
+			logging.Error(err.Error())
+			os.Exit(1)
+		}
 	default:
 		fmt.Printf("Invalid argument: %s\n", args[1])
 		fmt.Println(CreateHelpText)
@@ -0,0 +1,118 @@
+package secret
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"stormfront-cli/action"
+	"stormfront-cli/config"
+	"stormfront-cli/logging"
+	"strings"
+)
+
+var SecretHelpText = fmt.Sprintf(`usage: stormfront create secret <secret name> [-d|--data <key>=<value>] [-f|--file <key>=<path>] [-n|--namespace <namespace>] [-l|--log-level <log level>] [-h|--help]
+arguments:
+	-d|--data         A key and literal value to store in the secret, can be passed multiple times
+	-f|--file         A key and the path of a file whose contents to store in the secret, can be passed multiple times
+	-n|--namespace    Namespace to create the secret in
+	-l|--log-level    Sets the log level of the CLI. valid levels are: %s, defaults to %s
+	-h|--help         Show this help message and exit`, logging.GetDefaults(), logging.ERROR_NAME)
+
+func ParseSecretArgs(args []string) (string, string, map[string]string, error) {
+	name := ""
+	namespace := ""
+	data := map[string]string{}
+	envLogLevel, present := os.LookupEnv("STORMFRONT_LOG_LEVEL")
+	if present {
+		if err := logging.SetLevel(envLogLevel); err != nil {
+			fmt.Printf("Env logging level %s (from STORMFRONT_LOG_LEVEL) is invalid, skipping", envLogLevel)
+		}
+	}
+
+	for len(args) > 0 {
+		switch args[0] {
+		case "-d", "--data":
+			if len(args) > 1 {
+				key, value, err := splitKeyValue(args[1])
+				if err != nil {
+					return "", "", map[string]string{}, err
+				}
+				data[key] = value
+				args = args[2:]
+			} else {
+				return "", "", map[string]string{}, errors.New("no value passed after data flag")
+			}
+		case "-f", "--file":
+			if len(args) > 1 {
+				key, path, err := splitKeyValue(args[1])
+				if err != nil {
+					return "", "", map[string]string{}, err
+				}
+				contents, err := ioutil.ReadFile(path)
+				if err != nil {
+					return "", "", map[string]string{}, fmt.Errorf("unable to read file %s: %v", path, err)
+				}
+				data[key] = string(contents)
+				args = args[2:]
+			} else {
+				return "", "", map[string]string{}, errors.New("no value passed after file flag")
+			}
+		case "-n", "--namespace":
+			if len(args) > 1 {
+				namespace = args[1]
+				args = args[2:]
+			} else {
+				return "", "", map[string]string{}, errors.New("no value passed after namespace flag")
+			}
+		case "-l", "--log-level":
+			if len(args) > 1 {
+				err := logging.SetLevel(args[1])
+				if err != nil {
+					return "", "", map[string]string{}, err
+				}
+				args = args[2:]
+			} else {
+				return "", "", map[string]string{}, errors.New("no value passed after log-level flag")
+			}
+		default:
+			if strings.HasPrefix(args[0], "-") || name != "" {
+				fmt.Printf("Invalid argument: %s\n", args[0])
+				fmt.Println(SecretHelpText)
+				os.Exit(1)
+			} else {
+				name = args[0]
+				args = args[1:]
+			}
+		}
+	}
+
+	if name == "" {
+		return "", "", map[string]string{}, errors.New("name argument is required")
+	}
+	if len(data) == 0 {
+		return "", "", map[string]string{}, errors.New("at least one data or file value is required")
+	}
+
+	return name, namespace, data, nil
+}
+
+func splitKeyValue(arg string) (string, string, error) {
+	parts := strings.SplitN(arg, "=", 2)
+	if len(parts) != 2 || parts[0] == "" {
+		return "", "", fmt.Errorf("invalid value %s, expected <key>=<value>", arg)
+	}
+	return parts[0], parts[1], nil
+}
+
+func ExecuteSecret(name, namespace string, data map[string]string) error {
+	var err error
+	if namespace == "" {
+		namespace, err = config.GetNamespace()
+		if err != nil {
+			return err
+		}
+	}
+
+	return action.CreateSecret(name, namespace, data)
+}
@@ -8,6 +8,8 @@ import (
 	"stormfront-cli/delete/cluster"
 	"stormfront-cli/delete/namespace"
 	"stormfront-cli/delete/route"
+	"stormfront-cli/delete/secret"
+	"stormfront-cli/delete/volume"
 	"stormfront-cli/logging"
 	"stormfront-cli/utils"
 )
@@ -19,6 +21,8 @@ commands:
 	cluster           Delete a cluster from your .stormfrontconfig file
 	route             Delete an existing route
 	namespace         Delete a namespace from an existing cluster
+	secret            Delete an existing secret
+	volume            Delete an existing volume
 arguments:
 	-l|--log-level    Sets the log level of the CLI. valid levels are: %s, defaults to %s
 	-h|--help         Show this help message and exit`, logging.GetDefaults(), logging.ERROR_NAME)
@@ -117,6 +121,30 @@ func ParseDeleteArgs(args []string) {
 			logging.Error(err.Error())
 			os.Exit(1)
 		}
+	case "secret", "sc":
+		id, namespace, err := secret.ParseSecretArgs(args[2:])
+		if err != nil {
+			logging.Error(err.Error())
+			fmt.Println(DeleteHelpText)
+			os.Exit(1)
+		}
+		err = secret.ExecuteSecret(id, namespace)
+		if err != nil {
+			logging.Error(err.Error())
+			os.Exit(1)
+		}
+	case "volume", "vol":
+		id, namespace, err := volume.ParseVolumeArgs(args[2:])
+		if err != nil {
+			logging.Error(err.Error())
+			fmt.Println(DeleteHelpText)
+			os.Exit(1)
+		}
+		err = volume.ExecuteVolume(id, namespace)
+		if err != nil {
+			logging.Error(err.Error())
+			os.Exit(1)
+		}
 	default:
 		fmt.Printf("Invalid argument: %s\n", args[1])
 		fmt.Println(DeleteHelpText)
@@ -1,12 +1,10 @@
 package namespace
 
 import (
-	"encoding/json"
 	"errors"
 	"fmt"
-	"io/ioutil"
-	"net/http"
 	"os"
+	"stormfront-cli/action"
 	"stormfront-cli/config"
 	"stormfront-cli/logging"
 	"strings"
@@ -58,121 +56,19 @@ func ParseNamespaceArgs(args []string) (string, error) {
 }
 
 func ExecuteNamespace(name string) error {
-	host, err := config.GetHost()
+	// Applications and routes in the namespace are removed by the leader
+	err := action.DeleteNamespace(name)
 	if err != nil {
 		return err
 	}
-	port, err := config.GetPort()
-	if err != nil {
-		return err
-	}
-
-	logging.Info("Deleting namespace...")
-
-	requestURL := fmt.Sprintf("http://%s:%s/api/application", host, port)
-
-	logging.Debug("Sending GET request to client...")
-	logging.Trace(fmt.Sprintf("Sending request to %s", requestURL))
 
-	apiToken, err := config.GetAPIToken()
+	currentNamespace, err := config.GetNamespace()
 	if err != nil {
 		return err
 	}
-
-	httpClient := &http.Client{}
-	req, _ := http.NewRequest("GET", requestURL, nil)
-	req.Header.Set("Authorization", fmt.Sprintf("X-Stormfront-API %s", apiToken))
-	resp, err := httpClient.Do(req)
-	if err != nil {
-		return err
-	}
-
-	logging.Debug("Done!")
-
-	defer resp.Body.Close()
-	//Read the response body
-	body, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		return err
+	if currentNamespace == name {
+		logging.Warn(fmt.Sprintf("Deleted the currently selected namespace, switching to '%s'", config.DEFAULT_NAMESPACE))
+		err = config.ChangeNamespace(config.DEFAULT_NAMESPACE)
 	}
-	responseBody := string(body)
-
-	logging.Debug(fmt.Sprintf("Status code: %v", resp.StatusCode))
-	logging.Debug(fmt.Sprintf("Response body: %s", responseBody))
-
-	if resp.StatusCode == http.StatusOK {
-		var data []map[string]interface{}
-		if strings.HasPrefix(responseBody, "[") {
-			if err := json.Unmarshal([]byte(responseBody), &data); err != nil {
-				return err
-			}
-		} else {
-			if err := json.Unmarshal([]byte(fmt.Sprintf("[%s]", responseBody)), &data); err != nil {
-				return err
-			}
-		}
-
-		for _, app := range data {
-			if app["namespace"].(string) == name {
-
-				logging.Info("Deleting application...")
-
-				requestURL := fmt.Sprintf("http://%s:%s/api/application/%s", host, port, app["id"].(string))
-
-				logging.Debug("Sending DELETE request to client...")
-				logging.Trace(fmt.Sprintf("Sending request to %s", requestURL))
-
-				apiToken, err := config.GetAPIToken()
-				if err != nil {
-					return err
-				}
-
-				httpClient := &http.Client{}
-				req, _ := http.NewRequest("DELETE", requestURL, nil)
-				req.Header.Set("Authorization", fmt.Sprintf("X-Stormfront-API %s", apiToken))
-				resp, err := httpClient.Do(req)
-				if err != nil {
-					return err
-				}
-
-				logging.Debug("Done!")
-
-				defer resp.Body.Close()
-				//Read the response body
-				body, err := ioutil.ReadAll(resp.Body)
-				if err != nil {
-					return err
-				}
-				responseBody := string(body)
-
-				logging.Debug(fmt.Sprintf("Status code: %v", resp.StatusCode))
-				logging.Debug(fmt.Sprintf("Response body: %s", responseBody))
-
-				if resp.StatusCode == http.StatusOK || resp.StatusCode == http.StatusNoContent {
-					logging.Success("Done!")
-				} else {
-					var data map[string]string
-					if err := json.Unmarshal([]byte(responseBody), &data); err == nil {
-						if errMessage, ok := data["error"]; ok {
-							logging.Error(errMessage)
-						}
-					}
-					logging.Fatal(fmt.Sprintf("Client has returned error with status code %v", resp.StatusCode))
-				}
-			}
-		}
-
-		logging.Success("Done!")
-	} else {
-		var data map[string]string
-		if err := json.Unmarshal([]byte(responseBody), &data); err == nil {
-			if errMessage, ok := data["error"]; ok {
-				logging.Error(errMessage)
-			}
-		}
-		logging.Fatal(fmt.Sprintf("Client has returned error with status code %v", resp.StatusCode))
-	}
-
-	err = config.RemoveNamespace(name)
 	return err
 }
@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"stormfront-cli/action"
 	"stormfront-cli/config"
 	"stormfront-cli/logging"
 	"strings"
@@ -55,9 +56,17 @@ func ParseNamespaceArgs(args []string) (string, error) {
 }
 
 func ExecuteNamespace(name string) error {
-	logging.Info("Editing cluster...")
+	exists, err := action.NamespaceExists(name)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return fmt.Errorf("namespace %s does not exist in the current cluster", name)
+	}
+
+	logging.Info("Editing namespace...")
 
-	err := config.ChangeNamespace(name)
+	err = config.ChangeNamespace(name)
 
 	logging.Info("Done!")
 
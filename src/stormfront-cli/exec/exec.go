@@ -0,0 +1,128 @@
+package exec
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"stormfront-cli/action"
+	"stormfront-cli/config"
+	"stormfront-cli/logging"
+	"strings"
+)
+
+var ExecHelpText = fmt.Sprintf(`usage: stormfront exec <application name|application id> [-i|--interactive] [-t|--tty] [--instance <instance name>] [-n|--namespace <namespace>] [-l|--log-level <log level>] [-h|--help] -- <command> [<args>...]
+arguments:
+	-i|--interactive    Attach stdin to the command
+	-t|--tty            Run the command on a terminal, combine with -i for a shell as -it
+	--instance          Instance of the application to run the command in, defaults to the first
+	-n|--namespace      Namespace the application belongs to
+	-l|--log-level      Sets the log level of the CLI. valid levels are: %s, defaults to %s
+	-h|--help           Show this help message and exit`, logging.GetDefaults(), logging.ERROR_NAME)
+
+func ParseExecArgs(args []string) (string, string, action.ExecOptions, error) {
+	id := ""
+	namespace := ""
+	options := action.ExecOptions{}
+	envLogLevel, present := os.LookupEnv("STORMFRONT_LOG_LEVEL")
+	if present {
+		if err := logging.SetLevel(envLogLevel); err != nil {
+			fmt.Printf("Env logging level %s (from STORMFRONT_LOG_LEVEL) is invalid, skipping", envLogLevel)
+		}
+	}
+
+	for len(args) > 0 {
+		switch args[0] {
+		case "--":
+			options.Command = args[1:]
+			args = []string{}
+		case "-i", "--interactive":
+			options.Interactive = true
+			args = args[1:]
+		case "-t", "--tty":
+			options.TTY = true
+			args = args[1:]
+		case "-it", "-ti":
+			options.Interactive = true
+			options.TTY = true
+			args = args[1:]
+		case "--instance":
+			if len(args) > 1 {
+				options.Instance = args[1]
+				args = args[2:]
+			} else {
+				return "", "", options, errors.New("no value passed after instance flag")
+			}
+		case "-n", "--namespace":
+			if len(args) > 1 {
+				namespace = args[1]
+				args = args[2:]
+			} else {
+				return "", "", options, errors.New("no value passed after namespace flag")
+			}
+		case "-l", "--log-level":
+			if len(args) > 1 {
+				err := logging.SetLevel(args[1])
+				if err != nil {
+					return "", "", options, err
+				}
+				args = args[2:]
+			} else {
+				return "", "", options, errors.New("no value passed after log-level flag")
+			}
+		case "-h", "--help":
+			fmt.Println(ExecHelpText)
+			os.Exit(0)
+		default:
+			if strings.HasPrefix(args[0], "-") || id != "" {
+				fmt.Printf("Invalid argument: %s\n", args[0])
+				fmt.Println(ExecHelpText)
+				os.Exit(1)
+			} else {
+				id = args[0]
+				args = args[1:]
+			}
+		}
+	}
+
+	if id == "" {
+		return "", "", options, errors.New("id argument is required")
+	}
+	if len(options.Command) == 0 {
+		return "", "", options, errors.New("no command passed after '--'")
+	}
+
+	return id, namespace, options, nil
+}
+
+// ExecuteExec runs the command and returns its exit code so that it can be
+// used as the exit code of the CLI
+func ExecuteExec(id, namespace string, options action.ExecOptions) (int, error) {
+	var err error
+	if namespace == "" {
+		namespace, err = config.GetNamespace()
+		if err != nil {
+			return -1, err
+		}
+	}
+
+	if options.TTY && isTerminal(os.Stdin) {
+		options.Rows, options.Cols = terminalSize()
+		if options.Interactive {
+			restore, err := makeRaw()
+			if err != nil {
+				logging.Warn(fmt.Sprintf("Unable to switch terminal to raw mode: %v", err))
+			} else {
+				defer restore()
+			}
+		}
+	}
+
+	// Resolve the name up front, a session can not be retried once stdin
+	// has been read from
+	applications, err := action.GetApplicationByNameNamespace(id, namespace)
+	if err == nil && len(applications) > 0 {
+		id = applications[0]["id"].(string)
+	}
+
+	return action.ExecById(id, options, os.Stdin, os.Stdout, os.Stderr)
+}
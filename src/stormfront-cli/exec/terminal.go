@@ -0,0 +1,52 @@
+package exec
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+func isTerminal(file *os.File) bool {
+	info, err := file.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
+func stty(args ...string) (string, error) {
+	cmd := exec.Command("stty", args...)
+	cmd.Stdin = os.Stdin
+	output, err := cmd.Output()
+	return strings.TrimSpace(string(output)), err
+}
+
+// terminalSize returns the rows and columns of the terminal attached to
+// stdin, or zeroes if they can not be determined
+func terminalSize() (int, int) {
+	output, err := stty("size")
+	if err != nil {
+		return 0, 0
+	}
+	var rows, cols int
+	if _, err := fmt.Sscanf(output, "%d %d", &rows, &cols); err != nil {
+		return 0, 0
+	}
+	return rows, cols
+}
+
+// makeRaw passes keystrokes straight through to the remote terminal, the
+// returned function puts the local terminal back the way it was
+func makeRaw() (func(), error) {
+	state, err := stty("-g")
+	if err != nil {
+		return nil, err
+	}
+	if _, err := stty("raw", "-echo"); err != nil {
+		return nil, err
+	}
+	return func() {
+		stty(state)
+	}, nil
+}
@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"sort"
 	"stormfront-cli/action"
 	"stormfront-cli/config"
 	"stormfront-cli/logging"
@@ -14,18 +15,20 @@ import (
 	"gopkg.in/yaml.v2"
 )
 
-var ApplicationHelpText = fmt.Sprintf(`usage: stormfront application get [<application id>] [-o|--output <output>] [-n|--namespace] [-a|--all-namespaces] [-l|--log-level <log level>] [-h|--help]
+var ApplicationHelpText = fmt.Sprintf(`usage: stormfront application get [<application id>] [-o|--output <output>] [-n|--namespace] [-a|--all-namespaces] [-w|--watch] [-l|--log-level <log level>] [-h|--help]
 arguments:
 	-o|--output            Output format to print to console, valid options are "table", "yaml", and "json"
 	-n|--namespace         Namespace to grab applications from
 	-a|--all-namespaces    Show applications from all namespaces
+	-w|--watch             Keep updating the table as applications change until interrupted
 	-l|--log-level         Sets the log level of the CLI. valid levels are: %s, defaults to %s
 	-h|--help              Show this help message and exit`, logging.GetDefaults(), logging.ERROR_NAME)
 
-func ParseApplicationArgs(args []string) (string, string, string, error) {
+func ParseApplicationArgs(args []string) (string, string, string, bool, error) {
 	id := ""
 	output := "table"
 	namespace := ""
+	watch := false
 	envLogLevel, present := os.LookupEnv("STORMFRONT_LOG_LEVEL")
 	if present {
 		if err := logging.SetLevel(envLogLevel); err != nil {
@@ -41,31 +44,34 @@ func ParseApplicationArgs(args []string) (string, string, string, error) {
 				case "table", "yaml", "json":
 					output = args[1]
 				default:
-					return "", "", "", fmt.Errorf("invalid output value %s, allowed values are 'table', 'yaml', and 'json", args[1])
+					return "", "", "", false, fmt.Errorf("invalid output value %s, allowed values are 'table', 'yaml', and 'json", args[1])
 				}
 				args = args[2:]
 			} else {
-				return "", "", "", errors.New("no value passed after output flag")
+				return "", "", "", false, errors.New("no value passed after output flag")
 			}
 		case "-a", "--all-namespaces":
 			namespace = "all"
 			args = args[1:]
+		case "-w", "--watch":
+			watch = true
+			args = args[1:]
 		case "-n", "--namespace":
 			if len(args) > 1 {
 				namespace = args[1]
 				args = args[2:]
 			} else {
-				return "", "", "", errors.New("no value passed after namespace flag")
+				return "", "", "", false, errors.New("no value passed after namespace flag")
 			}
 		case "-l", "--log-level":
 			if len(args) > 1 {
 				err := logging.SetLevel(args[1])
 				if err != nil {
-					return "", "", "", err
+					return "", "", "", false, err
 				}
 				args = args[2:]
 			} else {
-				return "", "", "", errors.New("no value passed after log-level flag")
+				return "", "", "", false, errors.New("no value passed after log-level flag")
 			}
 		default:
 			if strings.HasPrefix(args[0], "-") || id != "" {
@@ -79,10 +85,10 @@ func ParseApplicationArgs(args []string) (string, string, string, error) {
 		}
 	}
 
-	return id, output, namespace, nil
+	return id, output, namespace, watch, nil
 }
 
-func ExecuteApplication(id, output, namespace string) error {
+func ExecuteApplication(id, output, namespace string, watch bool) error {
 	var applications []map[string]interface{}
 	var err error
 	if namespace == "" {
@@ -92,6 +98,10 @@ func ExecuteApplication(id, output, namespace string) error {
 		}
 	}
 
+	if watch {
+		return watchApplications(id, output, namespace)
+	}
+
 	if id == "" {
 		applications, err = action.GetAllApplications(namespace)
 		if err != nil {
@@ -107,8 +117,98 @@ func ExecuteApplication(id, output, namespace string) error {
 		}
 	}
 
+	printApplications(applications, output)
+	logging.Success("Done!")
+
+	return nil
+}
+
+// watchApplications keeps the applications matching id and namespace up to
+// date from the client's watch stream. Tables are redrawn once each batch of
+// changes has arrived, other outputs print every change as it comes in.
+func watchApplications(id, output, namespace string) error {
+	applications := map[string]map[string]interface{}{}
+	return action.WatchList("api/application", func(event action.WatchEvent) error {
+		if event.Type == action.WATCH_BOOKMARK {
+			if output == "table" {
+				ids := []string{}
+				for appID := range applications {
+					ids = append(ids, appID)
+				}
+				sort.Strings(ids)
+				data := []map[string]interface{}{}
+				for _, appID := range ids {
+					data = append(data, applications[appID])
+				}
+				// Move to the top left and clear the screen before redrawing
+				fmt.Print("\033[H\033[2J")
+				printApplications(data, output)
+			}
+			return nil
+		}
+
+		app := event.Object
+		if namespace != "all" && app["namespace"] != namespace {
+			return nil
+		}
+		if id != "" && app["id"] != id && app["name"] != id {
+			return nil
+		}
+
+		appID := fmt.Sprintf("%v", app["id"])
+		if event.Type == action.WATCH_DELETED {
+			delete(applications, appID)
+		} else {
+			applications[appID] = app
+		}
+
+		switch output {
+		case "yaml":
+			contents, _ := yaml.Marshal(&event)
+			fmt.Printf("---\n%s", string(contents))
+		case "json":
+			contents, _ := json.Marshal(&event)
+			fmt.Println(string(contents))
+		}
+		return nil
+	})
+}
+
+func printApplications(applications []map[string]interface{}, output string) {
 	for idx, app := range applications {
 		applications[idx]["state"] = app["status"].(map[string]interface{})["status"].(string)
+
+		// Summarize the replicas as running/desired along with the nodes they are placed on
+		instanceStatus, _ := app["instance_status"].(map[string]interface{})
+		instances, _ := app["instances"].([]interface{})
+		nodes := []string{}
+		running := 0
+		ready := 0
+		restarts := 0
+		for _, instance := range instances {
+			instanceMap := instance.(map[string]interface{})
+			node := instanceMap["node"].(string)
+			if !utils.Contains(nodes, node) {
+				nodes = append(nodes, node)
+			}
+			if status, ok := instanceStatus[instanceMap["name"].(string)].(map[string]interface{}); ok {
+				if status["status"] == "running" {
+					running++
+				}
+				if status["ready"] == true {
+					ready++
+				}
+				if count, ok := status["restarts"].(float64); ok {
+					restarts += int(count)
+				}
+			}
+		}
+		if len(instances) > 0 {
+			applications[idx]["node"] = strings.Join(nodes, ",")
+		}
+		applications[idx]["replicas"] = fmt.Sprintf("%v/%v", running, len(instances))
+		applications[idx]["ready"] = fmt.Sprintf("%v/%v", ready, len(instances))
+		applications[idx]["restarts"] = fmt.Sprintf("%v", restarts)
 	}
 
 	headers := []string{
@@ -118,6 +218,9 @@ func ExecuteApplication(id, output, namespace string) error {
 		"node",
 		"hostname",
 		"namespace",
+		"replicas",
+		"ready",
+		"restarts",
 		"state",
 	}
 	types := []string{
@@ -128,6 +231,9 @@ func ExecuteApplication(id, output, namespace string) error {
 		"string",
 		"string",
 		"string",
+		"string",
+		"string",
+		"string",
 	}
 
 	switch output {
@@ -140,7 +246,4 @@ func ExecuteApplication(id, output, namespace string) error {
 		contents, _ := json.Marshal(&applications)
 		fmt.Println(string(contents))
 	}
-	logging.Success("Done!")
-
-	return nil
 }
@@ -0,0 +1,174 @@
+package event
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"stormfront-cli/action"
+	"stormfront-cli/logging"
+	"stormfront-cli/utils"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+var EventHelpText = fmt.Sprintf(`usage: stormfront get event [-w|--watch] [--since <time>] [--object <object>] [--type <type>] [-o|--output <output>] [-l|--log-level <log level>] [-h|--help]
+arguments:
+	-w|--watch        Keep printing new events as they are recorded until interrupted
+	--since           Only show events since a timestamp or relative duration such as 10m
+	--object          Only show events about an object, given as <kind>/<name or id> such as application/web or node/<node id>
+	--type            Only show events of a type such as DeployFailed or NodeUnknown
+	-o|--output       Output format to print to console, valid options are "table", "yaml", and "json"
+	-l|--log-level    Sets the log level of the CLI. valid levels are: %s, defaults to %s
+	-h|--help         Show this help message and exit`, logging.GetDefaults(), logging.ERROR_NAME)
+
+func ParseEventArgs(args []string) (action.EventOptions, string, error) {
+	options := action.EventOptions{}
+	output := "table"
+	envLogLevel, present := os.LookupEnv("STORMFRONT_LOG_LEVEL")
+	if present {
+		if err := logging.SetLevel(envLogLevel); err != nil {
+			fmt.Printf("Env logging level %s (from STORMFRONT_LOG_LEVEL) is invalid, skipping", envLogLevel)
+		}
+	}
+
+	for len(args) > 0 {
+		switch args[0] {
+		case "-w", "--watch":
+			options.Watch = true
+			args = args[1:]
+		case "--since":
+			if len(args) > 1 {
+				options.Since = args[1]
+				args = args[2:]
+			} else {
+				return options, "", errors.New("no value passed after since flag")
+			}
+		case "--object":
+			if len(args) > 1 {
+				options.Object = args[1]
+				args = args[2:]
+			} else {
+				return options, "", errors.New("no value passed after object flag")
+			}
+		case "--type":
+			if len(args) > 1 {
+				options.Type = args[1]
+				args = args[2:]
+			} else {
+				return options, "", errors.New("no value passed after type flag")
+			}
+		case "-o", "--output":
+			if len(args) > 1 {
+				switch args[1] {
+				case "table", "yaml", "json":
+					output = args[1]
+				default:
+					return options, "", fmt.Errorf("invalid output value %s, allowed values are 'table', 'yaml', and 'json", args[1])
+				}
+				args = args[2:]
+			} else {
+				return options, "", errors.New("no value passed after output flag")
+			}
+		case "-l", "--log-level":
+			if len(args) > 1 {
+				err := logging.SetLevel(args[1])
+				if err != nil {
+					return options, "", err
+				}
+				args = args[2:]
+			} else {
+				return options, "", errors.New("no value passed after log-level flag")
+			}
+		default:
+			fmt.Printf("Invalid argument: %s\n", args[0])
+			fmt.Println(EventHelpText)
+			os.Exit(1)
+		}
+	}
+
+	return options, output, nil
+}
+
+func ExecuteEvent(options action.EventOptions, output string) error {
+	if options.Watch {
+		return action.WatchEvents(options, func(event action.Event) error {
+			return printEvent(event, output)
+		})
+	}
+
+	events, err := action.GetEvents(options)
+	if err != nil {
+		return err
+	}
+
+	switch output {
+	case "table":
+		headers := []string{
+			"time",
+			"type",
+			"object",
+			"namespace",
+			"node",
+			"message",
+		}
+		types := []string{
+			"string",
+			"string",
+			"string",
+			"string",
+			"string",
+			"string",
+		}
+		data := []map[string]interface{}{}
+		for _, event := range events {
+			data = append(data, map[string]interface{}{
+				"time":      formatTime(event.Time),
+				"type":      event.Type,
+				"object":    event.Object(),
+				"namespace": event.Namespace,
+				"node":      event.Node,
+				"message":   event.Message,
+			})
+		}
+		utils.PrintTable(data, headers, types)
+	case "yaml":
+		contents, _ := yaml.Marshal(&events)
+		fmt.Println(string(contents))
+	case "json":
+		contents, _ := json.Marshal(&events)
+		fmt.Println(string(contents))
+	}
+	logging.Success("Done!")
+
+	return nil
+}
+
+// printEvent prints a single watched event, one line per event for tables and
+// json so the output can be piped through line based tools
+func printEvent(event action.Event, output string) error {
+	switch output {
+	case "table":
+		namespace := event.Namespace
+		if namespace == "" {
+			namespace = "-"
+		}
+		fmt.Printf("%s  %s  %s  %s  %s  %s\n", formatTime(event.Time), event.Type, event.Object(), namespace, event.Node, event.Message)
+	case "yaml":
+		contents, _ := yaml.Marshal(&event)
+		fmt.Printf("---\n%s", string(contents))
+	case "json":
+		contents, _ := json.Marshal(&event)
+		fmt.Println(string(contents))
+	}
+	return nil
+}
+
+func formatTime(value string) string {
+	t, err := time.Parse(time.RFC3339Nano, value)
+	if err != nil {
+		return value
+	}
+	return t.Local().Format(time.RFC3339)
+}
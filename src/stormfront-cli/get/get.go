@@ -6,9 +6,12 @@ import (
 	"stormfront-cli/get/application"
 	"stormfront-cli/get/client"
 	"stormfront-cli/get/cluster"
+	"stormfront-cli/get/event"
 	"stormfront-cli/get/namespace"
 	"stormfront-cli/get/node"
 	"stormfront-cli/get/route"
+	"stormfront-cli/get/secret"
+	"stormfront-cli/get/volume"
 	"stormfront-cli/logging"
 	"stormfront-cli/utils"
 )
@@ -18,9 +21,12 @@ commands:
 	application       Get information about running applications
 	client            Get information about running clients
 	cluster           Get information about available clusters
+	event             Get or watch events recorded in current cluster
 	namespace         Get information about namespaces in current cluster
 	node              Get information about running nodes
 	route             Get information about defined routes
+	secret            Get information about defined secrets
+	volume            Get information about defined volumes
 arguments:
 	-l|--log-level    Sets the log level of the CLI. valid levels are: %s, defaults to %s
 	-h|--help         Show this help message and exit`, logging.GetDefaults(), logging.ERROR_NAME)
@@ -60,13 +66,13 @@ func ParseGetArgs(args []string) {
 
 	switch args[1] {
 	case "application", "app":
-		id, output, namespace, err := application.ParseApplicationArgs(args[2:])
+		id, output, namespace, watch, err := application.ParseApplicationArgs(args[2:])
 		if err != nil {
 			logging.Error(err.Error())
 			fmt.Println(GetHelpText)
 			os.Exit(1)
 		}
-		err = application.ExecuteApplication(id, output, namespace)
+		err = application.ExecuteApplication(id, output, namespace, watch)
 		if err != nil {
 			logging.Error(err.Error())
 			os.Exit(1)
@@ -83,6 +89,18 @@ func ParseGetArgs(args []string) {
 			logging.Error(err.Error())
 			os.Exit(1)
 		}
+	case "event", "events", "ev":
+		options, output, err := event.ParseEventArgs(args[2:])
+		if err != nil {
+			logging.Error(err.Error())
+			fmt.Println(GetHelpText)
+			os.Exit(1)
+		}
+		err = event.ExecuteEvent(options, output)
+		if err != nil {
+			logging.Error(err.Error())
+			os.Exit(1)
+		}
 	case "route", "rt":
 		id, output, namespace, err := route.ParseRouteArgs(args[2:])
 		if err != nil {
@@ -95,6 +113,30 @@ func ParseGetArgs(args []string) {
 			logging.Error(err.Error())
 			os.Exit(1)
 		}
+	case "secret", "sc":
+		id, output, namespace, err := secret.ParseSecretArgs(args[2:])
+		if err != nil {
+			logging.Error(err.Error())
+			fmt.Println(GetHelpText)
+			os.Exit(1)
+		}
+		err = secret.ExecuteSecret(id, output, namespace)
+		if err != nil {
+			logging.Error(err.Error())
+			os.Exit(1)
+		}
+	case "volume", "vol":
+		id, output, namespace, err := volume.ParseVolumeArgs(args[2:])
+		if err != nil {
+			logging.Error(err.Error())
+			fmt.Println(GetHelpText)
+			os.Exit(1)
+		}
+		err = volume.ExecuteVolume(id, output, namespace)
+		if err != nil {
+			logging.Error(err.Error())
+			os.Exit(1)
+		}
 	case "namespace", "ns":
 		output, err := namespace.ParseNamespaceArgs(args[2:])
 		if err != nil {
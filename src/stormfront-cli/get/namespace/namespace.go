@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"stormfront-cli/action"
 	"stormfront-cli/config"
 	"stormfront-cli/logging"
 	"stormfront-cli/utils"
@@ -62,9 +63,7 @@ func ParseNamespaceArgs(args []string) (string, error) {
 }
 
 func ExecuteNamespace(output string) error {
-	logging.Info("Getting namespaces...")
-
-	namespaces, err := config.GetNamespaces()
+	namespaceData, err := action.GetAllNamespaces()
 	if err != nil {
 		return err
 	}
@@ -73,25 +72,26 @@ func ExecuteNamespace(output string) error {
 		return err
 	}
 
-	namespaceData := []map[string]interface{}{}
-
-	for _, namespace := range namespaces {
-		datum := map[string]interface{}{}
-		datum["name"] = namespace
-		if namespace == currentNamespace {
-			datum["selected"] = "*"
+	for idx, namespace := range namespaceData {
+		name, ok := namespace["name"].(string)
+		if !ok {
+			return fmt.Errorf("invalid namespace returned by leader: %v", namespace)
+		}
+		if name == currentNamespace {
+			namespaceData[idx]["selected"] = "*"
 		} else {
-			datum["selected"] = ""
+			namespaceData[idx]["selected"] = ""
 		}
-		namespaceData = append(namespaceData, datum)
 	}
 	headers := []string{
+		"id",
 		"name",
 		"selected",
 	}
 	types := []string{
 		"string",
 		"string",
+		"string",
 	}
 
 	switch output {
@@ -38,7 +38,7 @@ func ParseNodeArgs(args []string) (string, string, error) {
 				case "table", "yaml", "json":
 					output = args[1]
 				default:
-					return "", "", fmt.Errorf("invalid output value %s, allowed values are 'table', 'yaml', and 'json")
+					return "", "", fmt.Errorf("invalid output value %s, allowed values are 'table', 'yaml', and 'json", args[1])
 				}
 				args = args[2:]
 			} else {
@@ -83,9 +83,9 @@ func ExecuteNode(id, output string) error {
 
 	requestURL := ""
 	if id == "" {
-		requestURL = fmt.Sprintf("http://%s:%s/api/node", host, port)
+		requestURL = fmt.Sprintf("https://%s:%s/api/node", host, port)
 	} else {
-		requestURL = fmt.Sprintf("http://%s:%s/api/node/%s", host, port, id)
+		requestURL = fmt.Sprintf("https://%s:%s/api/node/%s", host, port, id)
 	}
 
 	logging.Debug("Sending GET request to client...")
@@ -96,7 +96,10 @@ func ExecuteNode(id, output string) error {
 		return err
 	}
 
-	httpClient := &http.Client{}
+	httpClient, err := config.HTTPClient()
+	if err != nil {
+		return err
+	}
 	req, _ := http.NewRequest("GET", requestURL, nil)
 	req.Header.Set("Authorization", fmt.Sprintf("X-Stormfront-API %s", apiToken))
 	resp, err := httpClient.Do(req)
@@ -17,19 +17,21 @@ import (
 	"github.com/google/uuid"
 )
 
-var JoinHelpText = fmt.Sprintf(`usage: stormfront join -l <leader URL> -j <join-token> [-H <stormfront host>] [-p <stormfront port>] [-l <log level>] [-h|--help]
+var JoinHelpText = fmt.Sprintf(`usage: stormfront join -L <leader URL> -j <join-token> -c <CA fingerprint> [-H <stormfront host>] [-p <stormfront port>] [-l <log level>] [-h|--help]
 arguments:
-	-H|--host          The host of the stormfront client to connect to, defaults to "localhost"
-	-p|--port          The port of the stormfront client to connect to, defaults to "6626"
-	-L|--leader        URL of the leader to join in the form <host>:<port>
-	-j|--join-token    Join token to use to connect to the leader
-	-l|--log-level     Sets the log level of the CLI. valid levels are: %s, defaults to %s
-	-h|--help          Show this help message and exit`, logging.GetDefaults(), logging.ERROR_NAME)
-
-func ParseJoinArgs(args []string) (string, string, string, string, error) {
+	-H|--host              The host of the stormfront client to connect to, defaults to "localhost"
+	-p|--port              The port of the stormfront client to connect to, defaults to "6626"
+	-L|--leader            URL of the leader to join in the form <host>:<port>
+	-j|--join-token        Join token to use to connect to the leader
+	-c|--ca-fingerprint    SHA-256 fingerprint of the cluster CA, as printed by "stormfront token join command"
+	-l|--log-level         Sets the log level of the CLI. valid levels are: %s, defaults to %s
+	-h|--help              Show this help message and exit`, logging.GetDefaults(), logging.ERROR_NAME)
+
+func ParseJoinArgs(args []string) (string, string, string, string, string, error) {
 	host := "localhost"
 	port := "6626"
 	joinToken := ""
+	caFingerprint := ""
 	leader := ""
 	envLogLevel, present := os.LookupEnv("STORMFRONT_LOG_LEVEL")
 	if present {
@@ -45,38 +47,45 @@ func ParseJoinArgs(args []string) (string, string, string, string, error) {
 				host = args[1]
 				args = args[2:]
 			} else {
-				return "", "", "", "", errors.New("no value passed after host flag")
+				return "", "", "", "", "", errors.New("no value passed after host flag")
 			}
 		case "-j", "--join-token":
 			if len(args) > 1 {
 				joinToken = args[1]
 				args = args[2:]
 			} else {
-				return "", "", "", "", errors.New("no value passed after log-level flag")
+				return "", "", "", "", "", errors.New("no value passed after log-level flag")
+			}
+		case "-c", "--ca-fingerprint":
+			if len(args) > 1 {
+				caFingerprint = args[1]
+				args = args[2:]
+			} else {
+				return "", "", "", "", "", errors.New("no value passed after ca-fingerprint flag")
 			}
 		case "-L", "--leader":
 			if len(args) > 1 {
 				leader = args[1]
 				args = args[2:]
 			} else {
-				return "", "", "", "", errors.New("no value passed after log-level flag")
+				return "", "", "", "", "", errors.New("no value passed after log-level flag")
 			}
 		case "-p", "--port":
 			if len(args) > 1 {
 				port = args[1]
 				args = args[2:]
 			} else {
-				return "", "", "", "", errors.New("no value passed after port flag")
+				return "", "", "", "", "", errors.New("no value passed after port flag")
 			}
 		case "-l", "--log-level":
 			if len(args) > 1 {
 				err := logging.SetLevel(args[1])
 				if err != nil {
-					return "", "", "", "", err
+					return "", "", "", "", "", err
 				}
 				args = args[2:]
 			} else {
-				return "", "", "", "", errors.New("no value passed after log-level flag")
+				return "", "", "", "", "", errors.New("no value passed after log-level flag")
 			}
 		default:
 			fmt.Printf("Invalid argument: %s\n", args[0])
@@ -85,10 +94,16 @@ func ParseJoinArgs(args []string) (string, string, string, string, error) {
 		}
 	}
 
-	return host, port, leader, joinToken, nil
+	// The fingerprint is what lets the node tell the leader apart from
+	// whoever answers in its place, so joining without it is refused
+	if caFingerprint == "" {
+		return "", "", "", "", "", errors.New("no CA fingerprint given, use the join command printed by \"stormfront token join command\"")
+	}
+
+	return host, port, leader, joinToken, caFingerprint, nil
 }
 
-func ExecuteJoin(host, port, leader, joinToken string) error {
+func ExecuteJoin(host, port, leader, joinToken, caFingerprint string) error {
 
 	logging.Info("Deploying stormfront node...")
 
@@ -105,7 +120,7 @@ func ExecuteJoin(host, port, leader, joinToken string) error {
 		logging.Fatal(fmt.Sprintf("Invalid port number: %s", parts[1]))
 	}
 
-	data := map[string]interface{}{"leader_host": parts[0], "leader_port": intPort, "join_token": joinToken}
+	data := map[string]interface{}{"leader_host": parts[0], "leader_port": intPort, "join_token": joinToken, "ca_fingerprint": caFingerprint}
 
 	postBody, _ := json.Marshal(data)
 	postBodyBuffer := bytes.NewBuffer(postBody)
@@ -129,7 +144,12 @@ func ExecuteJoin(host, port, leader, joinToken string) error {
 	logging.Debug(fmt.Sprintf("Response body: %s", responseBody))
 
 	if resp.StatusCode == http.StatusOK {
-		apiToken, err := auth.GetAPIToken(host, port)
+		ca, err := auth.ReadCA()
+		if err != nil {
+			return err
+		}
+
+		apiToken, err := auth.GetAPIToken(host, port, ca)
 		if err != nil {
 			return err
 		}
@@ -139,10 +159,10 @@ func ExecuteJoin(host, port, leader, joinToken string) error {
 		clusterData := config.ClusterConfig{
 			Name:             clusterName,
 			Token:            apiToken,
-			CurrentNamespace: "default",
-			Namespaces:       []string{"default"},
+			CurrentNamespace: config.DEFAULT_NAMESPACE,
 			Host:             host,
 			Port:             port,
+			CA:               string(ca),
 		}
 
 		err = config.AddCluster(clusterData)
@@ -7,18 +7,25 @@ import (
 	"stormfront-cli/action"
 	"stormfront-cli/config"
 	"stormfront-cli/logging"
+	"strconv"
 	"strings"
 )
 
-var LogsHelpText = fmt.Sprintf(`usage: stormfront logs <application id> [-l|--log-level <log level>] [-h|--help]
+var LogsHelpText = fmt.Sprintf(`usage: stormfront logs <application id> [-f|--follow] [--tail <lines>] [--since <time>] [-t|--timestamps] [-n|--namespace <namespace>] [-l|--log-level <log level>] [-h|--help]
 arguments:
 	-i|--id           The ID of the application to get
+	-f|--follow       Keep streaming new log output until interrupted
+	--tail            Number of lines to show from the end of the logs, defaults to all
+	--since           Only show logs since a timestamp or relative duration such as 10m
+	-t|--timestamps   Prefix each line with its timestamp
+	-n|--namespace    Namespace the application belongs to
 	-l|--log-level    Sets the log level of the CLI. valid levels are: %s, defaults to %s
 	-h|--help         Show this help message and exit`, logging.GetDefaults(), logging.ERROR_NAME)
 
-func ParseLogsArgs(args []string) (string, string, error) {
+func ParseLogsArgs(args []string) (string, string, action.LogOptions, error) {
 	id := ""
 	namespace := ""
+	options := action.LogOptions{}
 	envLogLevel, present := os.LookupEnv("STORMFRONT_LOG_LEVEL")
 	if present {
 		if err := logging.SetLevel(envLogLevel); err != nil {
@@ -32,18 +39,41 @@ func ParseLogsArgs(args []string) (string, string, error) {
 			if len(args) > 1 {
 				err := logging.SetLevel(args[1])
 				if err != nil {
-					return "", "", err
+					return "", "", options, err
 				}
 				args = args[2:]
 			} else {
-				return "", "", errors.New("no value passed after log-level flag")
+				return "", "", options, errors.New("no value passed after log-level flag")
+			}
+		case "-f", "--follow":
+			options.Follow = true
+			args = args[1:]
+		case "-t", "--timestamps":
+			options.Timestamps = true
+			args = args[1:]
+		case "--tail":
+			if len(args) > 1 {
+				if lines, err := strconv.Atoi(args[1]); err != nil || lines < 0 {
+					return "", "", options, fmt.Errorf("invalid tail value %s, must be a positive number of lines", args[1])
+				}
+				options.Tail = args[1]
+				args = args[2:]
+			} else {
+				return "", "", options, errors.New("no value passed after tail flag")
+			}
+		case "--since":
+			if len(args) > 1 {
+				options.Since = args[1]
+				args = args[2:]
+			} else {
+				return "", "", options, errors.New("no value passed after since flag")
 			}
 		case "-n", "--namespace":
 			if len(args) > 1 {
 				namespace = args[1]
 				args = args[2:]
 			} else {
-				return "", "", errors.New("no value passed after namespace flag")
+				return "", "", options, errors.New("no value passed after namespace flag")
 			}
 		default:
 			if strings.HasPrefix(args[0], "-") || id != "" {
@@ -57,11 +87,10 @@ func ParseLogsArgs(args []string) (string, string, error) {
 		}
 	}
 
-	return id, namespace, nil
+	return id, namespace, options, nil
 }
 
-func ExecuteLogs(id, namespace string) error {
-	var output string
+func ExecuteLogs(id, namespace string, options action.LogOptions) error {
 	var err error
 	if namespace == "" {
 		namespace, err = config.GetNamespace()
@@ -70,15 +99,13 @@ func ExecuteLogs(id, namespace string) error {
 		}
 	}
 
-	output, err = action.GetLogsByNameNamespace(id, namespace)
+	err = action.GetLogsByNameNamespace(id, namespace, options, os.Stdout)
 	if err != nil {
-		output, err = action.GetLogsById(id)
+		err = action.GetLogsById(id, options, os.Stdout)
 		if err != nil {
 			return err
 		}
 	}
 
-	fmt.Println(output)
-
 	return nil
 }
@@ -4,28 +4,36 @@ import (
 	"fmt"
 	"os"
 	"stormfront-cli/apply"
+	"stormfront-cli/bolt"
 	"stormfront-cli/create"
 	"stormfront-cli/delete"
 	"stormfront-cli/edit"
+	"stormfront-cli/exec"
 	"stormfront-cli/get"
 	"stormfront-cli/join"
 	"stormfront-cli/logging"
 	"stormfront-cli/logs"
 	"stormfront-cli/token"
+	"stormfront-cli/top"
+	"stormfront-cli/update"
 	"stormfront-cli/utils"
 )
 
 var HelpText = fmt.Sprintf(`usage: stormfront <command> [-l|--log-level <log level>] [-h|--help]
 commands:
 	apply            Apply an object definition file
+	bolt             Run and manage shell commands on cluster nodes
 	create           Create a Stormfront client
 	delete           Delete Stormfront objects
 	edit             Change cluster or namespace in your ~/.stormfrontconfig file
+	exec             Run a command inside a running application
 	get              Get Stormfront cluster objects
 	join             Join an existing Stormfront cluster
 	logs             Get logs for a running application
 	restart          Restart a running client or application
 	token            Manage cluster access, API, and join tokens
+	top              Show resource usage history of nodes and applications
+	update           Update an existing Stormfront object
 arguments:
 	-l|--log-level    Sets the log level of the CLI. valid levels are: %s, defaults to %s
 	-h|--help         Show this help message and exit`, logging.GetDefaults(), logging.ERROR_NAME)
@@ -79,6 +87,8 @@ func main() {
 			logging.Error(err.Error())
 			os.Exit(1)
 		}
+	case "bolt":
+		bolt.ParseBoltArgs(args[1:])
 	case "create":
 		create.ParseCreateArgs(args[1:])
 	case "delete":
@@ -88,25 +98,38 @@ func main() {
 	case "edit":
 		edit.ParseEditArgs(args[1:])
 	case "join":
-		host, port, leader, joinToken, err := join.ParseJoinArgs(args[2:])
+		host, port, leader, joinToken, caFingerprint, err := join.ParseJoinArgs(args[2:])
 		if err != nil {
 			logging.Error(err.Error())
 			fmt.Println(HelpText)
 			os.Exit(1)
 		}
-		err = join.ExecuteJoin(host, port, leader, joinToken)
+		err = join.ExecuteJoin(host, port, leader, joinToken, caFingerprint)
 		if err != nil {
 			logging.Error(err.Error())
 			os.Exit(1)
 		}
+	case "exec":
+		id, namespace, options, err := exec.ParseExecArgs(args[2:])
+		if err != nil {
+			logging.Error(err.Error())
+			fmt.Println(exec.ExecHelpText)
+			os.Exit(1)
+		}
+		exitCode, err := exec.ExecuteExec(id, namespace, options)
+		if err != nil {
+			logging.Error(err.Error())
+			os.Exit(1)
+		}
+		os.Exit(exitCode)
 	case "logs":
-		id, namespace, err := logs.ParseLogsArgs(args[2:])
+		id, namespace, options, err := logs.ParseLogsArgs(args[2:])
 		if err != nil {
 			logging.Error(err.Error())
 			fmt.Println(HelpText)
 			os.Exit(1)
 		}
-		err = logs.ExecuteLogs(id, namespace)
+		err = logs.ExecuteLogs(id, namespace, options)
 		if err != nil {
 			logging.Error(err.Error())
 			os.Exit(1)
@@ -115,8 +138,10 @@ func main() {
 		get.ParseGetArgs(args[1:])
 	case "token":
 		token.ParseTokenArgs(args[1:])
-	// case "update":
-	// 	update.ParseUpdateArgs(args[1:])
+	case "top":
+		top.ParseTopArgs(args[1:])
+	case "update":
+		update.ParseUpdateArgs(args[1:])
 	default:
 		logging.Error(fmt.Sprintf("Invalid argument: %s\n", args[1]))
 		fmt.Println(HelpText)
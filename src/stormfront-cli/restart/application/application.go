@@ -78,7 +78,7 @@ func ParseApplicationArgs(args []string) (string, string, string, error) {
 func ExecuteApplication(host, port, id string) error {
 	logging.Info("Restarting application...")
 
-	requestURL := fmt.Sprintf("http://%s:%s/api/application/%s/restart", host, port, id)
+	requestURL := fmt.Sprintf("https://%s:%s/api/application/%s/restart", host, port, id)
 
 	logging.Debug("Sending GET request to client...")
 	logging.Trace(fmt.Sprintf("Sending request to %s", requestURL))
@@ -88,7 +88,10 @@ func ExecuteApplication(host, port, id string) error {
 		return err
 	}
 
-	httpClient := &http.Client{}
+	httpClient, err := config.HTTPClient()
+	if err != nil {
+		return err
+	}
 	req, _ := http.NewRequest("GET", requestURL, nil)
 	req.Header.Set("Authorization", fmt.Sprintf("X-Stormfront-API %s", apiToken))
 	resp, err := httpClient.Do(req)
@@ -62,12 +62,15 @@ func ExecuteRefresh() error {
 
 	logging.Info("Refreshing stormfront client token...")
 
-	requestURL := fmt.Sprintf("http://%s:%s/auth/refresh", host, port)
+	requestURL := fmt.Sprintf("https://%s:%s/auth/refresh", host, port)
 
 	logging.Debug("Sending GET request to client...")
 	logging.Trace(fmt.Sprintf("Sending request to %s", requestURL))
 
-	httpClient := &http.Client{}
+	httpClient, err := config.HTTPClient()
+	if err != nil {
+		return err
+	}
 	req, _ := http.NewRequest("GET", requestURL, nil)
 	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", clientInfo.AccessToken))
 	resp, err := httpClient.Do(req)
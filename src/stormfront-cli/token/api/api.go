@@ -10,6 +10,7 @@ import (
 var APIHelpText = fmt.Sprintf(`usage: stormfront token api <command> [-l|--log-level <log level>] [-h|--help]
 commands:
 	get               Get an API token for this stormfront cluster
+	list              List the API tokens for this stormfront cluster
 	revoke            Revoke an existing API token for this stormfront cluster
 arguments:
 	-l|--log-level    Sets the log level of the CLI. valid levels are: %s, defaults to %s
@@ -50,25 +51,37 @@ func ParseAPIArgs(args []string) {
 
 	switch args[1] {
 	case "get":
-		err := ParseGetArgs(args[2:])
+		name, role, namespaces, ttl, err := ParseGetArgs(args[2:])
 		if err != nil {
 			logging.Error(err.Error())
 			fmt.Println(APIHelpText)
 			os.Exit(1)
 		}
-		err = ExecuteGet()
+		err = ExecuteGet(name, role, namespaces, ttl)
+		if err != nil {
+			logging.Error(err.Error())
+			os.Exit(1)
+		}
+	case "list":
+		output, err := ParseListArgs(args[2:])
+		if err != nil {
+			logging.Error(err.Error())
+			fmt.Println(APIHelpText)
+			os.Exit(1)
+		}
+		err = ExecuteList(output)
 		if err != nil {
 			logging.Error(err.Error())
 			os.Exit(1)
 		}
 	case "revoke":
-		token, err := ParseRevokeArgs(args[2:])
+		token, name, err := ParseRevokeArgs(args[2:])
 		if err != nil {
 			logging.Error(err.Error())
 			fmt.Println(APIHelpText)
 			os.Exit(1)
 		}
-		err = ExecuteRevoke(token)
+		err = ExecuteRevoke(token, name)
 		if err != nil {
 			logging.Error(err.Error())
 			os.Exit(1)
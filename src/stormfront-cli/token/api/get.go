@@ -6,17 +6,27 @@ import (
 	"fmt"
 	"io/ioutil"
 	"net/http"
+	"net/url"
 	"os"
 	"stormfront-cli/config"
 	"stormfront-cli/logging"
+	"strconv"
 )
 
-var APITokenGetHelpText = fmt.Sprintf(`usage: stormfront api-token get [-l|--log-level <log level>] [-h|--help]
+var APITokenGetHelpText = fmt.Sprintf(`usage: stormfront token api get [-N|--name <token name>] [-r|--role <role>] [-n|--namespace <namespace>]... [--ttl <seconds>] [-l|--log-level <log level>] [-h|--help]
 arguments:
+	-N|--name         Name of the API token, defaults to a generated name
+	-r|--role         Role of the API token, one of admin, deployer, or read-only, defaults to admin
+	-n|--namespace    Namespace the API token is limited to, can be passed more than once
+	--ttl             Seconds until the API token expires, defaults to never expiring
 	-l|--log-level    Sets the log level of the CLI. valid levels are: %s, defaults to %s
 	-h|--help         Show this help message and exit`, logging.GetDefaults(), logging.ERROR_NAME)
 
-func ParseGetArgs(args []string) error {
+func ParseGetArgs(args []string) (string, string, []string, int, error) {
+	name := ""
+	role := ""
+	namespaces := []string{}
+	ttl := 0
 	envLogLevel, present := os.LookupEnv("STORMFRONT_LOG_LEVEL")
 	if present {
 		if err := logging.SetLevel(envLogLevel); err != nil {
@@ -26,15 +36,47 @@ func ParseGetArgs(args []string) error {
 
 	for len(args) > 0 {
 		switch args[0] {
+		case "-N", "--name":
+			if len(args) > 1 {
+				name = args[1]
+				args = args[2:]
+			} else {
+				return "", "", nil, 0, errors.New("no value passed after name flag")
+			}
+		case "-r", "--role":
+			if len(args) > 1 {
+				role = args[1]
+				args = args[2:]
+			} else {
+				return "", "", nil, 0, errors.New("no value passed after role flag")
+			}
+		case "-n", "--namespace":
+			if len(args) > 1 {
+				namespaces = append(namespaces, args[1])
+				args = args[2:]
+			} else {
+				return "", "", nil, 0, errors.New("no value passed after namespace flag")
+			}
+		case "--ttl":
+			if len(args) > 1 {
+				value, err := strconv.Atoi(args[1])
+				if err != nil || value < 0 {
+					return "", "", nil, 0, fmt.Errorf("invalid ttl %s, must be a non-negative number of seconds", args[1])
+				}
+				ttl = value
+				args = args[2:]
+			} else {
+				return "", "", nil, 0, errors.New("no value passed after ttl flag")
+			}
 		case "-l", "--log-level":
 			if len(args) > 1 {
 				err := logging.SetLevel(args[1])
 				if err != nil {
-					return err
+					return "", "", nil, 0, err
 				}
 				args = args[2:]
 			} else {
-				return errors.New("no value passed after log-level flag")
+				return "", "", nil, 0, errors.New("no value passed after log-level flag")
 			}
 		default:
 			fmt.Printf("Invalid argument: %s\n", args[0])
@@ -43,10 +85,10 @@ func ParseGetArgs(args []string) error {
 		}
 	}
 
-	return nil
+	return name, role, namespaces, ttl, nil
 }
 
-func ExecuteGet() error {
+func ExecuteGet(name, role string, namespaces []string, ttl int) error {
 	host, err := config.GetHost()
 	if err != nil {
 		return err
@@ -59,7 +101,21 @@ func ExecuteGet() error {
 
 	logging.Info("Getting API token...")
 
-	requestURL := fmt.Sprintf("http://%s:%s/auth/api", host, port)
+	query := url.Values{}
+	if name != "" {
+		query.Set("name", name)
+	}
+	if role != "" {
+		query.Set("role", role)
+	}
+	for _, namespace := range namespaces {
+		query.Add("namespace", namespace)
+	}
+	if ttl > 0 {
+		query.Set("ttl", strconv.Itoa(ttl))
+	}
+
+	requestURL := fmt.Sprintf("https://%s:%s/auth/api?%s", host, port, query.Encode())
 
 	logging.Debug("Sending GET request to client...")
 	logging.Trace(fmt.Sprintf("Sending request to %s", requestURL))
@@ -69,7 +125,10 @@ func ExecuteGet() error {
 		return err
 	}
 
-	httpClient := &http.Client{}
+	httpClient, err := config.HTTPClient()
+	if err != nil {
+		return err
+	}
 	req, _ := http.NewRequest("GET", requestURL, nil)
 	req.Header.Set("Authorization", fmt.Sprintf("X-Stormfront-API %s", apiToken))
 	resp, err := httpClient.Do(req)
@@ -91,8 +150,9 @@ func ExecuteGet() error {
 	logging.Debug(fmt.Sprintf("Response body: %s", responseBody))
 
 	if resp.StatusCode == http.StatusOK {
-		responseJSON := map[string]string{}
+		responseJSON := map[string]interface{}{}
 		json.Unmarshal(body, &responseJSON)
+		logging.Info(fmt.Sprintf("Created API token %v with role %v", responseJSON["name"], responseJSON["role"]))
 		fmt.Println(responseJSON["token"])
 		logging.Success("Done!")
 	} else {
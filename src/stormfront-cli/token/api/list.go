@@ -0,0 +1,170 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"stormfront-cli/config"
+	"stormfront-cli/logging"
+	"stormfront-cli/utils"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+var APITokenListHelpText = fmt.Sprintf(`usage: stormfront token api list [-o|--output <output>] [-l|--log-level <log level>] [-h|--help]
+arguments:
+	-o|--output       Output format to print to console, valid options are "table", "yaml", and "json"
+	-l|--log-level    Sets the log level of the CLI. valid levels are: %s, defaults to %s
+	-h|--help         Show this help message and exit`, logging.GetDefaults(), logging.ERROR_NAME)
+
+func ParseListArgs(args []string) (string, error) {
+	output := "table"
+	envLogLevel, present := os.LookupEnv("STORMFRONT_LOG_LEVEL")
+	if present {
+		if err := logging.SetLevel(envLogLevel); err != nil {
+			fmt.Printf("Env logging level %s (from STORMFRONT_LOG_LEVEL) is invalid, skipping\n", envLogLevel)
+		}
+	}
+
+	for len(args) > 0 {
+		switch args[0] {
+		case "-o", "--output":
+			if len(args) > 1 {
+				switch args[1] {
+				case "table", "yaml", "json":
+					output = args[1]
+				default:
+					return "", fmt.Errorf("invalid output value %s, allowed values are 'table', 'yaml', and 'json", args[1])
+				}
+				args = args[2:]
+			} else {
+				return "", errors.New("no value passed after output flag")
+			}
+		case "-l", "--log-level":
+			if len(args) > 1 {
+				err := logging.SetLevel(args[1])
+				if err != nil {
+					return "", err
+				}
+				args = args[2:]
+			} else {
+				return "", errors.New("no value passed after log-level flag")
+			}
+		default:
+			fmt.Printf("Invalid argument: %s\n", args[0])
+			fmt.Println(APITokenListHelpText)
+			os.Exit(1)
+		}
+	}
+
+	return output, nil
+}
+
+func ExecuteList(output string) error {
+	host, err := config.GetHost()
+	if err != nil {
+		return err
+	}
+
+	port, err := config.GetPort()
+	if err != nil {
+		return err
+	}
+
+	logging.Info("Listing API tokens...")
+
+	requestURL := fmt.Sprintf("https://%s:%s/auth/api/list", host, port)
+
+	logging.Debug("Sending GET request to client...")
+	logging.Trace(fmt.Sprintf("Sending request to %s", requestURL))
+
+	apiToken, err := config.GetAPIToken()
+	if err != nil {
+		return err
+	}
+
+	httpClient, err := config.HTTPClient()
+	if err != nil {
+		return err
+	}
+	req, _ := http.NewRequest("GET", requestURL, nil)
+	req.Header.Set("Authorization", fmt.Sprintf("X-Stormfront-API %s", apiToken))
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+
+	logging.Debug("Done!")
+
+	defer resp.Body.Close()
+	//Read the response body
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	responseBody := string(body)
+
+	logging.Debug(fmt.Sprintf("Status code: %v", resp.StatusCode))
+	logging.Debug(fmt.Sprintf("Response body: %s", responseBody))
+
+	if resp.StatusCode != http.StatusOK {
+		var data map[string]string
+		if err := json.Unmarshal([]byte(responseBody), &data); err == nil {
+			if errMessage, ok := data["error"]; ok {
+				return errors.New(errMessage)
+			}
+		}
+		return fmt.Errorf("client has returned error with status code %v", resp.StatusCode)
+	}
+
+	var apiTokens []map[string]interface{}
+	if err := json.Unmarshal(body, &apiTokens); err != nil {
+		return err
+	}
+
+	switch output {
+	case "table":
+		for _, token := range apiTokens {
+			namespaces := []string{}
+			if scoped, ok := token["namespaces"].([]interface{}); ok {
+				for _, namespace := range scoped {
+					namespaces = append(namespaces, fmt.Sprintf("%v", namespace))
+				}
+			}
+			token["namespaces"] = strings.Join(namespaces, ",")
+			if token["expires"] == "" {
+				token["expires"] = "never"
+			}
+		}
+		headers := []string{
+			"name",
+			"role",
+			"namespaces",
+			"created",
+			"created_by",
+			"expires",
+		}
+		types := []string{
+			"string",
+			"string",
+			"string",
+			"string",
+			"string",
+			"string",
+		}
+		utils.PrintTable(apiTokens, headers, types)
+	case "yaml":
+		contents, _ := yaml.Marshal(&apiTokens)
+		fmt.Println(string(contents))
+	case "json":
+		contents, _ := json.Marshal(&apiTokens)
+		fmt.Println(string(contents))
+	}
+	logging.Success("Done!")
+
+	return nil
+}
@@ -6,19 +6,22 @@ import (
 	"fmt"
 	"io/ioutil"
 	"net/http"
+	"net/url"
 	"os"
 	"stormfront-cli/config"
 	"stormfront-cli/logging"
 )
 
-var APITokenRevokeHelpText = fmt.Sprintf(`usage: stormfront api-token revoke -t <token> [-l|--log-level <log level>] [-h|--help]
+var APITokenRevokeHelpText = fmt.Sprintf(`usage: stormfront token api revoke (-t <token>|-N <token name>) [-l|--log-level <log level>] [-h|--help]
 arguments:
 	-t|--token        The API token to revoke
+	-N|--name         Name of the API token to revoke, requires an admin API token
 	-l|--log-level    Sets the log level of the CLI. valid levels are: %s, defaults to %s
 	-h|--help         Show this help message and exit`, logging.GetDefaults(), logging.ERROR_NAME)
 
-func ParseRevokeArgs(args []string) (string, error) {
+func ParseRevokeArgs(args []string) (string, string, error) {
 	token := ""
+	name := ""
 	envLogLevel, present := os.LookupEnv("STORMFRONT_LOG_LEVEL")
 	if present {
 		if err := logging.SetLevel(envLogLevel); err != nil {
@@ -33,17 +36,24 @@ func ParseRevokeArgs(args []string) (string, error) {
 				token = args[1]
 				args = args[2:]
 			} else {
-				return "", errors.New("no value passed after token flag")
+				return "", "", errors.New("no value passed after token flag")
+			}
+		case "-N", "--name":
+			if len(args) > 1 {
+				name = args[1]
+				args = args[2:]
+			} else {
+				return "", "", errors.New("no value passed after name flag")
 			}
 		case "-l", "--log-level":
 			if len(args) > 1 {
 				err := logging.SetLevel(args[1])
 				if err != nil {
-					return "", err
+					return "", "", err
 				}
 				args = args[2:]
 			} else {
-				return "", errors.New("no value passed after log-level flag")
+				return "", "", errors.New("no value passed after log-level flag")
 			}
 		default:
 			fmt.Printf("Invalid argument: %s\n", args[0])
@@ -52,14 +62,17 @@ func ParseRevokeArgs(args []string) (string, error) {
 		}
 	}
 
-	if token == "" {
-		return "", errors.New("no token passed to revoke")
+	if token == "" && name == "" {
+		return "", "", errors.New("no token or token name passed to revoke")
+	}
+	if token != "" && name != "" {
+		return "", "", errors.New("only one of token and token name can be passed to revoke")
 	}
 
-	return token, nil
+	return token, name, nil
 }
 
-func ExecuteRevoke(token string) error {
+func ExecuteRevoke(token, name string) error {
 	host, err := config.GetHost()
 	if err != nil {
 		return err
@@ -72,12 +85,24 @@ func ExecuteRevoke(token string) error {
 
 	logging.Info("Revoking API token...")
 
-	requestURL := fmt.Sprintf("http://%s:%s/auth/api", host, port)
+	requestURL := fmt.Sprintf("https://%s:%s/auth/api", host, port)
+	if name != "" {
+		// Revoking by name authenticates with the configured API token
+		// instead of the token being revoked
+		requestURL = fmt.Sprintf("%s/%s", requestURL, url.PathEscape(name))
+		token, err = config.GetAPIToken()
+		if err != nil {
+			return err
+		}
+	}
 
 	logging.Debug("Sending DELETE request to client...")
 	logging.Trace(fmt.Sprintf("Sending request to %s", requestURL))
 
-	httpClient := &http.Client{}
+	httpClient, err := config.HTTPClient()
+	if err != nil {
+		return err
+	}
 	req, _ := http.NewRequest("DELETE", requestURL, nil)
 	req.Header.Set("Authorization", fmt.Sprintf("X-Stormfront-API %s", token))
 	req.Header.Set("X-Stormfront-API", token)
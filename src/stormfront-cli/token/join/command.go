@@ -67,7 +67,7 @@ func ParseGetJoinCommandArgs(args []string) (string, string, error) {
 func ExecuteGetJoinCommand(host, port string) error {
 	logging.Info("Getting join command from leader...")
 
-	requestURL := fmt.Sprintf("http://%s:%v/auth/join/command", host, port)
+	requestURL := fmt.Sprintf("https://%s:%v/auth/join/command", host, port)
 
 	logging.Debug("Sending GET request to client...")
 	logging.Trace(fmt.Sprintf("Sending request to %s", requestURL))
@@ -77,7 +77,10 @@ func ExecuteGetJoinCommand(host, port string) error {
 		return err
 	}
 
-	httpClient := &http.Client{}
+	httpClient, err := config.HTTPClient()
+	if err != nil {
+		return err
+	}
 	req, _ := http.NewRequest("GET", requestURL, nil)
 	req.Header.Set("Authorization", fmt.Sprintf("X-Stormfront-API %s", apiToken))
 	resp, err := httpClient.Do(req)
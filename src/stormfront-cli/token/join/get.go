@@ -67,7 +67,7 @@ func ParseGetArgs(args []string) (string, string, error) {
 func ExecuteGet(host, port string) error {
 	logging.Info("Getting API token...")
 
-	requestURL := fmt.Sprintf("http://%s:%s/auth/join", host, port)
+	requestURL := fmt.Sprintf("https://%s:%s/auth/join", host, port)
 
 	logging.Debug("Sending GET request to client...")
 	logging.Trace(fmt.Sprintf("Sending request to %s", requestURL))
@@ -77,7 +77,10 @@ func ExecuteGet(host, port string) error {
 		return err
 	}
 
-	httpClient := &http.Client{}
+	httpClient, err := config.HTTPClient()
+	if err != nil {
+		return err
+	}
 	req, _ := http.NewRequest("GET", requestURL, nil)
 	req.Header.Set("Authorization", fmt.Sprintf("X-Stormfront-API %s", apiToken))
 	resp, err := httpClient.Do(req)
@@ -11,6 +11,7 @@ var JoinHelpText = fmt.Sprintf(`usage: stormfront token join <command> [-l|--log
 commands:
     command           Get a join command for this stormfront cluster
 	get               Get a join token for this stormfront cluster
+	list              List the join tokens for this stormfront cluster
 	revoke            Revoke an existing join token for this stormfront cluster
 arguments:
 	-l|--log-level    Sets the log level of the CLI. valid levels are: %s, defaults to %s
@@ -74,6 +75,18 @@ func ParseJoinArgs(args []string) {
 			logging.Error(err.Error())
 			os.Exit(1)
 		}
+	case "list":
+		output, err := ParseListArgs(args[2:])
+		if err != nil {
+			logging.Error(err.Error())
+			fmt.Println(JoinHelpText)
+			os.Exit(1)
+		}
+		err = ExecuteList(output)
+		if err != nil {
+			logging.Error(err.Error())
+			os.Exit(1)
+		}
 	case "revoke":
 		token, err := ParseRevokeArgs(args[2:])
 		if err != nil {
@@ -13,7 +13,7 @@ import (
 
 var APITokenRevokeHelpText = fmt.Sprintf(`usage: stormfront token join revoke -t <token> [-l|--log-level <log level>] [-h|--help]
 arguments:
-	-t|--token        The join token to revoke
+	-t|--token        The join token, or the ID of the join token, to revoke
 	-l|--log-level    Sets the log level of the CLI. valid levels are: %s, defaults to %s
 	-h|--help         Show this help message and exit`, logging.GetDefaults(), logging.ERROR_NAME)
 
@@ -72,7 +72,7 @@ func ExecuteRevoke(token string) error {
 
 	logging.Info("Revoking join token...")
 
-	requestURL := fmt.Sprintf("http://%s:%s/auth/join/%s", host, port, token)
+	requestURL := fmt.Sprintf("https://%s:%s/auth/join/%s", host, port, token)
 
 	logging.Debug("Sending DELETE request to client...")
 	logging.Trace(fmt.Sprintf("Sending request to %s", requestURL))
@@ -82,7 +82,10 @@ func ExecuteRevoke(token string) error {
 		return err
 	}
 
-	httpClient := &http.Client{}
+	httpClient, err := config.HTTPClient()
+	if err != nil {
+		return err
+	}
 	req, _ := http.NewRequest("DELETE", requestURL, nil)
 	req.Header.Set("Authorization", fmt.Sprintf("X-Stormfront-API %s", apiToken))
 	resp, err := httpClient.Do(req)
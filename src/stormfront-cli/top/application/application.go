@@ -0,0 +1,178 @@
+package application
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"stormfront-cli/action"
+	"stormfront-cli/logging"
+	"stormfront-cli/utils"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+var ApplicationHelpText = fmt.Sprintf(`usage: stormfront top application [<application name or id>] [-n|--namespace <namespace>] [-r|--range <range>] [-o|--output <output>] [-l|--log-level <log level>] [-h|--help]
+arguments:
+	-n|--namespace    Namespace to show applications from, defaults to the current namespace
+	-r|--range        How far back to show usage, such as 15m, 6h or 7d, defaults to 1h
+	-o|--output       Output format to print to console, valid options are "table", "yaml", and "json"
+	-l|--log-level    Sets the log level of the CLI. valid levels are: %s, defaults to %s
+	-h|--help         Show this help message and exit`, logging.GetDefaults(), logging.ERROR_NAME)
+
+func ParseApplicationArgs(args []string) (string, string, string, string, error) {
+	id := ""
+	namespace := ""
+	statsRange := "1h"
+	output := "table"
+	envLogLevel, present := os.LookupEnv("STORMFRONT_LOG_LEVEL")
+	if present {
+		if err := logging.SetLevel(envLogLevel); err != nil {
+			fmt.Printf("Env logging level %s (from STORMFRONT_LOG_LEVEL) is invalid, skipping", envLogLevel)
+		}
+	}
+
+	for len(args) > 0 {
+		switch args[0] {
+		case "-n", "--namespace":
+			if len(args) > 1 {
+				namespace = args[1]
+				args = args[2:]
+			} else {
+				return "", "", "", "", errors.New("no value passed after namespace flag")
+			}
+		case "-r", "--range":
+			if len(args) > 1 {
+				statsRange = args[1]
+				args = args[2:]
+			} else {
+				return "", "", "", "", errors.New("no value passed after range flag")
+			}
+		case "-o", "--output":
+			if len(args) > 1 {
+				switch args[1] {
+				case "table", "yaml", "json":
+					output = args[1]
+				default:
+					return "", "", "", "", fmt.Errorf("invalid output value %s, allowed values are 'table', 'yaml', and 'json", args[1])
+				}
+				args = args[2:]
+			} else {
+				return "", "", "", "", errors.New("no value passed after output flag")
+			}
+		case "-l", "--log-level":
+			if len(args) > 1 {
+				err := logging.SetLevel(args[1])
+				if err != nil {
+					return "", "", "", "", err
+				}
+				args = args[2:]
+			} else {
+				return "", "", "", "", errors.New("no value passed after log-level flag")
+			}
+		default:
+			if strings.HasPrefix(args[0], "-") || id != "" {
+				fmt.Printf("Invalid argument: %s\n", args[0])
+				fmt.Println(ApplicationHelpText)
+				os.Exit(1)
+			} else {
+				id = args[0]
+				args = args[1:]
+			}
+		}
+	}
+
+	return id, namespace, statsRange, output, nil
+}
+
+func ExecuteApplication(id, namespace, statsRange, output string) error {
+	applications, err := action.GetAllApplications(namespace)
+	if err != nil {
+		return err
+	}
+
+	// Applications are looked up by name in the namespace first, anything
+	// else is taken to be an application ID
+	ids := []string{}
+	for _, application := range applications {
+		if id == "" || application["name"] == id {
+			ids = append(ids, fmt.Sprintf("%v", application["id"]))
+		}
+	}
+	if id != "" && len(ids) == 0 {
+		ids = []string{id}
+	}
+
+	allStats := []action.ApplicationStats{}
+	for _, applicationID := range ids {
+		stats, err := action.GetApplicationStatsById(applicationID, statsRange)
+		if err != nil {
+			if id != "" {
+				return err
+			}
+			logging.Warn(fmt.Sprintf("Unable to get stats for application %s: %v", applicationID, err))
+			continue
+		}
+		allStats = append(allStats, stats)
+	}
+
+	switch output {
+	case "table":
+		data := []map[string]interface{}{}
+		for _, stats := range allStats {
+			instances := []string{}
+			for instance := range stats.Instances {
+				instances = append(instances, instance)
+			}
+			sort.Strings(instances)
+
+			for _, instance := range instances {
+				instanceStats := stats.Instances[instance]
+				if instanceStats.Error != "" {
+					logging.Warn(fmt.Sprintf("Unable to get stats for instance %s: %s", instance, instanceStats.Error))
+				}
+				cpu, cpuHistory := action.Usage(instanceStats.Points, "cpu_percent")
+				memory, memoryHistory := action.Usage(instanceStats.Points, "memory_percent")
+				data = append(data, map[string]interface{}{
+					"application":    stats.Name,
+					"instance":       instance,
+					"node":           instanceStats.Node,
+					"cpu":            cpu,
+					"cpu_history":    cpuHistory,
+					"memory":         memory,
+					"memory_history": memoryHistory,
+				})
+			}
+		}
+		headers := []string{
+			"application",
+			"instance",
+			"node",
+			"cpu",
+			"cpu_history",
+			"memory",
+			"memory_history",
+		}
+		types := []string{
+			"string",
+			"string",
+			"string",
+			"string",
+			"string",
+			"string",
+			"string",
+		}
+		utils.PrintTable(data, headers, types)
+	case "yaml":
+		contents, _ := yaml.Marshal(&allStats)
+		fmt.Println(string(contents))
+	case "json":
+		contents, _ := json.Marshal(&allStats)
+		fmt.Println(string(contents))
+	}
+	logging.Success("Done!")
+
+	return nil
+}
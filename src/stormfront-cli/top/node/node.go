@@ -0,0 +1,152 @@
+package node
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"stormfront-cli/action"
+	"stormfront-cli/logging"
+	"stormfront-cli/utils"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+var NodeHelpText = fmt.Sprintf(`usage: stormfront top node [<node id>] [-r|--range <range>] [-o|--output <output>] [-l|--log-level <log level>] [-h|--help]
+arguments:
+	-r|--range        How far back to show usage, such as 15m, 6h or 7d, defaults to 1h
+	-o|--output       Output format to print to console, valid options are "table", "yaml", and "json"
+	-l|--log-level    Sets the log level of the CLI. valid levels are: %s, defaults to %s
+	-h|--help         Show this help message and exit`, logging.GetDefaults(), logging.ERROR_NAME)
+
+func ParseNodeArgs(args []string) (string, string, string, error) {
+	id := ""
+	statsRange := "1h"
+	output := "table"
+	envLogLevel, present := os.LookupEnv("STORMFRONT_LOG_LEVEL")
+	if present {
+		if err := logging.SetLevel(envLogLevel); err != nil {
+			fmt.Printf("Env logging level %s (from STORMFRONT_LOG_LEVEL) is invalid, skipping", envLogLevel)
+		}
+	}
+
+	for len(args) > 0 {
+		switch args[0] {
+		case "-r", "--range":
+			if len(args) > 1 {
+				statsRange = args[1]
+				args = args[2:]
+			} else {
+				return "", "", "", errors.New("no value passed after range flag")
+			}
+		case "-o", "--output":
+			if len(args) > 1 {
+				switch args[1] {
+				case "table", "yaml", "json":
+					output = args[1]
+				default:
+					return "", "", "", fmt.Errorf("invalid output value %s, allowed values are 'table', 'yaml', and 'json", args[1])
+				}
+				args = args[2:]
+			} else {
+				return "", "", "", errors.New("no value passed after output flag")
+			}
+		case "-l", "--log-level":
+			if len(args) > 1 {
+				err := logging.SetLevel(args[1])
+				if err != nil {
+					return "", "", "", err
+				}
+				args = args[2:]
+			} else {
+				return "", "", "", errors.New("no value passed after log-level flag")
+			}
+		default:
+			if strings.HasPrefix(args[0], "-") || id != "" {
+				fmt.Printf("Invalid argument: %s\n", args[0])
+				fmt.Println(NodeHelpText)
+				os.Exit(1)
+			} else {
+				id = args[0]
+				args = args[1:]
+			}
+		}
+	}
+
+	return id, statsRange, output, nil
+}
+
+func ExecuteNode(id, statsRange, output string) error {
+	nodes, err := action.GetAllNodes()
+	if err != nil {
+		return err
+	}
+
+	hosts := map[string]string{}
+	ids := []string{}
+	for _, node := range nodes {
+		nodeID := fmt.Sprintf("%v", node["id"])
+		hosts[nodeID] = fmt.Sprintf("%v", node["host"])
+		ids = append(ids, nodeID)
+	}
+	if id != "" {
+		ids = []string{id}
+	}
+
+	allStats := []action.NodeStats{}
+	for _, nodeID := range ids {
+		stats, err := action.GetNodeStats(nodeID, statsRange)
+		if err != nil {
+			if id != "" {
+				return err
+			}
+			logging.Warn(fmt.Sprintf("Unable to get stats for node %s: %v", nodeID, err))
+			continue
+		}
+		allStats = append(allStats, stats)
+	}
+
+	switch output {
+	case "table":
+		data := []map[string]interface{}{}
+		for _, stats := range allStats {
+			cpu, cpuHistory := action.Usage(stats.Points, "cpu_percent")
+			memory, memoryHistory := action.Usage(stats.Points, "memory_percent")
+			data = append(data, map[string]interface{}{
+				"id":             stats.ID,
+				"host":           hosts[stats.ID],
+				"cpu":            cpu,
+				"cpu_history":    cpuHistory,
+				"memory":         memory,
+				"memory_history": memoryHistory,
+			})
+		}
+		headers := []string{
+			"id",
+			"host",
+			"cpu",
+			"cpu_history",
+			"memory",
+			"memory_history",
+		}
+		types := []string{
+			"string",
+			"string",
+			"string",
+			"string",
+			"string",
+			"string",
+		}
+		utils.PrintTable(data, headers, types)
+	case "yaml":
+		contents, _ := yaml.Marshal(&allStats)
+		fmt.Println(string(contents))
+	case "json":
+		contents, _ := json.Marshal(&allStats)
+		fmt.Println(string(contents))
+	}
+	logging.Success("Done!")
+
+	return nil
+}
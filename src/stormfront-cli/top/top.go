@@ -0,0 +1,84 @@
+package top
+
+import (
+	"fmt"
+	"os"
+	"stormfront-cli/logging"
+	"stormfront-cli/top/application"
+	"stormfront-cli/top/node"
+	"stormfront-cli/utils"
+)
+
+var TopHelpText = fmt.Sprintf(`usage: stormfront top <command> [-l|--log-level <log level>] [-h|--help]
+commands:
+	application       Show resource usage history of applications
+	node              Show resource usage history of nodes
+arguments:
+	-l|--log-level    Sets the log level of the CLI. valid levels are: %s, defaults to %s
+	-h|--help         Show this help message and exit`, logging.GetDefaults(), logging.ERROR_NAME)
+
+func ParseTopArgs(args []string) {
+	envLogLevel, present := os.LookupEnv("STORMFRONT_LOG_LEVEL")
+	if present {
+		if err := logging.SetLevel(envLogLevel); err != nil {
+			fmt.Printf("Env logging level %s (from STORMFRONT_LOG_LEVEL) is invalid, skipping", envLogLevel)
+		}
+	}
+
+	if len(args) > 1 {
+		if args[1] == "-l" || args[1] == "--log-level" {
+			if len(args) == 2 {
+				logging.Fatal("No value passed after log-level flag")
+			}
+			err := logging.SetLevel(args[2])
+			if err != nil {
+				logging.Fatal(err.Error())
+			}
+			args = append(args[:0], args[2:]...)
+		}
+	}
+
+	if len(args) == 2 {
+		if utils.Contains(args, "-h") || utils.Contains(args, "--help") {
+			fmt.Println(TopHelpText)
+			os.Exit(0)
+		}
+	}
+
+	if len(args) == 1 {
+		fmt.Println(TopHelpText)
+		os.Exit(1)
+	}
+
+	switch args[1] {
+	case "application", "app":
+		id, namespace, statsRange, output, err := application.ParseApplicationArgs(args[2:])
+		if err != nil {
+			logging.Error(err.Error())
+			fmt.Println(TopHelpText)
+			os.Exit(1)
+		}
+		err = application.ExecuteApplication(id, namespace, statsRange, output)
+		if err != nil {
+			logging.Error(err.Error())
+			os.Exit(1)
+		}
+	case "node", "no":
+		id, statsRange, output, err := node.ParseNodeArgs(args[2:])
+		if err != nil {
+			logging.Error(err.Error())
+			fmt.Println(TopHelpText)
+			os.Exit(1)
+		}
+		err = node.ExecuteNode(id, statsRange, output)
+		if err != nil {
+			logging.Error(err.Error())
+			os.Exit(1)
+		}
+	default:
+		fmt.Printf("Invalid argument: %s\n", args[1])
+		fmt.Println(TopHelpText)
+		os.Exit(1)
+	}
+
+}
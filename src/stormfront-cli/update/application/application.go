@@ -0,0 +1,119 @@
+package application
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"stormfront-cli/action"
+	"stormfront-cli/config"
+	"stormfront-cli/logging"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+var ApplicationHelpText = fmt.Sprintf(`usage: stormfront update application <application name or id> -f|--file <application definition file> [-n|--namespace <namespace>] [-l|--log-level <log level>] [-h|--help]
+arguments:
+	-f|--file         The path to the JSON or YAML file with the updated application definition
+	-n|--namespace    Namespace the application lives in
+	-l|--log-level    Sets the log level of the CLI. valid levels are: %s, defaults to %s
+	-h|--help         Show this help message and exit`, logging.GetDefaults(), logging.ERROR_NAME)
+
+func ParseApplicationArgs(args []string) (string, string, string, error) {
+	id := ""
+	definition := ""
+	namespace := ""
+	envLogLevel, present := os.LookupEnv("STORMFRONT_LOG_LEVEL")
+	if present {
+		if err := logging.SetLevel(envLogLevel); err != nil {
+			fmt.Printf("Env logging level %s (from STORMFRONT_LOG_LEVEL) is invalid, skipping", envLogLevel)
+		}
+	}
+
+	for len(args) > 0 {
+		switch args[0] {
+		case "-f", "--file":
+			if len(args) > 1 {
+				definition = args[1]
+				args = args[2:]
+			} else {
+				return "", "", "", errors.New("no value passed after file flag")
+			}
+		case "-n", "--namespace":
+			if len(args) > 1 {
+				namespace = args[1]
+				args = args[2:]
+			} else {
+				return "", "", "", errors.New("no value passed after namespace flag")
+			}
+		case "-l", "--log-level":
+			if len(args) > 1 {
+				err := logging.SetLevel(args[1])
+				if err != nil {
+					return "", "", "", err
+				}
+				args = args[2:]
+			} else {
+				return "", "", "", errors.New("no value passed after log-level flag")
+			}
+		default:
+			if strings.HasPrefix(args[0], "-") || id != "" {
+				fmt.Printf("Invalid argument: %s\n", args[0])
+				fmt.Println(ApplicationHelpText)
+				os.Exit(1)
+			} else {
+				id = args[0]
+				args = args[1:]
+			}
+		}
+	}
+
+	if id == "" {
+		return "", "", "", errors.New("id argument is required")
+	}
+	if definition == "" {
+		return "", "", "", errors.New("missing application definition file")
+	}
+
+	return id, definition, namespace, nil
+}
+
+func ExecuteApplication(id, definition, namespace string) error {
+	var err error
+	if namespace == "" {
+		namespace, err = config.GetNamespace()
+		if err != nil {
+			return err
+		}
+	}
+
+	file, err := ioutil.ReadFile(definition)
+	if err != nil {
+		return err
+	}
+
+	// JSON is a subset of YAML so both file formats can be read the same way
+	data := map[string]interface{}{}
+	if err := yaml.Unmarshal(file, &data); err != nil {
+		return err
+	}
+	delete(data, "kind")
+
+	changed, err := action.UpdateApplicationByNameNamespace(id, namespace, data)
+	if err != nil {
+		changed, err = action.UpdateApplicationById(id, data)
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(changed) == 0 {
+		logging.Info("Application is already up to date")
+	} else {
+		logging.Info(fmt.Sprintf("Updated fields: %s", strings.Join(changed, ", ")))
+	}
+	logging.Success("Done!")
+
+	return nil
+}
@@ -0,0 +1,46 @@
+package utils
+
+import (
+	"strings"
+)
+
+var sparks = []rune("▁▂▃▄▅▆▇█")
+
+// Sparkline draws values as a single line of block characters scaled between
+// min and max. Neighbouring values are averaged so the line is at most width
+// characters long.
+func Sparkline(values []float64, min, max float64, width int) string {
+	if len(values) == 0 || width <= 0 {
+		return ""
+	}
+
+	if len(values) > width {
+		averaged := make([]float64, width)
+		for idx := range averaged {
+			start := idx * len(values) / width
+			end := (idx + 1) * len(values) / width
+			sum := 0.0
+			for _, value := range values[start:end] {
+				sum += value
+			}
+			averaged[idx] = sum / float64(end-start)
+		}
+		values = averaged
+	}
+
+	var line strings.Builder
+	for _, value := range values {
+		level := 0
+		if max > min {
+			level = int((value - min) / (max - min) * float64(len(sparks)-1))
+		}
+		if level < 0 {
+			level = 0
+		}
+		if level > len(sparks)-1 {
+			level = len(sparks) - 1
+		}
+		line.WriteRune(sparks[level])
+	}
+	return line.String()
+}
@@ -5,6 +5,7 @@ package utils
 import (
 	"fmt"
 	"strings"
+	"unicode/utf8"
 )
 
 func Contains(s []string, e string) bool {
@@ -39,19 +40,19 @@ func PrintTable(data []map[string]interface{}, headers, types []string) {
 
 	// Figure out spacing
 	for _, header := range headers {
-		widths = append(widths, len(header))
+		widths = append(widths, utf8.RuneCountInString(header))
 	}
 	for _, datum := range data {
 		for idx, header := range headers {
-			if len(typeToString(datum[header], types[idx])) > widths[idx] {
-				widths[idx] = len(datum[header].(string))
+			if width := utf8.RuneCountInString(typeToString(datum[header], types[idx])); width > widths[idx] {
+				widths[idx] = width
 			}
 		}
 	}
 
 	// Print out headers
 	for idx, header := range headers {
-		delta := widths[idx] - len(header)
+		delta := widths[idx] - utf8.RuneCountInString(header)
 		if idx < len(headers)-1 {
 			output += header + strings.Repeat(" ", delta) + spacer
 		} else {
@@ -73,7 +74,7 @@ func PrintTable(data []map[string]interface{}, headers, types []string) {
 	// Print out data
 	for _, datum := range data {
 		for idx, header := range headers {
-			delta := widths[idx] - len(typeToString(datum[header], types[idx]))
+			delta := widths[idx] - utf8.RuneCountInString(typeToString(datum[header], types[idx]))
 			if idx < len(headers)-1 {
 				output += typeToString(datum[header], types[idx]) + strings.Repeat(" ", delta) + spacer
 			} else {
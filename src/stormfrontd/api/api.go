@@ -1,10 +1,10 @@
 package api
 
 import (
-	"log"
 	"net/http"
 
 	"stormfrontd/daemon"
+	"stormfrontd/logging"
 
 	"github.com/gin-gonic/gin"
 )
@@ -13,7 +13,7 @@ var Healthy = false
 
 func Test(c *gin.Context) {
 	remoteAddr := c.Request.RemoteAddr
-	log.Printf("Remote Address: %v", remoteAddr)
+	logging.Debug("Test request", "request_id", logging.RequestID(c.Request.Context()), "remote_address", remoteAddr)
 	c.Status(http.StatusOK)
 }
 
@@ -62,7 +62,15 @@ func Join(c *gin.Context) {
 	leaderPort := data["leader_port"]
 	joinToken := data["join_token"]
 
-	err := daemon.Join(leaderHost.(string), int(leaderPort.(float64)), joinToken.(string))
+	// Without the CA fingerprint the joining node can not tell the leader
+	// from whoever answers in its place, which would get the join token
+	caFingerprint, _ := data["ca_fingerprint"].(string)
+	if caFingerprint == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "missing required 'ca_fingerprint' key in request payload"})
+		return
+	}
+
+	err := daemon.Join(leaderHost.(string), int(leaderPort.(float64)), joinToken.(string), caFingerprint)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
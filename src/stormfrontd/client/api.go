@@ -1,13 +1,15 @@
 package client
 
 import (
-	"bytes"
 	"encoding/json"
 	"fmt"
 	"net/http"
-	"os/exec"
+	"regexp"
 	"stormfrontd/client/auth"
+	"stormfrontd/client/communication"
+	"stormfrontd/client/pki"
 	"stormfrontd/config"
+	"strconv"
 	"strings"
 
 	"github.com/gin-gonic/gin"
@@ -15,34 +17,82 @@ import (
 	"github.com/jfcarter2358/ceresdb-go/connection"
 )
 
+var namespaceNameRegex = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`)
+
 func GetJoinCommand(c *gin.Context) {
-	joinToken := auth.GenToken(128)
-	JoinTokens = append(JoinTokens, joinToken)
+	if Client.Type != "Leader" {
+		c.Redirect(http.StatusTemporaryRedirect, fmt.Sprintf("https://%s:%v/auth/join/command", Client.Leader.Host, Client.Leader.Port))
+		return
+	}
+
+	fingerprint, err := pki.CAFingerprint()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	_, joinToken, err := auth.CreateJoinToken(config.Config.JoinTokenExpiration, requestIdentity(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
 
-	joinCommand := fmt.Sprintf("stormfront join -L %s:%v -j %s", Client.Leader.Host, Client.Leader.Port, joinToken)
+	joinCommand := fmt.Sprintf("stormfront join -L %s:%v -j %s -c %s", Client.Leader.Host, Client.Leader.Port, joinToken, fingerprint)
 
 	c.JSON(http.StatusOK, gin.H{"join_command": joinCommand})
 }
 
 func GetJoinToken(c *gin.Context) {
-	joinToken := auth.GenToken(128)
-	JoinTokens = append(JoinTokens, joinToken)
+	if Client.Type != "Leader" {
+		c.Redirect(http.StatusTemporaryRedirect, fmt.Sprintf("https://%s:%v/auth/join", Client.Leader.Host, Client.Leader.Port))
+		return
+	}
+
+	_, joinToken, err := auth.CreateJoinToken(config.Config.JoinTokenExpiration, requestIdentity(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
 
 	c.JSON(http.StatusOK, gin.H{"token": joinToken})
 }
 
+func GetAllJoinTokens(c *gin.Context) {
+	if Client.Type != "Leader" {
+		c.Redirect(http.StatusTemporaryRedirect, fmt.Sprintf("https://%s:%v/auth/join/list", Client.Leader.Host, Client.Leader.Port))
+		return
+	}
+
+	joinTokens, err := auth.GetJoinTokens()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	for idx, joinToken := range joinTokens {
+		joinTokens[idx] = joinToken.Redact()
+	}
+
+	c.JSON(http.StatusOK, joinTokens)
+}
+
+// RevokeJoinToken revokes a join token by its value or ID
 func RevokeJoinToken(c *gin.Context) {
 	token := c.Param("token")
 
-	found := false
-	for idx, joinToken := range JoinTokens {
-		if joinToken == token {
-			JoinTokens = append(JoinTokens[:idx], JoinTokens[idx+1:]...)
-			found = true
-		}
+	if Client.Type != "Leader" {
+		c.Redirect(http.StatusTemporaryRedirect, fmt.Sprintf("https://%s:%v/auth/join/%s", Client.Leader.Host, Client.Leader.Port, token))
+		return
+	}
+
+	id, found, err := auth.RevokeJoinToken(token)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
 	}
 
 	if found {
+		recordEvent(tokenEvent(EVENT_TOKEN_REVOKED, id, "Join token revoked by %s", requestIdentity(c)))
 		c.Status(http.StatusOK)
 		return
 	}
@@ -50,6 +100,14 @@ func RevokeJoinToken(c *gin.Context) {
 	c.Status(http.StatusNotFound)
 }
 
+// joinResponse is returned to a node joining the cluster, carrying its access
+// token and the certificate signed for it
+type joinResponse struct {
+	auth.ClientInformation
+	Certificate   string `json:"certificate,omitempty"`
+	CACertificate string `json:"ca_certificate,omitempty"`
+}
+
 func GetAccessToken(c *gin.Context) {
 	token := c.Request.Header.Get("Authorization")
 	splitToken := strings.Split(token, "Bearer ")
@@ -59,24 +117,46 @@ func GetAccessToken(c *gin.Context) {
 	}
 	token = splitToken[1]
 
-	if contains(JoinTokens, token) {
+	var request map[string]string
+	if c.Request.Method == http.MethodPost {
+		if err := c.BindJSON(&request); err != nil {
+			return
+		}
+	}
+
+	joined, err := auth.ConsumeJoinToken(token)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
 
-		removeIdx := -1
-		for idx, joinToken := range JoinTokens {
-			if joinToken == token {
-				removeIdx = idx
+	if joined {
+		response := joinResponse{}
+		if request["certificate_request"] != "" {
+			certificate, err := pki.IssueCertificate([]byte(request["certificate_request"]))
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			caCertificate, _, err := pki.ReadCA()
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+				return
 			}
+			response.Certificate = string(certificate)
+			response.CACertificate = string(caCertificate)
 		}
-		JoinTokens = append(JoinTokens[:removeIdx], JoinTokens[removeIdx+1:]...)
+
 		clientInfo := auth.CreateClientInformation()
 
-		authData, _ := json.Marshal(clientInfo)
-		_, err := connection.Query(fmt.Sprintf("post record stormfront.auth %s", authData))
+		err := auth.StoreClientInformation(clientInfo)
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
 		}
 
-		c.JSON(http.StatusOK, clientInfo)
+		response.ClientInformation = clientInfo
+		c.JSON(http.StatusOK, response)
 		return
 	}
 
@@ -128,6 +208,8 @@ func GetHealth(c *gin.Context) {
 	}
 }
 
+// GetState returns this node's view of the cluster, including applications
+// from every namespace, so it is limited to nodes and admin tokens
 func GetState(c *gin.Context) {
 	c.JSON(http.StatusOK, Client)
 }
@@ -135,12 +217,16 @@ func GetState(c *gin.Context) {
 func GetAllNodes(c *gin.Context) {
 
 	if Client.Type != "Leader" {
-		fmt.Println("Redirecting")
-		c.Redirect(http.StatusTemporaryRedirect, fmt.Sprintf("http://%s:%v/api/node", Client.Leader.Host, Client.Leader.Port))
+		c.Redirect(http.StatusTemporaryRedirect, fmt.Sprintf("https://%s:%v/api/node?%s", Client.Leader.Host, Client.Leader.Port, c.Request.URL.RawQuery))
 		return
 	}
 
-	fmt.Println("Not leader")
+	if c.Query("watch") == "true" {
+		watchList(c, nodeWatch, func(interface{}) bool {
+			return true
+		})
+		return
+	}
 
 	nodes, err := getNodes()
 	if err != nil {
@@ -155,7 +241,7 @@ func GetNode(c *gin.Context) {
 	id := c.Param("id")
 
 	if Client.Type != "Leader" {
-		c.Redirect(http.StatusTemporaryRedirect, fmt.Sprintf("http://%s:%v/api/node/%s", Client.Leader.Host, Client.Leader.Port, id))
+		c.Redirect(http.StatusTemporaryRedirect, fmt.Sprintf("https://%s:%v/api/node/%s", Client.Leader.Host, Client.Leader.Port, id))
 		return
 	}
 
@@ -176,8 +262,7 @@ func GetNode(c *gin.Context) {
 func CreateApplication(c *gin.Context) {
 
 	if Client.Type != "Leader" {
-		fmt.Printf("Node is not leader, redirecting to http://%s:%v/api/application\n", Client.Leader.Host, Client.Leader.Port)
-		c.Redirect(http.StatusTemporaryRedirect, fmt.Sprintf("http://%s:%v/api/application", Client.Leader.Host, Client.Leader.Port))
+		c.Redirect(http.StatusTemporaryRedirect, fmt.Sprintf("https://%s:%v/api/application", Client.Leader.Host, Client.Leader.Port))
 		return
 	}
 
@@ -185,6 +270,17 @@ func CreateApplication(c *gin.Context) {
 	c.BindJSON(&app)
 	app.ID = uuid.NewString()
 
+	if app.Namespace == "" {
+		app.Namespace = DEFAULT_NAMESPACE
+	}
+	if !authorizeNamespace(c, app.Namespace) {
+		return
+	}
+	if _, found, err := getNamespace(app.Namespace); err != nil || !found {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("namespace %s does not exist", app.Namespace)})
+		return
+	}
+
 	nodeData, err := connection.Query("get record stormfront.node")
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
@@ -204,59 +300,93 @@ func CreateApplication(c *gin.Context) {
 	json.Unmarshal(applicationBytes, &applications)
 
 	for _, runningApp := range applications {
+		if runningApp.Name == app.Name && runningApp.Namespace == app.Namespace {
+			c.JSON(http.StatusConflict, gin.H{"error": fmt.Sprintf("application %s already exists in namespace %s", app.Name, app.Namespace)})
+			return
+		}
 		for exposedPort := range runningApp.Ports {
 			for desiredPort := range app.Ports {
 				if exposedPort == desiredPort {
-					c.JSON(http.StatusInternalServerError, gin.H{"error": "Port already allocated"})
+					c.JSON(http.StatusConflict, gin.H{"error": "Port already allocated"})
 					return
 				}
 			}
 		}
 	}
 
-	if app.Node != "" {
-		for _, node := range nodes {
-			if node.ID != app.Node {
-				continue
-			}
-			fmt.Printf("Available CPU: %v, requested CPU: %v, available memory: %v, requested memory: %v\n", node.System.CPUAvailable, app.CPU, node.System.MemoryAvailable, app.Memory)
-			if node.System.CPUAvailable >= app.CPU && node.System.MemoryAvailable >= app.Memory {
-				app.Node = node.ID
-				appBytes, _ := json.Marshal(app)
-				_, err := connection.Query(fmt.Sprintf("post record stormfront.application %s", string(appBytes)))
-				if err != nil {
-					c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("unable to create application: %v", err.Error())})
-					return
-				}
-				c.Status(http.StatusCreated)
-				return
-			}
-			fmt.Printf("Insufficient resources to schedule on node %s\n", node.ID)
-		}
-	} else {
-		for _, node := range nodes {
-			fmt.Printf("Available CPU: %v, requested CPU: %v, available memory: %v, requested memory: %v\n", node.System.CPUAvailable, app.CPU, node.System.MemoryAvailable, app.Memory)
-			if node.System.CPUAvailable >= app.CPU && node.System.MemoryAvailable >= app.Memory {
-				app.Node = node.ID
-				appBytes, _ := json.Marshal(app)
-				_, err := connection.Query(fmt.Sprintf("post record stormfront.application %s", string(appBytes)))
-				if err != nil {
-					c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("unable to create application: %v", err.Error())})
-					return
-				}
-				c.JSON(http.StatusCreated, gin.H{"id": app.ID})
-				return
-			}
-			fmt.Printf("Insufficient resources to schedule on node %s: Available CPU: %v, requested CPU: %v, available memory: %v, requested memory: %v\n", node.ID, node.System.CPUAvailable, app.CPU, node.System.MemoryAvailable, app.Memory)
-		}
+	if app.Replicas < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "replicas must not be negative"})
+		return
+	}
+	app.Replicas = app.replicaCount()
+	if app.RestartPolicy != "" && !contains(RestartPolicies, app.RestartPolicy) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("invalid restart policy %s, allowed policies are %s", app.RestartPolicy, strings.Join(RestartPolicies, ", "))})
+		return
+	}
+	app.RestartPolicy = app.restartPolicy()
+	if app.ImagePullPolicy != "" && !contains(ImagePullPolicies, app.ImagePullPolicy) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("invalid image pull policy %s, allowed policies are %s", app.ImagePullPolicy, strings.Join(ImagePullPolicies, ", "))})
+		return
+	}
+	app.ImagePullPolicy = app.imagePullPolicy()
+	if err := validateSecretReferences(app); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := setConfigVersion(&app); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := validateProbe(app.LivenessProbe, app.Ports); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("invalid liveness probe: %v", err)})
+		return
+	}
+	if err := validateProbe(app.ReadinessProbe, app.Ports); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("invalid readiness probe: %v", err)})
+		return
+	}
+	if err := validateVolumes(app, applications); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
 	}
-	c.JSON(http.StatusInternalServerError, gin.H{"error": "Insufficient resources to schedule"})
+	// Instances are placed by the scheduler, the node field only remains for
+	// applications created before replicas were introduced
+	app.Node = ""
+	app.Instances = []StormfrontInstance{}
+
+	decisions, err := scaleApplication(&app, nodes, applications)
+	if err != nil {
+		explanation := decisions[len(decisions)-1].Explanation
+		requestLog(c).Warn("Unable to schedule application", "application", app.Name, "namespace", app.Namespace, "explanation", explanation)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error(), "explanation": explanation})
+		return
+	}
+
+	if c.Query("dry_run") == "true" {
+		c.JSON(http.StatusOK, gin.H{"instances": app.Instances, "decisions": decisions})
+		return
+	}
+
+	appBytes, _ := json.Marshal(app)
+	_, err = connection.Query(fmt.Sprintf("post record stormfront.application %s", string(appBytes)))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("unable to create application: %v", err.Error())})
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"id": app.ID})
 }
 
 func GetAllApplications(c *gin.Context) {
 
 	if Client.Type != "Leader" {
-		c.Redirect(http.StatusTemporaryRedirect, fmt.Sprintf("http://%s:%v/api/application", Client.Leader.Host, Client.Leader.Port))
+		c.Redirect(http.StatusTemporaryRedirect, fmt.Sprintf("https://%s:%v/api/application?%s", Client.Leader.Host, Client.Leader.Port, c.Request.URL.RawQuery))
+		return
+	}
+
+	if c.Query("watch") == "true" {
+		watchList(c, applicationWatch, func(object interface{}) bool {
+			return namespaceAllowed(c, object.(StormfrontApplication).Namespace)
+		})
 		return
 	}
 
@@ -266,14 +396,21 @@ func GetAllApplications(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, applications)
+	scoped := []StormfrontApplication{}
+	for _, app := range applications {
+		if namespaceAllowed(c, app.Namespace) {
+			scoped = append(scoped, app)
+		}
+	}
+
+	c.JSON(http.StatusOK, scoped)
 }
 
 func GetApplication(c *gin.Context) {
 	id := c.Param("id")
 
 	if Client.Type != "Leader" {
-		c.Redirect(http.StatusTemporaryRedirect, fmt.Sprintf("http://%s:%v/api/application/%s", Client.Leader.Host, Client.Leader.Port, id))
+		c.Redirect(http.StatusTemporaryRedirect, fmt.Sprintf("https://%s:%v/api/application/%s", Client.Leader.Host, Client.Leader.Port, id))
 		return
 	}
 
@@ -295,7 +432,7 @@ func DeleteApplication(c *gin.Context) {
 	id := c.Param("id")
 
 	if Client.Type != "Leader" {
-		c.Redirect(http.StatusTemporaryRedirect, fmt.Sprintf("http://%s:%v/api/application/%s", Client.Leader.Host, Client.Leader.Port, id))
+		c.Redirect(http.StatusTemporaryRedirect, fmt.Sprintf("https://%s:%v/api/application/%s", Client.Leader.Host, Client.Leader.Port, id))
 		return
 	}
 
@@ -310,9 +447,12 @@ func DeleteApplication(c *gin.Context) {
 
 func RestartApplication(c *gin.Context) {
 	id := c.Param("id")
+	instanceName := c.Query("instance")
 
-	if Client.Type != "Leader" {
-		c.Redirect(http.StatusTemporaryRedirect, fmt.Sprintf("http://%s:%v/api/application/%s/restart", Client.Leader.Host, Client.Leader.Port, id))
+	// The leader fans restarts out to the nodes running each instance, those
+	// requests name the instance and are handled by the node directly
+	if Client.Type != "Leader" && instanceName == "" {
+		c.Redirect(http.StatusTemporaryRedirect, fmt.Sprintf("https://%s:%v/api/application/%s/restart", Client.Leader.Host, Client.Leader.Port, id))
 		return
 	}
 
@@ -333,95 +473,294 @@ func RestartApplication(c *gin.Context) {
 
 	json.Unmarshal(appBytes, &app)
 
-	destroyApplication(app.Name, false)
-	deployApplication(app, false, false)
-}
-
-// TODO: Re-implement the UpdateApplication logic
-// func UpdateApplication(c *gin.Context) {
-// 	id := c.Param("id")
-
-// 	if Client.Type != "Leader" {
-// 		c.Redirect(http.StatusTemporaryRedirect, fmt.Sprintf("http://%s:%v/api/application/%s", Client.Leader.Host, Client.Leader.Port, id))
-// 		return
-// 	}
-
-// 	var applicationDefinition StormfrontApplication
-// 	c.BindJSON(&applicationDefinition)
-
-// 	for idx, app := range Applications {
-// 		if app.ID == id {
-// 			applicationDefinition.ID = app.ID
-// 			if applicationDefinition.Node != app.Node {
-// 				if applicationDefinition.Node == "" {
-// 					applicationDefinition.Node = app.Node
-// 				} else {
-// 					c.JSON(http.StatusInternalServerError, gin.H{"error": "Node specification not allowed"})
-// 				}
-// 			}
-// 			if applicationDefinition.Name != app.Name {
-// 				if applicationDefinition.Name == "" {
-// 					applicationDefinition.Name = app.Name
-// 				} else {
-// 					c.JSON(http.StatusInternalServerError, gin.H{"error": "Renaming not allowed in application update"})
-// 				}
-// 			}
-// 			if applicationDefinition.Hostname != app.Hostname {
-// 				if applicationDefinition.Hostname == "" {
-// 					applicationDefinition.Hostname = app.Hostname
-// 				} else {
-// 					c.JSON(http.StatusInternalServerError, gin.H{"error": "Hostname change not allowed in application update"})
-// 				}
-// 			}
-// 			if applicationDefinition.Memory != app.Memory {
-// 				if applicationDefinition.Memory == 0 {
-// 					applicationDefinition.Memory = app.Memory
-// 				} else {
-// 					c.JSON(http.StatusInternalServerError, gin.H{"error": "Memory spec change not allowed in application update"})
-// 				}
-// 			}
-// 			if applicationDefinition.CPU != app.CPU {
-// 				if applicationDefinition.CPU == 0 {
-// 					applicationDefinition.CPU = app.CPU
-// 				} else {
-// 					c.JSON(http.StatusInternalServerError, gin.H{"error": "CPU spec change not allowed in application update"})
-// 				}
-// 			}
-// 			if applicationDefinition.Node != app.Node {
-// 				if applicationDefinition.Node == "" {
-// 					applicationDefinition.Node = app.Node
-// 				} else {
-// 					c.JSON(http.StatusInternalServerError, gin.H{"error": "Node specification not allowed"})
-// 				}
-// 			}
-
-// 			if applicationDefinition.Env == nil {
-// 				applicationDefinition.Env = app.Env
-// 			}
-// 			if applicationDefinition.Ports == nil {
-// 				applicationDefinition.Ports = app.Ports
-// 			}
-// 			if applicationDefinition.Image == "" {
-// 				applicationDefinition.Image = app.Image
-// 			}
-
-// 			Applications[idx] = applicationDefinition
-
-// 			c.Status(http.StatusOK)
-// 			return
-// 		}
-// 	}
-
-// 	c.Status(http.StatusNotFound)
-// }
+	restarted := []string{}
+	for _, instance := range app.Instances {
+		if instanceName != "" && instance.Name != instanceName {
+			continue
+		}
+		if instance.Node == Client.ID {
+			restartInstance(app, instance.Name)
+			restarted = append(restarted, instance.Name)
+			continue
+		}
+		if Client.Type != "Leader" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("instance %s is not running on this node", instance.Name)})
+			return
+		}
+		node, found, err := getNode(instance.Node)
+		if err != nil || !found {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("unable to find node %s for instance %s", instance.Node, instance.Name)})
+			return
+		}
+		status, _, err := communication.Get(c.Request.Context(), node.Host, node.Port, fmt.Sprintf("api/application/%s/restart?instance=%s", app.ID, instance.Name), AuthClient)
+		if err != nil || status != http.StatusOK {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("unable to restart instance %s on node %s", instance.Name, instance.Node)})
+			return
+		}
+		restarted = append(restarted, instance.Name)
+	}
+
+	if instanceName != "" && len(restarted) == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("application %s has no instance %s", app.ID, instanceName)})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"id": app.ID, "restarted": restarted})
+}
+
+func UpdateApplication(c *gin.Context) {
+	id := c.Param("id")
+
+	if Client.Type != "Leader" {
+		c.Redirect(http.StatusTemporaryRedirect, fmt.Sprintf("https://%s:%v/api/application/%s", Client.Leader.Host, Client.Leader.Port, id))
+		return
+	}
+
+	data, err := connection.Query(fmt.Sprintf(`get record stormfront.application | filter id = '%s'`, id))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if len(data) == 0 {
+		c.Status(http.StatusNotFound)
+		return
+	}
+
+	var app StormfrontApplication
+	appBytes, _ := json.Marshal(data[0])
+	json.Unmarshal(appBytes, &app)
+
+	var desired StormfrontApplication
+	if err := c.BindJSON(&desired); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if desired.ID != "" && desired.ID != app.ID {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID change not allowed in application update"})
+		return
+	}
+	if desired.Name != "" && desired.Name != app.Name {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Renaming not allowed in application update"})
+		return
+	}
+	if desired.Hostname != "" && desired.Hostname != app.Hostname {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Hostname change not allowed in application update"})
+		return
+	}
+	if desired.Namespace != "" && desired.Namespace != app.Namespace {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Namespace change not allowed in application update"})
+		return
+	}
+	if desired.Node != "" && desired.Node != app.Node {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Node specification not allowed in application update"})
+		return
+	}
+	if (desired.NodeSelector != nil && !stringMapsEqual(desired.NodeSelector, app.NodeSelector)) ||
+		(desired.Affinity != nil && !stringSlicesEqual(desired.Affinity, app.Affinity)) ||
+		(desired.AntiAffinity != nil && !stringSlicesEqual(desired.AntiAffinity, app.AntiAffinity)) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Placement constraint change not allowed in application update"})
+		return
+	}
+
+	// Fields left out of the request keep their current values
+	desired.ID = app.ID
+	desired.Name = app.Name
+	desired.Hostname = app.Hostname
+	desired.Namespace = app.Namespace
+	desired.Node = app.Node
+	desired.Status = app.Status
+	desired.Reschedules = app.Reschedules
+	desired.NodeSelector = app.NodeSelector
+	desired.Affinity = app.Affinity
+	desired.AntiAffinity = app.AntiAffinity
+	desired.Instances = app.Instances
+	desired.InstanceStatus = app.InstanceStatus
+	if desired.Replicas < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "replicas must not be negative"})
+		return
+	}
+	if desired.Replicas == 0 {
+		desired.Replicas = app.Replicas
+	}
+	if desired.RestartPolicy != "" && !contains(RestartPolicies, desired.RestartPolicy) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("invalid restart policy %s, allowed policies are %s", desired.RestartPolicy, strings.Join(RestartPolicies, ", "))})
+		return
+	}
+	if desired.RestartPolicy == "" {
+		desired.RestartPolicy = app.RestartPolicy
+	}
+	if desired.ImagePullPolicy != "" && !contains(ImagePullPolicies, desired.ImagePullPolicy) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("invalid image pull policy %s, allowed policies are %s", desired.ImagePullPolicy, strings.Join(ImagePullPolicies, ", "))})
+		return
+	}
+	if desired.ImagePullPolicy == "" {
+		desired.ImagePullPolicy = app.ImagePullPolicy
+	}
+	if desired.Image == "" {
+		desired.Image = app.Image
+	}
+	if desired.Env == nil {
+		desired.Env = app.Env
+	}
+	if desired.Ports == nil {
+		desired.Ports = app.Ports
+	}
+	if desired.Mounts == nil {
+		desired.Mounts = app.Mounts
+	}
+	if desired.SecretEnv == nil {
+		desired.SecretEnv = app.SecretEnv
+	}
+	if desired.SecretMounts == nil {
+		desired.SecretMounts = app.SecretMounts
+	}
+	if err := validateSecretReferences(desired); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if desired.ConfigMounts == nil {
+		desired.ConfigMounts = app.ConfigMounts
+	}
+	if err := setConfigVersion(&desired); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if desired.LivenessProbe == nil {
+		desired.LivenessProbe = app.LivenessProbe
+	}
+	if desired.ReadinessProbe == nil {
+		desired.ReadinessProbe = app.ReadinessProbe
+	}
+	if err := validateProbe(desired.LivenessProbe, desired.Ports); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("invalid liveness probe: %v", err)})
+		return
+	}
+	if err := validateProbe(desired.ReadinessProbe, desired.Ports); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("invalid readiness probe: %v", err)})
+		return
+	}
+	// Volumes are bound to the node holding their data so they can not be
+	// swapped out from under a running application
+	if desired.Volumes == nil {
+		desired.Volumes = app.Volumes
+	}
+	if !stringMapsEqual(desired.Volumes, app.Volumes) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "volumes can not be changed, delete and recreate the application instead"})
+		return
+	}
+	if len(desired.Volumes) > 0 && desired.replicaCount() > 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "applications mounting volumes can only run a single replica"})
+		return
+	}
+	if desired.CPU == 0 {
+		desired.CPU = app.CPU
+	}
+	if desired.Memory == 0 {
+		desired.Memory = app.Memory
+	}
+
+	changed := diffApplications(app, desired)
+	if desired.replicaCount() != app.replicaCount() {
+		changed = append(changed, "replicas")
+	}
+	if desired.restartPolicy() != app.restartPolicy() {
+		changed = append(changed, "restart_policy")
+	}
+	if desired.imagePullPolicy() != app.imagePullPolicy() {
+		changed = append(changed, "image_pull_policy")
+	}
+	// Probes are picked up by the owning nodes without a redeploy
+	if !probesEqual(desired.LivenessProbe, app.LivenessProbe) {
+		changed = append(changed, "liveness_probe")
+	}
+	if !probesEqual(desired.ReadinessProbe, app.ReadinessProbe) {
+		changed = append(changed, "readiness_probe")
+	}
+	if len(changed) == 0 {
+		c.JSON(http.StatusOK, gin.H{"id": app.ID, "changed": changed})
+		return
+	}
+
+	applications, err := getApplications()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	for _, runningApp := range applications {
+		if runningApp.ID == app.ID {
+			continue
+		}
+		for exposedPort := range runningApp.Ports {
+			for desiredPort := range desired.Ports {
+				if exposedPort == desiredPort {
+					c.JSON(http.StatusConflict, gin.H{"error": "Port already allocated"})
+					return
+				}
+			}
+		}
+	}
+
+	nodes, err := getNodes()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if desired.CPU > app.CPU || desired.Memory > app.Memory {
+		for idx, node := range nodes {
+			localInstances := len(app.instancesOn(node.ID))
+			if localInstances == 0 {
+				continue
+			}
+			// The node's available resources already account for the current reservation
+			cpuAvailable := node.System.CPUAvailable + app.CPU*float64(localInstances)
+			memoryAvailable := node.System.MemoryAvailable + app.Memory*localInstances
+			cpuRequested := desired.CPU * float64(localInstances)
+			memoryRequested := desired.Memory * localInstances
+			if cpuAvailable < cpuRequested || memoryAvailable < memoryRequested {
+				requestLog(c).Warn("Insufficient resources to update application", "application", app.ID, "node", node.ID, "cpu_available", cpuAvailable, "cpu_requested", cpuRequested, "memory_available", memoryAvailable, "memory_requested", memoryRequested)
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Insufficient resources on assigned node to update"})
+				return
+			}
+			nodes[idx].System.CPUAvailable = cpuAvailable - cpuRequested
+			nodes[idx].System.MemoryAvailable = memoryAvailable - memoryRequested
+		}
+	}
+
+	decisions, err := scaleApplication(&desired, nodes, applications)
+	if err != nil {
+		explanation := decisions[len(decisions)-1].Explanation
+		requestLog(c).Warn("Unable to scale application", "application", app.Name, "namespace", app.Namespace, "explanation", explanation)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error(), "explanation": explanation})
+		return
+	}
+
+	desiredData, _ := json.Marshal(desired)
+	var desiredMap map[string]interface{}
+	json.Unmarshal(desiredData, &desiredMap)
+	desiredMap[".id"] = data[0][".id"]
+	desiredData, _ = json.Marshal(desiredMap)
+	_, err = connection.Query(fmt.Sprintf(`put record stormfront.application %s`, desiredData))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("unable to update application: %v", err.Error())})
+		return
+	}
+
+	requestLog(c).Info("Updated application", "application", app.ID, "changed", changed)
+
+	c.JSON(http.StatusOK, gin.H{"id": app.ID, "changed": changed})
+}
 
 func GetApplicationLogs(c *gin.Context) {
 	id := c.Param("id")
 
 	data, err := connection.Query(fmt.Sprintf(`get record stormfront.application | filter id = '%s'`, id))
 	if err != nil {
-		fmt.Println("Error 1")
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err})
+		requestLog(c).Error("Unable to get application", "application", id, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
@@ -430,58 +769,145 @@ func GetApplicationLogs(c *gin.Context) {
 		return
 	}
 
-	app := data[0]
-	nodeId := app["node"].(string)
+	var app StormfrontApplication
+	appBytes, _ := json.Marshal(data[0])
+	json.Unmarshal(appBytes, &app)
+
+	// Logs are read from the first instance unless one is named
+	instanceName := c.Query("instance")
+	if instanceName == "" && len(app.Instances) > 0 {
+		instanceName = app.Instances[0].Name
+	}
+	var instance StormfrontInstance
+	for _, candidate := range app.Instances {
+		if candidate.Name == instanceName {
+			instance = candidate
+			break
+		}
+	}
+	if instance.Name == "" {
+		c.JSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("application %s has no instance %s", app.ID, instanceName)})
+		return
+	}
+
+	options, err := parseLogOptions(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 
-	if nodeId != Client.ID {
+	if instance.Node != Client.ID {
 		if Client.Type != "Leader" {
-			c.Redirect(http.StatusTemporaryRedirect, fmt.Sprintf("http://%s:%v/api/application/%s/logs", Client.Leader.Host, Client.Leader.Port, id))
+			c.Redirect(http.StatusTemporaryRedirect, fmt.Sprintf("https://%s:%v/api/application/%s/logs?%s", Client.Leader.Host, Client.Leader.Port, id, logQuery(instance.Name, options)))
 			return
 		}
-		nodeToQuery, err := getHostFromNode(nodeId)
-		if err != nil {
-			fmt.Println("Error 2")
-			fmt.Printf("Encountered error getting container logs: %v\n", err.Error())
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		proxyLogs(c, app, instance, options)
+		return
+	}
+
+	streamLogs(c, instance.Name, options)
+}
+
+func GetAPIToken(c *gin.Context) {
+	if Client.Type != "Leader" {
+		c.Redirect(http.StatusTemporaryRedirect, fmt.Sprintf("https://%s:%v/auth/api?%s", Client.Leader.Host, Client.Leader.Port, c.Request.URL.RawQuery))
+		return
+	}
+
+	name := c.Query("name")
+	if name != "" && !namespaceNameRegex.MatchString(name) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("invalid API token name '%s', names must be lowercase alphanumeric characters or '-'", name)})
+		return
+	}
+	ttl := 0
+	if c.Query("ttl") != "" {
+		var err error
+		ttl, err = strconv.Atoi(c.Query("ttl"))
+		if err != nil || ttl < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("invalid ttl %s, must be a non-negative number of seconds", c.Query("ttl"))})
 			return
 		}
-		c.Redirect(http.StatusTemporaryRedirect, fmt.Sprintf("http://%s/api/application/%s/logs", nodeToQuery, id))
+	}
+
+	apiToken, token, err := auth.CreateAPIToken(name, c.Query("role"), c.QueryArray("namespace"), ttl, requestIdentity(c))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"name":       apiToken.Name,
+		"token":      token,
+		"role":       apiToken.Role,
+		"namespaces": apiToken.Namespaces,
+		"expires":    apiToken.Expires,
+	})
+}
+
+func GetAllAPITokens(c *gin.Context) {
+	if Client.Type != "Leader" {
+		c.Redirect(http.StatusTemporaryRedirect, fmt.Sprintf("https://%s:%v/auth/api/list", Client.Leader.Host, Client.Leader.Port))
 		return
 	}
 
-	cmd := exec.Command("/bin/sh", "-c", fmt.Sprintf("%s logs %s", config.Config.ContainerEngine, app["name"].(string)))
-	var outb bytes.Buffer
-	cmd.Stdout = &outb
-	err = cmd.Run()
+	apiTokens, err := auth.GetAPITokens()
 	if err != nil {
-		fmt.Println("Error 3")
-		fmt.Println(outb.String())
-		fmt.Printf("Encountered error getting container logs: %v\n", err.Error())
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	logs := outb.String()
-	c.JSON(http.StatusOK, gin.H{"logs": logs})
+	for idx, apiToken := range apiTokens {
+		apiTokens[idx] = apiToken.Redact()
+	}
+
+	c.JSON(http.StatusOK, apiTokens)
 }
 
-func GetAPIToken(c *gin.Context) {
-	apiToken := auth.GenToken(128)
+// RevokeAPIToken revokes the API token the request was made with
+func RevokeAPIToken(c *gin.Context) {
+	if Client.Type != "Leader" {
+		c.Redirect(http.StatusTemporaryRedirect, fmt.Sprintf("https://%s:%v/auth/api", Client.Leader.Host, Client.Leader.Port))
+		return
+	}
+
+	apiToken, ok := requestAPIToken(c)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "request was not made with an API token"})
+		return
+	}
 
-	auth.APITokens = append(auth.APITokens, apiToken)
+	if _, err := auth.RevokeAPIToken(apiToken.Name, true); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	recordEvent(tokenEvent(EVENT_TOKEN_REVOKED, apiToken.Name, "API token revoked by itself"))
 
-	c.JSON(http.StatusOK, gin.H{"token": apiToken})
+	c.Status(http.StatusOK)
 }
 
-func RevokeAPIToken(c *gin.Context) {
-	apiToken := c.Request.Header.Get("X-Stormfront-API")
+func RevokeNamedAPIToken(c *gin.Context) {
+	name := c.Param("name")
 
-	for idx, token := range auth.APITokens {
-		if token == apiToken {
-			auth.APITokens = append(auth.APITokens[:idx], auth.APITokens[idx+1:]...)
-			break
-		}
+	if Client.Type != "Leader" {
+		c.Redirect(http.StatusTemporaryRedirect, fmt.Sprintf("https://%s:%v/auth/api/%s", Client.Leader.Host, Client.Leader.Port, name))
+		return
+	}
+
+	if !namespaceNameRegex.MatchString(name) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("invalid API token name '%s'", name)})
+		return
+	}
+
+	found, err := auth.RevokeAPIToken(name, true)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if !found {
+		c.JSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("API token %s does not exist", name)})
+		return
 	}
+	recordEvent(tokenEvent(EVENT_TOKEN_REVOKED, name, "API token revoked by %s", requestIdentity(c)))
 
 	c.Status(http.StatusOK)
 }
@@ -490,7 +916,7 @@ func GetClient(c *gin.Context) {
 	id := c.Param("id")
 
 	if Client.Type != "Leader" {
-		c.Redirect(http.StatusTemporaryRedirect, fmt.Sprintf("http://%s:%v/api/client/%s", Client.Leader.Host, Client.Leader.Port, id))
+		c.Redirect(http.StatusTemporaryRedirect, fmt.Sprintf("https://%s:%v/api/client/%s", Client.Leader.Host, Client.Leader.Port, id))
 		return
 	}
 
@@ -510,7 +936,7 @@ func GetClient(c *gin.Context) {
 
 func GetAllClients(c *gin.Context) {
 	if Client.Type != "Leader" {
-		c.Redirect(http.StatusTemporaryRedirect, fmt.Sprintf("http://%s:%v/api/client", Client.Leader.Host, Client.Leader.Port))
+		c.Redirect(http.StatusTemporaryRedirect, fmt.Sprintf("https://%s:%v/api/client", Client.Leader.Host, Client.Leader.Port))
 		return
 	}
 
@@ -526,8 +952,7 @@ func GetAllClients(c *gin.Context) {
 func CreateRoute(c *gin.Context) {
 
 	if Client.Type != "Leader" {
-		fmt.Printf("Node is not leader, redirecting to http://%s:%v/api/route\n", Client.Leader.Host, Client.Leader.Port)
-		c.Redirect(http.StatusTemporaryRedirect, fmt.Sprintf("http://%s:%v/api/route", Client.Leader.Host, Client.Leader.Port))
+		c.Redirect(http.StatusTemporaryRedirect, fmt.Sprintf("https://%s:%v/api/route", Client.Leader.Host, Client.Leader.Port))
 		return
 	}
 
@@ -535,6 +960,17 @@ func CreateRoute(c *gin.Context) {
 	c.BindJSON(&route)
 	route.ID = uuid.NewString()
 
+	if route.Namespace == "" {
+		route.Namespace = DEFAULT_NAMESPACE
+	}
+	if !authorizeNamespace(c, route.Namespace) {
+		return
+	}
+	if _, found, err := getNamespace(route.Namespace); err != nil || !found {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("namespace %s does not exist", route.Namespace)})
+		return
+	}
+
 	routeBytes, _ := json.Marshal(route)
 	_, err := connection.Query(fmt.Sprintf("post record stormfront.route %s", string(routeBytes)))
 	if err != nil {
@@ -548,7 +984,7 @@ func GetRoute(c *gin.Context) {
 	id := c.Param("id")
 
 	if Client.Type != "Leader" {
-		c.Redirect(http.StatusTemporaryRedirect, fmt.Sprintf("http://%s:%v/api/route/%s", Client.Leader.Host, Client.Leader.Port, id))
+		c.Redirect(http.StatusTemporaryRedirect, fmt.Sprintf("https://%s:%v/api/route/%s", Client.Leader.Host, Client.Leader.Port, id))
 		return
 	}
 
@@ -568,7 +1004,15 @@ func GetRoute(c *gin.Context) {
 
 func GetAllRoutes(c *gin.Context) {
 	if Client.Type != "Leader" {
-		c.Redirect(http.StatusTemporaryRedirect, fmt.Sprintf("http://%s:%v/api/route", Client.Leader.Host, Client.Leader.Port))
+		c.Redirect(http.StatusTemporaryRedirect, fmt.Sprintf("https://%s:%v/api/route?%s", Client.Leader.Host, Client.Leader.Port, c.Request.URL.RawQuery))
+		return
+	}
+
+	if c.Query("watch") == "true" {
+		watchList(c, routeWatch, func(object interface{}) bool {
+			namespace, _ := object.(map[string]interface{})["namespace"].(string)
+			return namespaceAllowed(c, namespace)
+		})
 		return
 	}
 
@@ -578,14 +1022,93 @@ func GetAllRoutes(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, data)
+	scoped := []map[string]interface{}{}
+	for _, datum := range data {
+		namespace, _ := datum["namespace"].(string)
+		if namespaceAllowed(c, namespace) {
+			scoped = append(scoped, datum)
+		}
+	}
+
+	c.JSON(http.StatusOK, scoped)
+}
+
+func UpdateRoute(c *gin.Context) {
+	id := c.Param("id")
+
+	if Client.Type != "Leader" {
+		c.Redirect(http.StatusTemporaryRedirect, fmt.Sprintf("https://%s:%v/api/route/%s", Client.Leader.Host, Client.Leader.Port, id))
+		return
+	}
+
+	var desired StormfrontRoute
+	if err := c.BindJSON(&desired); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	data, err := connection.Query(fmt.Sprintf(`get record stormfront.route | filter id = '%s'`, id))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if len(data) == 0 {
+		c.Status(http.StatusNotFound)
+		return
+	}
+
+	var route StormfrontRoute
+	routeBytes, _ := json.Marshal(data[0])
+	json.Unmarshal(routeBytes, &route)
+
+	if desired.ID != "" && desired.ID != route.ID {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID change not allowed in route update"})
+		return
+	}
+	if desired.Name != "" && desired.Name != route.Name {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Renaming not allowed in route update"})
+		return
+	}
+	if desired.Namespace != "" && desired.Namespace != route.Namespace {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Namespace change not allowed in route update"})
+		return
+	}
+
+	// Fields left out of the request keep their current values
+	changed := []string{}
+	if desired.Alias != "" && desired.Alias != route.Alias {
+		route.Alias = desired.Alias
+		changed = append(changed, "alias")
+	}
+	if desired.Hostname != "" && desired.Hostname != route.Hostname {
+		route.Hostname = desired.Hostname
+		changed = append(changed, "hostname")
+	}
+	if desired.Port != 0 && desired.Port != route.Port {
+		route.Port = desired.Port
+		changed = append(changed, "port")
+	}
+	if len(changed) == 0 {
+		c.JSON(http.StatusOK, gin.H{"id": route.ID, "changed": changed})
+		return
+	}
+
+	_, err = connection.Query(fmt.Sprintf(`patch record stormfront.route '%s' {"alias":"%s","hostname":"%s","port":%d}`, data[0][".id"].(string), route.Alias, route.Hostname, route.Port))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("unable to update route: %v", err.Error())})
+		return
+	}
+
+	requestLog(c).Info("Updated route", "route", route.ID, "changed", changed)
+
+	c.JSON(http.StatusOK, gin.H{"id": route.ID, "changed": changed})
 }
 
 func DeleteRoute(c *gin.Context) {
 	id := c.Param("id")
 
 	if Client.Type != "Leader" {
-		c.Redirect(http.StatusTemporaryRedirect, fmt.Sprintf("http://%s:%v/api/route/%s", Client.Leader.Host, Client.Leader.Port, id))
+		c.Redirect(http.StatusTemporaryRedirect, fmt.Sprintf("https://%s:%v/api/route/%s", Client.Leader.Host, Client.Leader.Port, id))
 		return
 	}
 
@@ -598,3 +1121,221 @@ func DeleteRoute(c *gin.Context) {
 	c.Status(http.StatusNoContent)
 
 }
+
+func CreateNamespace(c *gin.Context) {
+	if Client.Type != "Leader" {
+		c.Redirect(http.StatusTemporaryRedirect, fmt.Sprintf("https://%s:%v/api/namespace", Client.Leader.Host, Client.Leader.Port))
+		return
+	}
+
+	var namespace StormfrontNamespace
+	c.BindJSON(&namespace)
+
+	if !namespaceNameRegex.MatchString(namespace.Name) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("invalid namespace name '%s', names must be lowercase alphanumeric characters or '-'", namespace.Name)})
+		return
+	}
+
+	_, found, err := getNamespace(namespace.Name)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if found {
+		c.JSON(http.StatusConflict, gin.H{"error": fmt.Sprintf("namespace %s already exists", namespace.Name)})
+		return
+	}
+
+	namespace.ID = uuid.NewString()
+	namespaceBytes, _ := json.Marshal(namespace)
+	_, err = connection.Query(fmt.Sprintf("post record stormfront.namespace %s", string(namespaceBytes)))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("unable to create namespace: %v", err.Error())})
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"id": namespace.ID})
+}
+
+func GetAllNamespaces(c *gin.Context) {
+	if Client.Type != "Leader" {
+		c.Redirect(http.StatusTemporaryRedirect, fmt.Sprintf("https://%s:%v/api/namespace", Client.Leader.Host, Client.Leader.Port))
+		return
+	}
+
+	namespaces, err := getNamespaces()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	scoped := []StormfrontNamespace{}
+	for _, namespace := range namespaces {
+		if namespaceAllowed(c, namespace.Name) {
+			scoped = append(scoped, namespace)
+		}
+	}
+
+	c.JSON(http.StatusOK, scoped)
+}
+
+func GetNamespace(c *gin.Context) {
+	id := c.Param("id")
+
+	if Client.Type != "Leader" {
+		c.Redirect(http.StatusTemporaryRedirect, fmt.Sprintf("https://%s:%v/api/namespace/%s", Client.Leader.Host, Client.Leader.Port, id))
+		return
+	}
+
+	namespace, found, err := getNamespace(id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if !found {
+		c.Status(http.StatusNotFound)
+		return
+	}
+	if !authorizeNamespace(c, namespace.Name) {
+		return
+	}
+
+	c.JSON(http.StatusOK, namespace)
+}
+
+func UpdateNamespace(c *gin.Context) {
+	id := c.Param("id")
+
+	if Client.Type != "Leader" {
+		c.Redirect(http.StatusTemporaryRedirect, fmt.Sprintf("https://%s:%v/api/namespace/%s", Client.Leader.Host, Client.Leader.Port, id))
+		return
+	}
+
+	var desired StormfrontNamespace
+	c.BindJSON(&desired)
+
+	namespace, found, err := getNamespace(id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if !found {
+		c.Status(http.StatusNotFound)
+		return
+	}
+	if namespace.Name == DEFAULT_NAMESPACE {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "the default namespace cannot be renamed"})
+		return
+	}
+	if desired.Name == namespace.Name {
+		c.JSON(http.StatusOK, gin.H{"id": namespace.ID})
+		return
+	}
+	if !namespaceNameRegex.MatchString(desired.Name) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("invalid namespace name '%s', names must be lowercase alphanumeric characters or '-'", desired.Name)})
+		return
+	}
+	_, found, err = getNamespace(desired.Name)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if found {
+		c.JSON(http.StatusConflict, gin.H{"error": fmt.Sprintf("namespace %s already exists", desired.Name)})
+		return
+	}
+
+	// Move every object in the namespace over to the new name
+	for _, collection := range namespacedCollections {
+		data, err := connection.Query(fmt.Sprintf(`get record stormfront.%s .id | filter namespace = '%s'`, collection, namespace.Name))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		for _, datum := range data {
+			_, err := connection.Query(fmt.Sprintf(`patch record stormfront.%s '%s' {"namespace":"%s"}`, collection, datum[".id"].(string), desired.Name))
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+				return
+			}
+		}
+	}
+
+	if err := auth.RenameNamespace(namespace.Name, desired.Name); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("unable to update API token scopes: %v", err)})
+		return
+	}
+
+	namespaceIDs, err := connection.Query(fmt.Sprintf(`get record stormfront.namespace .id | filter id = '%s'`, namespace.ID))
+	if err != nil || len(namespaceIDs) == 0 {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("unable to find namespace record for %s", namespace.ID)})
+		return
+	}
+	_, err = connection.Query(fmt.Sprintf(`patch record stormfront.namespace '%s' {"name":"%s"}`, namespaceIDs[0][".id"].(string), desired.Name))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("unable to update namespace: %v", err.Error())})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"id": namespace.ID})
+}
+
+func DeleteNamespace(c *gin.Context) {
+	id := c.Param("id")
+
+	if Client.Type != "Leader" {
+		c.Redirect(http.StatusTemporaryRedirect, fmt.Sprintf("https://%s:%v/api/namespace/%s", Client.Leader.Host, Client.Leader.Port, id))
+		return
+	}
+
+	namespace, found, err := getNamespace(id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if !found {
+		c.Status(http.StatusNotFound)
+		return
+	}
+	if namespace.Name == DEFAULT_NAMESPACE {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "the default namespace cannot be deleted"})
+		return
+	}
+
+	// Volume data is removed the same way as when a volume is deleted on its
+	// own once the records are gone
+	volumes, err := getVolumes()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Cascade the delete to every object living in the namespace
+	for _, collection := range namespacedCollections {
+		data, err := connection.Query(fmt.Sprintf(`get record stormfront.%s .id | filter namespace = '%s'`, collection, namespace.Name))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		for _, datum := range data {
+			_, err := connection.Query(fmt.Sprintf(`delete record stormfront.%s %s`, collection, datum[".id"].(string)))
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+				return
+			}
+		}
+		requestLog(c).Info("Deleted namespaced objects", "namespace", namespace.Name, "collection", collection, "count", len(data))
+	}
+	for _, volume := range volumes {
+		if volume.Namespace == namespace.Name {
+			removeVolumeData(volume)
+		}
+	}
+
+	_, err = connection.Query(fmt.Sprintf(`get record stormfront.namespace .id | filter id = '%s' | delete record stormfront.namespace -`, namespace.ID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
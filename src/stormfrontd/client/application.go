@@ -1,37 +1,69 @@
 package client
 
 import (
-	"bytes"
 	"encoding/json"
 	"fmt"
 	"os"
-	"os/exec"
-	"sort"
+	"stormfrontd/client/engine"
 	"stormfrontd/config"
-	"strings"
 
 	"github.com/jfcarter2358/ceresdb-go/connection"
 )
 
+// StormfrontApplication is the leader's definition of an application. The
+// nodes running each instance write its status to InstanceStatus, keyed by
+// instance name, while Status summarizes the least healthy instance.
+// SecretEnv maps environment variables to <secret>/<key> references while
+// SecretMounts and ConfigMounts map secret and config names to the directory
+// their keys are mounted in, and Volumes maps volume names to the directory
+// their data is mounted in.
 type StormfrontApplication struct {
-	ID        string                      `json:"id" yaml:"id"`
-	Node      string                      `json:"node" yaml:"node"`
-	Name      string                      `json:"name" yaml:"name"`
-	Image     string                      `json:"image" yaml:"image"`
-	Hostname  string                      `json:"hostname" yaml:"hostname"`
-	Env       map[string]string           `json:"env" yaml:"env"`
-	Ports     map[string]string           `json:"ports" yaml:"ports"`
-	Memory    int                         `json:"memory" yaml:"memory"`
-	Mounts    map[string]string           `json:"mounts" yaml:"mounts"`
-	CPU       float64                     `json:"cpu" yaml:"cpu"`
-	Status    StormfrontApplicationStatus `json:"status" yaml:"status"`
-	Namespace string                      `json:"namespace" yaml:"namespace"`
+	ID              string                                 `json:"id" yaml:"id"`
+	Node            string                                 `json:"node" yaml:"node"`
+	Name            string                                 `json:"name" yaml:"name"`
+	Image           string                                 `json:"image" yaml:"image"`
+	Hostname        string                                 `json:"hostname" yaml:"hostname"`
+	Env             map[string]string                      `json:"env" yaml:"env"`
+	Ports           map[string]string                      `json:"ports" yaml:"ports"`
+	Memory          int                                    `json:"memory" yaml:"memory"`
+	Mounts          map[string]string                      `json:"mounts" yaml:"mounts"`
+	CPU             float64                                `json:"cpu" yaml:"cpu"`
+	Status          StormfrontApplicationStatus            `json:"status" yaml:"status"`
+	Namespace       string                                 `json:"namespace" yaml:"namespace"`
+	Reschedules     []StormfrontReschedule                 `json:"reschedules" yaml:"reschedules"`
+	NodeSelector    map[string]string                      `json:"node_selector" yaml:"node_selector"`
+	Affinity        []string                               `json:"affinity" yaml:"affinity"`
+	AntiAffinity    []string                               `json:"anti_affinity" yaml:"anti_affinity"`
+	Replicas        int                                    `json:"replicas" yaml:"replicas"`
+	RestartPolicy   string                                 `json:"restart_policy" yaml:"restart_policy"`
+	ImagePullPolicy string                                 `json:"image_pull_policy" yaml:"image_pull_policy"`
+	Instances       []StormfrontInstance                   `json:"instances" yaml:"instances"`
+	InstanceStatus  map[string]StormfrontApplicationStatus `json:"instance_status" yaml:"instance_status"`
+	SecretEnv       map[string]string                      `json:"secret_env" yaml:"secret_env"`
+	SecretMounts    map[string]string                      `json:"secret_mounts" yaml:"secret_mounts"`
+	ConfigMounts    map[string]string                      `json:"config_mounts" yaml:"config_mounts"`
+	ConfigVersion   string                                 `json:"config_version" yaml:"config_version"`
+	LivenessProbe   *StormfrontProbe                       `json:"liveness_probe" yaml:"liveness_probe"`
+	ReadinessProbe  *StormfrontProbe                       `json:"readiness_probe" yaml:"readiness_probe"`
+	Volumes         map[string]string                      `json:"volumes" yaml:"volumes"`
+}
+
+// StormfrontInstance is a single replica of an application and the node the
+// leader has placed it on
+type StormfrontInstance struct {
+	Name string `json:"name" yaml:"name"`
+	Node string `json:"node" yaml:"node"`
 }
 
 type StormfrontApplicationStatus struct {
-	CPU    string `json:"cpu" yaml:"cpu"`
-	Memory string `json:"memory" yaml:"memory"`
-	Status string `json:"status" yaml:"status"`
+	CPU              string `json:"cpu" yaml:"cpu"`
+	Memory           string `json:"memory" yaml:"memory"`
+	Status           string `json:"status" yaml:"status"`
+	Restarts         int    `json:"restarts" yaml:"restarts"`
+	ExitCode         int    `json:"exit_code" yaml:"exit_code"`
+	Ready            bool   `json:"ready" yaml:"ready"`
+	LivenessFailures int    `json:"liveness_failures" yaml:"liveness_failures"`
+	ProbeMessage     string `json:"probe_message" yaml:"probe_message"`
 }
 
 func updateApplicationStatus() error {
@@ -44,130 +76,161 @@ func updateApplicationStatus() error {
 		var app StormfrontApplication
 		appBytes, _ := json.Marshal(appMap)
 		json.Unmarshal(appBytes, &app)
-		if app.Node != Client.ID {
+
+		localInstances := app.instancesOn(Client.ID)
+		if len(localInstances) == 0 {
 			continue
 		}
-		status, cpu, memory := getApplicationStatus(app)
-		_, err := connection.Query(fmt.Sprintf(`patch record stormfront.application '%s' {"status": {"status":"%s","cpu":"%s","memory":"%s"}}`, appMap[".id"].(string), status, cpu, memory))
+
+		instanceStatus := map[string]StormfrontApplicationStatus{}
+		for _, instance := range app.Instances {
+			if existing, ok := app.InstanceStatus[instance.Name]; ok {
+				instanceStatus[instance.Name] = existing
+			}
+		}
+		for _, instance := range localInstances {
+			instanceStatus[instance.Name] = getInstanceStatus(app, instance.Name)
+			recordInstanceStats(app, instance.Name, instanceStatus[instance.Name])
+		}
+
+		summaryBytes, _ := json.Marshal(summarizeStatus(app.Instances, instanceStatus))
+		instanceStatusBytes, _ := json.Marshal(instanceStatus)
+		_, err := connection.Query(fmt.Sprintf(`patch record stormfront.application '%s' {"status":%s,"instance_status":%s}`, appMap[".id"].(string), summaryBytes, instanceStatusBytes))
 		if err != nil {
-			fmt.Printf("Unable to update database with status for application %s", app.ID)
+			reconcilerLog.Error("Unable to update application status", "application", app.ID, "error", err)
 		}
 	}
 	connection.Host = config.Config.CeresDBHost
 	return nil
 }
 
-func getApplicationStatus(app StormfrontApplication) (string, string, string) { // status, cpu, memory
+// summarizeStatus reports the status of the first instance which is not
+// running, or of the first instance if they all are
+func summarizeStatus(instances []StormfrontInstance, instanceStatus map[string]StormfrontApplicationStatus) StormfrontApplicationStatus {
+	summary := StormfrontApplicationStatus{}
+	for idx, instance := range instances {
+		status, ok := instanceStatus[instance.Name]
+		if !ok {
+			status = StormfrontApplicationStatus{CPU: "-1", Memory: "-1"}
+		}
+		if idx == 0 {
+			summary = status
+		}
+		if status.Status != "running" {
+			return status
+		}
+	}
+	return summary
+}
+
+func getApplicationStatus(name string) (string, string, string) { // status, cpu, memory
 	status := ""
 	cpu := "-1"
 	memory := "-1"
 
-	var cmd *exec.Cmd
-	if config.Config.ContainerEngine == "docker" {
-		cmd = exec.Command("/bin/sh", "-c", fmt.Sprintf("%s stats %s --no-stream --no-trunc --format \"{{.CPUPerc}}||{{.MemPerc}}\"", config.Config.ContainerEngine, app.Name))
-	} else {
-		cmd = exec.Command("/bin/sh", "-c", fmt.Sprintf("%s stats %s --no-stream --format \"{{.CPUPerc}}||{{.MemPerc}}\"", config.Config.ContainerEngine, app.Name))
-	}
-	var outb1 bytes.Buffer
-	cmd.Stdout = &outb1
-	err := cmd.Run()
+	stats, err := Runtime.Stats(name)
 	if err != nil {
-		fmt.Printf("Encountered error getting container stats: %v\n", err.Error())
+		reconcilerLog.Warn("Unable to get container stats", "container", name, "error", err)
 	} else {
-		lines := strings.Split(outb1.String(), "\n")
-		if len(lines) > 1 {
-			for _, line := range lines {
-				parts := strings.Split(line, "||")
-				if len(parts) == 2 {
-					cpu = parts[0]
-					memory = parts[1]
-				} else {
-					break
-				}
-			}
-		}
+		cpu = stats.CPU
+		memory = stats.Memory
 	}
 
-	if config.Config.ContainerEngine == "docker" {
-		cmd = exec.Command("/bin/sh", "-c", fmt.Sprintf("%s ps --no-trunc --all --format \"{{.Names}}||{{.Status}}\"", config.Config.ContainerEngine))
-	} else {
-		cmd = exec.Command("/bin/sh", "-c", fmt.Sprintf("%s ps --all --format \"{{.Names}}||{{.Status}}\"", config.Config.ContainerEngine))
-	}
-	var outb2 bytes.Buffer
-	cmd.Stdout = &outb2
-	err = cmd.Run()
+	info, err := Runtime.Inspect(name)
 	if err != nil {
-		fmt.Printf("Encountered error getting container stats: %v\n", err.Error())
+		reconcilerLog.Warn("Unable to get container status", "container", name, "error", err)
 	} else {
-		lines := strings.Split(outb2.String(), "\n")
-		if len(lines) > 1 {
-			for _, line := range lines {
-				parts := strings.Split(line, "||")
-				if len(parts) == 2 {
-					if parts[0] == app.Name {
-						status = parts[1]
-					}
-				} else {
-					break
-				}
-			}
-		}
+		status = info.Status
 	}
 
 	return status, cpu, memory
 }
 
-func deployApplication(app StormfrontApplication, shouldAppend, shouldWipeData bool) {
-	fmt.Printf("Deploying application %s\n", app.Name)
+func deployApplication(app StormfrontApplication, name string, shouldAppend, shouldWipeData bool) error {
+	reconcilerLog.Info("Deploying application", "application", app.Name, "namespace", app.Namespace, "container", name)
+
+	// Pull and resolve references before touching the existing container so
+	// that a bad image or missing object does not take down a running instance
+	if err := pullImage(app); err != nil {
+		reconcilerLog.Error("Unable to pull image", "application", app.Name, "image", app.Image, "error", err)
+		return deployFailed(app, name, err)
+	}
+
+	// Secrets are resolved here rather than stored on the application so that
+	// their values never leave the node running the instance
+	secretEnv, secretMounts, err := resolveSecrets(app, name)
+	if err != nil {
+		reconcilerLog.Error("Unable to resolve secrets", "application", app.Name, "error", err)
+		return deployFailed(app, name, err)
+	}
+	configMounts, err := resolveConfigs(app, name)
+	if err != nil {
+		reconcilerLog.Error("Unable to resolve configs", "application", app.Name, "error", err)
+		return deployFailed(app, name, err)
+	}
+	volumeMounts, err := mountVolumes(app)
+	if err != nil {
+		reconcilerLog.Error("Unable to mount volumes", "application", app.Name, "error", err)
+		return deployFailed(app, name, err)
+	}
 
 	// Clean up any possible artifacts
-	if err := exec.Command("/bin/sh", "-c", fmt.Sprintf("%s kill %s", config.Config.ContainerEngine, app.Name)).Run(); err != nil {
-		fmt.Printf("No running container with name %s exists, skipping kill\n", app.Name)
+	if err := Runtime.Stop(name); err != nil {
+		reconcilerLog.Debug("No running container to stop, skipping", "container", name)
 	}
-	if err := exec.Command("/bin/sh", "-c", fmt.Sprintf("%s rm %s", config.Config.ContainerEngine, app.Name)).Run(); err != nil {
-		fmt.Printf("No running container with name %s exists, skipping removal\n", app.Name)
+	if err := Runtime.Remove(name); err != nil {
+		reconcilerLog.Debug("No container to remove, skipping", "container", name)
 	}
 
-	// dockerCommand := fmt.Sprintf("%s run --net host -d --rm ", config.Config.ContainerEngine)
-	dockerCommand := fmt.Sprintf("%s run -d ", config.Config.ContainerEngine)
-	dockerCommand += fmt.Sprintf("--name %s ", app.Name)
-	dockerCommand += fmt.Sprintf("--cpus=\"%f\" ", app.CPU)
-	dockerCommand += fmt.Sprintf("--memory=\"%db\" ", int(app.Memory))
-	dockerCommand += fmt.Sprintf("--dns=\"%s\" --dns=\"8.8.8.8\" ", Client.Host)
-	for key, val := range app.Env {
-		dockerCommand += fmt.Sprintf("-e %s='%s' ", key, val)
+	spec := engine.ContainerSpec{
+		Name:   name,
+		Image:  app.Image,
+		CPU:    app.CPU,
+		Memory: app.Memory,
+		DNS:    []string{Client.Host, "8.8.8.8"},
+		Env:    map[string]string{},
+		Ports:  app.Ports,
+		Mounts: map[string]string{},
+	}
+	for src, dst := range secretMounts {
+		spec.Mounts[src] = dst
 	}
-	for to, from := range app.Ports {
-		dockerCommand += fmt.Sprintf("-p %s:%s ", to, from)
+	for src, dst := range configMounts {
+		spec.Mounts[src] = dst
+	}
+	// Volume data is never wiped here, it outlives the application
+	for src, dst := range volumeMounts {
+		spec.Mounts[src] = dst
+	}
+	for key, value := range app.Env {
+		spec.Env[key] = value
+	}
+	// Secret values take precedence over plain ones with the same name
+	spec.SecretEnv = secretEnv
+	for key := range secretEnv {
+		delete(spec.Env, key)
 	}
 	for src, dst := range app.Mounts {
 		if shouldWipeData {
-			os.RemoveAll(fmt.Sprintf("/var/stormfront/data/%s/%s", app.Name, src))
+			os.RemoveAll(fmt.Sprintf("/var/stormfront/data/%s/%s", name, src))
 		}
-		os.MkdirAll(fmt.Sprintf("/var/stormfront/data/%s/%s", app.Name, src), os.ModePerm)
-		dockerCommand += fmt.Sprintf("--mount type=bind,src=/var/stormfront/data/%s/%s,dst=%s ", app.Name, src, dst)
-	}
-	dockerCommand += app.Image
-	fmt.Printf("Docker command: %s\n", dockerCommand)
-	cmd := exec.Command("/bin/sh", "-c", dockerCommand)
-	var outb1, errb1 bytes.Buffer
-	cmd.Stdout = &outb1
-	cmd.Stderr = &errb1
-	err := cmd.Run()
+		os.MkdirAll(fmt.Sprintf("/var/stormfront/data/%s/%s", name, src), os.ModePerm)
+		spec.Mounts[fmt.Sprintf("/var/stormfront/data/%s/%s", name, src)] = dst
+	}
+	err = Runtime.Run(spec)
 	if err != nil {
-		fmt.Printf("Encountered error deploying Docker container: %v\n", err.Error())
-		fmt.Printf("STDOUT: %s\n", outb1.String())
-		fmt.Printf("STDERR: %s\n", errb1.String())
-		return
+		reconcilerLog.Error("Unable to run container", "application", app.Name, "container", name, "error", err)
+		return deployFailed(app, name, err)
 	}
+	recordEvent(containerEvent(EVENT_CONTAINER_STARTED, app, name, "Started container for application %s on node %s with image %s", app.Name, Client.ID, app.Image))
 
 	if shouldAppend {
-		Client.Applications = append(Client.Applications, app)
+		setDeployedApplication(app)
 
 		clientIDs, err := connection.Query(fmt.Sprintf(`get record stormfront.client .id | filter id = "%s"`, Client.ID))
 		if err != nil {
-			fmt.Printf("database error: %v", err)
-			return
+			reconcilerLog.Error("Unable to get client record", "error", err)
+			return err
 		}
 		clientData, _ := json.Marshal(Client)
 		var clientMap map[string]interface{}
@@ -176,34 +239,33 @@ func deployApplication(app StormfrontApplication, shouldAppend, shouldWipeData b
 		clientData, _ = json.Marshal(clientMap)
 		_, err = connection.Query(fmt.Sprintf(`put record stormfront.client %s`, clientData))
 		if err != nil {
-			fmt.Printf("database error: %v", err)
-			return
+			reconcilerLog.Error("Unable to update client record", "error", err)
+			return err
 		}
 	}
+
+	return nil
+}
+
+// deployFailed records a failed deployment of an instance and passes the error
+// back to the caller
+func deployFailed(app StormfrontApplication, name string, err error) error {
+	recordRepeatingEvent(containerEvent(EVENT_DEPLOY_FAILED, app, name, "Unable to deploy container for application %s on node %s: %v", app.Name, Client.ID, err))
+	return err
 }
 
 func destroyApplication(name string, shouldWipeData bool) {
-	fmt.Printf("Destroying application %s\n", name)
-	cmd := exec.Command("/bin/sh", "-c", fmt.Sprintf("%s kill %s", config.Config.ContainerEngine, name))
-	var outb1, errb1 bytes.Buffer
-	cmd.Stdout = &outb1
-	cmd.Stderr = &errb1
-	err := cmd.Run()
+	reconcilerLog.Info("Destroying container", "container", name)
+	err := Runtime.Stop(name)
 	if err != nil {
-		fmt.Printf("Encountered error killing container: %v\n", err.Error())
-		fmt.Printf("STDOUT: %s\n", outb1.String())
-		fmt.Printf("STDERR: %s\n", errb1.String())
-	}
-	cmd = exec.Command("/bin/sh", "-c", fmt.Sprintf("%s rm %s", config.Config.ContainerEngine, name))
-	var outb2, errb2 bytes.Buffer
-	cmd.Stdout = &outb1
-	cmd.Stderr = &errb1
-	err = cmd.Run()
+		reconcilerLog.Warn("Unable to stop container", "container", name, "error", err)
+	}
+	err = Runtime.Remove(name)
 	if err != nil {
-		fmt.Printf("Encountered error removing container: %v\n", err.Error())
-		fmt.Printf("STDOUT: %s\n", outb2.String())
-		fmt.Printf("STDERR: %s\n", errb2.String())
+		reconcilerLog.Warn("Unable to remove container", "container", name, "error", err)
 	}
+	removeSecretFiles(name)
+	removeConfigFiles(name)
 	// if shouldWipeData {
 	// 	for src := range app.Mounts {
 	// 		err := os.RemoveAll(fmt.Sprintf("/var/stormfront/data/%s/%s", name, src))
@@ -219,101 +281,83 @@ func reconcileApplications() {
 	data, err := connection.Query("get record stormfront.application")
 
 	if err != nil {
-		fmt.Printf("Unable to contact database: %v\n", err)
+		reconcilerLog.Error("Unable to get applications", "error", err)
 		return
 	}
 
 	dataBytes, _ := json.Marshal(data)
 	json.Unmarshal(dataBytes, &definedApplications)
 
-	// Check for applications that should be deployed
+	// Check for applications that should be deployed or updated
 	for _, definedApp := range definedApplications {
-		// shouldBeDeployed := true
+		localInstances := definedApp.instancesOn(Client.ID)
+		if len(localInstances) == 0 {
+			continue
+		}
 
-		if definedApp.Node == Client.ID {
-			err := checkContainerExists(definedApp.Name)
-			if err != nil {
-				deployApplication(definedApp, true, true)
-			}
+		deployedApp, found := getDeployedApplication(definedApp.ID)
+		changed := []string{}
+		if found {
+			changed = diffApplications(deployedApp, definedApp)
+		}
+		if len(changed) > 0 {
+			reconcilerLog.Info("Updating application", "application", definedApp.Name, "changed", changed)
 		}
-		// for _, runningApp := range Client.Applications {
-		// 	if definedApp.ID == runningApp.ID {
-		// 		shouldBeDeployed = false
-		// 		break
-		// 	}
-		// }
-		// if shouldBeDeployed {
-		// 	if definedApp.Node == Client.ID {
-		// 		deployApplication(definedApp, true, true)
-		// 	}
-		// }
-	}
 
-	toRemove := []int{}
+		for _, instance := range localInstances {
+			reconcileInstance(definedApp, instance.Name, len(changed) > 0)
+		}
+
+		if !found {
+			// Containers were started before this client came up, adopt them as-is
+			setDeployedApplication(definedApp)
+		}
+	}
 
 	// Check for applications that should be torn down
 	runningContainers, err := getRunningContainers()
-	fmt.Printf("Running containers: %v\n", runningContainers)
 	if err != nil {
+		reconcilerLog.Error("Unable to list running containers", "error", err)
 		return
 	}
+	reconcilerLog.Trace("Listed running containers", "containers", runningContainers)
 	for _, container := range runningContainers {
 		if container == "ceresdb" {
 			continue
 		}
 		shouldDestroy := true
 		for _, definedApp := range definedApplications {
-			if definedApp.Name == container {
-				shouldDestroy = false
-				break
+			for _, instance := range definedApp.instancesOn(Client.ID) {
+				if instance.Name == container {
+					shouldDestroy = false
+					break
+				}
 			}
 		}
 		if shouldDestroy {
 			destroyApplication(container, true)
+			recordEvent(containerEvent(EVENT_CONTAINER_REMOVED, StormfrontApplication{}, container, "Removed container from node %s as no application instance is scheduled there", Client.ID))
+			forgetInstanceState(container)
 		}
 	}
-	// for idx, runningApp := range Client.Applications {
-	// shouldBeDestroyed := true
-	// for _, definedApp := range definedApplications {
-	// 	if definedApp.ID == runningApp.ID {
-	// 		shouldBeDestroyed = false
-	// 		break
-	// 	}
-	// }
-	// if shouldBeDestroyed {
-	// 	if runningApp.Node == Client.ID {
-	// 		destroyApplication(runningApp, true)
-	// 		toRemove = append(toRemove, idx)
-	// 	}
-	// }
-	// }
 
-	// Remove applications that should have been destroyed
-	sort.Sort(sort.Reverse(sort.IntSlice(toRemove)))
-	for idx := range toRemove {
-		Client.Applications = append(Client.Applications[:idx], Client.Applications[idx+1:]...)
-	}
-
-	// for _, runningApp := range Client.Applications {
-	// 	for idx, definedApp := range definedApplications {
-	// 		if runningApp.ID == definedApp.ID {
-	// 			if !reflect.DeepEqual(runningApp.Env, definedApp.Env) || runningApp.Image != definedApp.Image || !reflect.DeepEqual(runningApp.Ports, definedApp.Ports) || runningApp.CPU != definedApp.CPU || runningApp.Memory != definedApp.Memory {
-	// 				fmt.Printf("Performing application update for %s\n", runningApp.Name)
-	// 				destroyApplication(runningApp)
-	// 				deployApplication(definedApp, false)
-	// 				Client.Applications[idx].Env = definedApp.Env
-	// 				Client.Applications[idx].Image = definedApp.Image
-	// 				Client.Applications[idx].Ports = definedApp.Ports
-	// 				Client.Applications[idx].CPU = definedApp.CPU
-	// 				Client.Applications[idx].Memory = definedApp.Memory
-	// 			}
-	// 		}
-	// 	}
-	// }
+	// Forget about applications which are no longer scheduled on this node
+	deployedApplications := []StormfrontApplication{}
+	for _, deployedApp := range Client.Applications {
+		for _, definedApp := range definedApplications {
+			if definedApp.ID == deployedApp.ID && len(definedApp.instancesOn(Client.ID)) > 0 {
+				// Placement changes do not redeploy, so track them here
+				deployedApp.Instances = definedApp.Instances
+				deployedApplications = append(deployedApplications, deployedApp)
+				break
+			}
+		}
+	}
+	Client.Applications = deployedApplications
 
 	clientIDs, err := connection.Query(fmt.Sprintf(`get record stormfront.client .id | filter id = "%s"`, Client.ID))
 	if err != nil {
-		fmt.Printf("database error: %v", err)
+		reconcilerLog.Error("Unable to get client record", "error", err)
 		return
 	}
 	clientData, _ := json.Marshal(Client)
@@ -323,63 +367,116 @@ func reconcileApplications() {
 	clientData, _ = json.Marshal(clientMap)
 	_, err = connection.Query(fmt.Sprintf(`put record stormfront.client %s`, clientData))
 	if err != nil {
-		fmt.Printf("database error: %v", err)
+		reconcilerLog.Error("Unable to update client record", "error", err)
 		return
 	}
 }
 
-func checkContainerExists(name string) error {
-	var cmd *exec.Cmd
-	if config.Config.ContainerEngine == "docker" {
-		cmd = exec.Command("/bin/sh", "-c", fmt.Sprintf("%s ps", config.Config.ContainerEngine))
-	} else {
-		cmd = exec.Command("/bin/sh", "-c", fmt.Sprintf("%s ps", config.Config.ContainerEngine))
-	}
-	var outb bytes.Buffer
-	cmd.Stdout = &outb
-	err := cmd.Run()
+func getRunningContainers() ([]string, error) {
+	containers, err := Runtime.List(false)
 	if err != nil {
-		return err
+		return []string{}, err
 	}
 
-	fmt.Printf("output: %v\n", outb.String())
-	lines := strings.Split(outb.String(), "\n")
-	fmt.Printf("lines: %v\n", lines)
+	output := []string{}
+	for _, container := range containers {
+		output = append(output, container.Name)
+	}
+	return output, nil
+}
 
-	for _, line := range lines[1 : len(lines)-1] {
-		idx := strings.LastIndex(line, " ")
-		containerName := line[idx+1:]
-		if containerName == name {
-			return nil
-		}
+// diffApplications returns the names of the spec fields which differ between
+// the currently deployed and desired definitions of an application
+func diffApplications(current, desired StormfrontApplication) []string {
+	changed := []string{}
+
+	if current.Image != desired.Image {
+		changed = append(changed, "image")
+	}
+	if !stringMapsEqual(current.Env, desired.Env) {
+		changed = append(changed, "env")
 	}
-	return fmt.Errorf("container with name %s does not exist", name)
+	if !stringMapsEqual(current.Ports, desired.Ports) {
+		changed = append(changed, "ports")
+	}
+	if !stringMapsEqual(current.Mounts, desired.Mounts) {
+		changed = append(changed, "mounts")
+	}
+	if !stringMapsEqual(current.SecretEnv, desired.SecretEnv) {
+		changed = append(changed, "secret_env")
+	}
+	if !stringMapsEqual(current.SecretMounts, desired.SecretMounts) {
+		changed = append(changed, "secret_mounts")
+	}
+	if !stringMapsEqual(current.ConfigMounts, desired.ConfigMounts) {
+		changed = append(changed, "config_mounts")
+	}
+	if !stringMapsEqual(current.Volumes, desired.Volumes) {
+		changed = append(changed, "volumes")
+	}
+	if current.ConfigVersion != desired.ConfigVersion {
+		changed = append(changed, "config_version")
+	}
+	if current.CPU != desired.CPU {
+		changed = append(changed, "cpu")
+	}
+	if current.Memory != desired.Memory {
+		changed = append(changed, "memory")
+	}
+
+	return changed
 }
 
-func getRunningContainers() ([]string, error) {
-	var cmd *exec.Cmd
-	if config.Config.ContainerEngine == "docker" {
-		cmd = exec.Command("/bin/sh", "-c", fmt.Sprintf("%s ps", config.Config.ContainerEngine))
-	} else {
-		cmd = exec.Command("/bin/sh", "-c", fmt.Sprintf("%s ps", config.Config.ContainerEngine))
+func getDeployedApplication(id string) (StormfrontApplication, bool) {
+	for _, app := range Client.Applications {
+		if app.ID == id {
+			return app, true
+		}
 	}
-	var outb bytes.Buffer
-	cmd.Stdout = &outb
-	err := cmd.Run()
-	if err != nil {
-		return []string{}, err
+	return StormfrontApplication{}, false
+}
+
+func setDeployedApplication(app StormfrontApplication) {
+	for idx, deployedApp := range Client.Applications {
+		if deployedApp.ID == app.ID {
+			Client.Applications[idx] = app
+			return
+		}
 	}
+	Client.Applications = append(Client.Applications, app)
+}
 
-	fmt.Printf("output: %v\n", outb.String())
-	lines := strings.Split(outb.String(), "\n")
-	fmt.Printf("lines: %v\n", lines)
+// replicaCount is the number of instances the application should run.
+// Applications defined before replicas were introduced run a single instance.
+func (app StormfrontApplication) replicaCount() int {
+	if app.Replicas < 1 {
+		return 1
+	}
+	return app.Replicas
+}
 
-	output := []string{}
+// instancesOn returns the instances of the application placed on a node
+func (app StormfrontApplication) instancesOn(nodeID string) []StormfrontInstance {
+	instances := []StormfrontInstance{}
+	for _, instance := range app.Instances {
+		if instance.Node == nodeID {
+			instances = append(instances, instance)
+		}
+	}
+	return instances
+}
 
-	for _, line := range lines[1 : len(lines)-1] {
-		idx := strings.LastIndex(line, " ")
-		containerName := line[idx+1:]
-		output = append(output, containerName)
+// instanceName returns the container name of the replica at idx. Names are
+// prefixed with the namespace, which can not contain dots, so that
+// applications sharing a name in different namespaces do not collide on a
+// node.
+func (app StormfrontApplication) instanceName(idx int) string {
+	namespace := app.Namespace
+	if namespace == "" {
+		namespace = DEFAULT_NAMESPACE
 	}
-	return output, nil
+	if idx == 0 {
+		return fmt.Sprintf("%s.%s", namespace, app.Name)
+	}
+	return fmt.Sprintf("%s.%s-%d", namespace, app.Name, idx)
 }
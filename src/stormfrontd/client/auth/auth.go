@@ -1,10 +1,11 @@
 package auth
 
 import (
+	"crypto/rand"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
-	"math/rand"
+	"math/big"
 	"net/http"
 	"os"
 	"time"
@@ -14,7 +15,6 @@ import (
 )
 
 var AuthClients []ClientInformation
-var APITokens []string
 
 type ClientInformation struct {
 	ID              string `json:"id"`
@@ -24,19 +24,38 @@ type ClientInformation struct {
 	TokenIssued     string `json:"token_issued"`
 }
 
+// ClientRecord is how a node's access and refresh tokens are stored in
+// stormfront.auth, only their hashes are kept so that the plain tokens live
+// solely in the auth.json of the node they were issued to
+type ClientRecord struct {
+	ID               string `json:"id"`
+	AccessTokenHash  string `json:"access_token_hash"`
+	RefreshTokenHash string `json:"refresh_token_hash"`
+	TokenExpiration  string `json:"token_expiration"`
+	TokenIssued      string `json:"token_issued"`
+}
+
 var letters = []rune("abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ")
 
+// GenToken returns n letters drawn from crypto/rand
 func GenToken(n int) string {
+	max := big.NewInt(int64(len(letters)))
 	b := make([]rune, n)
 	for i := range b {
-		b[i] = letters[rand.Intn(len(letters))]
+		idx, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			panic(fmt.Sprintf("unable to read random data: %v", err))
+		}
+		b[i] = letters[idx.Int64()]
 	}
 	return string(b)
 }
 
+// dataDirectory holds the state a node keeps outside of CeresDB
+var dataDirectory = "/var/stormfront"
+
 func getDataDirectory() string {
-	dataDir := "/var/stormfront"
-	return dataDir
+	return dataDirectory
 }
 
 func inTimeSpan(start, end, check time.Time) bool {
@@ -44,7 +63,6 @@ func inTimeSpan(start, end, check time.Time) bool {
 }
 
 func CreateClientInformation() ClientInformation {
-	rand.Seed(time.Now().UnixNano())
 	currentTime := time.Now()
 	expiration := currentTime.Add(time.Hour * 6)
 
@@ -61,18 +79,112 @@ func CreateClientInformation() ClientInformation {
 	return clientInfo
 }
 
+func (clientInfo ClientInformation) Record() ClientRecord {
+	return ClientRecord{
+		ID:               clientInfo.ID,
+		AccessTokenHash:  HashToken(clientInfo.AccessToken),
+		RefreshTokenHash: HashToken(clientInfo.RefreshToken),
+		TokenExpiration:  clientInfo.TokenExpiration,
+		TokenIssued:      clientInfo.TokenIssued,
+	}
+}
+
+// StoreClientInformation records the hashed tokens of clientInfo in
+// stormfront.auth
+func StoreClientInformation(clientInfo ClientInformation) error {
+	recordBytes, _ := json.Marshal(clientInfo.Record())
+	_, err := connection.Query(fmt.Sprintf("post record stormfront.auth %s", string(recordBytes)))
+	if err != nil {
+		return fmt.Errorf("database error: %v", err)
+	}
+	return nil
+}
+
+// MigrateClientRecord converts a stormfront.auth record written before tokens
+// were hashed, replacing its plain access and refresh tokens with their
+// hashes. The auth.json files holding those tokens stay valid, so nodes do not
+// need to rejoin. Records that are already hashed are returned unchanged.
+func MigrateClientRecord(record map[string]interface{}) map[string]interface{} {
+	if accessToken, ok := record["access_token"].(string); ok {
+		record["access_token_hash"] = HashToken(accessToken)
+		delete(record, "access_token")
+	}
+	if refreshToken, ok := record["refresh_token"].(string); ok {
+		record["refresh_token_hash"] = HashToken(refreshToken)
+		delete(record, "refresh_token")
+	}
+	return record
+}
+
+// MigrateClientRecords hashes the tokens of every stormfront.auth record
+// still holding them in plain text, it is run whenever a node becomes leader
+func MigrateClientRecords() error {
+	data, err := connection.Query("get record stormfront.auth")
+	if err != nil {
+		return fmt.Errorf("database error: %v", err)
+	}
+	migrated := 0
+	for _, datum := range data {
+		_, hasAccessToken := datum["access_token"]
+		_, hasRefreshToken := datum["refresh_token"]
+		if !hasAccessToken && !hasRefreshToken {
+			continue
+		}
+		recordBytes, _ := json.Marshal(MigrateClientRecord(datum))
+		_, err := connection.Query(fmt.Sprintf("put record stormfront.auth %s", recordBytes))
+		if err != nil {
+			return fmt.Errorf("database error: %v", err)
+		}
+		migrated++
+	}
+	if migrated > 0 {
+		authLog.Info("Hashed plain text client tokens", "records", migrated)
+	}
+	return nil
+}
+
+func getClientInformationPath() string {
+	return fmt.Sprintf("%s/auth.json", getDataDirectory())
+}
+
+// MigrateClientInformation brings an auth.json written before tokens were
+// hashed up to date when the client starts. The tokens in it stay valid as
+// the leader keeps their hashes, but the file was readable by every user on
+// the node so it is rewritten with the permissions WriteClientInformation
+// uses.
+func MigrateClientInformation() error {
+	path := getClientInformationPath()
+	info, err := os.Stat(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if info.Mode().Perm() == 0600 {
+		return nil
+	}
+	authLog.Info("Restricting permissions of client information", "path", path, "mode", info.Mode().Perm().String())
+	return WriteClientInformation(ReadClientInformation())
+}
+
+// WriteClientInformation stores this node's tokens so that only the daemon
+// can read them. The file is replaced rather than written in place so that
+// one created with wider permissions does not keep them.
 func WriteClientInformation(clientInfo ClientInformation) error {
 	clientData, _ := json.MarshalIndent(clientInfo, "", "    ")
 
-	err := ioutil.WriteFile(fmt.Sprintf("%s/auth.json", getDataDirectory()), clientData, 0644)
-
-	return err
+	path := getClientInformationPath()
+	if err := ioutil.WriteFile(path+".tmp", clientData, 0600); err != nil {
+		return err
+	}
+	return os.Rename(path+".tmp", path)
 }
 
 func ReadClientInformation() ClientInformation {
 	var clientInfo ClientInformation
 
-	jsonFile, _ := os.Open(fmt.Sprintf("%s/auth.json", getDataDirectory()))
+	jsonFile, _ := os.Open(getClientInformationPath())
 
 	byteValue, _ := ioutil.ReadAll(jsonFile)
 
@@ -81,55 +193,68 @@ func ReadClientInformation() ClientInformation {
 	return clientInfo
 }
 
+func getClientRecords(query string) ([]ClientRecord, error) {
+	data, err := connection.Query(query)
+	if err != nil {
+		return nil, fmt.Errorf("database error: %v", err)
+	}
+	records := []ClientRecord{}
+	recordBytes, _ := json.Marshal(data)
+	json.Unmarshal(recordBytes, &records)
+	return records, nil
+}
+
 func VerifyAccessToken(token string) int {
-	data, err := connection.Query(fmt.Sprintf(`get record stormfront.auth | filter access_token = "%s"`, token))
+	if token == "" {
+		return http.StatusUnauthorized
+	}
+	records, err := getClientRecords(fmt.Sprintf(`get record stormfront.auth | filter access_token_hash = '%s'`, HashToken(token)))
 	if err != nil {
 		return http.StatusInternalServerError
 	}
-	if len(data) > 0 {
-		issued := data[0]["token_issued"].(string)
-		expiration := data[0]["token_expiration"].(string)
-		start, _ := time.Parse(time.RFC3339, issued)
-		end, _ := time.Parse(time.RFC3339, expiration)
-		if !inTimeSpan(start, end, time.Now()) {
-			return http.StatusNotAcceptable
-		}
-		return http.StatusOK
+	if len(records) == 0 || !MatchToken(token, records[0].AccessTokenHash) {
+		return http.StatusUnauthorized
 	}
-	return http.StatusUnauthorized
-}
-
-func VerifyAPIToken(token string) int {
-	for _, apiToken := range APITokens {
-		if token == apiToken {
-			return http.StatusOK
-		}
+	start, _ := time.Parse(time.RFC3339, records[0].TokenIssued)
+	end, _ := time.Parse(time.RFC3339, records[0].TokenExpiration)
+	if !inTimeSpan(start, end, time.Now()) {
+		return http.StatusNotAcceptable
 	}
-	return http.StatusUnauthorized
+	return http.StatusOK
 }
 
 func RefreshClient(token string) (ClientInformation, error) {
-	data, err := connection.Query(fmt.Sprintf(`get record stormfront.auth | filter refresh_token = "%s"`, token))
+	data, err := connection.Query(fmt.Sprintf(`get record stormfront.auth | filter refresh_token_hash = '%s'`, HashToken(token)))
 	if err != nil {
 		return ClientInformation{}, fmt.Errorf("database error: %v", err)
 	}
 	if len(data) == 0 {
 		return ClientInformation{}, fmt.Errorf("no auth information with refresh token exists")
 	}
-	rand.Seed(time.Now().UnixNano())
+	refreshTokenHash, _ := data[0]["refresh_token_hash"].(string)
+	if !MatchToken(token, refreshTokenHash) {
+		return ClientInformation{}, fmt.Errorf("no auth information with refresh token exists")
+	}
 	currentTime := time.Now()
 	expiration := currentTime.Add(time.Hour * 6)
-	authClient := ClientInformation{}
 
-	data[0]["access_token"] = GenToken(128)
-	data[0]["refresh_token"] = GenToken(128)
-	data[0]["token_issued"] = currentTime.Format(time.RFC3339)
-	data[0]["token_expiration"] = expiration.Format(time.RFC3339)
+	id, _ := data[0]["id"].(string)
+	authClient := ClientInformation{
+		ID:              id,
+		AccessToken:     GenToken(128),
+		RefreshToken:    GenToken(128),
+		TokenExpiration: expiration.Format(time.RFC3339),
+		TokenIssued:     currentTime.Format(time.RFC3339),
+	}
+
+	record := authClient.Record()
+	data[0]["access_token_hash"] = record.AccessTokenHash
+	data[0]["refresh_token_hash"] = record.RefreshTokenHash
+	data[0]["token_issued"] = record.TokenIssued
+	data[0]["token_expiration"] = record.TokenExpiration
 
 	authData, _ := json.Marshal(data[0])
 
-	json.Unmarshal(authData, &authClient)
-
 	_, err = connection.Query(fmt.Sprintf(`put record stormfront.auth %s`, string(authData)))
 	if err != nil {
 		return ClientInformation{}, fmt.Errorf("database error: %v", err)
@@ -0,0 +1,111 @@
+package auth
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+)
+
+const CLUSTER_KEY_LENGTH = 32
+
+// ClusterKey encrypts secret values at rest. The leader generates it when the
+// cluster is created and hands it to followers as they register so that every
+// node can decrypt the secrets of the applications it runs.
+var ClusterKey []byte
+
+func getClusterKeyPath() string {
+	return fmt.Sprintf("%s/cluster.key", getDataDirectory())
+}
+
+// LoadClusterKey reads the cluster key from disk, generating a new one if the
+// node does not have one yet
+func LoadClusterKey() error {
+	keyData, err := ioutil.ReadFile(getClusterKeyPath())
+	if errors.Is(err, os.ErrNotExist) {
+		key := make([]byte, CLUSTER_KEY_LENGTH)
+		if _, err := io.ReadFull(rand.Reader, key); err != nil {
+			return fmt.Errorf("unable to generate cluster key: %v", err)
+		}
+		return WriteClusterKey(base64.StdEncoding.EncodeToString(key))
+	}
+	if err != nil {
+		return err
+	}
+	return setClusterKey(strings.TrimSpace(string(keyData)))
+}
+
+// WriteClusterKey stores a base64 encoded cluster key received from the leader
+func WriteClusterKey(encodedKey string) error {
+	if err := setClusterKey(encodedKey); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(getClusterKeyPath(), []byte(encodedKey), 0600)
+}
+
+func GetEncodedClusterKey() string {
+	return base64.StdEncoding.EncodeToString(ClusterKey)
+}
+
+func setClusterKey(encodedKey string) error {
+	key, err := base64.StdEncoding.DecodeString(encodedKey)
+	if err != nil {
+		return fmt.Errorf("invalid cluster key: %v", err)
+	}
+	if len(key) != CLUSTER_KEY_LENGTH {
+		return fmt.Errorf("invalid cluster key: expected %d bytes, got %d", CLUSTER_KEY_LENGTH, len(key))
+	}
+	ClusterKey = key
+	return nil
+}
+
+// Encrypt seals a value with AES-GCM under the cluster key and returns the
+// nonce and ciphertext base64 encoded
+func Encrypt(plaintext string) (string, error) {
+	gcm, err := newGCM()
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", err
+	}
+	sealed := gcm.Seal(nonce, nonce, []byte(plaintext), nil)
+	return base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+func Decrypt(ciphertext string) (string, error) {
+	gcm, err := newGCM()
+	if err != nil {
+		return "", err
+	}
+	sealed, err := base64.StdEncoding.DecodeString(ciphertext)
+	if err != nil {
+		return "", err
+	}
+	if len(sealed) < gcm.NonceSize() {
+		return "", errors.New("ciphertext is too short")
+	}
+	plaintext, err := gcm.Open(nil, sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():], nil)
+	if err != nil {
+		return "", err
+	}
+	return string(plaintext), nil
+}
+
+func newGCM() (cipher.AEAD, error) {
+	if len(ClusterKey) == 0 {
+		return nil, errors.New("cluster key has not been loaded")
+	}
+	block, err := aes.NewCipher(ClusterKey)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,484 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"regexp"
+	"stormfrontd/logging"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jfcarter2358/ceresdb-go/connection"
+)
+
+var authLog = logging.Subsystem("auth")
+
+const (
+	ROLE_ADMIN     = "admin"
+	ROLE_DEPLOYER  = "deployer"
+	ROLE_READ_ONLY = "read-only"
+
+	// Requests authenticated with an API token carry it in the gin context
+	// under this key, requests from nodes use access tokens and do not
+	API_TOKEN_CONTEXT_KEY = "api_token"
+)
+
+var Roles = []string{ROLE_ADMIN, ROLE_DEPLOYER, ROLE_READ_ONLY}
+
+// tokenNameRegex matches the names API tokens are created with, names given
+// to revoke a token are checked against it before they reach a query
+var tokenNameRegex = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`)
+
+// API and join tokens are stored in CeresDB as hashes so that they survive
+// restarts and failovers and are visible to every node, the plain token is
+// only handed out once when it is created
+type APIToken struct {
+	Name       string   `json:"name"`
+	Hash       string   `json:"hash,omitempty"`
+	Role       string   `json:"role"`
+	Namespaces []string `json:"namespaces"`
+	Created    string   `json:"created"`
+	CreatedBy  string   `json:"created_by"`
+	Expires    string   `json:"expires"`
+}
+
+type JoinToken struct {
+	ID        string `json:"id"`
+	Hash      string `json:"hash,omitempty"`
+	Created   string `json:"created"`
+	CreatedBy string `json:"created_by"`
+	Expires   string `json:"expires"`
+}
+
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// MatchToken reports whether token hashes to hash, comparing in constant time
+// so that stored hashes cannot be guessed from response timing
+func MatchToken(token, hash string) bool {
+	return subtle.ConstantTimeCompare([]byte(HashToken(token)), []byte(hash)) == 1
+}
+
+// expiration returns the expiry timestamp for a token living ttl seconds, a
+// ttl of zero or less never expires
+func expiration(ttl int) string {
+	if ttl <= 0 {
+		return ""
+	}
+	return time.Now().Add(time.Duration(ttl) * time.Second).Format(time.RFC3339)
+}
+
+func expired(expires string) bool {
+	if expires == "" {
+		return false
+	}
+	end, err := time.Parse(time.RFC3339, expires)
+	if err != nil {
+		return true
+	}
+	return time.Now().After(end)
+}
+
+// HasRole reports whether the token holds one of roles, admin tokens hold
+// every role
+func (t APIToken) HasRole(roles ...string) bool {
+	if t.Role == ROLE_ADMIN {
+		return true
+	}
+	for _, role := range roles {
+		if t.Role == role {
+			return true
+		}
+	}
+	return false
+}
+
+// HasNamespace reports whether the token is scoped to namespace, tokens
+// without namespaces reach all of them
+func (t APIToken) HasNamespace(namespace string) bool {
+	if len(t.Namespaces) == 0 {
+		return true
+	}
+	for _, scoped := range t.Namespaces {
+		if scoped == namespace {
+			return true
+		}
+	}
+	return false
+}
+
+func (t APIToken) Redact() APIToken {
+	t.Hash = ""
+	return t
+}
+
+func (t JoinToken) Redact() JoinToken {
+	t.Hash = ""
+	return t
+}
+
+func getAPITokens(query string) ([]APIToken, error) {
+	data, err := connection.Query(query)
+	if err != nil {
+		return nil, fmt.Errorf("database error: %v", err)
+	}
+	apiTokens := []APIToken{}
+	apiTokenBytes, _ := json.Marshal(data)
+	json.Unmarshal(apiTokenBytes, &apiTokens)
+	return apiTokens, nil
+}
+
+func GetAPITokens() ([]APIToken, error) {
+	return getAPITokens("get record stormfront.api")
+}
+
+// CreateAPIToken stores a new API token and returns it along with the plain
+// token, which cannot be recovered afterwards
+func CreateAPIToken(name, role string, namespaces []string, ttl int, createdBy string) (APIToken, string, error) {
+	if role == "" {
+		role = ROLE_ADMIN
+	}
+	validRole := false
+	for _, valid := range Roles {
+		if role == valid {
+			validRole = true
+		}
+	}
+	if !validRole {
+		return APIToken{}, "", fmt.Errorf("invalid role %s, allowed roles are %s", role, strings.Join(Roles, ", "))
+	}
+	if role == ROLE_ADMIN && len(namespaces) > 0 {
+		return APIToken{}, "", fmt.Errorf("%s tokens cannot be scoped to namespaces", ROLE_ADMIN)
+	}
+	if name == "" {
+		name = fmt.Sprintf("%s-%s", role, uuid.New().String()[:8])
+	}
+	existing, err := getAPITokens(fmt.Sprintf(`get record stormfront.api | filter name = '%s'`, name))
+	if err != nil {
+		return APIToken{}, "", err
+	}
+	if len(existing) > 0 {
+		return APIToken{}, "", fmt.Errorf("API token %s already exists", name)
+	}
+	if namespaces == nil {
+		namespaces = []string{}
+	}
+
+	token := GenToken(128)
+	apiToken := APIToken{
+		Name:       name,
+		Hash:       HashToken(token),
+		Role:       role,
+		Namespaces: namespaces,
+		Created:    time.Now().Format(time.RFC3339),
+		CreatedBy:  createdBy,
+		Expires:    expiration(ttl),
+	}
+
+	apiTokenBytes, _ := json.Marshal(apiToken)
+	_, err = connection.Query(fmt.Sprintf("post record stormfront.api %s", string(apiTokenBytes)))
+	if err != nil {
+		return APIToken{}, "", fmt.Errorf("database error: %v", err)
+	}
+	backupTokens()
+
+	return apiToken, token, nil
+}
+
+// RevokeAPIToken removes the API token with the given token value, or with
+// the given name if byName is set, and reports whether one was found
+func RevokeAPIToken(value string, byName bool) (bool, error) {
+	filter := fmt.Sprintf(`hash = '%s'`, HashToken(value))
+	if byName {
+		if !tokenNameRegex.MatchString(value) {
+			return false, fmt.Errorf("invalid API token name '%s'", value)
+		}
+		filter = fmt.Sprintf(`name = '%s'`, value)
+	}
+	data, err := connection.Query(fmt.Sprintf(`get record stormfront.api .id | filter %s`, filter))
+	if err != nil {
+		return false, fmt.Errorf("database error: %v", err)
+	}
+	for _, datum := range data {
+		_, err := connection.Query(fmt.Sprintf(`delete record stormfront.api %s`, datum[".id"].(string)))
+		if err != nil {
+			return false, fmt.Errorf("database error: %v", err)
+		}
+	}
+	if len(data) > 0 {
+		backupTokens()
+	}
+	return len(data) > 0, nil
+}
+
+// RenameNamespace moves the scopes of API tokens from a namespace which has
+// been renamed over to its new name. Tokens would otherwise lose access to
+// the namespace and gain access to any namespace later created under the old
+// name.
+func RenameNamespace(oldName, newName string) error {
+	data, err := connection.Query("get record stormfront.api")
+	if err != nil {
+		return fmt.Errorf("database error: %v", err)
+	}
+	renamed := 0
+	for _, datum := range data {
+		var apiToken APIToken
+		apiTokenBytes, _ := json.Marshal(datum)
+		json.Unmarshal(apiTokenBytes, &apiToken)
+
+		changed := false
+		for idx, namespace := range apiToken.Namespaces {
+			if namespace == oldName {
+				apiToken.Namespaces[idx] = newName
+				changed = true
+			}
+		}
+		if !changed {
+			continue
+		}
+		namespaceBytes, _ := json.Marshal(apiToken.Namespaces)
+		_, err := connection.Query(fmt.Sprintf(`patch record stormfront.api '%s' {"namespaces":%s}`, datum[".id"].(string), namespaceBytes))
+		if err != nil {
+			return fmt.Errorf("database error: %v", err)
+		}
+		renamed++
+	}
+	if renamed > 0 {
+		authLog.Info("Moved API token scopes to renamed namespace", "namespace", oldName, "new_name", newName, "tokens", renamed)
+		backupTokens()
+	}
+	return nil
+}
+
+func VerifyAPIToken(token string) (APIToken, int) {
+	if token == "" {
+		return APIToken{}, http.StatusUnauthorized
+	}
+	apiTokens, err := getAPITokens(fmt.Sprintf(`get record stormfront.api | filter hash = '%s'`, HashToken(token)))
+	if err != nil {
+		return APIToken{}, http.StatusInternalServerError
+	}
+	if len(apiTokens) == 0 || !MatchToken(token, apiTokens[0].Hash) {
+		return APIToken{}, http.StatusUnauthorized
+	}
+	if expired(apiTokens[0].Expires) {
+		return APIToken{}, http.StatusNotAcceptable
+	}
+	return apiTokens[0], http.StatusOK
+}
+
+func getJoinTokens(query string) ([]JoinToken, error) {
+	data, err := connection.Query(query)
+	if err != nil {
+		return nil, fmt.Errorf("database error: %v", err)
+	}
+	joinTokens := []JoinToken{}
+	joinTokenBytes, _ := json.Marshal(data)
+	json.Unmarshal(joinTokenBytes, &joinTokens)
+	return joinTokens, nil
+}
+
+func GetJoinTokens() ([]JoinToken, error) {
+	return getJoinTokens("get record stormfront.join")
+}
+
+// CreateJoinToken stores a new join token and returns it along with the plain
+// token, which cannot be recovered afterwards
+func CreateJoinToken(ttl int, createdBy string) (JoinToken, string, error) {
+	token := GenToken(128)
+	joinToken := JoinToken{
+		ID:        uuid.New().String(),
+		Hash:      HashToken(token),
+		Created:   time.Now().Format(time.RFC3339),
+		CreatedBy: createdBy,
+		Expires:   expiration(ttl),
+	}
+
+	joinTokenBytes, _ := json.Marshal(joinToken)
+	_, err := connection.Query(fmt.Sprintf("post record stormfront.join %s", string(joinTokenBytes)))
+	if err != nil {
+		return JoinToken{}, "", fmt.Errorf("database error: %v", err)
+	}
+	backupTokens()
+
+	return joinToken, token, nil
+}
+
+// RevokeJoinToken removes the join token with the given token value or ID and
+// returns the ID of the token it removed, if one was found
+func RevokeJoinToken(value string) (string, bool, error) {
+	data, err := connection.Query(fmt.Sprintf(`get record stormfront.join | filter hash = '%s'`, HashToken(value)))
+	if err != nil {
+		return "", false, fmt.Errorf("database error: %v", err)
+	}
+	if len(data) == 0 {
+		data, err = connection.Query(fmt.Sprintf(`get record stormfront.join | filter id = '%s'`, value))
+		if err != nil {
+			return "", false, fmt.Errorf("database error: %v", err)
+		}
+	}
+	id := ""
+	for _, datum := range data {
+		_, err := connection.Query(fmt.Sprintf(`delete record stormfront.join %s`, datum[".id"].(string)))
+		if err != nil {
+			return "", false, fmt.Errorf("database error: %v", err)
+		}
+		id, _ = datum["id"].(string)
+	}
+	if len(data) > 0 {
+		backupTokens()
+	}
+	return id, len(data) > 0, nil
+}
+
+// ConsumeJoinToken removes a join token as a node joins with it, returning
+// false if the token is unknown or has expired
+func ConsumeJoinToken(token string) (bool, error) {
+	joinTokens, err := getJoinTokens(fmt.Sprintf(`get record stormfront.join | filter hash = '%s'`, HashToken(token)))
+	if err != nil {
+		return false, err
+	}
+	if len(joinTokens) == 0 || !MatchToken(token, joinTokens[0].Hash) {
+		return false, nil
+	}
+	if _, _, err := RevokeJoinToken(token); err != nil {
+		return false, err
+	}
+	return !expired(joinTokens[0].Expires), nil
+}
+
+// DeleteExpiredTokens is run by the leader to drop API and join tokens past
+// their expiry
+func DeleteExpiredTokens() error {
+	apiTokens, err := GetAPITokens()
+	if err != nil {
+		return err
+	}
+	for _, apiToken := range apiTokens {
+		if expired(apiToken.Expires) {
+			if _, err := RevokeAPIToken(apiToken.Name, true); err != nil {
+				return err
+			}
+			authLog.Info("Deleted expired API token", "token", apiToken.Name)
+		}
+	}
+
+	joinTokens, err := GetJoinTokens()
+	if err != nil {
+		return err
+	}
+	for _, joinToken := range joinTokens {
+		if expired(joinToken.Expires) {
+			if _, _, err := RevokeJoinToken(joinToken.ID); err != nil {
+				return err
+			}
+			authLog.Info("Deleted expired join token", "token_id", joinToken.ID)
+		}
+	}
+	return nil
+}
+
+// tokenBackup mirrors the token records to disk. CeresDB starts out empty
+// whenever the leader restarts, the backup lets it put back the tokens which
+// were handed out before. Only hashes are stored, like in the database.
+type tokenBackup struct {
+	API  []APIToken  `json:"api"`
+	Join []JoinToken `json:"join"`
+}
+
+func getTokenBackupPath() string {
+	return fmt.Sprintf("%s/tokens.json", getDataDirectory())
+}
+
+// BackupTokens writes the current token records to disk
+func BackupTokens() error {
+	apiTokens, err := GetAPITokens()
+	if err != nil {
+		return err
+	}
+	joinTokens, err := GetJoinTokens()
+	if err != nil {
+		return err
+	}
+	backupBytes, _ := json.Marshal(tokenBackup{API: apiTokens, Join: joinTokens})
+
+	path := getTokenBackupPath()
+	if err := ioutil.WriteFile(path+".tmp", backupBytes, 0600); err != nil {
+		return err
+	}
+	return os.Rename(path+".tmp", path)
+}
+
+// backupTokens is called after tokens change, a failed backup only matters
+// once the leader restarts so the change itself still goes through
+func backupTokens() {
+	if err := BackupTokens(); err != nil {
+		authLog.Warn("Unable to back up tokens", "error", err)
+	}
+}
+
+// RestoreTokens adds the unexpired tokens from the backup which are missing
+// from the database
+func RestoreTokens() error {
+	backupBytes, err := ioutil.ReadFile(getTokenBackupPath())
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	var backup tokenBackup
+	if err := json.Unmarshal(backupBytes, &backup); err != nil {
+		return fmt.Errorf("invalid token backup: %v", err)
+	}
+
+	restored := 0
+	for _, apiToken := range backup.API {
+		if expired(apiToken.Expires) {
+			continue
+		}
+		existing, err := getAPITokens(fmt.Sprintf(`get record stormfront.api | filter name = '%s'`, apiToken.Name))
+		if err != nil {
+			return err
+		}
+		if len(existing) > 0 {
+			continue
+		}
+		apiTokenBytes, _ := json.Marshal(apiToken)
+		if _, err := connection.Query(fmt.Sprintf("post record stormfront.api %s", string(apiTokenBytes))); err != nil {
+			return fmt.Errorf("database error: %v", err)
+		}
+		restored++
+	}
+	for _, joinToken := range backup.Join {
+		if expired(joinToken.Expires) {
+			continue
+		}
+		existing, err := getJoinTokens(fmt.Sprintf(`get record stormfront.join | filter id = '%s'`, joinToken.ID))
+		if err != nil {
+			return err
+		}
+		if len(existing) > 0 {
+			continue
+		}
+		joinTokenBytes, _ := json.Marshal(joinToken)
+		if _, err := connection.Query(fmt.Sprintf("post record stormfront.join %s", string(joinTokenBytes))); err != nil {
+			return fmt.Errorf("database error: %v", err)
+		}
+		restored++
+	}
+	if restored > 0 {
+		authLog.Info("Restored tokens from backup", "tokens", restored)
+	}
+	return nil
+}
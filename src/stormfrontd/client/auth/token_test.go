@@ -0,0 +1,332 @@
+package auth
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"regexp"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jfcarter2358/ceresdb-go/connection"
+)
+
+var postQuery = regexp.MustCompile(`^post record stormfront\.(\w+) (.*)$`)
+var getQuery = regexp.MustCompile(`^get record stormfront\.(\w+)(?: \.id)?(?: \| filter (\w+) = '([^']*)')?$`)
+var deleteQuery = regexp.MustCompile(`^delete record stormfront\.(\w+) (\S+)$`)
+var patchQuery = regexp.MustCompile(`^patch record stormfront\.(\w+) '([^']*)' (.*)$`)
+var putQuery = regexp.MustCompile(`^put record stormfront\.(\w+) (.*)$`)
+
+// fakeCeresDB answers the handful of queries the token functions send
+type fakeCeresDB struct {
+	mutex       sync.Mutex
+	collections map[string][]map[string]interface{}
+	nextID      int
+}
+
+func (db *fakeCeresDB) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	var payload map[string]string
+	json.NewDecoder(r.Body).Decode(&payload)
+	query := payload["query"]
+
+	records := []map[string]interface{}{}
+	switch {
+	case postQuery.MatchString(query):
+		match := postQuery.FindStringSubmatch(query)
+		record := map[string]interface{}{}
+		if err := json.Unmarshal([]byte(match[2]), &record); err != nil {
+			json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+			return
+		}
+		db.nextID++
+		record[".id"] = strconv.Itoa(db.nextID)
+		db.collections[match[1]] = append(db.collections[match[1]], record)
+	case getQuery.MatchString(query):
+		match := getQuery.FindStringSubmatch(query)
+		for _, record := range db.collections[match[1]] {
+			if match[2] == "" || record[match[2]] == match[3] {
+				records = append(records, record)
+			}
+		}
+	case deleteQuery.MatchString(query):
+		match := deleteQuery.FindStringSubmatch(query)
+		remaining := []map[string]interface{}{}
+		for _, record := range db.collections[match[1]] {
+			if record[".id"] != match[2] {
+				remaining = append(remaining, record)
+			}
+		}
+		db.collections[match[1]] = remaining
+	case patchQuery.MatchString(query):
+		match := patchQuery.FindStringSubmatch(query)
+		fields := map[string]interface{}{}
+		if err := json.Unmarshal([]byte(match[3]), &fields); err != nil {
+			json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+			return
+		}
+		for _, record := range db.collections[match[1]] {
+			if record[".id"] == match[2] {
+				for key, value := range fields {
+					record[key] = value
+				}
+			}
+		}
+	case putQuery.MatchString(query):
+		match := putQuery.FindStringSubmatch(query)
+		record := map[string]interface{}{}
+		if err := json.Unmarshal([]byte(match[2]), &record); err != nil {
+			json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+			return
+		}
+		for idx, existing := range db.collections[match[1]] {
+			if existing[".id"] == record[".id"] {
+				db.collections[match[1]][idx] = record
+			}
+		}
+	default:
+		json.NewEncoder(w).Encode(map[string]string{"error": fmt.Sprintf("unsupported query %s", query)})
+		return
+	}
+	json.NewEncoder(w).Encode(records)
+}
+
+// wipe empties the database the way recreating the CeresDB container does
+func (db *fakeCeresDB) wipe() {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+	db.collections = map[string][]map[string]interface{}{}
+}
+
+func startFakeCeresDB(t *testing.T) *fakeCeresDB {
+	t.Helper()
+	db := &fakeCeresDB{collections: map[string][]map[string]interface{}{}}
+	server := httptest.NewServer(db)
+	t.Cleanup(server.Close)
+
+	serverURL, _ := url.Parse(server.URL)
+	port, _ := strconv.Atoi(serverURL.Port())
+	connection.Initialize("ceresdb", "ceresdb", serverURL.Hostname(), port)
+	return db
+}
+
+func TestTokensSurviveLeaderRestart(t *testing.T) {
+	dataDirectory = t.TempDir()
+	db := startFakeCeresDB(t)
+
+	_, apiToken, err := CreateAPIToken("ci", ROLE_DEPLOYER, nil, 0, "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	joinToken, _, err := CreateJoinToken(3600, "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db.wipe()
+	if _, status := VerifyAPIToken(apiToken); status != http.StatusUnauthorized {
+		t.Fatalf("expected token to be unknown to the new database, got status %d", status)
+	}
+
+	// Restoring twice must not duplicate tokens still in the database
+	for i := 0; i < 2; i++ {
+		if err := RestoreTokens(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	verified, status := VerifyAPIToken(apiToken)
+	if status != http.StatusOK {
+		t.Fatalf("expected token to verify after restart, got status %d", status)
+	}
+	if verified.Name != "ci" || verified.Role != ROLE_DEPLOYER {
+		t.Fatalf("expected restored token ci with role %s, got %s with role %s", ROLE_DEPLOYER, verified.Name, verified.Role)
+	}
+	apiTokens, _ := GetAPITokens()
+	if len(apiTokens) != 1 {
+		t.Fatalf("expected 1 API token, got %d", len(apiTokens))
+	}
+	joinTokens, _ := GetJoinTokens()
+	if len(joinTokens) != 1 || joinTokens[0].ID != joinToken.ID {
+		t.Fatalf("expected join token %s to be restored, got %v", joinToken.ID, joinTokens)
+	}
+}
+
+func TestRevokedTokensStayRevokedAfterRestart(t *testing.T) {
+	dataDirectory = t.TempDir()
+	db := startFakeCeresDB(t)
+
+	_, apiToken, err := CreateAPIToken("revoked", ROLE_READ_ONLY, nil, 0, "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if found, err := RevokeAPIToken("revoked", true); err != nil || !found {
+		t.Fatalf("unable to revoke token: %v", err)
+	}
+
+	db.wipe()
+	if err := RestoreTokens(); err != nil {
+		t.Fatal(err)
+	}
+	if _, status := VerifyAPIToken(apiToken); status != http.StatusUnauthorized {
+		t.Fatalf("expected revoked token to stay revoked, got status %d", status)
+	}
+}
+
+func TestMatchToken(t *testing.T) {
+	hash := HashToken("secret")
+
+	tests := []struct {
+		name  string
+		token string
+		hash  string
+		match bool
+	}{
+		{name: "same token", token: "secret", hash: hash, match: true},
+		{name: "different token", token: "Secret", hash: hash, match: false},
+		{name: "empty token", token: "", hash: hash, match: false},
+		{name: "plaintext stored", token: "secret", hash: "secret", match: false},
+		{name: "empty hash", token: "secret", hash: "", match: false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if match := MatchToken(test.token, test.hash); match != test.match {
+				t.Fatalf("expected match to be %v, got %v", test.match, match)
+			}
+		})
+	}
+}
+
+func TestExpiry(t *testing.T) {
+	tests := []struct {
+		name    string
+		expires string
+		expired bool
+	}{
+		{name: "never expires", expires: expiration(0), expired: false},
+		{name: "negative ttl never expires", expires: expiration(-10), expired: false},
+		{name: "future expiry", expires: expiration(3600), expired: false},
+		{name: "past expiry", expires: time.Now().Add(-time.Minute).Format(time.RFC3339), expired: true},
+		{name: "unparseable expiry", expires: "tomorrow", expired: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if isExpired := expired(test.expires); isExpired != test.expired {
+				t.Fatalf("expected expired to be %v for %q, got %v", test.expired, test.expires, isExpired)
+			}
+		})
+	}
+}
+
+func TestRenameNamespaceMovesTokenScopes(t *testing.T) {
+	dataDirectory = t.TempDir()
+	startFakeCeresDB(t)
+
+	tokens := []struct {
+		name       string
+		namespaces []string
+		expected   []string
+	}{
+		{name: "scoped", namespaces: []string{"team-a"}, expected: []string{"team-b"}},
+		{name: "several", namespaces: []string{"other", "team-a"}, expected: []string{"other", "team-b"}},
+		{name: "unrelated", namespaces: []string{"other"}, expected: []string{"other"}},
+		{name: "unscoped", namespaces: nil, expected: []string{}},
+	}
+	for _, token := range tokens {
+		if _, _, err := CreateAPIToken(token.name, ROLE_DEPLOYER, token.namespaces, 0, "test"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := RenameNamespace("team-a", "team-b"); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, token := range tokens {
+		apiTokens, err := getAPITokens(fmt.Sprintf(`get record stormfront.api | filter name = '%s'`, token.name))
+		if err != nil || len(apiTokens) != 1 {
+			t.Fatalf("unable to get token %s: %v", token.name, err)
+		}
+		if fmt.Sprint(apiTokens[0].Namespaces) != fmt.Sprint(token.expected) {
+			t.Errorf("expected %s to be scoped to %v, got %v", token.name, token.expected, apiTokens[0].Namespaces)
+		}
+	}
+}
+
+func TestRevokeAPITokenRejectsInvalidNames(t *testing.T) {
+	startFakeCeresDB(t)
+
+	for _, name := range []string{"", "a' | delete record stormfront.api -", "UPPER", "-leading"} {
+		if _, err := RevokeAPIToken(name, true); err == nil {
+			t.Errorf("expected name %q to be rejected", name)
+		}
+	}
+}
+
+func TestMigrateClientRecords(t *testing.T) {
+	db := startFakeCeresDB(t)
+	db.collections["auth"] = []map[string]interface{}{
+		{".id": "1", "id": "legacy", "access_token": "access", "refresh_token": "refresh"},
+		{".id": "2", "id": "current", "access_token_hash": HashToken("other"), "refresh_token_hash": HashToken("other")},
+	}
+
+	if err := MigrateClientRecords(); err != nil {
+		t.Fatalf("MigrateClientRecords returned %v", err)
+	}
+
+	legacy := db.collections["auth"][0]
+	if _, ok := legacy["access_token"]; ok {
+		t.Errorf("plain access token kept: %v", legacy)
+	}
+	if _, ok := legacy["refresh_token"]; ok {
+		t.Errorf("plain refresh token kept: %v", legacy)
+	}
+	if legacy["access_token_hash"] != HashToken("access") || legacy["refresh_token_hash"] != HashToken("refresh") {
+		t.Errorf("tokens not hashed: %v", legacy)
+	}
+	if current := db.collections["auth"][1]; current["access_token_hash"] != HashToken("other") {
+		t.Errorf("hashed record changed: %v", current)
+	}
+}
+
+func TestMigrateClientInformation(t *testing.T) {
+	dataDirectory = t.TempDir()
+	path := dataDirectory + "/auth.json"
+
+	if err := MigrateClientInformation(); err != nil {
+		t.Fatalf("MigrateClientInformation without auth.json returned %v", err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatalf("auth.json created: %v", err)
+	}
+
+	clientInfo := ClientInformation{ID: "node", AccessToken: "access", RefreshToken: "refresh"}
+	clientData, _ := json.Marshal(clientInfo)
+	if err := ioutil.WriteFile(path, clientData, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := MigrateClientInformation(); err != nil {
+		t.Fatalf("MigrateClientInformation returned %v", err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("auth.json mode is %v, want 0600", info.Mode().Perm())
+	}
+	if got := ReadClientInformation(); got != clientInfo {
+		t.Errorf("auth.json changed: got %+v, want %+v", got, clientInfo)
+	}
+}
@@ -1,32 +1,618 @@
 package client
 
 import (
+	"context"
+	"encoding/json"
+	"fmt"
 	"net/http"
+	"stormfrontd/client/communication"
 	"stormfrontd/client/lightning"
+	"stormfrontd/config"
+	"sync"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jfcarter2358/ceresdb-go/connection"
 )
 
+const BOLT_POLL_DELAY = 2
+const BOLT_DEFAULT_TIMEOUT = 3600
+
+// Runner executes the bolt runs assigned to this node
+var Runner = lightning.NewRunner()
+
+// runOutputs holds the output nodes have reported for their running runs on
+// the leader, the records only get the output once the run is done
+var runOutputs = struct {
+	sync.Mutex
+	runs map[string]*runOutput
+}{runs: map[string]*runOutput{}}
+
+type runOutput struct {
+	stdout string
+	stderr string
+}
+
+// StormfrontBolt is a shell command run on a set of nodes. The target is a
+// single node, every node, or the nodes matching a label selector, and
+// defaults to the leader. Each targeted node gets its own run which records
+// that node's output. Status and Runs are filled in when a bolt is read.
+type StormfrontBolt struct {
+	ID        string            `json:"id" yaml:"id"`
+	Command   string            `json:"command" yaml:"command"`
+	Node      string            `json:"node" yaml:"node"`
+	All       bool              `json:"all" yaml:"all"`
+	Selector  map[string]string `json:"selector" yaml:"selector"`
+	Timeout   int               `json:"timeout" yaml:"timeout"`
+	Cancelled bool              `json:"cancelled" yaml:"cancelled"`
+	Created   string            `json:"created" yaml:"created"`
+	Status    string            `json:"status,omitempty" yaml:"status,omitempty"`
+	Runs      []lightning.Run   `json:"runs,omitempty" yaml:"runs,omitempty"`
+}
+
+// boltAssignment is what a node needs to know to execute one of its runs
+type boltAssignment struct {
+	Run       lightning.Run `json:"run"`
+	Command   string        `json:"command"`
+	Timeout   int           `json:"timeout"`
+	Cancelled bool          `json:"cancelled"`
+}
+
+func getBolts() ([]StormfrontBolt, error) {
+	boltData, err := connection.Query("get record stormfront.bolt")
+	if err != nil {
+		return nil, err
+	}
+	bolts := []StormfrontBolt{}
+	boltBytes, _ := json.Marshal(boltData)
+	if err := json.Unmarshal(boltBytes, &bolts); err != nil {
+		return nil, err
+	}
+	return bolts, nil
+}
+
+func getBoltRuns(query string) ([]lightning.Run, error) {
+	runData, err := connection.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	runs := []lightning.Run{}
+	runBytes, _ := json.Marshal(runData)
+	if err := json.Unmarshal(runBytes, &runs); err != nil {
+		return nil, err
+	}
+
+	runOutputs.Lock()
+	defer runOutputs.Unlock()
+	for idx, run := range runs {
+		if output, ok := runOutputs.runs[run.ID]; ok && !run.Done() {
+			runs[idx].Stdout = output.stdout
+			runs[idx].Stderr = output.stderr
+		}
+	}
+	return runs, nil
+}
+
+// appendOutput adds chunk, which starts at offset within the output of a
+// run, to the output held so far. Output which is already held is skipped
+// and false is returned if the output in between is missing.
+func appendOutput(held, chunk string, offset int) (string, bool) {
+	if offset > len(held) {
+		return held, false
+	}
+	if offset+len(chunk) > len(held) {
+		held += chunk[len(held)-offset:]
+	}
+	return held, true
+}
+
+// boltStatus summarizes the runs of a bolt, it is running until every run
+// is done and failed if any of them failed
+func boltStatus(runs []lightning.Run) string {
+	status := lightning.BOLT_SUCCESS_STATUS
+	for _, run := range runs {
+		switch run.Status {
+		case lightning.BOLT_PENDING_STATUS, lightning.BOLT_RUNNING_STATUS:
+			return lightning.BOLT_RUNNING_STATUS
+		case lightning.BOLT_FAILURE_STATUS:
+			status = lightning.BOLT_FAILURE_STATUS
+		case lightning.BOLT_CANCELLED_STATUS:
+			if status == lightning.BOLT_SUCCESS_STATUS {
+				status = lightning.BOLT_CANCELLED_STATUS
+			}
+		}
+	}
+	return status
+}
+
+func attachRuns(bolts []StormfrontBolt, runs []lightning.Run) {
+	for idx := range bolts {
+		bolts[idx].Runs = []lightning.Run{}
+		for _, run := range runs {
+			if run.Bolt == bolts[idx].ID {
+				bolts[idx].Runs = append(bolts[idx].Runs, run)
+			}
+		}
+		bolts[idx].Status = boltStatus(bolts[idx].Runs)
+	}
+}
+
+// boltTargets returns the nodes a bolt should run on
+func boltTargets(bolt StormfrontBolt) ([]StormfrontNode, error) {
+	targeted := 0
+	if bolt.Node != "" {
+		targeted++
+	}
+	if bolt.All {
+		targeted++
+	}
+	if len(bolt.Selector) > 0 {
+		targeted++
+	}
+	if targeted > 1 {
+		return nil, fmt.Errorf("only one of node, all, and selector can be set")
+	}
+
+	nodes, err := getNodes()
+	if err != nil {
+		return nil, err
+	}
+
+	nodeID := bolt.Node
+	if targeted == 0 {
+		nodeID = Client.ID
+	}
+	targets := []StormfrontNode{}
+	for _, node := range nodes {
+		switch {
+		case bolt.All:
+			targets = append(targets, node)
+		case len(bolt.Selector) > 0:
+			matches := true
+			for key, val := range bolt.Selector {
+				if node.Labels[key] != val {
+					matches = false
+				}
+			}
+			if matches {
+				targets = append(targets, node)
+			}
+		case node.ID == nodeID:
+			targets = append(targets, node)
+		}
+	}
+	if len(targets) == 0 {
+		return nil, fmt.Errorf("no nodes match the bolt target")
+	}
+	return targets, nil
+}
+
+func GetAllBolts(c *gin.Context) {
+	if Client.Type != "Leader" {
+		c.Redirect(http.StatusTemporaryRedirect, fmt.Sprintf("https://%s:%v/lightning", Client.Leader.Host, Client.Leader.Port))
+		return
+	}
+
+	bolts, err := getBolts()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	runs, err := getBoltRuns("get record stormfront.bolt_run")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	attachRuns(bolts, runs)
+
+	c.JSON(http.StatusOK, bolts)
+}
+
 func GetBolt(c *gin.Context) {
 	boltId := c.Param("id")
 
-	bolt, err := lightning.GetBolt(boltId)
+	if Client.Type != "Leader" {
+		c.Redirect(http.StatusTemporaryRedirect, fmt.Sprintf("https://%s:%v/lightning/%s", Client.Leader.Host, Client.Leader.Port, boltId))
+		return
+	}
 
+	boltData, err := connection.Query(fmt.Sprintf(`get record stormfront.bolt | filter id = '%s'`, boltId))
 	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if len(boltData) == 0 {
 		c.Status(http.StatusNotFound)
 		return
 	}
+	bolts := []StormfrontBolt{}
+	boltBytes, _ := json.Marshal(boltData)
+	json.Unmarshal(boltBytes, &bolts)
 
-	c.JSON(http.StatusOK, bolt)
+	runs, err := getBoltRuns(fmt.Sprintf(`get record stormfront.bolt_run | filter bolt = '%s'`, boltId))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	attachRuns(bolts, runs)
+
+	c.JSON(http.StatusOK, bolts[0])
 }
 
 func PostBolt(c *gin.Context) {
-	var boltConstructor lightning.BoltConstructor
-	c.BindJSON(&boltConstructor)
+	if Client.Type != "Leader" {
+		c.Redirect(http.StatusTemporaryRedirect, fmt.Sprintf("https://%s:%v/lightning/", Client.Leader.Host, Client.Leader.Port))
+		return
+	}
 
-	bolt, idx := lightning.CreateBolt(boltConstructor.Command)
+	var bolt StormfrontBolt
+	if err := c.BindJSON(&bolt); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if bolt.Command == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "no command given to run"})
+		return
+	}
+	if bolt.Timeout < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "timeout must not be negative"})
+		return
+	}
+	if bolt.Timeout == 0 {
+		bolt.Timeout = BOLT_DEFAULT_TIMEOUT
+	}
+
+	targets, err := boltTargets(bolt)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 
-	go lightning.RunBolt(&lightning.Bolts[idx])
+	bolt.ID = uuid.NewString()
+	bolt.Cancelled = false
+	bolt.Created = time.Now().Format(time.RFC3339)
+	bolt.Status = ""
+	bolt.Runs = nil
+	boltBytes, _ := json.Marshal(bolt)
+	_, err = connection.Query(fmt.Sprintf("post record stormfront.bolt %s", boltBytes))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("unable to create bolt: %v", err)})
+		return
+	}
+
+	runs := []lightning.Run{}
+	for _, node := range targets {
+		run := lightning.Run{
+			ID:     uuid.NewString(),
+			Bolt:   bolt.ID,
+			Node:   node.ID,
+			Status: lightning.BOLT_PENDING_STATUS,
+		}
+		runBytes, _ := json.Marshal(run)
+		_, err = connection.Query(fmt.Sprintf("post record stormfront.bolt_run %s", runBytes))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("unable to create bolt run for node %s: %v", node.ID, err)})
+			return
+		}
+		runs = append(runs, run)
+	}
+
+	bolt.Runs = runs
+	bolt.Status = boltStatus(runs)
+	c.JSON(http.StatusCreated, bolt)
+}
+
+// CancelBolt stops a bolt, runs which have not started yet are cancelled
+// straight away while running ones are killed by their nodes
+func CancelBolt(c *gin.Context) {
+	boltId := c.Param("id")
+
+	if Client.Type != "Leader" {
+		c.Redirect(http.StatusTemporaryRedirect, fmt.Sprintf("https://%s:%v/lightning/%s/cancel", Client.Leader.Host, Client.Leader.Port, boltId))
+		return
+	}
+
+	boltData, err := connection.Query(fmt.Sprintf(`get record stormfront.bolt | filter id = '%s'`, boltId))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if len(boltData) == 0 {
+		c.Status(http.StatusNotFound)
+		return
+	}
+
+	_, err = connection.Query(fmt.Sprintf(`patch record stormfront.bolt '%s' {"cancelled":true}`, boltData[0][".id"].(string)))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	runData, err := connection.Query(fmt.Sprintf(`get record stormfront.bolt_run | filter bolt = '%s'`, boltId))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	for _, run := range runData {
+		if run["status"] != lightning.BOLT_PENDING_STATUS {
+			continue
+		}
+		_, err = connection.Query(fmt.Sprintf(`patch record stormfront.bolt_run '%s' {"status":"%s","error":"cancelled","finished":"%s"}`, run[".id"].(string), lightning.BOLT_CANCELLED_STATUS, time.Now().Format(time.RFC3339)))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	c.Status(http.StatusOK)
+}
+
+// GetNodeBolts returns the unfinished runs assigned to a node
+func GetNodeBolts(c *gin.Context) {
+	nodeId := c.Param("id")
+
+	if Client.Type != "Leader" {
+		c.Redirect(http.StatusTemporaryRedirect, fmt.Sprintf("https://%s:%v/lightning/node/%s", Client.Leader.Host, Client.Leader.Port, nodeId))
+		return
+	}
+
+	runs, err := getBoltRuns(fmt.Sprintf(`get record stormfront.bolt_run | filter node = '%s'`, nodeId))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	bolts, err := getBolts()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	assignments := []boltAssignment{}
+	for _, run := range runs {
+		if run.Done() {
+			continue
+		}
+		for _, bolt := range bolts {
+			if bolt.ID == run.Bolt {
+				// Output is left out, the node has its own copy
+				run.Stdout = ""
+				run.Stderr = ""
+				assignments = append(assignments, boltAssignment{Run: run, Command: bolt.Command, Timeout: bolt.Timeout, Cancelled: bolt.Cancelled})
+			}
+		}
+	}
+
+	c.JSON(http.StatusOK, assignments)
+}
+
+// UpdateBoltRun records the progress of a run reported by its node. Output
+// of running runs is kept in memory and the record is only written in full
+// once the run is done. The response tells the node how much output is
+// held, with a conflict status if some of it has to be sent again.
+func UpdateBoltRun(c *gin.Context) {
+	runId := c.Param("id")
+
+	if Client.Type != "Leader" {
+		c.Redirect(http.StatusTemporaryRedirect, fmt.Sprintf("https://%s:%v/lightning/run/%s", Client.Leader.Host, Client.Leader.Port, runId))
+		return
+	}
+
+	var run lightning.Run
+	if err := c.BindJSON(&run); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	runData, err := connection.Query(fmt.Sprintf(`get record stormfront.bolt_run | filter id = '%s'`, runId))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if len(runData) == 0 {
+		c.Status(http.StatusNotFound)
+		return
+	}
+
+	runOutputs.Lock()
+	output, ok := runOutputs.runs[runId]
+	if !ok {
+		output = &runOutput{}
+		runOutputs.runs[runId] = output
+	}
+	stdout, stdoutComplete := appendOutput(output.stdout, run.Stdout, run.StdoutOffset)
+	stderr, stderrComplete := appendOutput(output.stderr, run.Stderr, run.StderrOffset)
+	output.stdout = stdout
+	output.stderr = stderr
+	runOutputs.Unlock()
+
+	progress := lightning.Progress{Stdout: len(stdout), Stderr: len(stderr)}
+	if !stdoutComplete || !stderrComplete {
+		c.JSON(http.StatusConflict, progress)
+		return
+	}
+
+	if !run.Done() {
+		// Only the start of the run is recorded, a cancelled run stays
+		// cancelled until its node reports the end of it
+		if runData[0]["status"] == lightning.BOLT_PENDING_STATUS {
+			_, err = connection.Query(fmt.Sprintf(`patch record stormfront.bolt_run '%s' {"status":"%s","started":"%s"}`, runData[0][".id"].(string), run.Status, run.Started))
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+				return
+			}
+		}
+		c.JSON(http.StatusOK, progress)
+		return
+	}
+
+	// Keep the identifying fields from the database, only progress is
+	// taken from the node
+	run.ID = runId
+	run.Bolt = runData[0]["bolt"].(string)
+	run.Node = runData[0]["node"].(string)
+	run.Stdout = stdout
+	run.Stderr = stderr
+	run.StdoutOffset = 0
+	run.StderrOffset = 0
+	runMap := map[string]interface{}{}
+	runBytes, _ := json.Marshal(run)
+	json.Unmarshal(runBytes, &runMap)
+	runMap[".id"] = runData[0][".id"]
+	runBytes, _ = json.Marshal(runMap)
+	_, err = connection.Query(fmt.Sprintf("put record stormfront.bolt_run %s", runBytes))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	runOutputs.Lock()
+	delete(runOutputs.runs, runId)
+	runOutputs.Unlock()
+
+	c.JSON(http.StatusOK, progress)
+}
+
+// RunBolts polls the leader for runs assigned to this node, starts the new
+// ones, kills cancelled ones, and reports the progress of each back
+func RunBolts() {
+	for {
+		time.Sleep(BOLT_POLL_DELAY * time.Second)
+
+		status, body, err := communication.Get(context.Background(), Client.Leader.Host, Client.Leader.Port, fmt.Sprintf("lightning/node/%s", Client.ID), AuthClient)
+		if err != nil || status != http.StatusOK {
+			lightningLog.Error("Unable to get bolts from leader", "status", status, "body", string(body), "error", err)
+			continue
+		}
+		assignments := []boltAssignment{}
+		json.Unmarshal([]byte(body), &assignments)
+
+		for _, assignment := range assignments {
+			run := assignment.Run
+			switch {
+			case Runner.Tracking(run.ID):
+				if assignment.Cancelled {
+					Runner.Cancel(run.ID)
+				}
+			case run.Status == lightning.BOLT_PENDING_STATUS && !assignment.Cancelled:
+				lightningLog.Info("Running bolt", "bolt", run.Bolt, "run", run.ID)
+				Runner.Start(run, assignment.Command, assignment.Timeout)
+			case run.Status == lightning.BOLT_RUNNING_STATUS:
+				// The node restarted part way through the run
+				run.Status = lightning.BOLT_FAILURE_STATUS
+				run.Error = "interrupted by node restart"
+				run.ExitCode = -1
+				run.Finished = time.Now().Format(time.RFC3339)
+				reportBoltRun(run)
+			}
+		}
+
+		for _, run := range Runner.Unreported() {
+			if progress, ok := reportBoltRun(run); ok {
+				Runner.Reported(run, progress)
+			}
+		}
+	}
+}
+
+// reportBoltRun sends the progress of a run to the leader and returns how
+// much of the run's output the leader holds
+func reportBoltRun(run lightning.Run) (lightning.Progress, bool) {
+	var progress lightning.Progress
+	runBytes, _ := json.Marshal(run)
+	status, body, err := communication.Post(context.Background(), Client.Leader.Host, Client.Leader.Port, fmt.Sprintf("lightning/run/%s", run.ID), AuthClient, runBytes)
+	if err != nil || (status != http.StatusOK && status != http.StatusConflict) {
+		lightningLog.Error("Unable to report bolt run", "run", run.ID, "status", status, "body", string(body), "error", err)
+		return progress, false
+	}
+	if err := json.Unmarshal([]byte(body), &progress); err != nil {
+		lightningLog.Error("Unable to read bolt run progress", "run", run.ID, "error", err)
+		return progress, false
+	}
+	if status == http.StatusConflict {
+		lightningLog.Warn("Leader is missing bolt output, sending it again", "run", run.ID, "stdout", progress.Stdout, "stderr", progress.Stderr)
+	}
+	return progress, true
+}
+
+// cleanupBolts is run by the leader to fail runs stranded on nodes which have
+// left the cluster and to remove bolts which finished longer ago than the
+// configured retention
+func cleanupBolts() error {
+	bolts, err := getBolts()
+	if err != nil {
+		return err
+	}
+	runData, err := connection.Query("get record stormfront.bolt_run")
+	if err != nil {
+		return err
+	}
+	runs := []lightning.Run{}
+	runBytes, _ := json.Marshal(runData)
+	json.Unmarshal(runBytes, &runs)
+	nodes, err := getNodes()
+	if err != nil {
+		return err
+	}
+	nodeIDs := []string{}
+	for _, node := range nodes {
+		nodeIDs = append(nodeIDs, node.ID)
+	}
+
+	for idx, run := range runs {
+		if run.Done() || contains(nodeIDs, run.Node) {
+			continue
+		}
+		runs[idx].Status = lightning.BOLT_FAILURE_STATUS
+		runs[idx].Finished = time.Now().Format(time.RFC3339)
+		runOutputs.Lock()
+		stdout, stderr := "", ""
+		if output, ok := runOutputs.runs[run.ID]; ok {
+			stdout, stderr = output.stdout, output.stderr
+		}
+		runOutputs.Unlock()
+		stdoutBytes, _ := json.Marshal(stdout)
+		stderrBytes, _ := json.Marshal(stderr)
+		_, err := connection.Query(fmt.Sprintf(`patch record stormfront.bolt_run '%s' {"status":"%s","error":"node left the cluster","exit_code":-1,"finished":"%s","stdout":%s,"stderr":%s}`, runData[idx][".id"].(string), runs[idx].Status, runs[idx].Finished, stdoutBytes, stderrBytes))
+		if err != nil {
+			lightningLog.Error("Unable to record failure of bolt run", "run", run.ID, "error", err)
+			continue
+		}
+		runOutputs.Lock()
+		delete(runOutputs.runs, run.ID)
+		runOutputs.Unlock()
+	}
+
+	retention := time.Duration(config.Config.BoltRetention) * time.Second
+	for _, bolt := range bolts {
+		expired := true
+		for _, run := range runs {
+			if run.Bolt != bolt.ID {
+				continue
+			}
+			finished, err := time.Parse(time.RFC3339, run.Finished)
+			if !run.Done() || err != nil || time.Since(finished) < retention {
+				expired = false
+			}
+		}
+		if created, err := time.Parse(time.RFC3339, bolt.Created); err != nil || time.Since(created) < retention {
+			expired = false
+		}
+		if !expired {
+			continue
+		}
+
+		lightningLog.Info("Removing bolt past its retention", "bolt", bolt.ID)
+		_, err := connection.Query(fmt.Sprintf(`get record stormfront.bolt_run .id | filter bolt = '%s' | delete record stormfront.bolt_run -`, bolt.ID))
+		if err != nil {
+			lightningLog.Error("Unable to remove bolt runs", "bolt", bolt.ID, "error", err)
+			continue
+		}
+		_, err = connection.Query(fmt.Sprintf(`get record stormfront.bolt .id | filter id = '%s' | delete record stormfront.bolt -`, bolt.ID))
+		if err != nil {
+			lightningLog.Error("Unable to remove bolt", "bolt", bolt.ID, "error", err)
+		}
+	}
 
-	c.JSON(http.StatusOK, bolt)
+	return nil
 }
@@ -1,14 +1,20 @@
 package client
 
 import (
+	"context"
+	"crypto/tls"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"log"
 	"net/http"
 	"stormfrontd/client/auth"
 	"stormfrontd/client/communication"
 	"stormfrontd/client/dns"
+	"stormfrontd/client/engine"
+	"stormfrontd/client/pki"
+	"stormfrontd/client/scheduler"
 	"stormfrontd/config"
+	"stormfrontd/middleware"
 	"strconv"
 	"time"
 
@@ -19,8 +25,9 @@ import (
 
 var Client StormfrontClient
 var Running = false
-var JoinTokens []string
 var AuthClient auth.ClientInformation
+var Runtime engine.ContainerRuntime
+var Scheduler *scheduler.Scheduler
 
 const HEALTH_CHECK_DELAY = 10
 const UPDATE_RETRY_DELAY = 1
@@ -43,8 +50,26 @@ type StormfrontClient struct {
 	System       StormfrontSystemInfo    `json:"system" yaml:"system"`
 }
 
-func Initialize(joinToken string) error {
-	Client.Router = gin.Default()
+// Initialize starts the client API, followers first join the leader with
+// joinToken, checking its CA against caFingerprint
+func Initialize(joinToken, caFingerprint string) error {
+	containerRuntime, err := engine.New(config.Config.ContainerEngine)
+	if err != nil {
+		return err
+	}
+	Runtime = containerRuntime
+
+	if err := auth.MigrateClientInformation(); err != nil {
+		return fmt.Errorf("unable to migrate client information: %v", err)
+	}
+
+	Scheduler, err = scheduler.New(config.Config.SchedulingStrategy)
+	if err != nil {
+		return err
+	}
+
+	Client.Router = gin.New()
+	Client.Router.Use(middleware.Recovery(), middleware.RequestLogger())
 
 	InitializeRoutes(Client.Type)
 
@@ -53,17 +78,38 @@ func Initialize(joinToken string) error {
 	server.AddZoneData("stormfront", nil, lookupFunc, dns.DNSForwardLookupZone)
 	go server.StartAndServe()
 
+	if Client.Type != "Leader" {
+		err := joinLeader(joinToken, caFingerprint)
+		if err != nil {
+			return err
+		}
+	}
+
+	clientTLSConfig, err := pki.ClientTLSConfig(config.Config.MutualTLS)
+	if err != nil {
+		return err
+	}
+	communication.Configure(clientTLSConfig)
+
+	serverTLSConfig, err := pki.ServerTLSConfig(config.Config.MutualTLS)
+	if err != nil {
+		return err
+	}
+
 	Client.Server = &http.Server{
-		Addr:    ":" + strconv.Itoa(Client.Port),
-		Handler: Client.Router,
+		Addr:      ":" + strconv.Itoa(Client.Port),
+		Handler:   Client.Router,
+		TLSConfig: serverTLSConfig,
+		// Exec sessions hijack the connection, which HTTP/2 does not allow
+		TLSNextProto: map[string]func(*http.Server, *tls.Conn, http.Handler){},
 	}
 
 	Running = true
 
 	// Start serving the application
 	go func() {
-		if err := Client.Server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			log.Fatalf("listen: %s\n", err)
+		if err := Client.Server.ListenAndServeTLS("", ""); err != nil && err != http.ErrServerClosed {
+			clusterLog.Fatal("Unable to serve client API", "port", Client.Port, "error", err)
 		}
 	}()
 
@@ -73,13 +119,13 @@ func Initialize(joinToken string) error {
 			panic(err)
 		}
 	} else {
-		err := InitializeFollower(joinToken)
+		err := InitializeFollower()
 		if err != nil {
 			panic(err)
 		}
 	}
 
-	err := updateSystemInfo()
+	err = updateSystemInfo()
 	if err != nil {
 		panic(err)
 	}
@@ -87,7 +133,9 @@ func Initialize(joinToken string) error {
 	return nil
 }
 
-func InitializeFollower(joinToken string) error {
+// joinLeader trades the join token for an access token and a certificate
+// signed by the cluster CA
+func joinLeader(joinToken, caFingerprint string) error {
 	if config.Config.CeresDBHost == "" {
 		config.Config.CeresDBHost = Client.Host
 	}
@@ -98,41 +146,74 @@ func InitializeFollower(joinToken string) error {
 	AuthClient = auth.ClientInformation{}
 	AuthClient.AccessToken = joinToken
 
-	status, body, err := communication.Get(Client.Leader.Host, Client.Leader.Port, "auth/token", AuthClient)
+	if caFingerprint == "" {
+		return errors.New("a CA fingerprint is required to join a cluster")
+	}
+	communication.Configure(pki.PinnedTLSConfig(caFingerprint))
+
+	request, err := pki.CreateCertificateRequest(Client.Host)
 	if err != nil {
-		fmt.Printf("Encountered error: %v\n", err.Error())
+		return err
+	}
+	postBody, _ := json.Marshal(map[string]string{"certificate_request": string(request)})
+
+	status, body, err := communication.Post(context.Background(), Client.Leader.Host, Client.Leader.Port, "auth/token", AuthClient, postBody)
+	if err != nil {
+		clusterLog.Error("Unable to get access token from leader", "leader", Client.Leader.Host, "error", err)
 		return err
 	}
 	if status != http.StatusOK {
 		return fmt.Errorf("unable to contact client at %s:%v, received status code %v", Client.Leader.Host, Client.Leader.Port, status)
 	}
 
-	json.Unmarshal([]byte(body), &AuthClient)
+	var joined joinResponse
+	json.Unmarshal([]byte(body), &joined)
+	AuthClient = joined.ClientInformation
 
 	auth.WriteClientInformation(AuthClient)
 
-	node := StormfrontNode{ID: Client.ID, Host: Client.Host, Port: Client.Port, System: StormfrontSystemInfo{}, Health: "Healthy", Type: "Follower"}
+	err = pki.WriteCA([]byte(joined.CACertificate), nil)
+	if err != nil {
+		return err
+	}
+	return pki.WriteNodeCertificate([]byte(joined.Certificate))
+}
+
+func InitializeFollower() error {
+	node := StormfrontNode{ID: Client.ID, Host: Client.Host, Port: Client.Port, System: StormfrontSystemInfo{}, Health: "Healthy", Type: "Follower", Labels: config.Config.NodeLabels}
 
 	postBody, _ := json.Marshal(node)
 
-	status, _, err = communication.Post(Client.Leader.Host, Client.Leader.Port, "api/register", AuthClient, postBody)
+	status, body, err := communication.Post(context.Background(), Client.Leader.Host, Client.Leader.Port, "api/register", AuthClient, postBody)
 	if err != nil {
-		fmt.Printf("Encountered error: %v\n", err.Error())
+		clusterLog.Error("Unable to register with leader", "leader", Client.Leader.Host, "error", err)
 		return err
 	}
 	if status != http.StatusOK {
 		return fmt.Errorf("unable to contact client at %s:%v, received status code %v", Client.Leader.Host, Client.Leader.Port, status)
 	}
 
+	var registration map[string]string
+	json.Unmarshal([]byte(body), &registration)
+	err = auth.WriteClusterKey(registration["cluster_key"])
+	if err != nil {
+		return fmt.Errorf("unable to store cluster key from leader: %v", err)
+	}
+	err = pki.WriteCA([]byte(registration["ca_certificate"]), nil)
+	if err != nil {
+		return fmt.Errorf("unable to store cluster CA from leader: %v", err)
+	}
+
 	clientData, _ := json.Marshal(Client)
 	_, err = connection.Query(fmt.Sprintf(`post record stormfront.client %s`, clientData))
 	if err != nil {
-		fmt.Printf("database error: %v", err)
+		clusterLog.Error("Unable to add client record", "error", err)
 		return err
 	}
 
 	// Check follower healths
 	go HealthCheckFollower()
+	go RunBolts()
 
 	return nil
 }
@@ -142,16 +223,29 @@ func InitializeLeader() error {
 	err := CreateDatabases()
 
 	if err != nil {
-		fmt.Printf("Error: %v\n", err)
+		databaseLog.Fatal("Unable to create databases", "error", err)
+	}
+
+	err = auth.LoadClusterKey()
+	if err != nil {
 		panic(err)
 	}
 
+	err = auth.RestoreTokens()
+	if err != nil {
+		databaseLog.Error("Unable to restore tokens", "error", err)
+	}
+
+	err = auth.MigrateClientRecords()
+	if err != nil {
+		databaseLog.Error("Unable to migrate client records", "error", err)
+	}
+
 	Client.ID = Client.Leader.ID
 	AuthClient = auth.CreateClientInformation()
 	auth.WriteClientInformation(AuthClient)
 
-	authData, _ := json.Marshal(AuthClient)
-	_, err = connection.Query(fmt.Sprintf("post record stormfront.auth %s", authData))
+	err = auth.StoreClientInformation(AuthClient)
 
 	if err != nil {
 		panic(err)
@@ -159,10 +253,10 @@ func InitializeLeader() error {
 
 	leaderDataRaw := StormfrontLeader{
 		ID:         Client.ID,
-		Succession: make([]string, 0),
-		Healthy:    make([]string, 0),
-		Unhealthy:  make([]string, 0),
-		Unknown:    make([]string, 0),
+		Succession: make([]StormfrontNode, 0),
+		Healthy:    make([]StormfrontNode, 0),
+		Unhealthy:  make([]StormfrontNode, 0),
+		Unknown:    make([]StormfrontNode, 0),
 	}
 	leaderData, _ := json.Marshal(leaderDataRaw)
 
@@ -179,6 +273,7 @@ func InitializeLeader() error {
 		System: StormfrontSystemInfo{},
 		Health: "Healthy",
 		Type:   "Leader",
+		Labels: config.Config.NodeLabels,
 	}
 	nodeData, _ := json.Marshal(nodeDataRaw)
 
@@ -188,6 +283,12 @@ func InitializeLeader() error {
 		panic(err)
 	}
 
+	err = ensureDefaultNamespace()
+
+	if err != nil {
+		panic(err)
+	}
+
 	Client.Succession = []StormfrontNode{}
 	Client.Unhealthy = []StormfrontNode{}
 	Client.Unknown = []StormfrontNode{}
@@ -195,12 +296,32 @@ func InitializeLeader() error {
 	clientData, _ := json.Marshal(Client)
 	_, err = connection.Query(fmt.Sprintf(`post record stormfront.client %s`, clientData))
 	if err != nil {
-		fmt.Printf("database error: %v", err)
+		clusterLog.Error("Unable to add client record", "error", err)
 		return err
 	}
 
 	// Check follower healths
 	go HealthCheckLeader()
+	go RunBolts()
 
 	return nil
 }
+
+// updateClientRecord writes the current in-memory client state to stormfront.client
+func updateClientRecord() error {
+	clientIDs, err := connection.Query(fmt.Sprintf(`get record stormfront.client .id | filter id = "%s"`, Client.ID))
+	if err != nil {
+		return err
+	}
+	clientData, _ := json.Marshal(Client)
+	if len(clientIDs) == 0 {
+		_, err = connection.Query(fmt.Sprintf(`post record stormfront.client %s`, clientData))
+		return err
+	}
+	var clientMap map[string]interface{}
+	json.Unmarshal(clientData, &clientMap)
+	clientMap[".id"] = clientIDs[0][".id"]
+	clientData, _ = json.Marshal(clientMap)
+	_, err = connection.Query(fmt.Sprintf(`put record stormfront.client %s`, clientData))
+	return err
+}
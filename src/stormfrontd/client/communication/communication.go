@@ -1,31 +1,67 @@
 package communication
 
 import (
+	"bufio"
 	"bytes"
+	"context"
+	"crypto/tls"
 	"encoding/json"
 	"fmt"
+	"io"
 	"io/ioutil"
+	"net"
 	"net/http"
 	"stormfrontd/client/auth"
+	"stormfrontd/logging"
+	"time"
 )
 
-func Get(host string, port int, path string, AuthClient auth.ClientInformation) (int, string, error) {
-	httpClient := &http.Client{}
-	requestURL := fmt.Sprintf("http://%s:%v/%s", host, port, path)
-	req, _ := http.NewRequest("GET", requestURL, nil)
+const REQUEST_TIMEOUT = 10
+
+// TLSConfig is used for every request to another node, it trusts the cluster
+// CA and carries this node's certificate when mutual TLS is enabled
+var TLSConfig = &tls.Config{MinVersion: tls.VersionTLS12}
+var transport = &http.Transport{TLSClientConfig: TLSConfig}
+
+// Configure replaces the TLS configuration used to reach other nodes
+func Configure(tlsConfig *tls.Config) {
+	transport.CloseIdleConnections()
+	TLSConfig = tlsConfig
+	transport = &http.Transport{TLSClientConfig: tlsConfig}
+}
+
+var communicationLog = logging.Subsystem("communication")
+
+// setRequestID passes on the ID of the request being served, if any, so that
+// the receiving node logs it alongside its own lines
+func setRequestID(ctx context.Context, req *http.Request) {
+	if id := logging.RequestID(ctx); id != "" {
+		req.Header.Set(logging.REQUEST_ID_HEADER, id)
+	}
+}
+
+func newClient(timeout time.Duration) *http.Client {
+	return &http.Client{Timeout: timeout, Transport: transport}
+}
+
+func Get(ctx context.Context, host string, port int, path string, AuthClient auth.ClientInformation) (int, string, error) {
+	httpClient := newClient(REQUEST_TIMEOUT * time.Second)
+	requestURL := fmt.Sprintf("https://%s:%v/%s", host, port, path)
+	req, _ := http.NewRequestWithContext(ctx, "GET", requestURL, nil)
 	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", AuthClient.AccessToken))
+	setRequestID(ctx, req)
 	resp, err := httpClient.Do(req)
 	if err != nil {
 		return -1, "", err
 	}
 	if resp.StatusCode == http.StatusNotAcceptable {
-		refreshURL := fmt.Sprintf("http://%s:%v/auth/refresh", host, port)
-		refreshClient := &http.Client{}
+		refreshURL := fmt.Sprintf("https://%s:%v/auth/refresh", host, port)
+		refreshClient := newClient(REQUEST_TIMEOUT * time.Second)
 		refreshReq, _ := http.NewRequest("GET", refreshURL, nil)
 		refreshReq.Header.Set("Authorization", fmt.Sprintf("Bearer %s", AuthClient.RefreshToken))
 		refreshResp, err := refreshClient.Do(refreshReq)
 		if err != nil {
-			fmt.Printf("Unable to contact client at %s:%v/api/health\n", host, port)
+			communicationLog.Warn("Unable to refresh access token", "request_id", logging.RequestID(ctx), "host", host, "port", port, "error", err)
 			return -1, "", err
 		}
 		defer refreshResp.Body.Close()
@@ -55,23 +91,24 @@ func Get(host string, port int, path string, AuthClient auth.ClientInformation)
 	return resp.StatusCode, responseBody, nil
 }
 
-func Delete(host string, port int, path string, AuthClient auth.ClientInformation) (int, string, error) {
-	httpClient := &http.Client{}
-	requestURL := fmt.Sprintf("http://%s:%v/%s", host, port, path)
-	req, _ := http.NewRequest("DELETE", requestURL, nil)
+func Delete(ctx context.Context, host string, port int, path string, AuthClient auth.ClientInformation) (int, string, error) {
+	httpClient := newClient(REQUEST_TIMEOUT * time.Second)
+	requestURL := fmt.Sprintf("https://%s:%v/%s", host, port, path)
+	req, _ := http.NewRequestWithContext(ctx, "DELETE", requestURL, nil)
 	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", AuthClient.AccessToken))
+	setRequestID(ctx, req)
 	resp, err := httpClient.Do(req)
 	if err != nil {
 		return -1, "", err
 	}
 	if resp.StatusCode == http.StatusNotAcceptable {
-		refreshURL := fmt.Sprintf("http://%s:%v/auth/refresh", host, port)
-		refreshClient := &http.Client{}
+		refreshURL := fmt.Sprintf("https://%s:%v/auth/refresh", host, port)
+		refreshClient := newClient(REQUEST_TIMEOUT * time.Second)
 		refreshReq, _ := http.NewRequest("GET", refreshURL, nil)
 		refreshReq.Header.Set("Authorization", fmt.Sprintf("Bearer %s", AuthClient.RefreshToken))
 		refreshResp, err := refreshClient.Do(refreshReq)
 		if err != nil {
-			fmt.Printf("Unable to contact client at %s:%v/api/health\n", host, port)
+			communicationLog.Warn("Unable to refresh access token", "request_id", logging.RequestID(ctx), "host", host, "port", port, "error", err)
 			return -1, "", err
 		}
 		defer refreshResp.Body.Close()
@@ -101,25 +138,26 @@ func Delete(host string, port int, path string, AuthClient auth.ClientInformatio
 	return resp.StatusCode, responseBody, nil
 }
 
-func Post(host string, port int, path string, AuthClient auth.ClientInformation, postBody []byte) (int, string, error) {
+func Post(ctx context.Context, host string, port int, path string, AuthClient auth.ClientInformation, postBody []byte) (int, string, error) {
 	postBodyBuffer := bytes.NewBuffer(postBody)
 
-	httpClient := &http.Client{}
-	requestURL := fmt.Sprintf("http://%s:%v/%s", host, port, path)
-	req, _ := http.NewRequest("POST", requestURL, postBodyBuffer)
+	httpClient := newClient(REQUEST_TIMEOUT * time.Second)
+	requestURL := fmt.Sprintf("https://%s:%v/%s", host, port, path)
+	req, _ := http.NewRequestWithContext(ctx, "POST", requestURL, postBodyBuffer)
 	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", AuthClient.AccessToken))
+	setRequestID(ctx, req)
 	resp, err := httpClient.Do(req)
 	if err != nil {
 		return -1, "", err
 	}
 	if resp.StatusCode == http.StatusNotAcceptable {
-		refreshURL := fmt.Sprintf("http://%s:%v/auth/refresh", host, port)
-		refreshClient := &http.Client{}
+		refreshURL := fmt.Sprintf("https://%s:%v/auth/refresh", host, port)
+		refreshClient := newClient(REQUEST_TIMEOUT * time.Second)
 		refreshReq, _ := http.NewRequest("GET", refreshURL, nil)
 		refreshReq.Header.Set("Authorization", fmt.Sprintf("Bearer %s", AuthClient.RefreshToken))
 		refreshResp, err := refreshClient.Do(refreshReq)
 		if err != nil {
-			fmt.Printf("Unable to contact client at %s:%v/api/health\n", host, port)
+			communicationLog.Warn("Unable to refresh access token", "request_id", logging.RequestID(ctx), "host", host, "port", port, "error", err)
 			return -1, "", err
 		}
 		defer refreshResp.Body.Close()
@@ -148,3 +186,128 @@ func Post(host string, port int, path string, AuthClient auth.ClientInformation,
 
 	return resp.StatusCode, responseBody, nil
 }
+
+// PostStream sends body without a timeout so that large uploads, such as
+// volume archives, are not cut off part way through. The body can only be
+// read once, so when the access token has expired it is refreshed and the
+// rejected status returned for the caller to retry.
+func PostStream(ctx context.Context, host string, port int, path string, AuthClient auth.ClientInformation, postBody io.Reader) (int, string, error) {
+	httpClient := newClient(0)
+	requestURL := fmt.Sprintf("https://%s:%v/%s", host, port, path)
+	req, _ := http.NewRequestWithContext(ctx, "POST", requestURL, postBody)
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", AuthClient.AccessToken))
+	setRequestID(ctx, req)
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return -1, "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotAcceptable {
+		refreshURL := fmt.Sprintf("https://%s:%v/auth/refresh", host, port)
+		refreshClient := newClient(REQUEST_TIMEOUT * time.Second)
+		refreshReq, _ := http.NewRequest("GET", refreshURL, nil)
+		refreshReq.Header.Set("Authorization", fmt.Sprintf("Bearer %s", AuthClient.RefreshToken))
+		refreshResp, err := refreshClient.Do(refreshReq)
+		if err != nil {
+			communicationLog.Warn("Unable to refresh access token", "request_id", logging.RequestID(ctx), "host", host, "port", port, "error", err)
+			return -1, "", err
+		}
+		defer refreshResp.Body.Close()
+		//Read the response body
+		body, err := ioutil.ReadAll(refreshResp.Body)
+		if err != nil {
+			return -1, "", err
+		}
+		json.Unmarshal(body, &AuthClient)
+		auth.WriteClientInformation(AuthClient)
+	}
+	//Read the response body
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return -1, "", err
+	}
+	responseBody := string(body)
+
+	return resp.StatusCode, responseBody, nil
+}
+
+// Stream sends a GET request without a timeout and hands back the open
+// response so long lived bodies such as followed logs can be relayed as they
+// arrive. The caller is responsible for closing the body, cancelling ctx
+// aborts the request.
+func Stream(ctx context.Context, host string, port int, path string, AuthClient auth.ClientInformation) (*http.Response, error) {
+	httpClient := newClient(0)
+	requestURL := fmt.Sprintf("https://%s:%v/%s", host, port, path)
+	req, _ := http.NewRequestWithContext(ctx, "GET", requestURL, nil)
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", AuthClient.AccessToken))
+	setRequestID(ctx, req)
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusNotAcceptable {
+		resp.Body.Close()
+		refreshURL := fmt.Sprintf("https://%s:%v/auth/refresh", host, port)
+		refreshClient := newClient(REQUEST_TIMEOUT * time.Second)
+		refreshReq, _ := http.NewRequest("GET", refreshURL, nil)
+		refreshReq.Header.Set("Authorization", fmt.Sprintf("Bearer %s", AuthClient.RefreshToken))
+		refreshResp, err := refreshClient.Do(refreshReq)
+		if err != nil {
+			communicationLog.Warn("Unable to refresh access token", "request_id", logging.RequestID(ctx), "host", host, "port", port, "error", err)
+			return nil, err
+		}
+		defer refreshResp.Body.Close()
+		//Read the response body
+		body, err := ioutil.ReadAll(refreshResp.Body)
+		if err != nil {
+			return nil, err
+		}
+		json.Unmarshal(body, &AuthClient)
+		auth.WriteClientInformation(AuthClient)
+		// resent the request with the new access token
+		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", AuthClient.AccessToken))
+		resp, err = httpClient.Do(req)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return resp, nil
+}
+
+// Upgrade sends a GET request asking to switch the connection to protocol.
+// On success the raw connection is returned along with a reader holding
+// anything the server sent after its response headers. Otherwise the
+// connection is closed and the response returned so the caller can relay it.
+func Upgrade(ctx context.Context, host string, port int, path string, AuthClient auth.ClientInformation, protocol string) (net.Conn, *bufio.Reader, *http.Response, error) {
+	dialer := &tls.Dialer{NetDialer: &net.Dialer{Timeout: REQUEST_TIMEOUT * time.Second}, Config: TLSConfig}
+	conn, err := dialer.DialContext(ctx, "tcp", fmt.Sprintf("%s:%v", host, port))
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	requestURL := fmt.Sprintf("https://%s:%v/%s", host, port, path)
+	req, _ := http.NewRequest("GET", requestURL, nil)
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", AuthClient.AccessToken))
+	setRequestID(ctx, req)
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", protocol)
+	if err := req.Write(conn); err != nil {
+		conn.Close()
+		return nil, nil, nil, err
+	}
+
+	reader := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(reader, req)
+	if err != nil {
+		conn.Close()
+		return nil, nil, nil, err
+	}
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		defer conn.Close()
+		body, _ := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+		return nil, nil, resp, nil
+	}
+	return conn, reader, resp, nil
+}
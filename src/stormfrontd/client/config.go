@@ -0,0 +1,402 @@
+package client
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jfcarter2358/ceresdb-go/connection"
+)
+
+const CONFIG_DIRECTORY = "/var/stormfront/configs"
+
+// StormfrontConfig is a named set of files which applications can mount
+// through config_mounts. Each key in Data becomes a file of the same name in
+// the mounted directory.
+type StormfrontConfig struct {
+	ID        string            `json:"id" yaml:"id"`
+	Name      string            `json:"name" yaml:"name"`
+	Namespace string            `json:"namespace" yaml:"namespace"`
+	Data      map[string]string `json:"data" yaml:"data"`
+}
+
+func getConfigs() ([]StormfrontConfig, error) {
+	configData, err := connection.Query("get record stormfront.config")
+	if err != nil {
+		return nil, err
+	}
+	configs := []StormfrontConfig{}
+	configBytes, err := json.Marshal(configData)
+	if err != nil {
+		return nil, err
+	}
+	err = json.Unmarshal(configBytes, &configs)
+	if err != nil {
+		return nil, err
+	}
+
+	return configs, nil
+}
+
+func findConfig(configs []StormfrontConfig, name, namespace string) (StormfrontConfig, bool) {
+	for _, cfg := range configs {
+		if cfg.Name == name && cfg.Namespace == namespace {
+			return cfg, true
+		}
+	}
+	return StormfrontConfig{}, false
+}
+
+// configVersion fingerprints the contents of every config an application
+// mounts. The leader stores it on the application so that a change to a config
+// shows up as a changed field and the owning nodes redeploy.
+func configVersion(app StormfrontApplication, configs []StormfrontConfig) (string, error) {
+	if len(app.ConfigMounts) == 0 {
+		return "", nil
+	}
+
+	names := []string{}
+	for name := range app.ConfigMounts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	hash := sha256.New()
+	for _, name := range names {
+		cfg, found := findConfig(configs, name, app.Namespace)
+		if !found {
+			return "", fmt.Errorf("config %s does not exist in namespace %s", name, app.Namespace)
+		}
+		keys := []string{}
+		for key := range cfg.Data {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		fmt.Fprintf(hash, "%s\x00", name)
+		for _, key := range keys {
+			fmt.Fprintf(hash, "%s\x00%s\x00", key, cfg.Data[key])
+		}
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// setConfigVersion validates the configs an application mounts and records
+// their current version on it
+func setConfigVersion(app *StormfrontApplication) error {
+	if len(app.ConfigMounts) == 0 {
+		app.ConfigVersion = ""
+		return nil
+	}
+	configs, err := getConfigs()
+	if err != nil {
+		return err
+	}
+	version, err := configVersion(*app, configs)
+	if err != nil {
+		return fmt.Errorf("config_mounts: %v", err)
+	}
+	app.ConfigVersion = version
+	return nil
+}
+
+// refreshConfigVersions updates the config version of every application in a
+// namespace which mounts the named config
+func refreshConfigVersions(name, namespace string) error {
+	configs, err := getConfigs()
+	if err != nil {
+		return err
+	}
+	data, err := connection.Query(fmt.Sprintf(`get record stormfront.application | filter namespace = '%s'`, namespace))
+	if err != nil {
+		return err
+	}
+	for _, appMap := range data {
+		var app StormfrontApplication
+		appBytes, _ := json.Marshal(appMap)
+		json.Unmarshal(appBytes, &app)
+
+		if _, ok := app.ConfigMounts[name]; !ok {
+			continue
+		}
+		version, err := configVersion(app, configs)
+		if err != nil {
+			return err
+		}
+		if version == app.ConfigVersion {
+			continue
+		}
+		_, err = connection.Query(fmt.Sprintf(`patch record stormfront.application '%s' {"config_version":"%s"}`, appMap[".id"].(string), version))
+		if err != nil {
+			return err
+		}
+		reconcilerLog.Info("Config changed, redeploying application", "config", name, "application", app.Name, "namespace", app.Namespace)
+	}
+	return nil
+}
+
+// resolveConfigs writes the configs mounted by an instance to files under
+// CONFIG_DIRECTORY and returns the host directories to mount mapped to their
+// container directories
+func resolveConfigs(app StormfrontApplication, name string) (map[string]string, error) {
+	mounts := map[string]string{}
+	if len(app.ConfigMounts) == 0 {
+		return mounts, nil
+	}
+
+	configs, err := getConfigs()
+	if err != nil {
+		return nil, err
+	}
+
+	removeConfigFiles(name)
+	for configName, dst := range app.ConfigMounts {
+		cfg, found := findConfig(configs, configName, app.Namespace)
+		if !found {
+			return nil, fmt.Errorf("config %s does not exist in namespace %s", configName, app.Namespace)
+		}
+		src := filepath.Join(CONFIG_DIRECTORY, name, configName)
+		if err := os.MkdirAll(src, os.ModePerm); err != nil {
+			return nil, err
+		}
+		for key, value := range cfg.Data {
+			if err := ioutil.WriteFile(filepath.Join(src, key), []byte(value), 0644); err != nil {
+				return nil, err
+			}
+		}
+		mounts[src] = dst
+	}
+
+	return mounts, nil
+}
+
+func removeConfigFiles(name string) {
+	if err := os.RemoveAll(filepath.Join(CONFIG_DIRECTORY, name)); err != nil {
+		reconcilerLog.Warn("Unable to remove config files", "container", name, "error", err)
+	}
+}
+
+func CreateConfig(c *gin.Context) {
+	if Client.Type != "Leader" {
+		c.Redirect(http.StatusTemporaryRedirect, fmt.Sprintf("https://%s:%v/api/config", Client.Leader.Host, Client.Leader.Port))
+		return
+	}
+
+	var cfg StormfrontConfig
+	if err := c.BindJSON(&cfg); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if !namespaceNameRegex.MatchString(cfg.Name) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("invalid config name '%s', names must be lowercase alphanumeric characters or '-'", cfg.Name)})
+		return
+	}
+	if err := validateDataKeys(cfg.Data); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("invalid config: %v", err)})
+		return
+	}
+	if cfg.Data == nil {
+		cfg.Data = map[string]string{}
+	}
+
+	if cfg.Namespace == "" {
+		cfg.Namespace = DEFAULT_NAMESPACE
+	}
+	if !authorizeNamespace(c, cfg.Namespace) {
+		return
+	}
+	if _, found, err := getNamespace(cfg.Namespace); err != nil || !found {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("namespace %s does not exist", cfg.Namespace)})
+		return
+	}
+
+	configs, err := getConfigs()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if _, found := findConfig(configs, cfg.Name, cfg.Namespace); found {
+		c.JSON(http.StatusConflict, gin.H{"error": fmt.Sprintf("config %s already exists in namespace %s", cfg.Name, cfg.Namespace)})
+		return
+	}
+
+	cfg.ID = uuid.NewString()
+	configData, _ := json.Marshal(cfg)
+	_, err = connection.Query(fmt.Sprintf("post record stormfront.config %s", configData))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("unable to create config: %v", err.Error())})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"id": cfg.ID})
+}
+
+func GetAllConfigs(c *gin.Context) {
+	if Client.Type != "Leader" {
+		c.Redirect(http.StatusTemporaryRedirect, fmt.Sprintf("https://%s:%v/api/config", Client.Leader.Host, Client.Leader.Port))
+		return
+	}
+
+	configs, err := getConfigs()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	scoped := []StormfrontConfig{}
+	for _, cfg := range configs {
+		if namespaceAllowed(c, cfg.Namespace) {
+			scoped = append(scoped, cfg)
+		}
+	}
+
+	c.JSON(http.StatusOK, scoped)
+}
+
+func GetConfig(c *gin.Context) {
+	id := c.Param("id")
+
+	if Client.Type != "Leader" {
+		c.Redirect(http.StatusTemporaryRedirect, fmt.Sprintf("https://%s:%v/api/config/%s", Client.Leader.Host, Client.Leader.Port, id))
+		return
+	}
+
+	configs, err := getConfigs()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	for _, cfg := range configs {
+		if cfg.ID == id {
+			c.JSON(http.StatusOK, cfg)
+			return
+		}
+	}
+
+	c.Status(http.StatusNotFound)
+}
+
+// UpdateConfig replaces the data of a config. Applications mounting it are
+// redeployed by their nodes once the new version is recorded on them.
+func UpdateConfig(c *gin.Context) {
+	id := c.Param("id")
+
+	if Client.Type != "Leader" {
+		c.Redirect(http.StatusTemporaryRedirect, fmt.Sprintf("https://%s:%v/api/config/%s", Client.Leader.Host, Client.Leader.Port, id))
+		return
+	}
+
+	var desired StormfrontConfig
+	if err := c.BindJSON(&desired); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	data, err := connection.Query(fmt.Sprintf(`get record stormfront.config | filter id = '%s'`, id))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if len(data) == 0 {
+		c.Status(http.StatusNotFound)
+		return
+	}
+
+	var cfg StormfrontConfig
+	configBytes, _ := json.Marshal(data[0])
+	json.Unmarshal(configBytes, &cfg)
+
+	if desired.Name != "" && desired.Name != cfg.Name {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Renaming not allowed in config update"})
+		return
+	}
+	if desired.Namespace != "" && desired.Namespace != cfg.Namespace {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Namespace change not allowed in config update"})
+		return
+	}
+	if desired.Data == nil || stringMapsEqual(desired.Data, cfg.Data) {
+		c.JSON(http.StatusOK, gin.H{"id": cfg.ID, "changed": []string{}})
+		return
+	}
+	if err := validateDataKeys(desired.Data); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("invalid config: %v", err)})
+		return
+	}
+
+	dataBytes, _ := json.Marshal(desired.Data)
+	_, err = connection.Query(fmt.Sprintf(`patch record stormfront.config '%s' {"data":%s}`, data[0][".id"].(string), dataBytes))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("unable to update config: %v", err.Error())})
+		return
+	}
+
+	err = refreshConfigVersions(cfg.Name, cfg.Namespace)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("unable to update applications mounting config: %v", err.Error())})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"id": cfg.ID, "changed": []string{"data"}})
+}
+
+func DeleteConfig(c *gin.Context) {
+	id := c.Param("id")
+
+	if Client.Type != "Leader" {
+		c.Redirect(http.StatusTemporaryRedirect, fmt.Sprintf("https://%s:%v/api/config/%s", Client.Leader.Host, Client.Leader.Port, id))
+		return
+	}
+
+	configs, err := getConfigs()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	var cfg StormfrontConfig
+	found := false
+	for _, existing := range configs {
+		if existing.ID == id {
+			cfg = existing
+			found = true
+		}
+	}
+	if !found {
+		c.Status(http.StatusNotFound)
+		return
+	}
+
+	// Deleting a config out from under an application would leave its nodes
+	// unable to redeploy it
+	applications, err := getApplications()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	users := []string{}
+	for _, app := range applications {
+		if _, ok := app.ConfigMounts[cfg.Name]; ok && app.Namespace == cfg.Namespace {
+			users = append(users, app.Name)
+		}
+	}
+	if len(users) > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": fmt.Sprintf("config %s is mounted by applications %s", cfg.Name, strings.Join(users, ", "))})
+		return
+	}
+
+	_, err = connection.Query(fmt.Sprintf(`get record stormfront.config .id | filter id = '%s' | delete record stormfront.config -`, id))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
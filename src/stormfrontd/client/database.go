@@ -10,46 +10,44 @@ import (
 const CERESDB_USERNAME = "ceresdb"
 
 var Collections = map[string]string{
-	"auth":        `{"id":"STRING","access_token":"STRING","refresh_token":"STRING","token_expiration":"STRING","token_issued":"STRING"}`,
-	"api":         `{"token":"STRING"}`,
-	"application": `{"id":"STRING","node":"STRING","name":"STRING","image":"STRING","hostname":"STRING","env":"DICT","ports":"DICT","mounts":"DICT","memory":"INT","cpu":"FLOAT","status":"DICT","namespace":"STRING"}`,
+	"auth":        `{"id":"STRING","access_token_hash":"STRING","refresh_token_hash":"STRING","token_expiration":"STRING","token_issued":"STRING"}`,
+	"api":         `{"name":"STRING","hash":"STRING","role":"STRING","namespaces":"LIST","created":"STRING","created_by":"STRING","expires":"STRING"}`,
+	"join":        `{"id":"STRING","hash":"STRING","created":"STRING","created_by":"STRING","expires":"STRING"}`,
+	"application": `{"id":"STRING","node":"STRING","name":"STRING","image":"STRING","hostname":"STRING","env":"DICT","ports":"DICT","mounts":"DICT","memory":"INT","cpu":"FLOAT","status":"DICT","namespace":"STRING","reschedules":"LIST","node_selector":"DICT","affinity":"LIST","anti_affinity":"LIST","replicas":"INT","instances":"LIST","instance_status":"DICT","restart_policy":"STRING","image_pull_policy":"STRING","secret_env":"DICT","secret_mounts":"DICT","config_mounts":"DICT","config_version":"STRING","liveness_probe":"DICT","readiness_probe":"DICT","volumes":"DICT"}`,
 	"leader":      `{"id":"STRING","succession":"LIST","unhealthy":"LIST","unknown":"LIST","healthy":"LIST"}`,
-	"node":        `{"id":"STRING","host":"STRING","port":"INT","system":"DICT","health":"STRING","type":"STRING"}`,
+	"node":        `{"id":"STRING","host":"STRING","port":"INT","system":"DICT","health":"STRING","type":"STRING","unknown_since":"STRING","labels":"DICT"}`,
 	"client":      `{"id":"STRING","type":"STRING","leader":"DICT","succession":"LIST","unhealthy":"LIST","unknown":"LIST","updated":"STRING","host":"STRING","port":"INT","healthy":"BOOL","applications":"LIST","system":"DICT"}`,
 	"route":       `{"id":"STRING","hostname":"STRING","port":"INT","namespace":"STRING","alias":"STRING","name":"STRING"}`,
+	"namespace":   `{"id":"STRING","name":"STRING"}`,
+	"registry":    `{"id":"STRING","name":"STRING","registry":"STRING","username":"STRING","password":"STRING"}`,
+	"secret":      `{"id":"STRING","name":"STRING","namespace":"STRING","data":"DICT"}`,
+	"config":      `{"id":"STRING","name":"STRING","namespace":"STRING","data":"DICT"}`,
+	"bolt":        `{"id":"STRING","command":"STRING","node":"STRING","all":"BOOL","selector":"DICT","timeout":"INT","cancelled":"BOOL","created":"STRING"}`,
+	"bolt_run":    `{"id":"STRING","bolt":"STRING","node":"STRING","status":"STRING","stdout":"STRING","stderr":"STRING","error":"STRING","exit_code":"INT","started":"STRING","finished":"STRING"}`,
+	"volume":      `{"id":"STRING","name":"STRING","namespace":"STRING","policy":"STRING","replicate":"BOOL","replication_interval":"INT","node":"STRING","replica":"STRING","replicated":"STRING"}`,
+	"event":       `{"id":"STRING","type":"STRING","object_kind":"STRING","object_id":"STRING","object_name":"STRING","namespace":"STRING","node":"STRING","message":"STRING","time":"STRING"}`,
 }
 
 func CreateDatabases() error {
-	fmt.Println("Initializing CeresDB connection")
+	databaseLog.Info("Initializing CeresDB connection")
 	if config.Config.CeresDBHost == "" {
 		config.Config.CeresDBHost = Client.Host
 	}
 	connection.Initialize(CERESDB_USERNAME, config.Config.CeresDBPassword, config.Config.CeresDBHost, config.Config.CeresDBPort)
-	fmt.Println("Done!")
 
-	// fmt.Printf("Username: %s\n", connection.Username)
-	// fmt.Printf("Password: %s\n", connection.Password)
-	// fmt.Printf("Host: %s\n", connection.Host)
-	// fmt.Printf("Port: %d\n", connection.Port)
-
-	fmt.Println("Creating stormfront database")
+	databaseLog.Info("Creating stormfront database")
 	data, err := connection.Query("post database stormfront")
 	if err != nil {
-		if data != nil {
-			fmt.Printf("Data: %v\n", data)
-		}
+		databaseLog.Error("Unable to create stormfront database", "data", data, "error", err)
 		return err
 	}
-	fmt.Println("Done!")
 	for name, schema := range Collections {
-		fmt.Printf("Creating %s collection\n", name)
-		fmt.Printf("post collection stormfront.%s %s\n", name, schema)
+		databaseLog.Debug("Creating collection", "collection", name, "schema", schema)
 		_, err := connection.Query(fmt.Sprintf("post collection stormfront.%s %s", name, schema))
 		if err != nil {
-			fmt.Printf("Data: %v\n", data)
+			databaseLog.Error("Unable to create collection", "collection", name, "error", err)
 			return err
 		}
-		fmt.Println("Done!")
 	}
 	return nil
 }
@@ -1,13 +1,15 @@
 package dns
 
 import (
-	"fmt"
 	"net"
+	"stormfrontd/logging"
 
 	"github.com/google/gopacket"
 	"github.com/google/gopacket/layers"
 )
 
+var dnsLog = logging.Subsystem("dns")
+
 type ZoneType uint16
 
 const (
@@ -53,8 +55,7 @@ func (srv *serveMux) serveDNS(u *udpConnection, request *layers.DNS) {
 		return
 	}
 	if h = srv.match(string(request.Questions[0].Name), request.Questions[0].Type); h == nil {
-		//todo: log handler not found
-		fmt.Println("no handler found for ", string(request.Questions[0].Name))
+		dnsLog.Debug("No handler found for query", "name", string(request.Questions[0].Name))
 	} else {
 		h.serveDNS(u, request)
 	}
@@ -66,7 +67,11 @@ func (server *DNSServer) StartAndServe() {
 		Port: server.port,
 		IP:   net.ParseIP(server.ip),
 	}
-	l, _ := net.ListenUDP("udp", &addr)
+	l, err := net.ListenUDP("udp", &addr)
+	if err != nil {
+		dnsLog.Error("Unable to listen for DNS queries", "address", addr.String(), "error", err)
+		return
+	}
 	udpConnection := &udpConnection{conn: l}
 	server.serve(udpConnection)
 }
@@ -99,7 +104,7 @@ func (udp *udpConnection) Write(b []byte) error {
 	return nil
 }
 
-type customHandler func(string) (string, error)
+type customHandler func(string) ([]string, error)
 
 func generateHandler(records map[string]string, lookupFunc customHandler) func(w *udpConnection, r *layers.DNS) {
 	return func(w *udpConnection, r *layers.DNS) {
@@ -111,7 +116,7 @@ func generateHandler(records map[string]string, lookupFunc customHandler) func(w
 }
 
 //AddZoneData - Depending on the zoneType and recordType  this function generates appropriate handler and registers in the serveMux
-func (server *DNSServer) AddZoneData(zone string, records map[string]string, lookupFunc func(string) (string, error), lookupZone ZoneType) {
+func (server *DNSServer) AddZoneData(zone string, records map[string]string, lookupFunc func(string) ([]string, error), lookupZone ZoneType) {
 	if lookupZone == DNSForwardLookupZone {
 		serveMuxCurrent := server.handler.(*serveMux)
 		serveMuxCurrent.handleFunc(zone, generateHandler(records, lookupFunc))
@@ -171,29 +176,34 @@ func nextLabel(s string, offset int) (i int, end bool) {
 
 func handleATypeQuery(w *udpConnection, r *layers.DNS, records map[string]string, lookupFunc customHandler) {
 	replyMess := r
-	var dnsAnswer layers.DNSResourceRecord
-	dnsAnswer.Type = layers.DNSTypeA
-	var ip string
+	var ips []string
 	var err error
-	var ok bool
 	if lookupFunc == nil {
-		ip, ok = records[string(r.Questions[0].Name)]
+		ip, ok := records[string(r.Questions[0].Name)]
 		if !ok {
 			//Todo: Log no data present for the IP and handle:todo
 		}
+		ips = []string{ip}
 	} else {
-		ip, err = lookupFunc(string(r.Questions[0].Name))
+		ips, err = lookupFunc(string(r.Questions[0].Name))
+		if err != nil {
+			dnsLog.Debug("Lookup failed", "name", string(r.Questions[0].Name), "error", err)
+		}
+	}
+	// One answer per address, the lookup rotates their order between queries
+	for _, ip := range ips {
+		var dnsAnswer layers.DNSResourceRecord
+		a, _, _ := net.ParseCIDR(ip + "/24")
+		dnsAnswer.Type = layers.DNSTypeA
+		dnsAnswer.IP = a
+		dnsAnswer.Name = []byte(r.Questions[0].Name)
+		dnsAnswer.Class = layers.DNSClassIN
+		replyMess.Answers = append(replyMess.Answers, dnsAnswer)
 	}
-	a, _, _ := net.ParseCIDR(ip + "/24")
-	dnsAnswer.Type = layers.DNSTypeA
-	dnsAnswer.IP = a
-	dnsAnswer.Name = []byte(r.Questions[0].Name)
-	dnsAnswer.Class = layers.DNSClassIN
 	replyMess.QR = true
-	replyMess.ANCount = 1
+	replyMess.ANCount = uint16(len(replyMess.Answers))
 	replyMess.OpCode = layers.DNSOpCodeNotify
 	replyMess.AA = true
-	replyMess.Answers = append(replyMess.Answers, dnsAnswer)
 	replyMess.ResponseCode = layers.DNSResponseCodeNoErr
 	buf := gopacket.NewSerializeBuffer()
 	opts := gopacket.SerializeOptions{} // See SerializeOptions for more details.
@@ -0,0 +1,345 @@
+package engine
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+)
+
+// cliRuntime drives a docker-compatible command line client. The docker and
+// podman runtimes only differ in the binary they call and a handful of flags.
+// LOG_ERROR_LIMIT bounds how much of the engine's stderr is kept for error
+// messages while following logs, which can run for as long as the client
+// stays connected
+const LOG_ERROR_LIMIT = 4096
+
+type cliRuntime struct {
+	binary  string
+	noTrunc bool
+}
+
+type inspectOutput struct {
+	Name  string `json:"Name"`
+	State struct {
+		Status   string `json:"Status"`
+		Running  bool   `json:"Running"`
+		ExitCode int    `json:"ExitCode"`
+	} `json:"State"`
+	Config struct {
+		Image string `json:"Image"`
+	} `json:"Config"`
+}
+
+func (r cliRuntime) execute(args ...string) (string, error) {
+	return r.executeContext(context.Background(), args...)
+}
+
+// executeContext runs the client like execute, killing it once ctx is done
+func (r cliRuntime) executeContext(ctx context.Context, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, r.binary, args...)
+	var outb, errb bytes.Buffer
+	cmd.Stdout = &outb
+	cmd.Stderr = &errb
+	err := cmd.Run()
+	if ctx.Err() == context.DeadlineExceeded {
+		return outb.String(), fmt.Errorf("%s %s timed out", r.binary, args[0])
+	}
+	if err != nil {
+		return outb.String(), fmt.Errorf("%s %s failed: %v: %s", r.binary, args[0], err, strings.TrimSpace(errb.String()))
+	}
+	return outb.String(), nil
+}
+
+func (r cliRuntime) Run(spec ContainerSpec) error {
+	args := []string{"run", "-d"}
+	args = append(args, "--name", spec.Name)
+	args = append(args, fmt.Sprintf("--cpus=%f", spec.CPU))
+	args = append(args, fmt.Sprintf("--memory=%db", spec.Memory))
+	for _, server := range spec.DNS {
+		args = append(args, fmt.Sprintf("--dns=%s", server))
+	}
+	for _, key := range sortedKeys(spec.Env) {
+		args = append(args, "-e", fmt.Sprintf("%s=%s", key, spec.Env[key]))
+	}
+	// The client reads the file before run returns, so it only needs to
+	// exist for as long as the command runs
+	if len(spec.SecretEnv) > 0 {
+		envFile, err := writeEnvFile(spec.SecretEnv)
+		if err != nil {
+			return err
+		}
+		defer os.Remove(envFile)
+		args = append(args, "--env-file", envFile)
+	}
+	for _, to := range sortedKeys(spec.Ports) {
+		args = append(args, "-p", fmt.Sprintf("%s:%s", to, spec.Ports[to]))
+	}
+	for _, src := range sortedKeys(spec.Mounts) {
+		args = append(args, "--mount", fmt.Sprintf("type=bind,src=%s,dst=%s", src, spec.Mounts[src]))
+	}
+	args = append(args, spec.Image)
+
+	_, err := r.execute(args...)
+	return err
+}
+
+// writeEnvFile writes variables to a temporary file only readable by the
+// daemon, in the format taken by --env-file
+func writeEnvFile(env map[string]string) (string, error) {
+	file, err := os.CreateTemp("", "stormfront-env-")
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	for _, key := range sortedKeys(env) {
+		if strings.ContainsAny(env[key], "\r\n") {
+			os.Remove(file.Name())
+			return "", fmt.Errorf("value of environment variable %s cannot contain line breaks", key)
+		}
+		if _, err := fmt.Fprintf(file, "%s=%s\n", key, env[key]); err != nil {
+			os.Remove(file.Name())
+			return "", err
+		}
+	}
+	return file.Name(), nil
+}
+
+func (r cliRuntime) Stop(name string) error {
+	_, err := r.execute("kill", name)
+	return err
+}
+
+func (r cliRuntime) Remove(name string) error {
+	_, err := r.execute("rm", name)
+	return err
+}
+
+func (r cliRuntime) Inspect(name string) (ContainerInfo, error) {
+	output, err := r.execute("inspect", "--type", "container", "--format", "{{json .}}", name)
+	if err != nil {
+		return ContainerInfo{}, err
+	}
+
+	var data inspectOutput
+	if err := json.Unmarshal([]byte(strings.TrimSpace(output)), &data); err != nil {
+		return ContainerInfo{}, fmt.Errorf("unable to parse %s inspect output: %v", r.binary, err)
+	}
+
+	info := ContainerInfo{
+		Name:     strings.TrimPrefix(data.Name, "/"),
+		Image:    data.Config.Image,
+		Status:   data.State.Status,
+		Running:  data.State.Running,
+		ExitCode: data.State.ExitCode,
+	}
+	return info, nil
+}
+
+func (r cliRuntime) Stats(name string) (ContainerStats, error) {
+	args := []string{"stats", name, "--no-stream"}
+	if r.noTrunc {
+		args = append(args, "--no-trunc")
+	}
+	args = append(args, "--format", "{{.CPUPerc}}||{{.MemPerc}}")
+
+	output, err := r.execute(args...)
+	if err != nil {
+		return ContainerStats{}, err
+	}
+
+	for _, line := range strings.Split(output, "\n") {
+		parts := strings.Split(strings.TrimSpace(line), "||")
+		if len(parts) == 2 {
+			return ContainerStats{CPU: parts[0], Memory: parts[1]}, nil
+		}
+	}
+	return ContainerStats{}, fmt.Errorf("no stats returned for container %s", name)
+}
+
+// Logs copies the container's output to out as it is produced. Only stdout
+// is relayed, the engine reports its own failures on stderr and those must
+// not end up in the log stream. When following, the engine process runs
+// until ctx is cancelled.
+func (r cliRuntime) Logs(ctx context.Context, name string, options LogOptions, out io.Writer) error {
+	args := []string{"logs"}
+	if options.Follow {
+		args = append(args, "--follow")
+	}
+	if options.Since != "" {
+		args = append(args, "--since", options.Since)
+	}
+	if options.Tail != "" {
+		args = append(args, "--tail", options.Tail)
+	}
+	if options.Timestamps {
+		args = append(args, "--timestamps")
+	}
+	args = append(args, name)
+
+	errb := &tailBuffer{limit: LOG_ERROR_LIMIT}
+	cmd := exec.CommandContext(ctx, r.binary, args...)
+	cmd.Stdout = out
+	cmd.Stderr = errb
+	err := cmd.Run()
+	if err != nil && ctx.Err() == nil {
+		return fmt.Errorf("%s logs failed: %v: %s", r.binary, err, strings.TrimSpace(errb.String()))
+	}
+	return nil
+}
+
+// Exec runs a command inside a running container, returning an error if the
+// command exits with a non-zero code or is still running once ctx is done
+func (r cliRuntime) Exec(ctx context.Context, name string, command []string) (string, error) {
+	args := append([]string{"exec", name}, command...)
+	return r.executeContext(ctx, args...)
+}
+
+// ExecStream runs a command inside a running container with its streams
+// attached to the caller, returning the command's exit code. Cancelling ctx
+// kills the command.
+func (r cliRuntime) ExecStream(ctx context.Context, name string, command []string, options ExecOptions) (int, error) {
+	args := []string{"exec"}
+	if options.Stdin != nil {
+		args = append(args, "--interactive")
+	}
+	if options.TTY {
+		args = append(args, "--tty")
+	}
+	args = append(args, name)
+	args = append(args, command...)
+
+	cmd := exec.CommandContext(ctx, r.binary, args...)
+	if !options.TTY {
+		cmd.Stdout = options.Stdout
+		cmd.Stderr = options.Stderr
+		if options.Stdin == nil {
+			return exitCode(cmd.Run())
+		}
+		// Handing the caller's stream to cmd would make Wait block until it
+		// closes, with a pipe Wait closes it as soon as the command exits
+		stdin, err := cmd.StdinPipe()
+		if err != nil {
+			return -1, err
+		}
+		if err := cmd.Start(); err != nil {
+			return -1, err
+		}
+		go func() {
+			io.Copy(stdin, options.Stdin)
+			stdin.Close()
+		}()
+		return exitCode(cmd.Wait())
+	}
+
+	// The engine refuses to allocate a terminal unless its own stdin is one
+	// so the command is run on a pseudo terminal which is relayed to the caller
+	master, slave, err := openPty(options.Rows, options.Cols)
+	if err != nil {
+		return -1, err
+	}
+	defer master.Close()
+	cmd.Stdin = slave
+	cmd.Stdout = slave
+	cmd.Stderr = slave
+	setControllingTerminal(cmd)
+	if err := cmd.Start(); err != nil {
+		slave.Close()
+		return -1, err
+	}
+	slave.Close()
+
+	if options.Stdin != nil {
+		go io.Copy(master, options.Stdin)
+	}
+	// Reading the master fails once the command exits and the slave closes
+	io.Copy(options.Stdout, master)
+	return exitCode(cmd.Wait())
+}
+
+func exitCode(err error) (int, error) {
+	if err == nil {
+		return 0, nil
+	}
+	if exitErr, ok := err.(*exec.ExitError); ok {
+		return exitErr.ExitCode(), nil
+	}
+	return -1, err
+}
+
+func (r cliRuntime) ImageExists(image string) (bool, error) {
+	_, err := r.execute("image", "inspect", image)
+	if err == nil {
+		return true, nil
+	}
+	// Both a missing image and a failing engine end up here, only a missing
+	// image leaves the engine able to report its version
+	if _, versionErr := r.execute("version"); versionErr != nil {
+		return false, err
+	}
+	return false, nil
+}
+
+func (r cliRuntime) List(all bool) ([]ContainerInfo, error) {
+	args := []string{"ps"}
+	if all {
+		args = append(args, "--all")
+	}
+	if r.noTrunc {
+		args = append(args, "--no-trunc")
+	}
+	args = append(args, "--format", "{{.Names}}||{{.Image}}||{{.State}}||{{.Status}}")
+
+	output, err := r.execute(args...)
+	if err != nil {
+		return []ContainerInfo{}, err
+	}
+
+	containers := []ContainerInfo{}
+	for _, line := range strings.Split(output, "\n") {
+		parts := strings.Split(strings.TrimSpace(line), "||")
+		if len(parts) != 4 {
+			continue
+		}
+		containers = append(containers, ContainerInfo{
+			Name:    parts[0],
+			Image:   parts[1],
+			Status:  parts[3],
+			Running: parts[2] == "running",
+		})
+	}
+	return containers, nil
+}
+
+// tailBuffer keeps the last limit bytes written to it
+type tailBuffer struct {
+	data  []byte
+	limit int
+}
+
+func (b *tailBuffer) Write(p []byte) (int, error) {
+	b.data = append(b.data, p...)
+	if len(b.data) > b.limit {
+		b.data = b.data[len(b.data)-b.limit:]
+	}
+	return len(p), nil
+}
+
+func (b *tailBuffer) String() string {
+	return string(b.data)
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,232 @@
+package engine
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// fakeBinary writes a script standing in for the engine's client. It records
+// its arguments, one per line, along with the contents of any --env-file, and
+// prints output.
+func fakeBinary(t *testing.T, output string) (cliRuntime, string) {
+	t.Helper()
+	dir := t.TempDir()
+	record := filepath.Join(dir, "args")
+	script := `#!/bin/sh
+for arg in "$@"; do
+	if [ "$previous" = "--env-file" ]; then
+		cp "$arg" "` + record + `.env"
+		ls -l "$arg" | cut -c1-10 > "` + record + `.mode"
+	fi
+	echo "$arg" >> "` + record + `"
+	previous="$arg"
+done
+cat <<'OUTPUT'
+` + output + `
+OUTPUT
+`
+	binary := filepath.Join(dir, "engine")
+	if err := os.WriteFile(binary, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	return cliRuntime{binary: binary, noTrunc: true}, record
+}
+
+func readLines(t *testing.T, path string) []string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+}
+
+func TestRunArguments(t *testing.T) {
+	tests := []struct {
+		name     string
+		spec     ContainerSpec
+		expected []string
+	}{
+		{
+			name:     "minimal",
+			spec:     ContainerSpec{Name: "web", Image: "nginx:1", CPU: 0.5, Memory: 1024},
+			expected: []string{"run", "-d", "--name", "web", "--cpus=0.500000", "--memory=1024b", "nginx:1"},
+		},
+		{
+			name: "dns, env, ports and mounts in order",
+			spec: ContainerSpec{
+				Name:   "web",
+				Image:  "nginx:1",
+				DNS:    []string{"10.0.0.1", "8.8.8.8"},
+				Env:    map[string]string{"B": "2", "A": "1 2"},
+				Ports:  map[string]string{"8081": "443", "8080": "80"},
+				Mounts: map[string]string{"/data/b": "/b", "/data/a": "/a"},
+			},
+			expected: []string{
+				"run", "-d", "--name", "web", "--cpus=0.000000", "--memory=0b",
+				"--dns=10.0.0.1", "--dns=8.8.8.8",
+				"-e", "A=1 2", "-e", "B=2",
+				"-p", "8080:80", "-p", "8081:443",
+				"--mount", "type=bind,src=/data/a,dst=/a", "--mount", "type=bind,src=/data/b,dst=/b",
+				"nginx:1",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r, record := fakeBinary(t, "")
+			if err := r.Run(test.spec); err != nil {
+				t.Fatal(err)
+			}
+			if args := readLines(t, record); !reflect.DeepEqual(args, test.expected) {
+				t.Fatalf("expected %q, got %q", test.expected, args)
+			}
+		})
+	}
+}
+
+func TestRunSecretEnvFile(t *testing.T) {
+	r, record := fakeBinary(t, "")
+	spec := ContainerSpec{
+		Name:      "web",
+		Image:     "nginx:1",
+		Env:       map[string]string{"PLAIN": "visible"},
+		SecretEnv: map[string]string{"PASSWORD": "hunter2", "API_KEY": "a=b c"},
+	}
+	if err := r.Run(spec); err != nil {
+		t.Fatal(err)
+	}
+
+	args := readLines(t, record)
+	for idx, arg := range args {
+		if strings.Contains(arg, "hunter2") {
+			t.Fatalf("secret value passed as argument %d: %q", idx, arg)
+		}
+		if arg == "--env-file" {
+			if _, err := os.Stat(args[idx+1]); !os.IsNotExist(err) {
+				t.Fatalf("expected env file to be removed after run, got %v", err)
+			}
+		}
+	}
+	if env := readLines(t, record+".env"); !reflect.DeepEqual(env, []string{"API_KEY=a=b c", "PASSWORD=hunter2"}) {
+		t.Fatalf("unexpected env file contents %q", env)
+	}
+	if mode := readLines(t, record+".mode"); mode[0] != "-rw-------" {
+		t.Fatalf("expected env file to only be readable by its owner, got %s", mode[0])
+	}
+}
+
+func TestRunRejectsMultilineSecret(t *testing.T) {
+	r, record := fakeBinary(t, "")
+	err := r.Run(ContainerSpec{Name: "web", Image: "nginx:1", SecretEnv: map[string]string{"KEY": "a\nb"}})
+	if err == nil {
+		t.Fatal("expected a multiline secret to be rejected")
+	}
+	if _, err := os.Stat(record); !os.IsNotExist(err) {
+		t.Fatal("expected the engine not to be called")
+	}
+}
+
+func TestListParsing(t *testing.T) {
+	tests := []struct {
+		name     string
+		all      bool
+		noTrunc  bool
+		output   string
+		args     []string
+		expected []ContainerInfo
+	}{
+		{
+			name:     "empty",
+			output:   "",
+			args:     []string{"ps", "--format", "{{.Names}}||{{.Image}}||{{.State}}||{{.Status}}"},
+			expected: []ContainerInfo{},
+		},
+		{
+			name:    "running and exited",
+			all:     true,
+			noTrunc: true,
+			output:  "web||nginx:1||running||Up 2 hours\ndb||postgres:15||exited||Exited (1) 5 minutes ago\n",
+			args:    []string{"ps", "--all", "--no-trunc", "--format", "{{.Names}}||{{.Image}}||{{.State}}||{{.Status}}"},
+			expected: []ContainerInfo{
+				{Name: "web", Image: "nginx:1", Status: "Up 2 hours", Running: true},
+				{Name: "db", Image: "postgres:15", Status: "Exited (1) 5 minutes ago", Running: false},
+			},
+		},
+		{
+			name:     "malformed lines are skipped",
+			output:   "WARNING: something\nweb||nginx:1||running||Up 1 second\n",
+			args:     []string{"ps", "--format", "{{.Names}}||{{.Image}}||{{.State}}||{{.Status}}"},
+			expected: []ContainerInfo{{Name: "web", Image: "nginx:1", Status: "Up 1 second", Running: true}},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r, record := fakeBinary(t, test.output)
+			r.noTrunc = test.noTrunc
+			containers, err := r.List(test.all)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(containers, test.expected) {
+				t.Fatalf("expected %+v, got %+v", test.expected, containers)
+			}
+			if args := readLines(t, record); !reflect.DeepEqual(args, test.args) {
+				t.Fatalf("expected arguments %q, got %q", test.args, args)
+			}
+		})
+	}
+}
+
+func TestStatsParsing(t *testing.T) {
+	tests := []struct {
+		name     string
+		noTrunc  bool
+		output   string
+		args     []string
+		expected ContainerStats
+		err      bool
+	}{
+		{
+			name:     "docker",
+			noTrunc:  true,
+			output:   "12.34%||5.67%",
+			args:     []string{"stats", "web", "--no-stream", "--no-trunc", "--format", "{{.CPUPerc}}||{{.MemPerc}}"},
+			expected: ContainerStats{CPU: "12.34%", Memory: "5.67%"},
+		},
+		{
+			name:     "podman with leading noise",
+			output:   "\n  0.00%||--  \n",
+			args:     []string{"stats", "web", "--no-stream", "--format", "{{.CPUPerc}}||{{.MemPerc}}"},
+			expected: ContainerStats{CPU: "0.00%", Memory: "--"},
+		},
+		{
+			name:   "no stats",
+			output: "",
+			args:   []string{"stats", "web", "--no-stream", "--format", "{{.CPUPerc}}||{{.MemPerc}}"},
+			err:    true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r, record := fakeBinary(t, test.output)
+			r.noTrunc = test.noTrunc
+			stats, err := r.Stats("web")
+			if (err != nil) != test.err {
+				t.Fatalf("expected error %v, got %v", test.err, err)
+			}
+			if stats != test.expected {
+				t.Fatalf("expected %+v, got %+v", test.expected, stats)
+			}
+			if args := readLines(t, record); !reflect.DeepEqual(args, test.args) {
+				t.Fatalf("expected arguments %q, got %q", test.args, args)
+			}
+		})
+	}
+}
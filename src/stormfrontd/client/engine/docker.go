@@ -0,0 +1,46 @@
+package engine
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+type DockerRuntime struct {
+	cliRuntime
+}
+
+func NewDockerRuntime() *DockerRuntime {
+	return &DockerRuntime{cliRuntime{binary: DOCKER_ENGINE, noTrunc: true}}
+}
+
+// Pull authenticates through a throwaway client config directory rather than
+// docker login so that credentials are never written to the node's own config
+func (r *DockerRuntime) Pull(image string, credential *RegistryCredential) error {
+	if credential == nil {
+		_, err := r.execute("pull", image)
+		return err
+	}
+
+	configDir, err := os.MkdirTemp("", "stormfront-docker-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(configDir)
+
+	auth := base64.StdEncoding.EncodeToString([]byte(fmt.Sprintf("%s:%s", credential.Username, credential.Password)))
+	configData, _ := json.Marshal(map[string]interface{}{
+		"auths": map[string]interface{}{
+			credential.Registry: map[string]string{"auth": auth},
+		},
+	})
+	err = os.WriteFile(filepath.Join(configDir, "config.json"), configData, 0600)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.execute("--config", configDir, "pull", image)
+	return err
+}
@@ -0,0 +1,98 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"io"
+)
+
+const DOCKER_ENGINE = "docker"
+const PODMAN_ENGINE = "podman"
+const FAKE_ENGINE = "fake"
+
+// ContainerRuntime is the set of operations Stormfront needs from a container
+// engine in order to run and observe applications on a node
+type ContainerRuntime interface {
+	Run(spec ContainerSpec) error
+	Stop(name string) error
+	Remove(name string) error
+	Inspect(name string) (ContainerInfo, error)
+	Stats(name string) (ContainerStats, error)
+	Logs(ctx context.Context, name string, options LogOptions, out io.Writer) error
+	List(all bool) ([]ContainerInfo, error)
+	Exec(ctx context.Context, name string, command []string) (string, error)
+	ExecStream(ctx context.Context, name string, command []string, options ExecOptions) (int, error)
+	ImageExists(image string) (bool, error)
+	Pull(image string, credential *RegistryCredential) error
+}
+
+// RegistryCredential authenticates image pulls against a private registry
+type RegistryCredential struct {
+	Registry string `json:"registry" yaml:"registry"`
+	Username string `json:"username" yaml:"username"`
+	Password string `json:"password" yaml:"password"`
+}
+
+// LogOptions select which part of a container's logs are written and whether
+// to keep following them until the context is cancelled. Since accepts
+// anything the engine does, such as a duration like 10m or a timestamp, and
+// Tail is a number of lines or empty for all of them.
+type LogOptions struct {
+	Follow     bool   `json:"follow" yaml:"follow"`
+	Since      string `json:"since" yaml:"since"`
+	Tail       string `json:"tail" yaml:"tail"`
+	Timestamps bool   `json:"timestamps" yaml:"timestamps"`
+}
+
+// ExecOptions wire a command run inside a container up to the caller. Stdin
+// is left unattached when nil. With TTY set the command runs on a terminal of
+// Rows by Cols and its stderr is merged into Stdout.
+type ExecOptions struct {
+	Stdin  io.Reader
+	Stdout io.Writer
+	Stderr io.Writer
+	TTY    bool
+	Rows   uint16
+	Cols   uint16
+}
+
+// ContainerSpec describes a container to run. SecretEnv holds decrypted
+// secret values, engines must not pass them on the command line where any
+// user on the node can read them.
+type ContainerSpec struct {
+	Name      string            `json:"name" yaml:"name"`
+	Image     string            `json:"image" yaml:"image"`
+	CPU       float64           `json:"cpu" yaml:"cpu"`
+	Memory    int               `json:"memory" yaml:"memory"`
+	DNS       []string          `json:"dns" yaml:"dns"`
+	Env       map[string]string `json:"env" yaml:"env"`
+	SecretEnv map[string]string `json:"-" yaml:"-"`
+	Ports     map[string]string `json:"ports" yaml:"ports"`   // host port -> container port
+	Mounts    map[string]string `json:"mounts" yaml:"mounts"` // host path -> container path
+}
+
+type ContainerInfo struct {
+	Name     string `json:"name" yaml:"name"`
+	Image    string `json:"image" yaml:"image"`
+	Status   string `json:"status" yaml:"status"`
+	Running  bool   `json:"running" yaml:"running"`
+	ExitCode int    `json:"exit_code" yaml:"exit_code"`
+}
+
+type ContainerStats struct {
+	CPU    string `json:"cpu" yaml:"cpu"`
+	Memory string `json:"memory" yaml:"memory"`
+}
+
+// New returns the container runtime matching the configured engine name
+func New(engineName string) (ContainerRuntime, error) {
+	switch engineName {
+	case DOCKER_ENGINE:
+		return NewDockerRuntime(), nil
+	case PODMAN_ENGINE:
+		return NewPodmanRuntime(), nil
+	case FAKE_ENGINE:
+		return NewFakeRuntime(), nil
+	}
+	return nil, fmt.Errorf("unsupported container engine '%s', allowed engines are '%s', '%s' and '%s'", engineName, DOCKER_ENGINE, PODMAN_ENGINE, FAKE_ENGINE)
+}
@@ -0,0 +1,33 @@
+package engine
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name string
+		err  bool
+	}{
+		{name: DOCKER_ENGINE},
+		{name: PODMAN_ENGINE},
+		{name: FAKE_ENGINE},
+		{name: "containerd", err: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			runtime, err := New(test.name)
+			if (err != nil) != test.err {
+				t.Fatalf("expected error %v, got %v", test.err, err)
+			}
+			if test.err && !strings.Contains(err.Error(), FAKE_ENGINE) {
+				t.Fatalf("expected error to list every engine, got %v", err)
+			}
+			if !test.err && runtime == nil {
+				t.Fatal("expected a runtime")
+			}
+		})
+	}
+}
@@ -0,0 +1,232 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// FakeRuntime keeps containers in memory so that reconciliation logic can be
+// exercised without a container engine installed
+type FakeRuntime struct {
+	mutex      sync.Mutex
+	Containers map[string]*FakeContainer
+	Images     map[string]bool
+	// PullErrors makes pulls of the given images fail with the given error
+	PullErrors map[string]error
+	// ExecErrors makes commands run in the given containers fail with the
+	// given error
+	ExecErrors map[string]error
+}
+
+type FakeContainer struct {
+	Spec     ContainerSpec
+	Running  bool
+	ExitCode int
+	Logs     string
+	Stats    ContainerStats
+}
+
+func NewFakeRuntime() *FakeRuntime {
+	return &FakeRuntime{Containers: map[string]*FakeContainer{}, Images: map[string]bool{}, PullErrors: map[string]error{}, ExecErrors: map[string]error{}}
+}
+
+func (r *FakeRuntime) Run(spec ContainerSpec) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if _, ok := r.Containers[spec.Name]; ok {
+		return fmt.Errorf("container with name %s already exists", spec.Name)
+	}
+	r.Containers[spec.Name] = &FakeContainer{
+		Spec:    spec,
+		Running: true,
+		Stats:   ContainerStats{CPU: "0.00%", Memory: "0.00%"},
+	}
+	return nil
+}
+
+func (r *FakeRuntime) Stop(name string) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	container, ok := r.Containers[name]
+	if !ok || !container.Running {
+		return fmt.Errorf("container %s is not running", name)
+	}
+	container.Running = false
+	container.ExitCode = 137
+	return nil
+}
+
+func (r *FakeRuntime) Remove(name string) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	container, ok := r.Containers[name]
+	if !ok {
+		return fmt.Errorf("no such container: %s", name)
+	}
+	if container.Running {
+		return fmt.Errorf("container %s is running, stop it before removal", name)
+	}
+	delete(r.Containers, name)
+	return nil
+}
+
+func (r *FakeRuntime) Inspect(name string) (ContainerInfo, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	container, ok := r.Containers[name]
+	if !ok {
+		return ContainerInfo{}, fmt.Errorf("no such container: %s", name)
+	}
+	return container.info(name), nil
+}
+
+func (r *FakeRuntime) Stats(name string) (ContainerStats, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	container, ok := r.Containers[name]
+	if !ok || !container.Running {
+		return ContainerStats{}, fmt.Errorf("container %s is not running", name)
+	}
+	return container.Stats, nil
+}
+
+// Logs writes the container's recorded logs, following returns straight away
+// since fake containers never produce more output
+func (r *FakeRuntime) Logs(ctx context.Context, name string, options LogOptions, out io.Writer) error {
+	r.mutex.Lock()
+	container, ok := r.Containers[name]
+	if !ok {
+		r.mutex.Unlock()
+		return fmt.Errorf("no such container: %s", name)
+	}
+	logs := container.Logs
+	r.mutex.Unlock()
+
+	if lines, err := strconv.Atoi(options.Tail); err == nil {
+		split := strings.SplitAfter(logs, "\n")
+		if split[len(split)-1] == "" {
+			split = split[:len(split)-1]
+		}
+		if lines < len(split) {
+			logs = strings.Join(split[len(split)-lines:], "")
+		}
+	}
+	_, err := io.WriteString(out, logs)
+	return err
+}
+
+// ExecStream echoes stdin back on stdout in place of running a command
+func (r *FakeRuntime) ExecStream(ctx context.Context, name string, command []string, options ExecOptions) (int, error) {
+	r.mutex.Lock()
+	container, ok := r.Containers[name]
+	if !ok || !container.Running {
+		r.mutex.Unlock()
+		return -1, fmt.Errorf("container %s is not running", name)
+	}
+	err := r.ExecErrors[name]
+	r.mutex.Unlock()
+
+	if err != nil {
+		stderr := options.Stderr
+		if options.TTY || stderr == nil {
+			stderr = options.Stdout
+		}
+		io.WriteString(stderr, err.Error())
+		return 1, nil
+	}
+	if options.Stdin != nil {
+		io.Copy(options.Stdout, options.Stdin)
+	}
+	return 0, nil
+}
+
+func (r *FakeRuntime) List(all bool) ([]ContainerInfo, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	names := make([]string, 0, len(r.Containers))
+	for name := range r.Containers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	containers := []ContainerInfo{}
+	for _, name := range names {
+		container := r.Containers[name]
+		if !all && !container.Running {
+			continue
+		}
+		containers = append(containers, container.info(name))
+	}
+	return containers, nil
+}
+
+func (r *FakeRuntime) Exec(ctx context.Context, name string, command []string) (string, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	container, ok := r.Containers[name]
+	if !ok || !container.Running {
+		return "", fmt.Errorf("container %s is not running", name)
+	}
+	if err, ok := r.ExecErrors[name]; ok {
+		return "", err
+	}
+	return "", ctx.Err()
+}
+
+func (r *FakeRuntime) ImageExists(image string) (bool, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	return r.Images[image], nil
+}
+
+func (r *FakeRuntime) Pull(image string, credential *RegistryCredential) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if err, ok := r.PullErrors[image]; ok {
+		return err
+	}
+	r.Images[image] = true
+	return nil
+}
+
+// Exit simulates the process inside a container terminating on its own
+func (r *FakeRuntime) Exit(name string, exitCode int) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	container, ok := r.Containers[name]
+	if !ok {
+		return fmt.Errorf("no such container: %s", name)
+	}
+	container.Running = false
+	container.ExitCode = exitCode
+	return nil
+}
+
+func (c *FakeContainer) info(name string) ContainerInfo {
+	status := "exited"
+	if c.Running {
+		status = "running"
+	}
+	return ContainerInfo{
+		Name:     name,
+		Image:    c.Spec.Image,
+		Status:   status,
+		Running:  c.Running,
+		ExitCode: c.ExitCode,
+	}
+}
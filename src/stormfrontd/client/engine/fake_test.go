@@ -0,0 +1,166 @@
+package engine
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestFakeRuntimeLifecycle(t *testing.T) {
+	r := NewFakeRuntime()
+
+	steps := []struct {
+		name    string
+		action  func() error
+		err     bool
+		running bool
+		exists  bool
+		code    int
+	}{
+		{name: "run", action: func() error { return r.Run(ContainerSpec{Name: "web", Image: "web:1"}) }, running: true, exists: true},
+		{name: "run existing name", action: func() error { return r.Run(ContainerSpec{Name: "web"}) }, err: true, running: true, exists: true},
+		{name: "remove running", action: func() error { return r.Remove("web") }, err: true, running: true, exists: true},
+		{name: "exit", action: func() error { return r.Exit("web", 3) }, exists: true, code: 3},
+		{name: "stop exited", action: func() error { return r.Stop("web") }, err: true, exists: true, code: 3},
+		{name: "remove exited", action: func() error { return r.Remove("web") }},
+		{name: "remove missing", action: func() error { return r.Remove("web") }, err: true},
+		{name: "run again", action: func() error { return r.Run(ContainerSpec{Name: "web", Image: "web:1"}) }, running: true, exists: true},
+		{name: "stop", action: func() error { return r.Stop("web") }, exists: true, code: 137},
+	}
+
+	for _, step := range steps {
+		err := step.action()
+		if (err != nil) != step.err {
+			t.Fatalf("%s: expected error %v, got %v", step.name, step.err, err)
+		}
+		info, err := r.Inspect("web")
+		if (err == nil) != step.exists {
+			t.Fatalf("%s: expected container to exist %v, got %v", step.name, step.exists, err)
+		}
+		if step.exists && (info.Running != step.running || info.ExitCode != step.code || info.Image != "web:1") {
+			t.Fatalf("%s: expected running %v with exit code %d, got %+v", step.name, step.running, step.code, info)
+		}
+	}
+}
+
+func TestFakeRuntimeList(t *testing.T) {
+	r := NewFakeRuntime()
+	r.Run(ContainerSpec{Name: "b"})
+	r.Run(ContainerSpec{Name: "a"})
+	r.Run(ContainerSpec{Name: "c"})
+	r.Exit("c", 0)
+
+	tests := []struct {
+		all      bool
+		expected []string
+	}{
+		{all: false, expected: []string{"a", "b"}},
+		{all: true, expected: []string{"a", "b", "c"}},
+	}
+
+	for _, test := range tests {
+		containers, err := r.List(test.all)
+		if err != nil {
+			t.Fatal(err)
+		}
+		names := []string{}
+		for _, container := range containers {
+			names = append(names, container.Name)
+		}
+		if strings.Join(names, ",") != strings.Join(test.expected, ",") {
+			t.Fatalf("all=%v: expected %v, got %v", test.all, test.expected, names)
+		}
+	}
+}
+
+func TestFakeRuntimePull(t *testing.T) {
+	r := NewFakeRuntime()
+	r.PullErrors["private:1"] = errors.New("unauthorized")
+
+	tests := []struct {
+		image  string
+		err    bool
+		exists bool
+	}{
+		{image: "public:1", exists: true},
+		{image: "private:1", err: true, exists: false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.image, func(t *testing.T) {
+			if err := r.Pull(test.image, nil); (err != nil) != test.err {
+				t.Fatalf("expected error %v, got %v", test.err, err)
+			}
+			if exists, _ := r.ImageExists(test.image); exists != test.exists {
+				t.Fatalf("expected image to exist %v, got %v", test.exists, exists)
+			}
+		})
+	}
+}
+
+func TestFakeRuntimeLogs(t *testing.T) {
+	r := NewFakeRuntime()
+	r.Run(ContainerSpec{Name: "web"})
+	r.Containers["web"].Logs = "one\ntwo\nthree\n"
+
+	tests := []struct {
+		tail     string
+		expected string
+	}{
+		{tail: "", expected: "one\ntwo\nthree\n"},
+		{tail: "2", expected: "two\nthree\n"},
+		{tail: "10", expected: "one\ntwo\nthree\n"},
+	}
+
+	for _, test := range tests {
+		var out bytes.Buffer
+		if err := r.Logs(context.Background(), "web", LogOptions{Tail: test.tail}, &out); err != nil {
+			t.Fatal(err)
+		}
+		if out.String() != test.expected {
+			t.Fatalf("tail %q: expected %q, got %q", test.tail, test.expected, out.String())
+		}
+	}
+}
+
+func TestFakeRuntimeExec(t *testing.T) {
+	r := NewFakeRuntime()
+	r.Run(ContainerSpec{Name: "web"})
+	r.Run(ContainerSpec{Name: "broken"})
+	r.Run(ContainerSpec{Name: "stopped"})
+	r.Exit("stopped", 0)
+	r.ExecErrors["broken"] = errors.New("exit status 1")
+
+	tests := []struct {
+		name string
+		err  bool
+		code int
+	}{
+		{name: "web", code: 0},
+		{name: "broken", err: true, code: 1},
+		{name: "stopped", err: true, code: -1},
+		{name: "missing", err: true, code: -1},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := r.Exec(context.Background(), test.name, []string{"true"}); (err != nil) != test.err {
+				t.Fatalf("expected error %v, got %v", test.err, err)
+			}
+
+			var stdout, stderr bytes.Buffer
+			code, _ := r.ExecStream(context.Background(), test.name, []string{"cat"}, ExecOptions{Stdin: strings.NewReader("hello"), Stdout: &stdout, Stderr: &stderr})
+			if code != test.code {
+				t.Fatalf("expected exit code %d, got %d", test.code, code)
+			}
+			if code == 0 && stdout.String() != "hello" {
+				t.Fatalf("expected stdin to be echoed, got %q", stdout.String())
+			}
+			if code == 1 && stderr.String() != "exit status 1" {
+				t.Fatalf("expected the error on stderr, got %q", stderr.String())
+			}
+		})
+	}
+}
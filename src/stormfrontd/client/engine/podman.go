@@ -0,0 +1,48 @@
+package engine
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+type PodmanRuntime struct {
+	cliRuntime
+}
+
+// podman does not accept --no-trunc on stats and ps
+func NewPodmanRuntime() *PodmanRuntime {
+	return &PodmanRuntime{cliRuntime{binary: PODMAN_ENGINE, noTrunc: false}}
+}
+
+// Pull authenticates through a throwaway auth file rather than --creds so
+// that the password never shows up in the node's process list
+func (r *PodmanRuntime) Pull(image string, credential *RegistryCredential) error {
+	if credential == nil {
+		_, err := r.execute("pull", image)
+		return err
+	}
+
+	authDir, err := os.MkdirTemp("", "stormfront-podman-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(authDir)
+
+	auth := base64.StdEncoding.EncodeToString([]byte(fmt.Sprintf("%s:%s", credential.Username, credential.Password)))
+	authData, _ := json.Marshal(map[string]interface{}{
+		"auths": map[string]interface{}{
+			credential.Registry: map[string]string{"auth": auth},
+		},
+	})
+	authFile := filepath.Join(authDir, "auth.json")
+	err = os.WriteFile(authFile, authData, 0600)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.execute("pull", "--authfile", authFile, image)
+	return err
+}
@@ -0,0 +1,15 @@
+//go:build !linux
+
+package engine
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+)
+
+func openPty(rows, cols uint16) (*os.File, *os.File, error) {
+	return nil, nil, errors.New("terminals are only supported on linux nodes")
+}
+
+func setControllingTerminal(cmd *exec.Cmd) {}
@@ -0,0 +1,410 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"stormfrontd/client/communication"
+	"stormfrontd/client/timeseries"
+	"stormfrontd/config"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jfcarter2358/ceresdb-go/connection"
+)
+
+const EVENT_APPLICATION_SCHEDULED = "ApplicationScheduled"
+const EVENT_APPLICATION_RESCHEDULED = "ApplicationRescheduled"
+const EVENT_SCHEDULING_FAILED = "SchedulingFailed"
+const EVENT_CONTAINER_STARTED = "ContainerStarted"
+const EVENT_CONTAINER_RESTARTED = "ContainerRestarted"
+const EVENT_CONTAINER_KILLED = "ContainerKilled"
+const EVENT_CONTAINER_REMOVED = "ContainerRemoved"
+const EVENT_DEPLOY_FAILED = "DeployFailed"
+const EVENT_NODE_HEALTHY = "NodeHealthy"
+const EVENT_NODE_UNKNOWN = "NodeUnknown"
+const EVENT_LEADER_ELECTED = "LeaderElected"
+const EVENT_TOKEN_REVOKED = "TokenRevoked"
+
+const EVENT_OBJECT_APPLICATION = "application"
+const EVENT_OBJECT_CONTAINER = "container"
+const EVENT_OBJECT_NODE = "node"
+const EVENT_OBJECT_TOKEN = "token"
+
+// EVENT_WATCH_BUFFER is how many events a watcher can fall behind by before
+// its stream is ended
+const EVENT_WATCH_BUFFER = 256
+
+// Failures which are retried on every pass of the health loop are only
+// recorded again once EVENT_REPEAT_INTERVAL seconds have passed
+const EVENT_REPEAT_INTERVAL = 300
+
+// StormfrontEvent records something that happened to an object in the
+// cluster. Events are written by the leader, followers send theirs to it.
+type StormfrontEvent struct {
+	ID         string `json:"id" yaml:"id"`
+	Type       string `json:"type" yaml:"type"`
+	ObjectKind string `json:"object_kind" yaml:"object_kind"`
+	ObjectID   string `json:"object_id" yaml:"object_id"`
+	ObjectName string `json:"object_name" yaml:"object_name"`
+	Namespace  string `json:"namespace" yaml:"namespace"`
+	Node       string `json:"node" yaml:"node"`
+	Message    string `json:"message" yaml:"message"`
+	Time       string `json:"time" yaml:"time"`
+}
+
+// matchesObject reports whether the event is about the object named by
+// reference, which may be kind/id, kind/name or just the id or name
+func (e StormfrontEvent) matchesObject(reference string) bool {
+	kind, value := "", reference
+	if parts := strings.SplitN(reference, "/", 2); len(parts) == 2 {
+		kind, value = parts[0], parts[1]
+	}
+	if kind != "" && kind != e.ObjectKind {
+		return false
+	}
+	return value == e.ObjectID || value == e.ObjectName
+}
+
+func (e StormfrontEvent) recorded() time.Time {
+	t, _ := time.Parse(time.RFC3339Nano, e.Time)
+	return t
+}
+
+func applicationEvent(eventType string, app StormfrontApplication, format string, args ...interface{}) StormfrontEvent {
+	return StormfrontEvent{
+		Type:       eventType,
+		ObjectKind: EVENT_OBJECT_APPLICATION,
+		ObjectID:   app.ID,
+		ObjectName: app.Name,
+		Namespace:  app.Namespace,
+		Message:    fmt.Sprintf(format, args...),
+	}
+}
+
+func containerEvent(eventType string, app StormfrontApplication, name, format string, args ...interface{}) StormfrontEvent {
+	return StormfrontEvent{
+		Type:       eventType,
+		ObjectKind: EVENT_OBJECT_CONTAINER,
+		ObjectID:   name,
+		ObjectName: name,
+		Namespace:  app.Namespace,
+		Message:    fmt.Sprintf(format, args...),
+	}
+}
+
+func nodeEvent(eventType, id, format string, args ...interface{}) StormfrontEvent {
+	return StormfrontEvent{
+		Type:       eventType,
+		ObjectKind: EVENT_OBJECT_NODE,
+		ObjectID:   id,
+		ObjectName: id,
+		Message:    fmt.Sprintf(format, args...),
+	}
+}
+
+func tokenEvent(eventType, id, format string, args ...interface{}) StormfrontEvent {
+	return StormfrontEvent{
+		Type:       eventType,
+		ObjectKind: EVENT_OBJECT_TOKEN,
+		ObjectID:   id,
+		ObjectName: id,
+		Message:    fmt.Sprintf(format, args...),
+	}
+}
+
+// recordEvent stamps an event with this node and the current time and stores
+// it, followers hand it to the leader in the background so that a slow leader
+// does not hold up their health loop
+func recordEvent(event StormfrontEvent) {
+	event.ID = uuid.New().String()
+	event.Node = Client.ID
+	event.Time = time.Now().Format(time.RFC3339Nano)
+
+	if Client.Type == "Leader" {
+		if err := storeEvent(event); err != nil {
+			eventLog.Error("Unable to record event", "type", event.Type, "object_kind", event.ObjectKind, "object", event.ObjectName, "error", err)
+		}
+		return
+	}
+	go reportEvent(event)
+}
+
+var recentEvents = map[string]time.Time{}
+var recentEventsLock sync.Mutex
+
+// recordRepeatingEvent records an event unless this node recorded the same
+// one within the last EVENT_REPEAT_INTERVAL seconds
+func recordRepeatingEvent(event StormfrontEvent) {
+	recentEventsLock.Lock()
+	for key, recorded := range recentEvents {
+		if time.Since(recorded) > EVENT_REPEAT_INTERVAL*time.Second {
+			delete(recentEvents, key)
+		}
+	}
+	key := strings.Join([]string{event.Type, event.ObjectKind, event.ObjectID, event.Message}, "\xff")
+	_, repeated := recentEvents[key]
+	if !repeated {
+		recentEvents[key] = time.Now()
+	}
+	recentEventsLock.Unlock()
+
+	if !repeated {
+		recordEvent(event)
+	}
+}
+
+func storeEvent(event StormfrontEvent) error {
+	eventBytes, _ := json.Marshal(event)
+	_, err := connection.Query(fmt.Sprintf("post record stormfront.event %s", eventBytes))
+	if err != nil {
+		return err
+	}
+	publishEvent(event)
+	return nil
+}
+
+// eventWatchers are handed every event the leader stores so that watches do
+// not have to read the whole collection again to find new ones
+var eventWatchers = map[chan StormfrontEvent]bool{}
+var eventWatchersLock sync.Mutex
+
+func subscribeEvents() chan StormfrontEvent {
+	events := make(chan StormfrontEvent, EVENT_WATCH_BUFFER)
+	eventWatchersLock.Lock()
+	eventWatchers[events] = true
+	eventWatchersLock.Unlock()
+	return events
+}
+
+func unsubscribeEvents(events chan StormfrontEvent) {
+	eventWatchersLock.Lock()
+	defer eventWatchersLock.Unlock()
+	if eventWatchers[events] {
+		delete(eventWatchers, events)
+		close(events)
+	}
+}
+
+// publishEvent never blocks the caller, a watcher whose buffer is full is
+// closed instead so that its client reconnects and starts from the full list
+func publishEvent(event StormfrontEvent) {
+	eventWatchersLock.Lock()
+	defer eventWatchersLock.Unlock()
+	for events := range eventWatchers {
+		select {
+		case events <- event:
+		default:
+			delete(eventWatchers, events)
+			close(events)
+		}
+	}
+}
+
+func reportEvent(event StormfrontEvent) {
+	eventBytes, _ := json.Marshal(event)
+	status, body, err := communication.Post(context.Background(), Client.Leader.Host, Client.Leader.Port, "api/event", AuthClient, eventBytes)
+	if err != nil || status != http.StatusOK {
+		eventLog.Error("Unable to report event to leader", "type", event.Type, "object_kind", event.ObjectKind, "object", event.ObjectName, "status", status, "body", string(body), "error", err)
+	}
+}
+
+// getEvents returns every recorded event, oldest first
+func getEvents() ([]StormfrontEvent, error) {
+	data, err := connection.Query("get record stormfront.event")
+	if err != nil {
+		return nil, err
+	}
+	events := []StormfrontEvent{}
+	eventBytes, _ := json.Marshal(data)
+	json.Unmarshal(eventBytes, &events)
+
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].recorded().Before(events[j].recorded())
+	})
+	return events, nil
+}
+
+// cleanupEvents is run by the leader to remove events older than the
+// configured retention
+func cleanupEvents() error {
+	data, err := connection.Query("get record stormfront.event")
+	if err != nil {
+		return err
+	}
+
+	retention := time.Duration(config.Config.EventRetention) * time.Second
+	for _, datum := range data {
+		recorded, err := time.Parse(time.RFC3339Nano, fmt.Sprintf("%v", datum["time"]))
+		if err == nil && time.Since(recorded) < retention {
+			continue
+		}
+		_, err = connection.Query(fmt.Sprintf(`delete record stormfront.event %s`, datum[".id"].(string)))
+		if err != nil {
+			eventLog.Error("Unable to remove event", "event", datum["id"], "error", err)
+		}
+	}
+
+	return nil
+}
+
+// eventFilter selects the events a request asked for and is allowed to see
+type eventFilter struct {
+	since     time.Time
+	object    string
+	eventType string
+}
+
+// parseEventFilter reads the since parameter as either a time or a duration
+// to look back over, such as 15m
+func parseEventFilter(c *gin.Context) (eventFilter, error) {
+	filter := eventFilter{object: c.Query("object"), eventType: c.Query("type")}
+
+	since := c.Query("since")
+	if since == "" {
+		return filter, nil
+	}
+	if t, err := time.Parse(time.RFC3339Nano, since); err == nil {
+		filter.since = t
+		return filter, nil
+	}
+	window, err := timeseries.ParseRange(since)
+	if err != nil {
+		return filter, fmt.Errorf("invalid since %s, must be a time such as %s or a duration such as 15m", since, time.Now().Format(time.RFC3339))
+	}
+	filter.since = time.Now().Add(-window)
+	return filter, nil
+}
+
+func (f eventFilter) matches(c *gin.Context, event StormfrontEvent) bool {
+	if !namespaceAllowed(c, event.Namespace) {
+		return false
+	}
+	if !f.since.IsZero() && event.recorded().Before(f.since) {
+		return false
+	}
+	if f.object != "" && !event.matchesObject(f.object) {
+		return false
+	}
+	return f.eventType == "" || f.eventType == event.Type
+}
+
+// GetAllEvents returns the events matching the since, object and type
+// parameters. With watch set the response is a stream of server-sent events
+// which carries the matching events and then each new one as it is recorded.
+func GetAllEvents(c *gin.Context) {
+	if Client.Type != "Leader" {
+		c.Redirect(http.StatusTemporaryRedirect, fmt.Sprintf("https://%s:%v/api/event?%s", Client.Leader.Host, Client.Leader.Port, c.Request.URL.RawQuery))
+		return
+	}
+
+	filter, err := parseEventFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if c.Query("watch") == "true" {
+		watchEvents(c, filter)
+		return
+	}
+
+	events, err := getEvents()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	scoped := []StormfrontEvent{}
+	for _, event := range events {
+		if filter.matches(c, event) {
+			scoped = append(scoped, event)
+		}
+	}
+
+	c.JSON(http.StatusOK, scoped)
+}
+
+// watchEvents streams the recorded events and then each new one as the
+// leader stores it, until the client goes away
+func watchEvents(c *gin.Context, filter eventFilter) {
+	// Subscribing first means no event is missed while the recorded ones are
+	// read, those stored in between arrive twice and are skipped by ID
+	updates := subscribeEvents()
+	defer unsubscribeEvents(updates)
+
+	pending, err := getEvents()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	recorded := map[string]bool{}
+	for _, event := range pending {
+		recorded[event.ID] = true
+	}
+
+	w := startStream(c)
+	pollStream(c, w, func() (bool, error) {
+	drain:
+		for {
+			select {
+			case event, ok := <-updates:
+				if !ok {
+					return false, fmt.Errorf("watch fell more than %d events behind, reconnect to resume", EVENT_WATCH_BUFFER)
+				}
+				if recorded[event.ID] {
+					delete(recorded, event.ID)
+					continue
+				}
+				pending = append(pending, event)
+			default:
+				break drain
+			}
+		}
+
+		sent := false
+		for _, event := range pending {
+			if !filter.matches(c, event) {
+				continue
+			}
+			if err := writeStreamEvent(w, event.ID, event.Type, event); err != nil {
+				return false, err
+			}
+			sent = true
+		}
+		pending = nil
+		return sent, nil
+	})
+}
+
+// PostEvent records an event reported by a follower
+func PostEvent(c *gin.Context) {
+	if Client.Type != "Leader" {
+		c.Redirect(http.StatusTemporaryRedirect, fmt.Sprintf("https://%s:%v/api/event", Client.Leader.Host, Client.Leader.Port))
+		return
+	}
+
+	var event StormfrontEvent
+	if err := c.BindJSON(&event); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if event.ID == "" {
+		event.ID = uuid.New().String()
+	}
+	if _, err := time.Parse(time.RFC3339Nano, event.Time); err != nil {
+		event.Time = time.Now().Format(time.RFC3339Nano)
+	}
+
+	if err := storeEvent(event); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Status(http.StatusOK)
+}
@@ -0,0 +1,33 @@
+package client
+
+import "testing"
+
+func TestPublishEventReachesWatchers(t *testing.T) {
+	updates := subscribeEvents()
+	defer unsubscribeEvents(updates)
+
+	publishEvent(StormfrontEvent{ID: "event", Type: EVENT_NODE_HEALTHY})
+	select {
+	case event := <-updates:
+		if event.ID != "event" {
+			t.Fatalf("expected event to be published, got %v", event)
+		}
+	default:
+		t.Fatal("expected an event to be published")
+	}
+}
+
+func TestPublishEventClosesSlowWatchers(t *testing.T) {
+	updates := subscribeEvents()
+	defer unsubscribeEvents(updates)
+
+	for i := 0; i <= EVENT_WATCH_BUFFER; i++ {
+		publishEvent(StormfrontEvent{Type: EVENT_NODE_HEALTHY})
+	}
+	for i := 0; i < EVENT_WATCH_BUFFER; i++ {
+		<-updates
+	}
+	if _, ok := <-updates; ok {
+		t.Fatal("expected watcher which fell behind to be closed")
+	}
+}
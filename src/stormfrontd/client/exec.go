@@ -0,0 +1,229 @@
+package client
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"stormfrontd/client/communication"
+	"stormfrontd/client/engine"
+	"stormfrontd/client/multiplex"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jfcarter2358/ceresdb-go/connection"
+)
+
+type execRequest struct {
+	Instance    string
+	Command     []string
+	Interactive bool
+	TTY         bool
+	Rows        uint16
+	Cols        uint16
+}
+
+func parseExecRequest(c *gin.Context) (execRequest, error) {
+	request := execRequest{
+		Instance:    c.Query("instance"),
+		Command:     c.QueryArray("command"),
+		Interactive: c.Query("interactive") == "true",
+		TTY:         c.Query("tty") == "true",
+	}
+	if len(request.Command) == 0 {
+		return request, fmt.Errorf("no command given to run")
+	}
+	for key, size := range map[string]*uint16{"rows": &request.Rows, "cols": &request.Cols} {
+		if value := c.Query(key); value != "" {
+			parsed, err := strconv.ParseUint(value, 10, 16)
+			if err != nil {
+				return request, fmt.Errorf("invalid terminal %s value %s", key, value)
+			}
+			*size = uint16(parsed)
+		}
+	}
+	return request, nil
+}
+
+func (request execRequest) query() string {
+	query := url.Values{}
+	query.Set("instance", request.Instance)
+	query.Set("interactive", strconv.FormatBool(request.Interactive))
+	query.Set("tty", strconv.FormatBool(request.TTY))
+	query.Set("rows", strconv.Itoa(int(request.Rows)))
+	query.Set("cols", strconv.Itoa(int(request.Cols)))
+	query["command"] = request.Command
+	return query.Encode()
+}
+
+// hijack takes over the connection behind a request once it has been
+// accepted for an upgrade to an exec session
+func hijack(c *gin.Context) (net.Conn, *bufio.ReadWriter, error) {
+	conn, buffer, err := c.Writer.Hijack()
+	if err != nil {
+		return nil, nil, err
+	}
+	response := fmt.Sprintf("HTTP/1.1 101 Switching Protocols\r\nUpgrade: %s\r\nConnection: Upgrade\r\n\r\n", multiplex.UPGRADE_PROTOCOL)
+	if _, err := buffer.WriteString(response); err != nil {
+		conn.Close()
+		return nil, nil, err
+	}
+	if err := buffer.Flush(); err != nil {
+		conn.Close()
+		return nil, nil, err
+	}
+	return conn, buffer, nil
+}
+
+// ExecApplication runs a command inside an instance of an application over a
+// connection upgraded to a multiplexed exec session. Followers send clients on
+// to the leader, which relays the session from the node running the instance.
+func ExecApplication(c *gin.Context) {
+	id := c.Param("id")
+
+	if !strings.EqualFold(c.GetHeader("Upgrade"), multiplex.UPGRADE_PROTOCOL) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("exec requires upgrading the connection to %s", multiplex.UPGRADE_PROTOCOL)})
+		return
+	}
+
+	request, err := parseExecRequest(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	data, err := connection.Query(fmt.Sprintf(`get record stormfront.application | filter id = '%s'`, id))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if len(data) == 0 {
+		c.Status(http.StatusNotFound)
+		return
+	}
+
+	var app StormfrontApplication
+	appBytes, _ := json.Marshal(data[0])
+	json.Unmarshal(appBytes, &app)
+
+	// Commands run in the first instance unless one is named
+	if request.Instance == "" && len(app.Instances) > 0 {
+		request.Instance = app.Instances[0].Name
+	}
+	var instance StormfrontInstance
+	for _, candidate := range app.Instances {
+		if candidate.Name == request.Instance {
+			instance = candidate
+			break
+		}
+	}
+	if instance.Name == "" {
+		c.JSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("application %s has no instance %s", app.ID, request.Instance)})
+		return
+	}
+
+	if instance.Node != Client.ID {
+		if Client.Type != "Leader" {
+			c.Redirect(http.StatusTemporaryRedirect, fmt.Sprintf("https://%s:%v/api/application/%s/exec?%s", Client.Leader.Host, Client.Leader.Port, id, request.query()))
+			return
+		}
+		proxyExec(c, app, instance, request)
+		return
+	}
+
+	conn, buffer, err := hijack(c)
+	if err != nil {
+		requestLog(c).Error("Unable to start exec session", "instance", instance.Name, "error", err)
+		return
+	}
+	defer conn.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	mutex := &sync.Mutex{}
+	options := engine.ExecOptions{
+		Stdout: multiplex.NewWriter(conn, mutex, multiplex.STREAM_STDOUT),
+		Stderr: multiplex.NewWriter(conn, mutex, multiplex.STREAM_STDERR),
+		TTY:    request.TTY,
+		Rows:   request.Rows,
+		Cols:   request.Cols,
+	}
+	stdinReader, stdinWriter := io.Pipe()
+	if request.Interactive {
+		options.Stdin = stdinReader
+	}
+	go func() {
+		multiplex.ReadStdin(buffer, stdinWriter)
+		// Keep reading after stdin closes so that a dropped connection
+		// still stops the command
+		for {
+			if _, _, err := multiplex.ReadFrame(buffer); err != nil {
+				break
+			}
+		}
+		cancel()
+	}()
+
+	requestLog(c).Info("Running command in instance", "instance", instance.Name, "command", request.Command)
+	exitCode, err := Runtime.ExecStream(ctx, instance.Name, request.Command, options)
+	// Stops anything still copying stdin into the finished command
+	stdinReader.Close()
+	if err != nil {
+		requestLog(c).Error("Unable to run command in instance", "instance", instance.Name, "command", request.Command, "error", err)
+		options.Stderr.Write([]byte(fmt.Sprintf("%v\n", err)))
+	}
+
+	mutex.Lock()
+	multiplex.WriteFrame(conn, multiplex.STREAM_EXIT, []byte(strconv.Itoa(exitCode)))
+	mutex.Unlock()
+}
+
+// proxyExec relays an exec session between the client and the node running
+// the instance. Frames are passed through untouched in both directions.
+func proxyExec(c *gin.Context, app StormfrontApplication, instance StormfrontInstance, request execRequest) {
+	node, found, err := getNode(instance.Node)
+	if err != nil || !found {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("unable to find node %s running instance %s", instance.Node, instance.Name)})
+		return
+	}
+
+	request.Instance = instance.Name
+	path := fmt.Sprintf("api/application/%s/exec?%s", app.ID, request.query())
+	nodeConn, nodeReader, resp, err := communication.Upgrade(c.Request.Context(), node.Host, node.Port, path, AuthClient, multiplex.UPGRADE_PROTOCOL)
+	if err != nil {
+		requestLog(c).Error("Unable to start exec session on node", "node", node.ID, "instance", instance.Name, "error", err)
+		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+		return
+	}
+	if nodeConn == nil {
+		body, _ := io.ReadAll(resp.Body)
+		c.Data(resp.StatusCode, resp.Header.Get("Content-Type"), body)
+		return
+	}
+	defer nodeConn.Close()
+
+	conn, buffer, err := hijack(c)
+	if err != nil {
+		requestLog(c).Error("Unable to start exec session", "instance", instance.Name, "error", err)
+		return
+	}
+	defer conn.Close()
+
+	done := make(chan struct{}, 2)
+	go func() {
+		io.Copy(nodeConn, buffer)
+		done <- struct{}{}
+	}()
+	go func() {
+		io.Copy(conn, nodeReader)
+		done <- struct{}{}
+	}()
+	<-done
+}
@@ -0,0 +1,267 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"stormfrontd/client/auth"
+	"stormfrontd/client/communication"
+	"stormfrontd/client/pki"
+	"stormfrontd/config"
+	"stormfrontd/database"
+	"time"
+
+	"github.com/jfcarter2358/ceresdb-go/connection"
+)
+
+const LEADER_FAILURE_THRESHOLD = 3
+const LEADER_ELECTION_TIMEOUT = 60
+const DATABASE_STARTUP_DELAY = 5
+
+var leaderFailures = 0
+
+// checkLeader is called from the follower health loop. It tracks how many
+// consecutive health checks against the leader have failed and starts a
+// failover once the threshold is reached. It returns true if this node has
+// been promoted to leader.
+func checkLeader() bool {
+	status, _, err := communication.Get(context.Background(), Client.Leader.Host, Client.Leader.Port, "api/health", AuthClient)
+	if err == nil && status == http.StatusOK {
+		leaderFailures = 0
+		return false
+	}
+
+	leaderFailures++
+	if err != nil {
+		failoverLog.Warn("Unable to reach leader", "leader", Client.Leader.Host, "port", Client.Leader.Port, "failures", leaderFailures, "threshold", LEADER_FAILURE_THRESHOLD, "error", err)
+	} else {
+		failoverLog.Warn("Leader is unhealthy", "leader", Client.Leader.Host, "port", Client.Leader.Port, "status", status, "failures", leaderFailures, "threshold", LEADER_FAILURE_THRESHOLD)
+	}
+	if leaderFailures < LEADER_FAILURE_THRESHOLD {
+		return false
+	}
+	leaderFailures = 0
+
+	err = failover()
+	if err != nil {
+		failoverLog.Error("Leader failover failed", "error", err)
+		return false
+	}
+	return Client.Type == "Leader"
+}
+
+// failover elects the first healthy node in the succession order as the new
+// leader. Every follower walks the same replicated succession list, so they
+// all arrive at the same successor.
+func failover() error {
+	connection.Host = config.Config.CeresDBHost
+
+	leaderData, err := connection.Query("get record stormfront.leader")
+	if err != nil {
+		return err
+	}
+	if len(leaderData) == 0 {
+		return errors.New("no leader record found in local database")
+	}
+	var leader StormfrontLeader
+	leaderBytes, _ := json.Marshal(leaderData[0])
+	json.Unmarshal(leaderBytes, &leader)
+
+	successor, err := electSuccessor(leader)
+	if err != nil {
+		return err
+	}
+
+	failoverLog.Warn("Leader lost, elected successor", "leader", Client.Leader.Host, "port", Client.Leader.Port, "successor", successor.ID, "successor_host", successor.Host, "successor_port", successor.Port)
+
+	if successor.ID == Client.ID {
+		return promote(leader)
+	}
+	return followLeader(successor)
+}
+
+func electSuccessor(leader StormfrontLeader) (StormfrontNode, error) {
+	for _, successor := range leader.Succession {
+		if successor.ID == leader.ID {
+			continue
+		}
+		if successor.ID == Client.ID {
+			return successor, nil
+		}
+		status, _, err := communication.Get(context.Background(), successor.Host, successor.Port, "api/health", AuthClient)
+		if err == nil && status == http.StatusOK {
+			return successor, nil
+		}
+		failoverLog.Warn("Successor is not healthy, skipping", "successor", successor.ID, "successor_host", successor.Host, "successor_port", successor.Port)
+	}
+	return StormfrontNode{}, errors.New("no healthy successor available")
+}
+
+// promote turns this follower into the cluster leader. The local CeresDB
+// instance is a replica of the old leader's database, so its contents are
+// copied out, the instance is redeployed as a standalone leader, and the data
+// is written back before the leader health loop takes over.
+func promote(leader StormfrontLeader) error {
+	failoverLog.Info("Promoting this node to leader")
+
+	snapshot := map[string][]map[string]interface{}{}
+	for name := range Collections {
+		data, err := connection.Query(fmt.Sprintf("get record stormfront.%s", name))
+		if err != nil {
+			return fmt.Errorf("unable to snapshot collection %s: %v", name, err)
+		}
+		snapshot[name] = data
+	}
+
+	err := database.Deploy("")
+	if err != nil {
+		return err
+	}
+	time.Sleep(DATABASE_STARTUP_DELAY * time.Second)
+
+	err = CreateDatabases()
+	if err != nil {
+		return err
+	}
+
+	for name, records := range snapshot {
+		for _, record := range records {
+			delete(record, ".id")
+			if name == "auth" {
+				// Leaders from before tokens were hashed stored them in
+				// plain text
+				record = auth.MigrateClientRecord(record)
+			}
+			recordBytes, _ := json.Marshal(record)
+			_, err := connection.Query(fmt.Sprintf("post record stormfront.%s %s", name, recordBytes))
+			if err != nil {
+				failoverLog.Error("Unable to restore record", "collection", name, "error", err)
+			}
+		}
+	}
+
+	if err := ensureDefaultNamespace(); err != nil {
+		return err
+	}
+	if !pki.HasCAKey() {
+		failoverLog.Warn("Node does not hold the CA key, new nodes can not join until it is restored", "path", pki.TLS_DIRECTORY)
+	}
+	// The tokens are restored from this node's backup should it restart
+	if err := auth.BackupTokens(); err != nil {
+		failoverLog.Error("Unable to back up tokens", "error", err)
+	}
+
+	oldLeader := Client.Leader
+	oldLeader.ID = leader.ID
+	oldLeader.Health = "Unknown"
+	oldLeader.Type = "Leader"
+
+	succession := []StormfrontNode{}
+	for _, successor := range leader.Succession {
+		if successor.ID != Client.ID && successor.ID != leader.ID {
+			succession = append(succession, successor)
+		}
+	}
+
+	Client.Type = "Leader"
+	Client.Leader = StormfrontNode{
+		ID:   Client.ID,
+		Host: Client.Host,
+		Port: Client.Port,
+	}
+	Client.Succession = succession
+	Client.Updated = time.Now().Format(time.RFC3339)
+
+	leaderData, err := connection.Query("get record stormfront.leader")
+	if err != nil || len(leaderData) == 0 {
+		return fmt.Errorf("unable to read restored leader record: %v", err)
+	}
+	leaderData[0]["id"] = Client.ID
+	leaderData[0]["succession"] = succession
+	leaderData[0]["unknown"] = dedupeNodes(append(leader.Unknown, oldLeader))
+	leaderBytes, _ := json.Marshal(leaderData[0])
+	_, err = connection.Query(fmt.Sprintf("put record stormfront.leader %s", leaderBytes))
+	if err != nil {
+		return err
+	}
+
+	nodes, err := getNodes()
+	if err != nil {
+		return err
+	}
+	for _, node := range nodes {
+		switch node.ID {
+		case Client.ID:
+			node.Type = "Leader"
+			node.Health = "Healthy"
+		case leader.ID:
+			node.Type = "Follower"
+			node.Health = "Unknown"
+		default:
+			continue
+		}
+		if err := putNode(node); err != nil {
+			return err
+		}
+	}
+
+	err = updateClientRecord()
+	if err != nil {
+		return err
+	}
+
+	failoverLog.Info("Promotion complete, now acting as leader")
+	recordEvent(nodeEvent(EVENT_LEADER_ELECTED, Client.ID, "Node took over as leader from %s", leader.ID))
+
+	return nil
+}
+
+// followLeader waits for the elected successor to finish promoting itself,
+// then re-points the local CeresDB replica at it and re-registers this node.
+func followLeader(successor StormfrontNode) error {
+	deadline := time.Now().Add(LEADER_ELECTION_TIMEOUT * time.Second)
+	for {
+		status, body, err := communication.Get(context.Background(), successor.Host, successor.Port, "api/state", AuthClient)
+		if err == nil && status == http.StatusOK {
+			var state StormfrontClient
+			json.Unmarshal([]byte(body), &state)
+			if state.Type == "Leader" {
+				break
+			}
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("successor %s:%v did not become leader within %v seconds", successor.Host, successor.Port, LEADER_ELECTION_TIMEOUT)
+		}
+		time.Sleep(UPDATE_RETRY_DELAY * time.Second)
+	}
+
+	Client.Leader = StormfrontNode{
+		ID:   successor.ID,
+		Host: successor.Host,
+		Port: successor.Port,
+	}
+	Client.Updated = time.Now().Format(time.RFC3339)
+
+	err := database.Deploy(fmt.Sprintf("%s:%d", successor.Host, config.Config.CeresDBPort))
+	if err != nil {
+		return err
+	}
+	time.Sleep(DATABASE_STARTUP_DELAY * time.Second)
+
+	node := StormfrontNode{ID: Client.ID, Host: Client.Host, Port: Client.Port, System: Client.System, Health: "Healthy", Type: "Follower", Labels: config.Config.NodeLabels}
+	postBody, _ := json.Marshal(node)
+
+	status, _, err := communication.Post(context.Background(), Client.Leader.Host, Client.Leader.Port, "api/register", AuthClient, postBody)
+	if err != nil {
+		return err
+	}
+	if status != http.StatusOK {
+		return fmt.Errorf("unable to register with new leader at %s:%v, received status code %v", Client.Leader.Host, Client.Leader.Port, status)
+	}
+
+	failoverLog.Info("Now following new leader", "leader", Client.Leader.Host, "port", Client.Leader.Port)
+
+	return updateClientRecord()
+}
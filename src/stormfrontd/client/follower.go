@@ -1,9 +1,13 @@
 package client
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"stormfrontd/client/auth"
+	"stormfrontd/client/communication"
+	"stormfrontd/client/pki"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -11,35 +15,181 @@ import (
 )
 
 func RegisterFollower(c *gin.Context) {
+	if Client.Type != "Leader" {
+		c.JSON(http.StatusConflict, gin.H{"error": fmt.Sprintf("node is not the leader, current leader is %s:%v", Client.Leader.Host, Client.Leader.Port)})
+		return
+	}
+
 	var follower StormfrontNode
 	c.BindJSON(&follower)
 
 	currentTime := time.Now()
 	Client.Updated = currentTime.Format(time.RFC3339)
 
-	followerData, _ := json.Marshal(follower)
+	leaderData, err := connection.Query("get record stormfront.leader")
+	if err != nil || len(leaderData) == 0 {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("unable to read leader record: %v", err)})
+		return
+	}
+	var succession []StormfrontNode
+	successionBytes, _ := json.Marshal(leaderData[0]["succession"])
+	json.Unmarshal(successionBytes, &succession)
+
+	// Followers re-register after a failover, so replace any existing entry
+	// instead of appending a duplicate
+	found := false
+	for idx, successor := range succession {
+		if successor.ID == follower.ID {
+			succession[idx] = follower
+			found = true
+		}
+	}
+	if !found {
+		succession = append(succession, follower)
+	}
+	leaderData[0]["succession"] = succession
+
+	leaderBytes, _ := json.Marshal(leaderData[0])
+	_, err = connection.Query(fmt.Sprintf("put record stormfront.leader %s", leaderBytes))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
 
-	// This should append the follower JSON instead of "baz"
-	// Also the original host needs to be added to the nodes list so that it is not empty
-	_, err := connection.Query(fmt.Sprintf(`get record stormfront.node | jq '.[0].succession[.[0].succession|length] |= . + %s' | put record stormfront.node`, followerData))
+	err = putNode(follower)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	clientIDs, err := connection.Query(fmt.Sprintf(`get record stormfront.client .id | filter id = "%s"`, Client.ID))
+	err = updateClientRecord()
 	if err != nil {
-		fmt.Printf("database error: %v", err)
+		requestLog(c).Error("Unable to update client record", "node", follower.ID, "error", err)
 		return
 	}
-	clientData, _ := json.Marshal(Client)
-	var clientMap map[string]interface{}
-	json.Unmarshal(clientData, &clientMap)
-	clientMap[".id"] = clientIDs[0][".id"]
-	clientData, _ = json.Marshal(clientMap)
-	_, err = connection.Query(fmt.Sprintf(`put record stormfront.client %s`, clientData))
+
+	caCertificate, err := pki.ReadCACertificate()
 	if err != nil {
-		fmt.Printf("database error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("unable to read cluster CA: %v", err)})
+		return
+	}
+
+	// Followers need the cluster key to decrypt the secrets of the
+	// applications scheduled onto them. The CA key is only handed to the
+	// nodes next in line for leadership by distributeCAKey.
+	c.JSON(http.StatusOK, gin.H{
+		"cluster_key":    auth.GetEncodedClusterKey(),
+		"ca_certificate": string(caCertificate),
+	})
+}
+
+// CA_KEY_HOLDERS is how many of the nodes next in line for leadership hold
+// the CA key
+const CA_KEY_HOLDERS = 2
+
+// caKeyHolders are the followers the leader has given the CA key to. It is
+// nil until the first pass after the leader starts, when any follower could
+// still hold a copy from a previous leader.
+var caKeyHolders map[string]bool
+
+// distributeCAKey gives the CA key to the first CA_KEY_HOLDERS nodes of the
+// succession, so that a new leader can still admit nodes after a failover,
+// and removes it from every other follower
+func distributeCAKey(succession []StormfrontNode) {
+	_, caKey, err := pki.ReadCA()
+	if err != nil {
+		clusterLog.Error("Unable to read cluster CA", "error", err)
+		return
+	}
+
+	holders := map[string]bool{}
+	for idx, successor := range succession {
+		if idx >= CA_KEY_HOLDERS {
+			break
+		}
+		if caKeyHolders[successor.ID] {
+			holders[successor.ID] = true
+			continue
+		}
+		postBody, _ := json.Marshal(map[string]string{"ca_key": string(caKey)})
+		status, body, err := communication.Post(context.Background(), successor.Host, successor.Port, "api/ca", AuthClient, postBody)
+		if err != nil || status != http.StatusOK {
+			clusterLog.Error("Unable to hand CA key to successor", "node", successor.ID, "status", status, "body", body, "error", err)
+			continue
+		}
+		clusterLog.Info("Handed CA key to successor", "node", successor.ID)
+		holders[successor.ID] = true
+	}
+
+	for _, successor := range succession {
+		if holders[successor.ID] || (caKeyHolders != nil && !caKeyHolders[successor.ID]) {
+			continue
+		}
+		status, body, err := communication.Delete(context.Background(), successor.Host, successor.Port, "api/ca", AuthClient)
+		if err != nil || status != http.StatusOK {
+			clusterLog.Error("Unable to remove CA key from follower", "node", successor.ID, "status", status, "body", body, "error", err)
+			// Try again on the next pass
+			holders[successor.ID] = true
+			continue
+		}
+	}
+	// Holders which are unreachable keep their copy until they return
+	for id := range caKeyHolders {
+		if !containsNode(succession, id) {
+			holders[id] = true
+		}
+	}
+	caKeyHolders = holders
+}
+
+func containsNode(nodes []StormfrontNode, id string) bool {
+	for _, node := range nodes {
+		if node.ID == id {
+			return true
+		}
+	}
+	return false
+}
+
+// ReceiveCAKey is called by the leader on the nodes next in line for
+// leadership with the key of the cluster CA
+func ReceiveCAKey(c *gin.Context) {
+	if Client.Type == "Leader" {
+		c.JSON(http.StatusConflict, gin.H{"error": "node is the leader"})
+		return
+	}
+
+	var request map[string]string
+	if err := c.BindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	// Only the key is taken, the certificate this node trusts stays the one
+	// it received when it joined
+	caCertificate, err := pki.ReadCACertificate()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("unable to read cluster CA: %v", err)})
+		return
+	}
+	err = pki.WriteCA(caCertificate, []byte(request["ca_key"]))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("unable to store cluster CA: %v", err)})
+		return
+	}
+
+	c.Status(http.StatusOK)
+}
+
+// DeleteCAKey is called by the leader on followers which are no longer next
+// in line for leadership
+func DeleteCAKey(c *gin.Context) {
+	if Client.Type == "Leader" {
+		c.JSON(http.StatusConflict, gin.H{"error": "node is the leader"})
+		return
+	}
+
+	if err := pki.RemoveCAKey(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
@@ -53,25 +203,51 @@ func DeregisterFollower(c *gin.Context) {
 	currentTime := time.Now()
 	Client.Updated = currentTime.Format(time.RFC3339)
 
-	_, err := connection.Query(fmt.Sprintf(`get record stormfront.node | jq 'del(.[0].succession[] | select(.host == "%s"))' | put record stormfront.node`, follower.Host))
+	leaderData, err := connection.Query("get record stormfront.leader")
+	if err != nil || len(leaderData) == 0 {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("unable to read leader record: %v", err)})
+		return
+	}
+	for _, key := range []string{"succession", "unhealthy", "unknown"} {
+		var nodes []StormfrontNode
+		nodeBytes, _ := json.Marshal(leaderData[0][key])
+		json.Unmarshal(nodeBytes, &nodes)
+
+		remaining := []StormfrontNode{}
+		for _, node := range nodes {
+			if node.Host != follower.Host || node.Port != follower.Port {
+				remaining = append(remaining, node)
+			}
+		}
+		leaderData[0][key] = remaining
+	}
+
+	leaderBytes, _ := json.Marshal(leaderData[0])
+	_, err = connection.Query(fmt.Sprintf("put record stormfront.leader %s", leaderBytes))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	clientIDs, err := connection.Query(fmt.Sprintf(`get record stormfront.client .id | filter id = "%s"`, Client.ID))
+	nodeData, err := connection.Query(fmt.Sprintf(`get record stormfront.node | filter host = "%s"`, follower.Host))
 	if err != nil {
-		fmt.Printf("database error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
-	clientData, _ := json.Marshal(Client)
-	var clientMap map[string]interface{}
-	json.Unmarshal(clientData, &clientMap)
-	clientMap[".id"] = clientIDs[0][".id"]
-	clientData, _ = json.Marshal(clientMap)
-	_, err = connection.Query(fmt.Sprintf(`put record stormfront.client %s`, clientData))
+	for _, node := range nodeData {
+		if port, ok := node["port"].(float64); ok && int(port) != follower.Port {
+			continue
+		}
+		_, err = connection.Query(fmt.Sprintf(`delete record stormfront.node %s`, node[".id"].(string)))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	err = updateClientRecord()
 	if err != nil {
-		fmt.Printf("database error: %v", err)
+		requestLog(c).Error("Unable to update client record", "node", follower.ID, "error", err)
 		return
 	}
 
@@ -1,17 +1,21 @@
 package client
 
 import (
-	"fmt"
 	"time"
 )
 
 func HealthCheckFollower() {
 	for {
+		if checkLeader() {
+			go HealthCheckLeader()
+			return
+		}
 		reconcileApplications()
 		updateApplicationStatus()
+		replicateVolumes()
 		err := updateSystemInfo()
 		if err != nil {
-			fmt.Printf("Encountered error getting system info: %v\n", err)
+			healthLog.Error("Unable to update system info", "error", err)
 		}
 		time.Sleep(HEALTH_CHECK_DELAY * time.Second)
 	}
@@ -21,12 +25,13 @@ func HealthCheckLeader() {
 	for {
 		err := updateSuccession()
 		if err != nil {
-			fmt.Printf("Encountered error updating succession: %v\n", err)
+			healthLog.Error("Unable to update succession", "error", err)
 		}
 		updateApplicationStatus()
+		replicateVolumes()
 		err = updateSystemInfo()
 		if err != nil {
-			fmt.Printf("Encountered error getting system info: %v\n", err)
+			healthLog.Error("Unable to update system info", "error", err)
 		}
 		time.Sleep(HEALTH_CHECK_DELAY * time.Second)
 	}
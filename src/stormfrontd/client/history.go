@@ -0,0 +1,243 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"stormfrontd/client/communication"
+	"stormfrontd/client/metrics"
+	"stormfrontd/client/timeseries"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jfcarter2358/ceresdb-go/connection"
+)
+
+const STATS_DEFAULT_RANGE = "1h"
+
+// Each node keeps the resource usage history of itself and of the application
+// instances it runs in memory. Samples arrive every HEALTH_CHECK_DELAY seconds
+// and are averaged into coarser tiers so that a week of history stays small.
+var Stats = timeseries.NewStore(
+	timeseries.Tier{Resolution: HEALTH_CHECK_DELAY * time.Second, Capacity: 360},
+	timeseries.Tier{Resolution: time.Minute, Capacity: 1440},
+	timeseries.Tier{Resolution: 15 * time.Minute, Capacity: 672},
+)
+
+type NodeStats struct {
+	ID     string             `json:"id" yaml:"id"`
+	Range  string             `json:"range" yaml:"range"`
+	Points []timeseries.Point `json:"points" yaml:"points"`
+}
+
+type InstanceStats struct {
+	Node   string             `json:"node" yaml:"node"`
+	Points []timeseries.Point `json:"points" yaml:"points"`
+	Error  string             `json:"error,omitempty" yaml:"error,omitempty"`
+}
+
+type ApplicationStats struct {
+	ID        string                   `json:"id" yaml:"id"`
+	Name      string                   `json:"name" yaml:"name"`
+	Namespace string                   `json:"namespace" yaml:"namespace"`
+	Range     string                   `json:"range" yaml:"range"`
+	Instances map[string]InstanceStats `json:"instances" yaml:"instances"`
+}
+
+func nodeStatsKey(id string) string {
+	return fmt.Sprintf("node/%s", id)
+}
+
+func instanceStatsKey(appID, instance string) string {
+	return fmt.Sprintf("application/%s/%s", appID, instance)
+}
+
+// recordNodeStats adds the latest system information to this node's history
+// and drops the history of instances which are no longer running here
+func recordNodeStats(system StormfrontSystemInfo) {
+	now := time.Now()
+	memoryPercent := 0.0
+	if system.TotalMemory > 0 {
+		memoryPercent = float64(system.TotalMemory-system.FreeMemory) / float64(system.TotalMemory) * 100
+	}
+	Stats.Add(nodeStatsKey(Client.ID), now, map[string]float64{
+		"cpu_percent":      system.CPUUsage,
+		"memory_percent":   memoryPercent,
+		"cpu_available":    system.CPUAvailable,
+		"memory_available": float64(system.MemoryAvailable),
+	})
+	Stats.Prune(now.Add(-Stats.MaxRange()))
+}
+
+// recordInstanceStats adds the latest runtime stats of a local instance to
+// its history, samples the runtime could not report are skipped
+func recordInstanceStats(app StormfrontApplication, instance string, status StormfrontApplicationStatus) {
+	cpu, cpuOK := metrics.ParsePercent(status.CPU)
+	memory, memoryOK := metrics.ParsePercent(status.Memory)
+	if !cpuOK || !memoryOK {
+		return
+	}
+	Stats.Add(instanceStatsKey(app.ID, instance), time.Now(), map[string]float64{
+		"cpu_percent":    cpu,
+		"memory_percent": memory,
+	})
+}
+
+func parseStatsRange(c *gin.Context) (string, time.Time, error) {
+	statsRange := c.DefaultQuery("range", STATS_DEFAULT_RANGE)
+	window, err := timeseries.ParseRange(statsRange)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	if window > Stats.MaxRange() {
+		return "", time.Time{}, fmt.Errorf("invalid range %s, at most %v of history is kept", statsRange, Stats.MaxRange())
+	}
+	return statsRange, time.Now().Add(-window), nil
+}
+
+// relayStats passes on the response of the node which holds the requested
+// history
+func relayStats(c *gin.Context, node StormfrontNode, path string) {
+	status, body, err := communication.Get(c.Request.Context(), node.Host, node.Port, path, AuthClient)
+	if err != nil {
+		requestLog(c).Error("Unable to get stats from node", "node", node.ID, "path", path, "error", err)
+		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+		return
+	}
+	c.Data(status, "application/json; charset=utf-8", []byte(body))
+}
+
+// GetNodeStats returns the resource usage history of a node. The node serves
+// its own history, other nodes send the request on through the leader.
+func GetNodeStats(c *gin.Context) {
+	id := c.Param("id")
+
+	statsRange, since, err := parseStatsRange(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if id == Client.ID {
+		points, _ := Stats.Query(nodeStatsKey(id), since)
+		c.JSON(http.StatusOK, NodeStats{ID: id, Range: statsRange, Points: points})
+		return
+	}
+
+	if Client.Type != "Leader" {
+		c.Redirect(http.StatusTemporaryRedirect, fmt.Sprintf("https://%s:%v/api/node/%s/stats?%s", Client.Leader.Host, Client.Leader.Port, id, c.Request.URL.RawQuery))
+		return
+	}
+
+	node, found, err := getNode(id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if !found {
+		c.Status(http.StatusNotFound)
+		return
+	}
+
+	relayStats(c, node, fmt.Sprintf("api/node/%s/stats?%s", id, url.Values{"range": {statsRange}}.Encode()))
+}
+
+// GetApplicationStats returns the resource usage history of each instance of
+// an application, or only of the instance named by the instance parameter.
+// The leader gathers the history from the nodes running the instances.
+func GetApplicationStats(c *gin.Context) {
+	id := c.Param("id")
+
+	statsRange, since, err := parseStatsRange(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	data, err := connection.Query(fmt.Sprintf(`get record stormfront.application | filter id = '%s'`, id))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if len(data) == 0 {
+		c.Status(http.StatusNotFound)
+		return
+	}
+
+	var app StormfrontApplication
+	appBytes, _ := json.Marshal(data[0])
+	json.Unmarshal(appBytes, &app)
+
+	instances := []StormfrontInstance{}
+	for _, instance := range app.Instances {
+		if c.Query("instance") == "" || instance.Name == c.Query("instance") {
+			instances = append(instances, instance)
+		}
+	}
+	if c.Query("instance") != "" && len(instances) == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("application %s has no instance %s", app.ID, c.Query("instance"))})
+		return
+	}
+
+	if Client.Type != "Leader" {
+		for _, instance := range instances {
+			if instance.Node != Client.ID {
+				c.Redirect(http.StatusTemporaryRedirect, fmt.Sprintf("https://%s:%v/api/application/%s/stats?%s", Client.Leader.Host, Client.Leader.Port, id, c.Request.URL.RawQuery))
+				return
+			}
+		}
+	}
+
+	stats := ApplicationStats{
+		ID:        app.ID,
+		Name:      app.Name,
+		Namespace: app.Namespace,
+		Range:     statsRange,
+		Instances: map[string]InstanceStats{},
+	}
+	for _, instance := range instances {
+		if instance.Node == Client.ID {
+			points, _ := Stats.Query(instanceStatsKey(app.ID, instance.Name), since)
+			stats.Instances[instance.Name] = InstanceStats{Node: instance.Node, Points: points}
+			continue
+		}
+		stats.Instances[instance.Name] = getRemoteInstanceStats(c.Request.Context(), app, instance, statsRange)
+	}
+
+	c.JSON(http.StatusOK, stats)
+}
+
+// getRemoteInstanceStats asks the node running instance for its history, a
+// node which cannot be reached leaves an error in place of the points
+func getRemoteInstanceStats(ctx context.Context, app StormfrontApplication, instance StormfrontInstance, statsRange string) InstanceStats {
+	instanceStats := InstanceStats{Node: instance.Node, Points: []timeseries.Point{}}
+
+	node, found, err := getNode(instance.Node)
+	if err != nil || !found {
+		instanceStats.Error = fmt.Sprintf("unable to find node %s running instance %s", instance.Node, instance.Name)
+		return instanceStats
+	}
+
+	query := url.Values{"instance": {instance.Name}, "range": {statsRange}}
+	status, body, err := communication.Get(ctx, node.Host, node.Port, fmt.Sprintf("api/application/%s/stats?%s", app.ID, query.Encode()), AuthClient)
+	if err != nil {
+		instanceStats.Error = err.Error()
+		return instanceStats
+	}
+	if status != http.StatusOK {
+		instanceStats.Error = fmt.Sprintf("node %s returned status code %v", instance.Node, status)
+		return instanceStats
+	}
+
+	var remote ApplicationStats
+	if err := json.Unmarshal([]byte(body), &remote); err != nil {
+		instanceStats.Error = err.Error()
+		return instanceStats
+	}
+	if remoteInstance, ok := remote.Instances[instance.Name]; ok {
+		instanceStats.Points = remoteInstance.Points
+	}
+	return instanceStats
+}
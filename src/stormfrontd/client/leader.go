@@ -1,9 +1,9 @@
 package client
 
 type StormfrontLeader struct {
-	ID         string   `json:"id" yaml:"id"`
-	Succession []string `json:"succession" yaml:"succession"`
-	Healthy    []string `json:"healthy" yaml:"healthy"`
-	Unhealthy  []string `json:"unhealthy" yaml:"unhealthy"`
-	Unknown    []string `json:"unknown" yaml:"unknown"`
+	ID         string           `json:"id" yaml:"id"`
+	Succession []StormfrontNode `json:"succession" yaml:"succession"`
+	Healthy    []StormfrontNode `json:"healthy" yaml:"healthy"`
+	Unhealthy  []StormfrontNode `json:"unhealthy" yaml:"unhealthy"`
+	Unknown    []StormfrontNode `json:"unknown" yaml:"unknown"`
 }
@@ -2,10 +2,12 @@ package lightning
 
 import (
 	"bytes"
+	"context"
 	"errors"
+	"fmt"
 	"os/exec"
-
-	"github.com/google/uuid"
+	"sync"
+	"time"
 )
 
 // valid bolt statuses:
@@ -13,69 +15,250 @@ import (
 // Running
 // Success
 // Failure
-
-var Bolts []Bolt
+// Cancelled
 
 const BOLT_PENDING_STATUS = "Pending"
 const BOLT_RUNNING_STATUS = "Running"
 const BOLT_SUCCESS_STATUS = "Success"
 const BOLT_FAILURE_STATUS = "Failure"
+const BOLT_CANCELLED_STATUS = "Cancelled"
+
+// MAX_OUTPUT_SIZE caps how much of each output stream is kept so a chatty
+// command can not bloat the database
+const MAX_OUTPUT_SIZE = 1024 * 1024
+
+// Run is the execution of a bolt on a single node
+type Run struct {
+	ID       string `json:"id"`
+	Bolt     string `json:"bolt"`
+	Node     string `json:"node"`
+	Status   string `json:"status"`
+	Stdout   string `json:"stdout"`
+	Stderr   string `json:"stderr"`
+	Error    string `json:"error"`
+	ExitCode int    `json:"exit_code"`
+	Started  string `json:"started"`
+	Finished string `json:"finished"`
+
+	// Where Stdout and Stderr start within the output of the run, nodes only
+	// report the output the leader has not received yet
+	StdoutOffset int `json:"stdout_offset,omitempty"`
+	StderrOffset int `json:"stderr_offset,omitempty"`
+}
+
+// Progress is how much of a run's output the leader has received
+type Progress struct {
+	Stdout int `json:"stdout"`
+	Stderr int `json:"stderr"`
+}
+
+func (run Run) Done() bool {
+	return run.Status == BOLT_SUCCESS_STATUS || run.Status == BOLT_FAILURE_STATUS || run.Status == BOLT_CANCELLED_STATUS
+}
+
+// Runner executes the runs assigned to this node and tracks their progress
+// until they have been reported back to the leader
+type Runner struct {
+	mutex      sync.Mutex
+	executions map[string]*execution
+}
 
-type Bolt struct {
-	ID      string `json:"id"`
-	Stdout  string `json:"stdout"`
-	Stderr  string `json:"stderr"`
-	Error   string `json:"error"`
-	Command string `json:"command"`
-	Status  string `json:"status"`
+type execution struct {
+	run      Run
+	cancel   context.CancelFunc
+	stdout   *outputBuffer
+	stderr   *outputBuffer
+	reported bool
+	sent     Progress
 }
 
-type BoltConstructor struct {
-	Command string `json:"command"`
+// outputBuffer is written to by the running command while the runner reads
+// snapshots of it, anything past MAX_OUTPUT_SIZE is dropped
+type outputBuffer struct {
+	mutex     sync.Mutex
+	buffer    bytes.Buffer
+	truncated bool
+	changed   bool
 }
 
-func CreateBolt(command string) (Bolt, int) {
-	bolt := Bolt{
-		ID:      uuid.New().String(),
-		Stdout:  "",
-		Stderr:  "",
-		Error:   "",
-		Command: command,
-		Status:  BOLT_PENDING_STATUS,
+func (b *outputBuffer) Write(p []byte) (int, error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	remaining := MAX_OUTPUT_SIZE - b.buffer.Len()
+	if remaining < len(p) {
+		b.truncated = true
+		if remaining > 0 {
+			b.buffer.Write(p[:remaining])
+		}
+	} else {
+		b.buffer.Write(p)
 	}
+	b.changed = true
+	return len(p), nil
+}
 
-	idx := len(Bolts)
-	Bolts = append(Bolts, bolt)
+// read returns the output so far and whether it changed since the last
+// read. The truncation notice is only added to the final output so that
+// earlier reads are always a prefix of later ones.
+func (b *outputBuffer) read(final bool) (string, bool) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
 
-	return bolt, idx
+	changed := b.changed
+	b.changed = false
+	output := b.buffer.String()
+	if b.truncated && final {
+		output += "\n[output truncated]\n"
+	}
+	return output, changed
 }
 
-func RunBolt(bolt *Bolt) {
-	bolt.Status = BOLT_RUNNING_STATUS
+func NewRunner() *Runner {
+	return &Runner{executions: map[string]*execution{}}
+}
 
-	cmd := exec.Command("/bin/sh", "-c", bolt.Command)
+// Tracking reports whether the run is executing or waiting to be reported
+func (r *Runner) Tracking(id string) bool {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
 
-	var outb, errb bytes.Buffer
-	cmd.Stdout = &outb
-	cmd.Stderr = &errb
+	_, ok := r.executions[id]
+	return ok
+}
 
-	err := cmd.Run()
-	if err != nil {
-		bolt.Error = err.Error()
-		bolt.Status = BOLT_FAILURE_STATUS
-	} else {
-		bolt.Status = BOLT_SUCCESS_STATUS
+// Start runs command for the given run in the background, killing it once
+// timeout seconds have passed if timeout is positive
+func (r *Runner) Start(run Run, command string, timeout int) {
+	ctx, cancel := context.WithCancel(context.Background())
+	if timeout > 0 {
+		ctx, cancel = context.WithTimeout(context.Background(), time.Duration(timeout)*time.Second)
 	}
 
-	bolt.Stdout = outb.String()
-	bolt.Stderr = errb.String()
+	run.Status = BOLT_RUNNING_STATUS
+	run.Started = time.Now().Format(time.RFC3339)
+	current := &execution{
+		run:    run,
+		cancel: cancel,
+		stdout: &outputBuffer{},
+		stderr: &outputBuffer{},
+	}
+
+	r.mutex.Lock()
+	r.executions[run.ID] = current
+	r.mutex.Unlock()
+
+	go func() {
+		defer cancel()
+
+		cmd := exec.Command("/bin/sh", "-c", command)
+		cmd.Stdout = current.stdout
+		cmd.Stderr = current.stderr
+		setProcessGroup(cmd)
+		err := cmd.Start()
+		if err == nil {
+			finished := make(chan struct{})
+			go func() {
+				select {
+				case <-ctx.Done():
+					killProcessGroup(cmd)
+				case <-finished:
+				}
+			}()
+			err = cmd.Wait()
+			close(finished)
+		}
+
+		r.mutex.Lock()
+		defer r.mutex.Unlock()
+
+		current.run.Finished = time.Now().Format(time.RFC3339)
+		current.run.Status = BOLT_SUCCESS_STATUS
+		if err != nil {
+			current.run.Status = BOLT_FAILURE_STATUS
+			current.run.Error = err.Error()
+			var exitErr *exec.ExitError
+			if errors.As(err, &exitErr) {
+				current.run.ExitCode = exitErr.ExitCode()
+			} else {
+				current.run.ExitCode = -1
+			}
+		}
+		switch {
+		case errors.Is(ctx.Err(), context.DeadlineExceeded):
+			current.run.Status = BOLT_FAILURE_STATUS
+			current.run.Error = fmt.Sprintf("timed out after %d seconds", timeout)
+		case errors.Is(ctx.Err(), context.Canceled) && err != nil:
+			current.run.Status = BOLT_CANCELLED_STATUS
+			current.run.Error = "cancelled"
+		}
+		current.reported = false
+	}()
 }
 
-func GetBolt(boltId string) (Bolt, error) {
-	for _, bolt := range Bolts {
-		if boltId == bolt.ID {
-			return bolt, nil
+// Cancel kills the command of a run if it is still executing
+func (r *Runner) Cancel(id string) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if current, ok := r.executions[id]; ok && !current.run.Done() {
+		current.cancel()
+	}
+}
+
+// Unreported returns the runs which have progressed since they were last
+// reported, holding only the output the leader has not received
+func (r *Runner) Unreported() []Run {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	runs := []Run{}
+	for _, current := range r.executions {
+		stdout, stdoutChanged := current.stdout.read(current.run.Done())
+		stderr, stderrChanged := current.stderr.read(current.run.Done())
+		if stdoutChanged || stderrChanged {
+			current.reported = false
 		}
+		if current.reported {
+			continue
+		}
+		run := current.run
+		run.StdoutOffset = clampOffset(current.sent.Stdout, stdout)
+		run.StderrOffset = clampOffset(current.sent.Stderr, stderr)
+		run.Stdout = stdout[run.StdoutOffset:]
+		run.Stderr = stderr[run.StderrOffset:]
+		runs = append(runs, run)
+	}
+	return runs
+}
+
+func clampOffset(offset int, output string) int {
+	if offset < 0 || offset > len(output) {
+		return 0
+	}
+	return offset
+}
+
+// Reported records that the leader has received progress of the output of
+// the given run. A run only counts as reported once the leader holds all of
+// its output, finished runs are then no longer tracked.
+func (r *Runner) Reported(run Run, progress Progress) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	current, ok := r.executions[run.ID]
+	if !ok {
+		return
+	}
+	current.sent = progress
+	if current.run.Status != run.Status {
+		return
+	}
+	if progress.Stdout != run.StdoutOffset+len(run.Stdout) || progress.Stderr != run.StderrOffset+len(run.Stderr) {
+		return
+	}
+	current.reported = true
+	if run.Done() {
+		delete(r.executions, run.ID)
 	}
-	return Bolt{}, errors.New("no bolt found with ID")
 }
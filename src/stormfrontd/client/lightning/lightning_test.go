@@ -0,0 +1,42 @@
+package lightning
+
+import "testing"
+
+func TestRunnerReportsOnlyNewOutput(t *testing.T) {
+	runner := NewRunner()
+	current := &execution{
+		run:    Run{ID: "run", Status: BOLT_RUNNING_STATUS},
+		stdout: &outputBuffer{},
+		stderr: &outputBuffer{},
+	}
+	runner.executions["run"] = current
+
+	steps := []struct {
+		name     string
+		write    string
+		progress Progress
+		stdout   string
+		offset   int
+	}{
+		{name: "first report", write: "abc", progress: Progress{Stdout: 3}, stdout: "abc", offset: 0},
+		{name: "only new output", write: "de", progress: Progress{Stdout: 5}, stdout: "de", offset: 3},
+		{name: "leader lost the output", write: "f", progress: Progress{}, stdout: "f", offset: 5},
+		{name: "everything is sent again", stdout: "abcdef", offset: 0, progress: Progress{Stdout: 6}},
+	}
+
+	for _, step := range steps {
+		current.stdout.Write([]byte(step.write))
+		runs := runner.Unreported()
+		if len(runs) != 1 {
+			t.Fatalf("%s: expected 1 unreported run, got %d", step.name, len(runs))
+		}
+		if runs[0].Stdout != step.stdout || runs[0].StdoutOffset != step.offset {
+			t.Fatalf("%s: expected %q at %d, got %q at %d", step.name, step.stdout, step.offset, runs[0].Stdout, runs[0].StdoutOffset)
+		}
+		runner.Reported(runs[0], step.progress)
+	}
+
+	if runs := runner.Unreported(); len(runs) != 0 {
+		t.Fatalf("expected run to be reported, got %v", runs)
+	}
+}
@@ -0,0 +1,20 @@
+//go:build !windows
+
+package lightning
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// Commands run in their own process group so that killing a bolt also kills
+// anything the shell started
+func setProcessGroup(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+}
+
+func killProcessGroup(cmd *exec.Cmd) {
+	if cmd.Process != nil {
+		syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	}
+}
@@ -0,0 +1,27 @@
+package client
+
+import (
+	"stormfrontd/logging"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Each part of the client logs through its own logger so that lines can be
+// filtered by the subsystem field
+var apiLog = logging.Subsystem("api")
+var schedulerLog = logging.Subsystem("scheduler")
+var reconcilerLog = logging.Subsystem("reconciler")
+var healthLog = logging.Subsystem("health")
+var failoverLog = logging.Subsystem("failover")
+var databaseLog = logging.Subsystem("database")
+var clusterLog = logging.Subsystem("cluster")
+var lightningLog = logging.Subsystem("lightning")
+var volumeLog = logging.Subsystem("volume")
+var eventLog = logging.Subsystem("event")
+var dnsLog = logging.Subsystem("dns")
+
+// requestLog returns the API logger tagged with the ID of the request being
+// handled so lines can be matched up with the request log
+func requestLog(c *gin.Context) *logging.Logger {
+	return apiLog.With("request_id", logging.RequestID(c.Request.Context()))
+}
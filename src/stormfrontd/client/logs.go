@@ -0,0 +1,101 @@
+package client
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"stormfrontd/client/communication"
+	"stormfrontd/client/engine"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// flushWriter pushes every write out to the client straight away so followed
+// logs show up as they are written rather than when a buffer fills
+type flushWriter struct {
+	writer gin.ResponseWriter
+}
+
+func (w flushWriter) Write(p []byte) (int, error) {
+	n, err := w.writer.Write(p)
+	w.writer.Flush()
+	return n, err
+}
+
+func parseLogOptions(c *gin.Context) (engine.LogOptions, error) {
+	options := engine.LogOptions{
+		Follow:     c.Query("follow") == "true",
+		Since:      c.Query("since"),
+		Tail:       c.Query("tail"),
+		Timestamps: c.Query("timestamps") == "true",
+	}
+	if options.Tail == "all" {
+		options.Tail = ""
+	}
+	if options.Tail != "" {
+		if lines, err := strconv.Atoi(options.Tail); err != nil || lines < 0 {
+			return options, fmt.Errorf("invalid tail value %s, must be a positive number of lines or 'all'", options.Tail)
+		}
+	}
+	return options, nil
+}
+
+func logQuery(instance string, options engine.LogOptions) string {
+	query := url.Values{}
+	query.Set("instance", instance)
+	query.Set("follow", strconv.FormatBool(options.Follow))
+	query.Set("timestamps", strconv.FormatBool(options.Timestamps))
+	if options.Since != "" {
+		query.Set("since", options.Since)
+	}
+	if options.Tail != "" {
+		query.Set("tail", options.Tail)
+	}
+	return query.Encode()
+}
+
+// streamLogs writes the logs of a local instance to the response as chunked
+// plain text. The status is only sent along with the first chunk, so errors
+// before then are reported as JSON with an error status, after that they end
+// the stream.
+func streamLogs(c *gin.Context, instance string, options engine.LogOptions) {
+	c.Header("Content-Type", "text/plain; charset=utf-8")
+	c.Header("X-Content-Type-Options", "nosniff")
+	c.Status(http.StatusOK)
+
+	err := Runtime.Logs(c.Request.Context(), instance, options, flushWriter{writer: c.Writer})
+	if err != nil {
+		requestLog(c).Error("Unable to get container logs", "instance", instance, "error", err)
+		if !c.Writer.Written() {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+	}
+}
+
+// proxyLogs relays the logs of an instance from the node running it. The
+// leader proxies rather than redirects so that clients only ever need to
+// reach and authenticate against the leader.
+func proxyLogs(c *gin.Context, app StormfrontApplication, instance StormfrontInstance, options engine.LogOptions) {
+	node, found, err := getNode(instance.Node)
+	if err != nil || !found {
+		requestLog(c).Error("Unable to find node running instance", "node", instance.Node, "instance", instance.Name, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("unable to find node %s running instance %s", instance.Node, instance.Name)})
+		return
+	}
+
+	path := fmt.Sprintf("api/application/%s/logs?%s", app.ID, logQuery(instance.Name, options))
+	resp, err := communication.Stream(c.Request.Context(), node.Host, node.Port, path, AuthClient)
+	if err != nil {
+		requestLog(c).Error("Unable to get container logs from node", "node", node.ID, "instance", instance.Name, "error", err)
+		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+		return
+	}
+	defer resp.Body.Close()
+
+	c.Header("Content-Type", resp.Header.Get("Content-Type"))
+	c.Header("X-Content-Type-Options", "nosniff")
+	c.Status(resp.StatusCode)
+	io.Copy(flushWriter{writer: c.Writer}, resp.Body)
+}
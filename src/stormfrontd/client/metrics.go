@@ -0,0 +1,131 @@
+package client
+
+import (
+	"fmt"
+	"net/http"
+	"stormfrontd/client/metrics"
+
+	"github.com/gin-gonic/gin"
+)
+
+var (
+	nodeCPUCores              = metrics.NewGauge("stormfront_node_cpu_cores", "Number of logical CPU cores on the node", "node", "host")
+	nodeCPUUsage              = metrics.NewGauge("stormfront_node_cpu_usage_percent", "CPU usage of the node as a percentage", "node", "host")
+	nodeCPUReserved           = metrics.NewGauge("stormfront_node_cpu_reserved_cores", "CPU cores reserved by the node and the applications scheduled on it", "node", "host")
+	nodeCPUAvailable          = metrics.NewGauge("stormfront_node_cpu_available_cores", "CPU cores left for scheduling applications", "node", "host")
+	nodeMemoryTotal           = metrics.NewGauge("stormfront_node_memory_total_bytes", "Total memory of the node", "node", "host")
+	nodeMemoryFree            = metrics.NewGauge("stormfront_node_memory_free_bytes", "Free memory on the node", "node", "host")
+	nodeMemoryReserved        = metrics.NewGauge("stormfront_node_memory_reserved_bytes", "Memory reserved by the node and the applications scheduled on it", "node", "host")
+	nodeMemoryAvailable       = metrics.NewGauge("stormfront_node_memory_available_bytes", "Memory left for scheduling applications", "node", "host")
+	nodeDiskTotal             = metrics.NewGauge("stormfront_node_disk_total_bytes", "Total disk space of the node's root filesystem", "node", "host")
+	nodeDiskFree              = metrics.NewGauge("stormfront_node_disk_free_bytes", "Free disk space on the node's root filesystem", "node", "host")
+	leaderHealthCheckDuration = metrics.NewGauge("stormfront_leader_health_check_seconds", "Time taken by the last health check from the leader to a follower", "node", "host")
+	leaderHealthCheckFailures = metrics.NewCounter("stormfront_leader_health_check_failures_total", "Health checks from the leader to a follower which did not succeed", "node", "host")
+	dnsQueries                = metrics.NewCounter("stormfront_dns_queries_total", "DNS queries answered by the node, by result", "node", "result")
+)
+
+// observeHealthCheck records the outcome of a leader health check against a
+// follower
+func observeHealthCheck(node StormfrontNode, seconds float64, healthy bool) {
+	if healthy {
+		leaderHealthCheckDuration.Set(seconds, node.ID, node.Host)
+	} else {
+		leaderHealthCheckFailures.Inc(node.ID, node.Host)
+	}
+}
+
+func observeDNSQuery(err error) {
+	if err != nil {
+		dnsQueries.Inc(Client.ID, "failure")
+	} else {
+		dnsQueries.Inc(Client.ID, "success")
+	}
+}
+
+// updateNodeMetrics copies the system information gathered by the health
+// check loop into the node gauges
+func updateNodeMetrics() {
+	system := Client.System
+	nodeCPUCores.Set(float64(system.Cores), Client.ID, Client.Host)
+	nodeCPUUsage.Set(system.CPUUsage, Client.ID, Client.Host)
+	nodeCPUReserved.Set(float64(system.Cores)-system.CPUAvailable, Client.ID, Client.Host)
+	nodeCPUAvailable.Set(system.CPUAvailable, Client.ID, Client.Host)
+	nodeMemoryTotal.Set(float64(system.TotalMemory), Client.ID, Client.Host)
+	nodeMemoryFree.Set(float64(system.FreeMemory), Client.ID, Client.Host)
+	nodeMemoryReserved.Set(float64(system.TotalMemory-system.MemoryAvailable), Client.ID, Client.Host)
+	nodeMemoryAvailable.Set(float64(system.MemoryAvailable), Client.ID, Client.Host)
+	nodeDiskTotal.Set(float64(system.TotalDiskSpace), Client.ID, Client.Host)
+	nodeDiskFree.Set(float64(system.FreeDiskSpace), Client.ID, Client.Host)
+}
+
+// applicationMetrics builds the application metrics from the status of the
+// instances running on this node, leaving out namespaces the request may not
+// see. They are built per request so that scrapes with differently scoped API
+// tokens do not share samples.
+func applicationMetrics(c *gin.Context) ([]*metrics.Vec, error) {
+	applicationCPU := metrics.NewGauge("stormfront_application_cpu_percent", "CPU usage of an application instance as reported by the container runtime", "node", "namespace", "application", "instance")
+	applicationMemory := metrics.NewGauge("stormfront_application_memory_percent", "Memory usage of an application instance as reported by the container runtime", "node", "namespace", "application", "instance")
+	applicationRestarts := metrics.NewCounter("stormfront_application_restarts_total", "Number of times an application instance has been restarted", "node", "namespace", "application", "instance")
+	applicationReady := metrics.NewGauge("stormfront_application_ready", "Whether an application instance is running and passing its readiness probe", "node", "namespace", "application", "instance")
+
+	applications, err := getApplications()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, app := range applications {
+		if !namespaceAllowed(c, app.Namespace) {
+			continue
+		}
+		for _, instance := range app.instancesOn(Client.ID) {
+			status, ok := app.InstanceStatus[instance.Name]
+			if !ok {
+				continue
+			}
+			if cpu, ok := metrics.ParsePercent(status.CPU); ok {
+				applicationCPU.Set(cpu, Client.ID, app.Namespace, app.Name, instance.Name)
+			}
+			if memory, ok := metrics.ParsePercent(status.Memory); ok {
+				applicationMemory.Set(memory, Client.ID, app.Namespace, app.Name, instance.Name)
+			}
+			applicationRestarts.Set(float64(status.Restarts), Client.ID, app.Namespace, app.Name, instance.Name)
+			ready := 0.0
+			if status.Status == "running" && (app.ReadinessProbe == nil || status.Ready) {
+				ready = 1
+			}
+			applicationReady.Set(ready, Client.ID, app.Namespace, app.Name, instance.Name)
+		}
+	}
+	return []*metrics.Vec{applicationCPU, applicationMemory, applicationRestarts, applicationReady}, nil
+}
+
+// GetMetrics serves this node's metrics for Prometheus to scrape. Each node
+// only reports the applications running on it, so every node should be
+// scraped rather than just the leader.
+func GetMetrics(c *gin.Context) {
+	updateNodeMetrics()
+	applicationVecs, err := applicationMetrics(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("unable to read applications: %v", err)})
+		return
+	}
+
+	vecs := []*metrics.Vec{
+		nodeCPUCores,
+		nodeCPUUsage,
+		nodeCPUReserved,
+		nodeCPUAvailable,
+		nodeMemoryTotal,
+		nodeMemoryFree,
+		nodeMemoryReserved,
+		nodeMemoryAvailable,
+		nodeDiskTotal,
+		nodeDiskFree,
+	}
+	vecs = append(vecs, applicationVecs...)
+	vecs = append(vecs, leaderHealthCheckDuration, leaderHealthCheckFailures, dnsQueries)
+
+	c.Header("Content-Type", metrics.CONTENT_TYPE)
+	c.Status(http.StatusOK)
+	metrics.WriteAll(c.Writer, vecs...)
+}
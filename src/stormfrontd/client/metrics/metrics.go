@@ -0,0 +1,146 @@
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Metrics are exposed in the Prometheus text format so that an existing
+// Prometheus can scrape each node directly.
+const CONTENT_TYPE = "text/plain; version=0.0.4; charset=utf-8"
+
+const TYPE_COUNTER = "counter"
+const TYPE_GAUGE = "gauge"
+
+type sample struct {
+	labelValues []string
+	value       float64
+}
+
+// Vec is a metric family, one sample is kept per distinct set of label values
+type Vec struct {
+	Name   string
+	Help   string
+	Type   string
+	Labels []string
+
+	mutex   sync.Mutex
+	samples map[string]*sample
+}
+
+func NewCounter(name, help string, labels ...string) *Vec {
+	return &Vec{Name: name, Help: help, Type: TYPE_COUNTER, Labels: labels, samples: map[string]*sample{}}
+}
+
+func NewGauge(name, help string, labels ...string) *Vec {
+	return &Vec{Name: name, Help: help, Type: TYPE_GAUGE, Labels: labels, samples: map[string]*sample{}}
+}
+
+func (v *Vec) get(labelValues []string) *sample {
+	if len(labelValues) != len(v.Labels) {
+		panic(fmt.Sprintf("metric %s expects %d label values, got %d", v.Name, len(v.Labels), len(labelValues)))
+	}
+	key := strings.Join(labelValues, "\xff")
+	s, ok := v.samples[key]
+	if !ok {
+		s = &sample{labelValues: append([]string{}, labelValues...)}
+		v.samples[key] = s
+	}
+	return s
+}
+
+// Add increases the sample for labelValues by value
+func (v *Vec) Add(value float64, labelValues ...string) {
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+	v.get(labelValues).value += value
+}
+
+func (v *Vec) Inc(labelValues ...string) {
+	v.Add(1, labelValues...)
+}
+
+// Set replaces the sample for labelValues with value
+func (v *Vec) Set(value float64, labelValues ...string) {
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+	v.get(labelValues).value = value
+}
+
+// Write prints the family in the Prometheus text format, samples are sorted
+// so that scrapes are stable
+func (v *Vec) Write(w io.Writer) error {
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+
+	if _, err := fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", v.Name, escapeHelp(v.Help), v.Name, v.Type); err != nil {
+		return err
+	}
+
+	keys := make([]string, 0, len(v.samples))
+	for key := range v.samples {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		s := v.samples[key]
+		labels := ""
+		if len(v.Labels) > 0 {
+			pairs := make([]string, len(v.Labels))
+			for idx, label := range v.Labels {
+				pairs[idx] = fmt.Sprintf(`%s="%s"`, label, escapeLabel(s.labelValues[idx]))
+			}
+			labels = "{" + strings.Join(pairs, ",") + "}"
+		}
+		if _, err := fmt.Fprintf(w, "%s%s %s\n", v.Name, labels, formatValue(s.value)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteAll prints each family in turn
+func WriteAll(w io.Writer, vecs ...*Vec) error {
+	for _, v := range vecs {
+		if err := v.Write(w); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ParsePercent converts a container runtime percentage such as "12.34%" to a
+// number, returning false for values the runtime could not report
+func ParsePercent(value string) (float64, bool) {
+	number, err := strconv.ParseFloat(strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(value), "%")), 64)
+	if err != nil || number < 0 {
+		return 0, false
+	}
+	return number, true
+}
+
+func formatValue(value float64) string {
+	switch {
+	case math.IsInf(value, 1):
+		return "+Inf"
+	case math.IsInf(value, -1):
+		return "-Inf"
+	case math.IsNaN(value):
+		return "NaN"
+	}
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
+
+func escapeHelp(help string) string {
+	return strings.NewReplacer(`\`, `\\`, "\n", `\n`).Replace(help)
+}
+
+func escapeLabel(value string) string {
+	return strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`).Replace(value)
+}
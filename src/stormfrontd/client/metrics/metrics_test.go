@@ -0,0 +1,104 @@
+package metrics
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestWrite(t *testing.T) {
+	tests := []struct {
+		name     string
+		vec      func() *Vec
+		expected string
+	}{
+		{
+			name:     "no samples",
+			vec:      func() *Vec { return NewGauge("stormfront_nodes", "Nodes in the cluster") },
+			expected: "# HELP stormfront_nodes Nodes in the cluster\n# TYPE stormfront_nodes gauge\n",
+		},
+		{
+			name: "counter without labels",
+			vec: func() *Vec {
+				v := NewCounter("stormfront_requests_total", "Requests served")
+				v.Inc()
+				v.Add(2)
+				return v
+			},
+			expected: "# HELP stormfront_requests_total Requests served\n# TYPE stormfront_requests_total counter\nstormfront_requests_total 3\n",
+		},
+		{
+			name: "labels are sorted and escaped",
+			vec: func() *Vec {
+				v := NewGauge("stormfront_cpu", "CPU usage\nin percent", "app", "node")
+				v.Set(1.5, "web", `b"1`)
+				v.Set(2, "api", `a\1`)
+				return v
+			},
+			expected: "# HELP stormfront_cpu CPU usage\\nin percent\n# TYPE stormfront_cpu gauge\n" +
+				"stormfront_cpu{app=\"api\",node=\"a\\\\1\"} 2\n" +
+				"stormfront_cpu{app=\"web\",node=\"b\\\"1\"} 1.5\n",
+		},
+		{
+			name: "special values",
+			vec: func() *Vec {
+				v := NewGauge("stormfront_value", "Value", "kind")
+				v.Set(math.Inf(1), "a")
+				v.Set(math.Inf(-1), "b")
+				v.Set(math.NaN(), "c")
+				v.Set(1e21, "d")
+				return v
+			},
+			expected: "# HELP stormfront_value Value\n# TYPE stormfront_value gauge\n" +
+				"stormfront_value{kind=\"a\"} +Inf\n" +
+				"stormfront_value{kind=\"b\"} -Inf\n" +
+				"stormfront_value{kind=\"c\"} NaN\n" +
+				"stormfront_value{kind=\"d\"} 1e+21\n",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var out strings.Builder
+			if err := test.vec().Write(&out); err != nil {
+				t.Fatal(err)
+			}
+			if out.String() != test.expected {
+				t.Fatalf("expected\n%s\ngot\n%s", test.expected, out.String())
+			}
+		})
+	}
+}
+
+func TestWrongLabelCountPanics(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatal("expected a panic")
+		}
+	}()
+	NewGauge("stormfront_cpu", "CPU", "app").Set(1)
+}
+
+func TestParsePercent(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected float64
+		ok       bool
+	}{
+		{value: "12.34%", expected: 12.34, ok: true},
+		{value: " 0.00% ", expected: 0, ok: true},
+		{value: "100", expected: 100, ok: true},
+		{value: "--", ok: false},
+		{value: "", ok: false},
+		{value: "-1%", ok: false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.value, func(t *testing.T) {
+			number, ok := ParsePercent(test.value)
+			if ok != test.ok || number != test.expected {
+				t.Fatalf("expected %v and %v, got %v and %v", test.expected, test.ok, number, ok)
+			}
+		})
+	}
+}
@@ -0,0 +1,94 @@
+package multiplex
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// Exec sessions carry stdin, stdout, stderr and the final exit code over a
+// single upgraded connection. Each frame is a one byte stream id followed by
+// a big endian uint32 payload length and the payload itself. An empty stdin
+// frame closes the command's stdin.
+const STREAM_STDIN byte = 0
+const STREAM_STDOUT byte = 1
+const STREAM_STDERR byte = 2
+const STREAM_EXIT byte = 3
+
+const MAX_FRAME_SIZE = 1024 * 1024
+
+const UPGRADE_PROTOCOL = "stormfront-exec"
+
+func WriteFrame(w io.Writer, stream byte, data []byte) error {
+	header := make([]byte, 5)
+	header[0] = stream
+	binary.BigEndian.PutUint32(header[1:], uint32(len(data)))
+	if _, err := w.Write(append(header, data...)); err != nil {
+		return err
+	}
+	return nil
+}
+
+func ReadFrame(r io.Reader) (byte, []byte, error) {
+	header := make([]byte, 5)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return 0, nil, err
+	}
+	length := binary.BigEndian.Uint32(header[1:])
+	if length > MAX_FRAME_SIZE {
+		return 0, nil, fmt.Errorf("frame of %d bytes exceeds maximum size of %d", length, MAX_FRAME_SIZE)
+	}
+	data := make([]byte, length)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return 0, nil, err
+	}
+	return header[0], data, nil
+}
+
+// Writer frames everything written to it as a single stream. Writers for
+// different streams sharing a connection must share a mutex so their frames
+// are not interleaved.
+type Writer struct {
+	mutex  *sync.Mutex
+	writer io.Writer
+	stream byte
+}
+
+func NewWriter(w io.Writer, mutex *sync.Mutex, stream byte) *Writer {
+	return &Writer{mutex: mutex, writer: w, stream: stream}
+}
+
+func (w *Writer) Write(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	if err := WriteFrame(w.writer, w.stream, p); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// ReadStdin copies stdin frames from r into w until the client closes stdin
+// or the connection, closing w with the error which ended the copy
+func ReadStdin(r io.Reader, w *io.PipeWriter) error {
+	for {
+		stream, data, err := ReadFrame(r)
+		if err != nil {
+			w.CloseWithError(err)
+			return err
+		}
+		if stream != STREAM_STDIN {
+			continue
+		}
+		if len(data) == 0 {
+			w.Close()
+			return nil
+		}
+		if _, err := w.Write(data); err != nil {
+			return err
+		}
+	}
+}
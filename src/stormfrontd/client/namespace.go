@@ -0,0 +1,40 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jfcarter2358/ceresdb-go/connection"
+)
+
+const DEFAULT_NAMESPACE = "default"
+
+// namespacedCollections are the collections whose records belong to a
+// namespace and follow it when it is renamed or deleted
+var namespacedCollections = []string{"application", "route", "secret", "config", "volume"}
+
+type StormfrontNamespace struct {
+	ID   string `json:"id" yaml:"id"`
+	Name string `json:"name" yaml:"name"`
+}
+
+// ensureDefaultNamespace creates the default namespace when the leader comes
+// up without one, such as on clusters created before namespaces existed
+func ensureDefaultNamespace() error {
+	_, found, err := getNamespace(DEFAULT_NAMESPACE)
+	if err != nil {
+		return err
+	}
+	if found {
+		return nil
+	}
+
+	namespaceData, _ := json.Marshal(StormfrontNamespace{ID: uuid.NewString(), Name: DEFAULT_NAMESPACE})
+	_, err = connection.Query(fmt.Sprintf("post record stormfront.namespace %s", namespaceData))
+	if err != nil {
+		return err
+	}
+	clusterLog.Info("Created missing namespace", "namespace", DEFAULT_NAMESPACE)
+	return nil
+}
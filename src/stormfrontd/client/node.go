@@ -1,10 +1,12 @@
 package client
 
 type StormfrontNode struct {
-	ID     string               `json:"id" yaml:"id"`
-	Host   string               `json:"host" yaml:"host"`
-	Port   int                  `json:"port" yaml:"port"`
-	System StormfrontSystemInfo `json:"system" yaml:"system"`
-	Health string               `json:"health" yaml:"health"`
-	Type   string               `json:"type" yaml:"type"`
+	ID           string               `json:"id" yaml:"id"`
+	Host         string               `json:"host" yaml:"host"`
+	Port         int                  `json:"port" yaml:"port"`
+	System       StormfrontSystemInfo `json:"system" yaml:"system"`
+	Health       string               `json:"health" yaml:"health"`
+	Type         string               `json:"type" yaml:"type"`
+	UnknownSince string               `json:"unknown_since" yaml:"unknown_since"`
+	Labels       map[string]string    `json:"labels" yaml:"labels"`
 }
@@ -0,0 +1,399 @@
+package pki
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"os"
+	"time"
+)
+
+// The leader generates the cluster CA when the cluster is deployed and signs a
+// certificate for every node as it joins. Followers receive the CA key when
+// they register so that whichever node becomes leader can keep issuing
+// certificates after a failover.
+const (
+	TLS_DIRECTORY                = "/var/stormfront/tls"
+	CA_VALIDITY                  = 10 * 365 * 24 * time.Hour
+	CERTIFICATE_VALIDITY         = 365 * 24 * time.Hour
+	CA_COMMON_NAME               = "stormfront-ca"
+	CERTIFICATE_PEM_TYPE         = "CERTIFICATE"
+	PRIVATE_KEY_PEM_TYPE         = "EC PRIVATE KEY"
+	CERTIFICATE_REQUEST_PEM_TYPE = "CERTIFICATE REQUEST"
+)
+
+func caCertificatePath() string {
+	return fmt.Sprintf("%s/ca.crt", TLS_DIRECTORY)
+}
+
+func caKeyPath() string {
+	return fmt.Sprintf("%s/ca.key", TLS_DIRECTORY)
+}
+
+func nodeCertificatePath() string {
+	return fmt.Sprintf("%s/node.crt", TLS_DIRECTORY)
+}
+
+func nodeKeyPath() string {
+	return fmt.Sprintf("%s/node.key", TLS_DIRECTORY)
+}
+
+func serialNumber() (*big.Int, error) {
+	return rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+}
+
+func encodeKey(key *ecdsa.PrivateKey) ([]byte, error) {
+	keyBytes, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: PRIVATE_KEY_PEM_TYPE, Bytes: keyBytes}), nil
+}
+
+func decodeCertificate(certificatePEM []byte) (*x509.Certificate, error) {
+	block, _ := pem.Decode(certificatePEM)
+	if block == nil || block.Type != CERTIFICATE_PEM_TYPE {
+		return nil, errors.New("invalid certificate PEM")
+	}
+	return x509.ParseCertificate(block.Bytes)
+}
+
+// CreateCA generates the cluster CA unless this node already has one, so a
+// redeployed leader keeps the CA the CLI has pinned
+func CreateCA() error {
+	if _, err := os.Stat(caKeyPath()); err == nil {
+		return nil
+	}
+	if err := os.MkdirAll(TLS_DIRECTORY, 0700); err != nil {
+		return err
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return fmt.Errorf("unable to generate CA key: %v", err)
+	}
+	serial, err := serialNumber()
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	template := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: CA_COMMON_NAME},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.Add(CA_VALIDITY),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	certificate, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return fmt.Errorf("unable to create CA certificate: %v", err)
+	}
+
+	keyPEM, err := encodeKey(key)
+	if err != nil {
+		return err
+	}
+	certificatePEM := pem.EncodeToMemory(&pem.Block{Type: CERTIFICATE_PEM_TYPE, Bytes: certificate})
+	return WriteCA(certificatePEM, keyPEM)
+}
+
+// WriteCA stores the cluster CA, the key may be empty on nodes which only
+// need to trust the CA
+func WriteCA(certificatePEM, keyPEM []byte) error {
+	if err := os.MkdirAll(TLS_DIRECTORY, 0700); err != nil {
+		return err
+	}
+	if _, err := decodeCertificate(certificatePEM); err != nil {
+		return fmt.Errorf("invalid CA certificate: %v", err)
+	}
+	if err := ioutil.WriteFile(caCertificatePath(), certificatePEM, 0644); err != nil {
+		return err
+	}
+	if len(keyPEM) == 0 {
+		return nil
+	}
+	return ioutil.WriteFile(caKeyPath(), keyPEM, 0600)
+}
+
+func ReadCA() ([]byte, []byte, error) {
+	certificatePEM, err := ioutil.ReadFile(caCertificatePath())
+	if err != nil {
+		return nil, nil, err
+	}
+	keyPEM, err := ioutil.ReadFile(caKeyPath())
+	if err != nil {
+		return nil, nil, err
+	}
+	return certificatePEM, keyPEM, nil
+}
+
+// ReadCACertificate returns the cluster CA certificate, which every node has
+func ReadCACertificate() ([]byte, error) {
+	return ioutil.ReadFile(caCertificatePath())
+}
+
+// HasCAKey reports whether this node holds the CA key and so can sign
+// certificates for joining nodes
+func HasCAKey() bool {
+	_, err := os.Stat(caKeyPath())
+	return err == nil
+}
+
+// RemoveCAKey deletes this node's copy of the CA key
+func RemoveCAKey() error {
+	err := os.Remove(caKeyPath())
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+func caPool() (*x509.CertPool, error) {
+	certificatePEM, err := ioutil.ReadFile(caCertificatePath())
+	if err != nil {
+		return nil, err
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(certificatePEM) {
+		return nil, errors.New("invalid CA certificate")
+	}
+	return pool, nil
+}
+
+// Fingerprint returns the SHA-256 hash of a DER encoded certificate
+func Fingerprint(certificate []byte) string {
+	sum := sha256.Sum256(certificate)
+	return hex.EncodeToString(sum[:])
+}
+
+// CAFingerprint returns the hash joining nodes use to pin the cluster CA
+func CAFingerprint() (string, error) {
+	certificatePEM, err := ioutil.ReadFile(caCertificatePath())
+	if err != nil {
+		return "", err
+	}
+	certificate, err := decodeCertificate(certificatePEM)
+	if err != nil {
+		return "", err
+	}
+	return Fingerprint(certificate.Raw), nil
+}
+
+// CreateCertificateRequest generates this node's key and returns a
+// certificate request for host for the leader to sign
+func CreateCertificateRequest(host string) ([]byte, error) {
+	if err := os.MkdirAll(TLS_DIRECTORY, 0700); err != nil {
+		return nil, err
+	}
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("unable to generate node key: %v", err)
+	}
+	keyPEM, err := encodeKey(key)
+	if err != nil {
+		return nil, err
+	}
+	if err := ioutil.WriteFile(nodeKeyPath(), keyPEM, 0600); err != nil {
+		return nil, err
+	}
+
+	template := &x509.CertificateRequest{
+		Subject:     pkix.Name{CommonName: host},
+		DNSNames:    []string{"localhost"},
+		IPAddresses: []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	if ip := net.ParseIP(host); ip != nil {
+		template.IPAddresses = append(template.IPAddresses, ip)
+	} else {
+		template.DNSNames = append(template.DNSNames, host)
+	}
+	request, err := x509.CreateCertificateRequest(rand.Reader, template, key)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create certificate request: %v", err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: CERTIFICATE_REQUEST_PEM_TYPE, Bytes: request}), nil
+}
+
+// IssueCertificate signs a node's certificate request with the cluster CA,
+// the certificate is valid both for serving and for mutual TLS
+func IssueCertificate(requestPEM []byte) ([]byte, error) {
+	block, _ := pem.Decode(requestPEM)
+	if block == nil || block.Type != CERTIFICATE_REQUEST_PEM_TYPE {
+		return nil, errors.New("invalid certificate request PEM")
+	}
+	request, err := x509.ParseCertificateRequest(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("invalid certificate request: %v", err)
+	}
+	if err := request.CheckSignature(); err != nil {
+		return nil, fmt.Errorf("invalid certificate request signature: %v", err)
+	}
+
+	caCertificatePEM, caKeyPEM, err := ReadCA()
+	if err != nil {
+		return nil, fmt.Errorf("unable to read cluster CA: %v", err)
+	}
+	caCertificate, err := decodeCertificate(caCertificatePEM)
+	if err != nil {
+		return nil, err
+	}
+	keyBlock, _ := pem.Decode(caKeyPEM)
+	if keyBlock == nil {
+		return nil, errors.New("invalid CA key PEM")
+	}
+	caKey, err := x509.ParseECPrivateKey(keyBlock.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("invalid CA key: %v", err)
+	}
+
+	serial, err := serialNumber()
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      request.Subject,
+		DNSNames:     request.DNSNames,
+		IPAddresses:  request.IPAddresses,
+		NotBefore:    now.Add(-time.Hour),
+		NotAfter:     now.Add(CERTIFICATE_VALIDITY),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	certificate, err := x509.CreateCertificate(rand.Reader, template, caCertificate, request.PublicKey, caKey)
+	if err != nil {
+		return nil, fmt.Errorf("unable to issue certificate: %v", err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: CERTIFICATE_PEM_TYPE, Bytes: certificate}), nil
+}
+
+func WriteNodeCertificate(certificatePEM []byte) error {
+	if _, err := decodeCertificate(certificatePEM); err != nil {
+		return fmt.Errorf("invalid node certificate: %v", err)
+	}
+	return ioutil.WriteFile(nodeCertificatePath(), certificatePEM, 0644)
+}
+
+// IssueNodeCertificate is used by the leader to sign its own certificate
+func IssueNodeCertificate(host string) error {
+	request, err := CreateCertificateRequest(host)
+	if err != nil {
+		return err
+	}
+	certificate, err := IssueCertificate(request)
+	if err != nil {
+		return err
+	}
+	return WriteNodeCertificate(certificate)
+}
+
+func nodeCertificate() (tls.Certificate, error) {
+	certificatePEM, err := ioutil.ReadFile(nodeCertificatePath())
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	caCertificatePEM, err := ioutil.ReadFile(caCertificatePath())
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	keyPEM, err := ioutil.ReadFile(nodeKeyPath())
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	// Serve the CA along with the node certificate so joining nodes can
+	// check it against the hash in their join command
+	return tls.X509KeyPair(append(certificatePEM, caCertificatePEM...), keyPEM)
+}
+
+// ServerTLSConfig returns the TLS configuration for the client API, with
+// mutual set node certificates presented by callers are verified
+func ServerTLSConfig(mutual bool) (*tls.Config, error) {
+	certificate, err := nodeCertificate()
+	if err != nil {
+		return nil, fmt.Errorf("unable to load node certificate: %v", err)
+	}
+	tlsConfig := &tls.Config{
+		Certificates: []tls.Certificate{certificate},
+		MinVersion:   tls.VersionTLS12,
+	}
+	if mutual {
+		pool, err := caPool()
+		if err != nil {
+			return nil, err
+		}
+		// The CLI authenticates with API tokens and has no certificate, so
+		// certificates are only required of node requests by the middleware
+		tlsConfig.ClientCAs = pool
+		tlsConfig.ClientAuth = tls.VerifyClientCertIfGiven
+	}
+	return tlsConfig, nil
+}
+
+// ClientTLSConfig returns the TLS configuration nodes use to talk to each
+// other, trusting only the cluster CA
+func ClientTLSConfig(mutual bool) (*tls.Config, error) {
+	pool, err := caPool()
+	if err != nil {
+		return nil, fmt.Errorf("unable to load cluster CA: %v", err)
+	}
+	tlsConfig := &tls.Config{
+		RootCAs:    pool,
+		MinVersion: tls.VersionTLS12,
+	}
+	if mutual {
+		certificate, err := nodeCertificate()
+		if err != nil {
+			return nil, fmt.Errorf("unable to load node certificate: %v", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{certificate}
+	}
+	return tlsConfig, nil
+}
+
+// PinnedTLSConfig is used by a joining node before it has the cluster CA. The
+// leader must present a CA matching fingerprint, with an empty fingerprint
+// every connection fails.
+func PinnedTLSConfig(fingerprint string) *tls.Config {
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		// Verification is done against the pinned CA below
+		InsecureSkipVerify: true,
+		VerifyPeerCertificate: func(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+			if len(rawCerts) < 2 {
+				return errors.New("leader did not present the cluster CA")
+			}
+			ca, err := x509.ParseCertificate(rawCerts[len(rawCerts)-1])
+			if err != nil {
+				return err
+			}
+			if fingerprint == "" {
+				return errors.New("no CA fingerprint pinned")
+			}
+			if Fingerprint(ca.Raw) != fingerprint {
+				return fmt.Errorf("leader CA fingerprint %s does not match %s", Fingerprint(ca.Raw), fingerprint)
+			}
+			leaf, err := x509.ParseCertificate(rawCerts[0])
+			if err != nil {
+				return err
+			}
+			pool := x509.NewCertPool()
+			pool.AddCert(ca)
+			_, err = leaf.Verify(x509.VerifyOptions{Roots: pool})
+			return err
+		},
+	}
+}
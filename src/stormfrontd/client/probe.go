@@ -0,0 +1,179 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"reflect"
+	"strings"
+	"time"
+)
+
+const PROBE_TYPE_HTTP = "http"
+const PROBE_TYPE_TCP = "tcp"
+const PROBE_TYPE_EXEC = "exec"
+
+const PROBE_DEFAULT_TIMEOUT = 1
+const PROBE_DEFAULT_FAILURE_THRESHOLD = 3
+
+var ProbeTypes = []string{PROBE_TYPE_HTTP, PROBE_TYPE_TCP, PROBE_TYPE_EXEC}
+
+// StormfrontProbe checks the health of an instance from the node running it.
+// HTTP and TCP probes connect to the host port published for Port, which is
+// the port inside the container. Probes can not run more often than the
+// node's health check loop. An instance stops being ready on its first failed
+// readiness probe, while FailureThreshold consecutive liveness failures are
+// needed before it is restarted.
+type StormfrontProbe struct {
+	Type             string   `json:"type" yaml:"type"`
+	Path             string   `json:"path" yaml:"path"`
+	Port             string   `json:"port" yaml:"port"`
+	Command          []string `json:"command" yaml:"command"`
+	InitialDelay     int      `json:"initial_delay" yaml:"initial_delay"`
+	Period           int      `json:"period" yaml:"period"`
+	Timeout          int      `json:"timeout" yaml:"timeout"`
+	FailureThreshold int      `json:"failure_threshold" yaml:"failure_threshold"`
+}
+
+// validateProbe checks a probe definition and fills in its defaults
+func validateProbe(probe *StormfrontProbe, ports map[string]string) error {
+	if probe == nil {
+		return nil
+	}
+	switch probe.Type {
+	case PROBE_TYPE_HTTP:
+		if probe.Path == "" {
+			probe.Path = "/"
+		}
+		fallthrough
+	case PROBE_TYPE_TCP:
+		if probe.Port == "" {
+			return fmt.Errorf("%s probes require a port", probe.Type)
+		}
+		if _, err := probeHostPort(probe.Port, ports); err != nil {
+			return err
+		}
+	case PROBE_TYPE_EXEC:
+		if len(probe.Command) == 0 {
+			return errors.New("exec probes require a command")
+		}
+	default:
+		return fmt.Errorf("invalid probe type %s, allowed types are %s", probe.Type, strings.Join(ProbeTypes, ", "))
+	}
+	if probe.InitialDelay < 0 || probe.Period < 0 || probe.Timeout < 0 || probe.FailureThreshold < 0 {
+		return errors.New("probe timings must not be negative")
+	}
+	if probe.Period == 0 {
+		probe.Period = HEALTH_CHECK_DELAY
+	}
+	if probe.Timeout == 0 {
+		probe.Timeout = PROBE_DEFAULT_TIMEOUT
+	}
+	if probe.FailureThreshold == 0 {
+		probe.FailureThreshold = PROBE_DEFAULT_FAILURE_THRESHOLD
+	}
+	return nil
+}
+
+func probesEqual(a, b *StormfrontProbe) bool {
+	return reflect.DeepEqual(a, b)
+}
+
+// probeHostPort finds the host port a container port is published on
+func probeHostPort(containerPort string, ports map[string]string) (string, error) {
+	for hostPort, port := range ports {
+		if port == containerPort {
+			return hostPort, nil
+		}
+	}
+	return "", fmt.Errorf("probe port %s is not published in the application's ports", containerPort)
+}
+
+// runProbe executes a single probe against an instance, returning an error
+// describing why it failed
+func runProbe(probe *StormfrontProbe, app StormfrontApplication, name string) error {
+	timeout := time.Duration(probe.Timeout) * time.Second
+
+	switch probe.Type {
+	case PROBE_TYPE_HTTP:
+		hostPort, err := probeHostPort(probe.Port, app.Ports)
+		if err != nil {
+			return err
+		}
+		httpClient := &http.Client{Timeout: timeout}
+		resp, err := httpClient.Get(fmt.Sprintf("http://%s:%s%s", Client.Host, hostPort, probe.Path))
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+		if resp.StatusCode < 200 || resp.StatusCode >= 400 {
+			return fmt.Errorf("GET %s returned status code %v", probe.Path, resp.StatusCode)
+		}
+	case PROBE_TYPE_TCP:
+		hostPort, err := probeHostPort(probe.Port, app.Ports)
+		if err != nil {
+			return err
+		}
+		conn, err := net.DialTimeout("tcp", net.JoinHostPort(Client.Host, hostPort), timeout)
+		if err != nil {
+			return err
+		}
+		conn.Close()
+	case PROBE_TYPE_EXEC:
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+		if _, err := Runtime.Exec(ctx, name, probe.Command); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// probeInstance runs the liveness and readiness probes of a running instance
+// when they are due. It reports whether the liveness probe has failed enough
+// times in a row that the container should be restarted.
+func probeInstance(app StormfrontApplication, name string, state *instanceState) bool {
+	if app.LivenessProbe == nil && app.ReadinessProbe == nil {
+		state.Ready = true
+		return false
+	}
+
+	if probe := app.ReadinessProbe; probe == nil {
+		state.Ready = true
+	} else if probeDue(probe, state.StartedAt, state.ReadinessProbed) {
+		state.ReadinessProbed = time.Now()
+		if err := runProbe(probe, app, name); err != nil {
+			if state.Ready {
+				reconcilerLog.Warn("Readiness probe failed", "application", app.Name, "container", name, "error", err)
+			}
+			state.Ready = false
+			state.ProbeMessage = fmt.Sprintf("readiness: %v", err)
+		} else {
+			state.Ready = true
+			state.ProbeMessage = ""
+		}
+	}
+
+	probe := app.LivenessProbe
+	if probe == nil || !probeDue(probe, state.StartedAt, state.LivenessProbed) {
+		return false
+	}
+	state.LivenessProbed = time.Now()
+	if err := runProbe(probe, app, name); err != nil {
+		state.LivenessFailures++
+		state.ProbeMessage = fmt.Sprintf("liveness: %v", err)
+		reconcilerLog.Warn("Liveness probe failed", "application", app.Name, "container", name, "failures", state.LivenessFailures, "threshold", probe.FailureThreshold, "error", err)
+		return state.LivenessFailures >= probe.FailureThreshold
+	}
+	state.LivenessFailures = 0
+	return false
+}
+
+func probeDue(probe *StormfrontProbe, startedAt, lastProbe time.Time) bool {
+	if time.Since(startedAt) < time.Duration(probe.InitialDelay)*time.Second {
+		return false
+	}
+	return time.Since(lastProbe) >= time.Duration(probe.Period)*time.Second
+}
@@ -0,0 +1,129 @@
+package client
+
+import (
+	"errors"
+	"stormfrontd/client/engine"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestValidateProbe(t *testing.T) {
+	ports := map[string]string{"8080": "80"}
+
+	tests := []struct {
+		name     string
+		probe    *StormfrontProbe
+		err      string
+		expected *StormfrontProbe
+	}{
+		{name: "no probe", probe: nil},
+		{
+			name:     "http defaults",
+			probe:    &StormfrontProbe{Type: PROBE_TYPE_HTTP, Port: "80"},
+			expected: &StormfrontProbe{Type: PROBE_TYPE_HTTP, Path: "/", Port: "80", Period: HEALTH_CHECK_DELAY, Timeout: PROBE_DEFAULT_TIMEOUT, FailureThreshold: PROBE_DEFAULT_FAILURE_THRESHOLD},
+		},
+		{
+			name:     "exec keeps its timings",
+			probe:    &StormfrontProbe{Type: PROBE_TYPE_EXEC, Command: []string{"true"}, Period: 30, Timeout: 5, FailureThreshold: 1},
+			expected: &StormfrontProbe{Type: PROBE_TYPE_EXEC, Command: []string{"true"}, Period: 30, Timeout: 5, FailureThreshold: 1},
+		},
+		{name: "tcp without port", probe: &StormfrontProbe{Type: PROBE_TYPE_TCP}, err: "require a port"},
+		{name: "port not published", probe: &StormfrontProbe{Type: PROBE_TYPE_TCP, Port: "443"}, err: "not published"},
+		{name: "exec without command", probe: &StormfrontProbe{Type: PROBE_TYPE_EXEC}, err: "require a command"},
+		{name: "unknown type", probe: &StormfrontProbe{Type: "grpc"}, err: "invalid probe type"},
+		{name: "negative timing", probe: &StormfrontProbe{Type: PROBE_TYPE_EXEC, Command: []string{"true"}, Period: -1}, err: "must not be negative"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := validateProbe(test.probe, ports)
+			if test.err != "" {
+				if err == nil || !strings.Contains(err.Error(), test.err) {
+					t.Fatalf("expected error containing %q, got %v", test.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if test.expected != nil && !probesEqual(test.probe, test.expected) {
+				t.Fatalf("expected %+v, got %+v", test.expected, test.probe)
+			}
+		})
+	}
+}
+
+func TestProbeDue(t *testing.T) {
+	probe := &StormfrontProbe{InitialDelay: 10, Period: 5}
+	now := time.Now()
+
+	tests := []struct {
+		name      string
+		startedAt time.Time
+		lastProbe time.Time
+		due       bool
+	}{
+		{name: "within initial delay", startedAt: now.Add(-5 * time.Second), due: false},
+		{name: "first probe after initial delay", startedAt: now.Add(-11 * time.Second), due: true},
+		{name: "within period", startedAt: now.Add(-time.Minute), lastProbe: now.Add(-2 * time.Second), due: false},
+		{name: "period passed", startedAt: now.Add(-time.Minute), lastProbe: now.Add(-6 * time.Second), due: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if due := probeDue(probe, test.startedAt, test.lastProbe); due != test.due {
+				t.Fatalf("expected due to be %v, got %v", test.due, due)
+			}
+		})
+	}
+}
+
+func TestProbeInstance(t *testing.T) {
+	fake := engine.NewFakeRuntime()
+	previous := Runtime
+	Runtime = fake
+	defer func() { Runtime = previous }()
+	fake.Run(engine.ContainerSpec{Name: "web"})
+
+	app := StormfrontApplication{
+		Name:           "web",
+		LivenessProbe:  &StormfrontProbe{Type: PROBE_TYPE_EXEC, Command: []string{"check"}, Timeout: 1, FailureThreshold: 2},
+		ReadinessProbe: &StormfrontProbe{Type: PROBE_TYPE_EXEC, Command: []string{"ready"}, Timeout: 1},
+	}
+
+	tests := []struct {
+		name     string
+		err      error
+		restart  bool
+		ready    bool
+		failures int
+	}{
+		{name: "passing probes", ready: true},
+		{name: "first liveness failure", err: errors.New("unhealthy"), failures: 1},
+		{name: "failure threshold reached", err: errors.New("unhealthy"), restart: true, failures: 2},
+		{name: "recovered", ready: true},
+	}
+
+	state := instanceState{StartedAt: time.Now().Add(-time.Minute)}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if test.err != nil {
+				fake.ExecErrors["web"] = test.err
+			} else {
+				delete(fake.ExecErrors, "web")
+			}
+			// Make both probes due again
+			state.LivenessProbed = time.Time{}
+			state.ReadinessProbed = time.Time{}
+
+			restart := probeInstance(app, "web", &state)
+			if restart != test.restart || state.Ready != test.ready || state.LivenessFailures != test.failures {
+				t.Fatalf("expected restart %v, ready %v and %d failures, got %v, %v and %d", test.restart, test.ready, test.failures, restart, state.Ready, state.LivenessFailures)
+			}
+			if test.err != nil && !strings.Contains(state.ProbeMessage, test.err.Error()) {
+				t.Errorf("expected probe message to carry the error, got %q", state.ProbeMessage)
+			}
+		})
+	}
+}
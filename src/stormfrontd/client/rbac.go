@@ -0,0 +1,84 @@
+package client
+
+import (
+	"fmt"
+	"net/http"
+	"stormfrontd/client/auth"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jfcarter2358/ceresdb-go/connection"
+)
+
+// requestAPIToken returns the API token a request was authenticated with,
+// requests from nodes use access tokens and have none
+func requestAPIToken(c *gin.Context) (auth.APIToken, bool) {
+	value, ok := c.Get(auth.API_TOKEN_CONTEXT_KEY)
+	if !ok {
+		return auth.APIToken{}, false
+	}
+	apiToken, ok := value.(auth.APIToken)
+	return apiToken, ok
+}
+
+// requestIdentity names who made a request for token creation metadata
+func requestIdentity(c *gin.Context) string {
+	if apiToken, ok := requestAPIToken(c); ok {
+		return apiToken.Name
+	}
+	return "node"
+}
+
+// namespaceAllowed reports whether the request may reach objects in namespace
+func namespaceAllowed(c *gin.Context, namespace string) bool {
+	apiToken, ok := requestAPIToken(c)
+	if !ok {
+		return true
+	}
+	return apiToken.HasNamespace(namespace)
+}
+
+// authorizeNamespace responds with a 403 and returns false if the request may
+// not reach objects in namespace
+func authorizeNamespace(c *gin.Context, namespace string) bool {
+	if namespaceAllowed(c, namespace) {
+		return true
+	}
+	apiToken, _ := requestAPIToken(c)
+	c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": fmt.Sprintf("API token %s is not allowed to access namespace %s", apiToken.Name, namespace)})
+	return false
+}
+
+// namespaceScope returns middleware which rejects requests for the object in
+// collection named by the id parameter when it lives in a namespace outside
+// the API token's scope, unknown objects are left to the handler. Object IDs
+// are UUIDs, anything else is rejected before it reaches a query.
+func namespaceScope(collection string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.Param("id")
+		if _, err := uuid.Parse(id); err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("invalid %s id '%s'", collection, id)})
+			return
+		}
+
+		apiToken, ok := requestAPIToken(c)
+		if !ok || len(apiToken.Namespaces) == 0 {
+			c.Next()
+			return
+		}
+
+		data, err := connection.Query(fmt.Sprintf(`get record stormfront.%s | filter id = '%s'`, collection, id))
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if len(data) > 0 {
+			namespace, _ := data[0]["namespace"].(string)
+			if !authorizeNamespace(c, namespace) {
+				return
+			}
+		}
+
+		c.Next()
+	}
+}
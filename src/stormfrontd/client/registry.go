@@ -0,0 +1,234 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"stormfrontd/client/auth"
+	"stormfrontd/client/engine"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jfcarter2358/ceresdb-go/connection"
+)
+
+const IMAGE_PULL_POLICY_ALWAYS = "Always"
+const IMAGE_PULL_POLICY_IF_NOT_PRESENT = "IfNotPresent"
+const IMAGE_PULL_POLICY_NEVER = "Never"
+
+const IMAGE_PULL_ERROR_STATUS = "ImagePullError"
+
+const DEFAULT_REGISTRY = "docker.io"
+const REDACTED_VALUE = "********"
+
+var ImagePullPolicies = []string{IMAGE_PULL_POLICY_ALWAYS, IMAGE_PULL_POLICY_IF_NOT_PRESENT, IMAGE_PULL_POLICY_NEVER}
+
+// StormfrontRegistry holds the credentials used by every node to pull images
+// from a private registry. The records replicate to followers along with the
+// rest of the database so each node can authenticate its own pulls, with the
+// password encrypted under the cluster key.
+type StormfrontRegistry struct {
+	ID       string `json:"id" yaml:"id"`
+	Name     string `json:"name" yaml:"name"`
+	Registry string `json:"registry" yaml:"registry"`
+	Username string `json:"username" yaml:"username"`
+	Password string `json:"password" yaml:"password"`
+}
+
+// imagePullError marks deployment failures caused by the image not being
+// available so that they can be reported separately from container failures
+type imagePullError struct {
+	image string
+	err   error
+}
+
+func (e *imagePullError) Error() string {
+	return fmt.Sprintf("unable to pull image %s: %v", e.image, e.err)
+}
+
+func (app StormfrontApplication) imagePullPolicy() string {
+	if app.ImagePullPolicy == "" {
+		return IMAGE_PULL_POLICY_IF_NOT_PRESENT
+	}
+	return app.ImagePullPolicy
+}
+
+// registryForImage returns the registry host an image reference points at,
+// following the docker convention that the first path component is only a
+// registry if it looks like a hostname
+func registryForImage(image string) string {
+	parts := strings.SplitN(image, "/", 2)
+	if len(parts) == 1 {
+		return DEFAULT_REGISTRY
+	}
+	if strings.ContainsAny(parts[0], ".:") || parts[0] == "localhost" {
+		return parts[0]
+	}
+	return DEFAULT_REGISTRY
+}
+
+func getRegistries() ([]StormfrontRegistry, error) {
+	registryData, err := connection.Query("get record stormfront.registry")
+	if err != nil {
+		return nil, err
+	}
+	registries := []StormfrontRegistry{}
+	registryBytes, err := json.Marshal(registryData)
+	if err != nil {
+		return nil, err
+	}
+	err = json.Unmarshal(registryBytes, &registries)
+	if err != nil {
+		return nil, err
+	}
+
+	return registries, nil
+}
+
+func getRegistryCredential(image string) (*engine.RegistryCredential, error) {
+	registries, err := getRegistries()
+	if err != nil {
+		return nil, err
+	}
+	host := registryForImage(image)
+	for _, registry := range registries {
+		if registry.Registry == host {
+			password, err := auth.Decrypt(registry.Password)
+			if err != nil {
+				return nil, fmt.Errorf("unable to decrypt password for registry %s: %v", registry.Name, err)
+			}
+			return &engine.RegistryCredential{Registry: registry.Registry, Username: registry.Username, Password: password}, nil
+		}
+	}
+	return nil, nil
+}
+
+// pullImage makes sure the application's image is available on this node
+// according to its pull policy
+func pullImage(app StormfrontApplication) error {
+	policy := app.imagePullPolicy()
+
+	if policy != IMAGE_PULL_POLICY_ALWAYS {
+		exists, err := Runtime.ImageExists(app.Image)
+		if err != nil {
+			return &imagePullError{image: app.Image, err: err}
+		}
+		if exists {
+			return nil
+		}
+		if policy == IMAGE_PULL_POLICY_NEVER {
+			return &imagePullError{image: app.Image, err: fmt.Errorf("image is not present and pull policy is %s", IMAGE_PULL_POLICY_NEVER)}
+		}
+	}
+
+	credential, err := getRegistryCredential(app.Image)
+	if err != nil {
+		return &imagePullError{image: app.Image, err: err}
+	}
+
+	reconcilerLog.Info("Pulling image", "application", app.Name, "image", app.Image)
+	err = Runtime.Pull(app.Image, credential)
+	if err != nil {
+		return &imagePullError{image: app.Image, err: err}
+	}
+	return nil
+}
+
+func CreateRegistry(c *gin.Context) {
+	if Client.Type != "Leader" {
+		c.Redirect(http.StatusTemporaryRedirect, fmt.Sprintf("https://%s:%v/api/registry", Client.Leader.Host, Client.Leader.Port))
+		return
+	}
+
+	var registry StormfrontRegistry
+	if err := c.BindJSON(&registry); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if registry.Name == "" || registry.Registry == "" || registry.Username == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "name, registry and username are required"})
+		return
+	}
+
+	registries, err := getRegistries()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	for _, existing := range registries {
+		if existing.Name == registry.Name {
+			c.JSON(http.StatusConflict, gin.H{"error": fmt.Sprintf("registry %s already exists", registry.Name)})
+			return
+		}
+		if existing.Registry == registry.Registry {
+			c.JSON(http.StatusConflict, gin.H{"error": fmt.Sprintf("credentials for %s already exist as %s", registry.Registry, existing.Name)})
+			return
+		}
+	}
+
+	registry.Password, err = auth.Encrypt(registry.Password)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("unable to encrypt registry password: %v", err)})
+		return
+	}
+
+	registry.ID = uuid.NewString()
+	registryData, _ := json.Marshal(registry)
+	_, err = connection.Query(fmt.Sprintf("post record stormfront.registry %s", registryData))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("unable to create registry: %v", err.Error())})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"id": registry.ID})
+}
+
+func GetAllRegistries(c *gin.Context) {
+	if Client.Type != "Leader" {
+		c.Redirect(http.StatusTemporaryRedirect, fmt.Sprintf("https://%s:%v/api/registry", Client.Leader.Host, Client.Leader.Port))
+		return
+	}
+
+	registries, err := getRegistries()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	for idx := range registries {
+		registries[idx].Password = REDACTED_VALUE
+	}
+
+	c.JSON(http.StatusOK, registries)
+}
+
+func DeleteRegistry(c *gin.Context) {
+	id := c.Param("id")
+
+	if Client.Type != "Leader" {
+		c.Redirect(http.StatusTemporaryRedirect, fmt.Sprintf("https://%s:%v/api/registry/%s", Client.Leader.Host, Client.Leader.Port, id))
+		return
+	}
+
+	registries, err := getRegistries()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	for _, registry := range registries {
+		if registry.ID != id && registry.Name != id {
+			continue
+		}
+		_, err := connection.Query(fmt.Sprintf(`get record stormfront.registry .id | filter id = '%s' | delete record stormfront.registry -`, registry.ID))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.Status(http.StatusNoContent)
+		return
+	}
+
+	c.Status(http.StatusNotFound)
+}
@@ -0,0 +1,149 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"stormfrontd/config"
+	"strings"
+	"time"
+
+	"github.com/jfcarter2358/ceresdb-go/connection"
+)
+
+const RESCHEDULE_HISTORY_LENGTH = 10
+
+type StormfrontReschedule struct {
+	Instance string `json:"instance" yaml:"instance"`
+	From     string `json:"from" yaml:"from"`
+	To       string `json:"to" yaml:"to"`
+	Reason   string `json:"reason" yaml:"reason"`
+	Time     string `json:"time" yaml:"time"`
+}
+
+// setNodeHealth records the result of the latest health check against a node.
+// The time a node was first seen as unknown is kept so that the grace period
+// before rescheduling is measured from the start of the outage.
+func setNodeHealth(id, health string) error {
+	nodeData, err := connection.Query(fmt.Sprintf(`get record stormfront.node | filter id = "%s"`, id))
+	if err != nil {
+		return err
+	}
+	if len(nodeData) == 0 {
+		return nil
+	}
+
+	var node StormfrontNode
+	nodeBytes, _ := json.Marshal(nodeData[0])
+	json.Unmarshal(nodeBytes, &node)
+
+	if node.Health == health {
+		return nil
+	}
+
+	unknownSince := ""
+	if health != "Healthy" {
+		unknownSince = node.UnknownSince
+		if unknownSince == "" {
+			unknownSince = time.Now().Format(time.RFC3339)
+		}
+	}
+
+	healthLog.Warn("Node health changed", "node", id, "from", node.Health, "to", health)
+	eventType := EVENT_NODE_UNKNOWN
+	if health == "Healthy" {
+		eventType = EVENT_NODE_HEALTHY
+	}
+	recordEvent(nodeEvent(eventType, id, "Node health changed from %s to %s", node.Health, health))
+	_, err = connection.Query(fmt.Sprintf(`patch record stormfront.node '%s' {"health":"%s","unknown_since":"%s"}`, nodeData[0][".id"].(string), health, unknownSince))
+	return err
+}
+
+// rescheduleApplications moves applications off of nodes which have been
+// unreachable for longer than the configured grace period, or which are no
+// longer registered at all, onto whichever healthy node the scheduler picks
+func rescheduleApplications() error {
+	nodes, err := getNodes()
+	if err != nil {
+		return err
+	}
+	applicationData, err := connection.Query("get record stormfront.application")
+	if err != nil {
+		return err
+	}
+
+	applications := []StormfrontApplication{}
+	applicationBytes, _ := json.Marshal(applicationData)
+	json.Unmarshal(applicationBytes, &applications)
+
+	gracePeriod := time.Duration(config.Config.RescheduleGracePeriod) * time.Second
+
+	for idx := range applications {
+		app := &applications[idx]
+		moved := false
+
+		for instanceIdx, instance := range app.Instances {
+			reason := ""
+			found := false
+			for _, node := range nodes {
+				if node.ID != instance.Node {
+					continue
+				}
+				found = true
+				if node.Health == "Healthy" {
+					break
+				}
+				unknownSince, err := time.Parse(time.RFC3339, node.UnknownSince)
+				if err != nil || time.Since(unknownSince) < gracePeriod {
+					break
+				}
+				reason = fmt.Sprintf("node %s has been %s since %s, exceeding the %v grace period", node.ID, node.Health, node.UnknownSince, gracePeriod)
+				break
+			}
+			if !found {
+				reason = fmt.Sprintf("node %s is no longer registered", instance.Node)
+			}
+			if reason == "" {
+				continue
+			}
+
+			decision, err := scheduleInstance(*app, instance.Name, "", nodes, applications)
+			if err != nil {
+				schedulerLog.Error("Unable to reschedule instance", "application", app.Name, "namespace", app.Namespace, "instance", instance.Name, "reason", reason, "explanation", decision.Explanation, "error", err)
+				recordRepeatingEvent(applicationEvent(EVENT_SCHEDULING_FAILED, *app, "Unable to reschedule instance %s: %s, but %v: %s", instance.Name, reason, err, strings.Join(decision.Explanation, "; ")))
+				continue
+			}
+			target := decision.Node
+
+			schedulerLog.Info("Rescheduling instance", "application", app.Name, "namespace", app.Namespace, "instance", instance.Name, "from", instance.Node, "to", target, "reason", reason)
+			recordEvent(applicationEvent(EVENT_APPLICATION_RESCHEDULED, *app, "Rescheduled instance %s from node %s to %s: %s", instance.Name, instance.Node, target, reason))
+
+			reserveResources(nodes, target, *app)
+			app.Instances[instanceIdx].Node = target
+			app.Reschedules = append(app.Reschedules, StormfrontReschedule{
+				Instance: instance.Name,
+				From:     instance.Node,
+				To:       target,
+				Reason:   reason,
+				Time:     time.Now().Format(time.RFC3339),
+			})
+			moved = true
+		}
+
+		if !moved {
+			continue
+		}
+
+		if len(app.Reschedules) > RESCHEDULE_HISTORY_LENGTH {
+			app.Reschedules = app.Reschedules[len(app.Reschedules)-RESCHEDULE_HISTORY_LENGTH:]
+		}
+		instancesBytes, _ := json.Marshal(app.Instances)
+		reschedulesBytes, _ := json.Marshal(app.Reschedules)
+
+		_, err = connection.Query(fmt.Sprintf(`patch record stormfront.application '%s' {"instances":%s,"reschedules":%s}`, applicationData[idx][".id"].(string), instancesBytes, reschedulesBytes))
+		if err != nil {
+			schedulerLog.Error("Unable to update application instances", "application", app.ID, "error", err)
+		}
+	}
+
+	return nil
+}
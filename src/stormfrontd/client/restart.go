@@ -0,0 +1,301 @@
+package client
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+const RESTART_POLICY_ALWAYS = "always"
+const RESTART_POLICY_ON_FAILURE = "on-failure"
+const RESTART_POLICY_NEVER = "never"
+
+const RESTART_BACKOFF_INITIAL = 10
+const RESTART_BACKOFF_MAX = 300
+const RESTART_BACKOFF_RESET = 600
+
+const CRASH_LOOP_BACKOFF_STATUS = "CrashLoopBackOff"
+
+var RestartPolicies = []string{RESTART_POLICY_ALWAYS, RESTART_POLICY_ON_FAILURE, RESTART_POLICY_NEVER}
+
+// instanceState is what a node remembers about the containers it runs between
+// passes of the reconciler
+type instanceState struct {
+	Restarts         int
+	ExitCode         int
+	Backoff          time.Duration
+	NextRestart      time.Time
+	StartedAt        time.Time
+	PullError        string
+	DeployError      string
+	PullBackoff      time.Duration
+	NextPull         time.Time
+	Ready            bool
+	LivenessFailures int
+	LivenessProbed   time.Time
+	ReadinessProbed  time.Time
+	ProbeMessage     string
+}
+
+// started resets the state kept about a container after it has been
+// (re)deployed
+func (state *instanceState) started() {
+	state.StartedAt = time.Now()
+	state.Ready = false
+	state.LivenessFailures = 0
+	state.LivenessProbed = time.Time{}
+	state.ReadinessProbed = time.Time{}
+	state.ProbeMessage = ""
+}
+
+var instanceStates = map[string]*instanceState{}
+var instanceStatesLock sync.Mutex
+
+// instanceLocks serialize the deploys, restarts and probes of each instance.
+// They are held while containers are pulled and started so that a slow
+// instance only holds up itself, instanceStatesLock is only ever held for as
+// long as it takes to read or write a state.
+var instanceLocks = map[string]*sync.Mutex{}
+
+func lockInstance(name string) func() {
+	instanceStatesLock.Lock()
+	lock, ok := instanceLocks[name]
+	if !ok {
+		lock = &sync.Mutex{}
+		instanceLocks[name] = lock
+	}
+	instanceStatesLock.Unlock()
+
+	lock.Lock()
+	return lock.Unlock
+}
+
+// loadInstanceState returns a copy of the state of an instance which can be
+// worked on without holding instanceStatesLock
+func loadInstanceState(name string, status StormfrontApplicationStatus) instanceState {
+	instanceStatesLock.Lock()
+	defer instanceStatesLock.Unlock()
+
+	return *getInstanceState(name, status)
+}
+
+func storeInstanceState(name string, state instanceState) {
+	instanceStatesLock.Lock()
+	defer instanceStatesLock.Unlock()
+
+	if existing, ok := instanceStates[name]; ok {
+		*existing = state
+		return
+	}
+	instanceStates[name] = &state
+}
+
+// getInstanceState returns the state for a container, creating it from the
+// last status written to the database if this node has not seen it before so
+// that restart counts survive a daemon restart. instanceStatesLock must be
+// held.
+func getInstanceState(name string, status StormfrontApplicationStatus) *instanceState {
+	state, ok := instanceStates[name]
+	if !ok {
+		state = &instanceState{Restarts: status.Restarts, ExitCode: status.ExitCode, StartedAt: time.Now()}
+		instanceStates[name] = state
+	}
+	return state
+}
+
+func forgetInstanceState(name string) {
+	instanceStatesLock.Lock()
+	defer instanceStatesLock.Unlock()
+
+	delete(instanceStates, name)
+}
+
+func (app StormfrontApplication) restartPolicy() string {
+	if app.RestartPolicy == "" {
+		return RESTART_POLICY_ALWAYS
+	}
+	return app.RestartPolicy
+}
+
+func shouldRestart(policy string, exitCode int) bool {
+	switch policy {
+	case RESTART_POLICY_NEVER:
+		return false
+	case RESTART_POLICY_ON_FAILURE:
+		return exitCode != 0
+	}
+	return true
+}
+
+// reconcileInstance makes sure a single instance of an application is running
+// as defined. Containers which have exited are restarted according to the
+// application's restart policy, waiting twice as long after each restart up to
+// RESTART_BACKOFF_MAX seconds. The backoff is cleared once a container has
+// stayed up for RESTART_BACKOFF_RESET seconds.
+func reconcileInstance(app StormfrontApplication, name string, changed bool) {
+	defer lockInstance(name)()
+
+	state := loadInstanceState(name, app.InstanceStatus[name])
+	defer func() {
+		storeInstanceState(name, state)
+	}()
+
+	info, err := Runtime.Inspect(name)
+	if err != nil || changed {
+		// A change to the application may fix the image or whatever else
+		// failed the last deploy, so it is deployed again straight away
+		if !changed && (time.Now().Before(state.NextPull) || time.Now().Before(state.NextRestart)) {
+			return
+		}
+		if recordDeployError(&state, deployApplication(app, name, true, false)) {
+			return
+		}
+		state.Backoff = 0
+		state.NextRestart = time.Time{}
+		state.started()
+		return
+	}
+
+	if info.Running {
+		if state.Backoff > 0 && time.Since(state.StartedAt) > RESTART_BACKOFF_RESET*time.Second {
+			reconcilerLog.Debug("Container is stable, resetting restart backoff", "container", name, "uptime_seconds", RESTART_BACKOFF_RESET)
+			state.Backoff = 0
+			state.NextRestart = time.Time{}
+		}
+		// A container failing its liveness probe is stopped here and then
+		// restarted according to its restart policy on the next pass
+		if probeInstance(app, name, &state) {
+			reconcilerLog.Warn("Container failed its liveness probe, stopping it", "application", app.Name, "container", name)
+			recordEvent(containerEvent(EVENT_CONTAINER_KILLED, app, name, "Stopped container after %v failed liveness probes, %s", app.LivenessProbe.FailureThreshold, state.ProbeMessage))
+			if err := Runtime.Stop(name); err != nil {
+				reconcilerLog.Error("Unable to stop container", "container", name, "error", err)
+			}
+			state.Ready = false
+			state.LivenessFailures = 0
+		}
+		return
+	}
+
+	state.Ready = false
+
+	state.ExitCode = info.ExitCode
+	if !shouldRestart(app.restartPolicy(), info.ExitCode) {
+		return
+	}
+	if time.Now().Before(state.NextRestart) || time.Now().Before(state.NextPull) {
+		reconcilerLog.Debug("Container exited, waiting to restart", "container", name, "exit_code", info.ExitCode, "next_restart", state.NextRestart.Format(time.RFC3339))
+		return
+	}
+
+	reconcilerLog.Info("Container exited, restarting", "application", app.Name, "container", name, "exit_code", info.ExitCode, "restart_policy", app.restartPolicy())
+	recordEvent(containerEvent(EVENT_CONTAINER_RESTARTED, app, name, "Restarting container after it exited with code %v under restart policy %s", info.ExitCode, app.restartPolicy()))
+	if recordDeployError(&state, deployApplication(app, name, true, false)) {
+		return
+	}
+
+	state.Restarts++
+	state.Backoff = nextBackoff(state.Backoff)
+	state.NextRestart = time.Now().Add(state.Backoff)
+	state.started()
+}
+
+// restartInstance is used for restarts requested through the API, which clear
+// any backoff the instance is in
+func restartInstance(app StormfrontApplication, name string) {
+	defer lockInstance(name)()
+
+	recordEvent(containerEvent(EVENT_CONTAINER_RESTARTED, app, name, "Restarting container on request"))
+	destroyApplication(name, false)
+	err := deployApplication(app, name, false, false)
+
+	state := loadInstanceState(name, app.InstanceStatus[name])
+	defer func() {
+		storeInstanceState(name, state)
+	}()
+	state.Backoff = 0
+	state.NextRestart = time.Time{}
+	if recordDeployError(&state, err) {
+		return
+	}
+	state.Restarts++
+	state.started()
+}
+
+// nextBackoff doubles a backoff, starting from RESTART_BACKOFF_INITIAL
+// seconds and going up to RESTART_BACKOFF_MAX seconds
+func nextBackoff(backoff time.Duration) time.Duration {
+	if backoff == 0 {
+		return RESTART_BACKOFF_INITIAL * time.Second
+	}
+	backoff *= 2
+	if backoff > RESTART_BACKOFF_MAX*time.Second {
+		return RESTART_BACKOFF_MAX * time.Second
+	}
+	return backoff
+}
+
+// recordDeployError reports whether a deployment failed. Any failure other
+// than an image pull, such as a port conflict or a bad mount, backs off like
+// a crash so that the instance is not redeployed on every pass and shows as
+// CrashLoopBackOff in the meantime.
+func recordDeployError(state *instanceState, err error) bool {
+	if recordPullError(state, err) {
+		return true
+	}
+	if err == nil {
+		state.DeployError = ""
+		return false
+	}
+	state.DeployError = err.Error()
+	state.Backoff = nextBackoff(state.Backoff)
+	state.NextRestart = time.Now().Add(state.Backoff)
+	return true
+}
+
+// recordPullError keeps the latest image pull failure for an instance and
+// reports whether the deployment failed because of one. Failed pulls are
+// retried with the same backoff as restarts so that a missing image or bad
+// credentials do not hit the registry on every pass.
+func recordPullError(state *instanceState, err error) bool {
+	var pullErr *imagePullError
+	if errors.As(err, &pullErr) {
+		state.PullError = pullErr.Error()
+		state.PullBackoff = nextBackoff(state.PullBackoff)
+		state.NextPull = time.Now().Add(state.PullBackoff)
+		return true
+	}
+	state.PullError = ""
+	state.PullBackoff = 0
+	state.NextPull = time.Time{}
+	return false
+}
+
+// getInstanceStatus reports the container status of an instance along with
+// its restart history. An instance whose image could not be pulled is
+// reported as ImagePullError and an exited or undeployable container waiting
+// out its backoff as CrashLoopBackOff. Ready reflects the instance's readiness probe, or that
+// the container is running if it has none.
+func getInstanceStatus(app StormfrontApplication, name string) StormfrontApplicationStatus {
+	status, cpu, memory := getApplicationStatus(name)
+
+	instanceStatesLock.Lock()
+	defer instanceStatesLock.Unlock()
+
+	state := getInstanceState(name, app.InstanceStatus[name])
+	if status != "running" && state.PullError != "" {
+		status = IMAGE_PULL_ERROR_STATUS
+	} else if status != "running" && (state.DeployError != "" || shouldRestart(app.restartPolicy(), state.ExitCode)) && time.Now().Before(state.NextRestart) {
+		status = CRASH_LOOP_BACKOFF_STATUS
+	}
+
+	return StormfrontApplicationStatus{
+		Status:           status,
+		CPU:              cpu,
+		Memory:           memory,
+		Restarts:         state.Restarts,
+		ExitCode:         state.ExitCode,
+		Ready:            status == "running" && state.Ready,
+		LivenessFailures: state.LivenessFailures,
+		ProbeMessage:     state.ProbeMessage,
+	}
+}
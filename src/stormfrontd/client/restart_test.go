@@ -0,0 +1,207 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"stormfrontd/client/engine"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jfcarter2358/ceresdb-go/connection"
+)
+
+// startFakeRuntime swaps the container runtime for a FakeRuntime and points
+// the database at a server which accepts every write, so that instances can
+// be reconciled as they would be on a leader without any containers or
+// database running
+func startFakeRuntime(t *testing.T) *engine.FakeRuntime {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload map[string]string
+		json.NewDecoder(r.Body).Decode(&payload)
+		if strings.HasPrefix(payload["query"], "get record stormfront.client") {
+			w.Write([]byte(`[{".id":"client"}]`))
+			return
+		}
+		w.Write([]byte("[]"))
+	}))
+	t.Cleanup(server.Close)
+	serverURL, _ := url.Parse(server.URL)
+	port, _ := strconv.Atoi(serverURL.Port())
+	connection.Initialize("ceresdb", "ceresdb", serverURL.Hostname(), port)
+
+	fake := engine.NewFakeRuntime()
+	previousRuntime, previousClient := Runtime, Client
+	Runtime = fake
+	Client = StormfrontClient{ID: "node", Type: "Leader", Host: "127.0.0.1"}
+	t.Cleanup(func() {
+		Runtime, Client = previousRuntime, previousClient
+	})
+	return fake
+}
+
+func TestReconcileInstanceRestartPolicy(t *testing.T) {
+	tests := []struct {
+		name      string
+		policy    string
+		exitCode  int
+		restarted bool
+	}{
+		{name: "always after success", policy: RESTART_POLICY_ALWAYS, exitCode: 0, restarted: true},
+		{name: "always after failure", policy: RESTART_POLICY_ALWAYS, exitCode: 1, restarted: true},
+		{name: "default policy", policy: "", exitCode: 0, restarted: true},
+		{name: "on-failure after success", policy: RESTART_POLICY_ON_FAILURE, exitCode: 0, restarted: false},
+		{name: "on-failure after failure", policy: RESTART_POLICY_ON_FAILURE, exitCode: 2, restarted: true},
+		{name: "never", policy: RESTART_POLICY_NEVER, exitCode: 1, restarted: false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			fake := startFakeRuntime(t)
+			name := "reconcile-policy-" + strings.ReplaceAll(test.name, " ", "-")
+			defer forgetInstanceState(name)
+			app := StormfrontApplication{ID: name, Name: name, Image: "web:1", RestartPolicy: test.policy}
+			fake.Images[app.Image] = true
+
+			reconcileInstance(app, name, false)
+			if info, err := fake.Inspect(name); err != nil || !info.Running {
+				t.Fatalf("expected missing container to be deployed, got %+v (%v)", info, err)
+			}
+
+			fake.Exit(name, test.exitCode)
+			reconcileInstance(app, name, false)
+
+			info, _ := fake.Inspect(name)
+			state := loadInstanceState(name, StormfrontApplicationStatus{})
+			if info.Running != test.restarted {
+				t.Fatalf("expected running to be %v, got %v", test.restarted, info.Running)
+			}
+			if test.restarted && (state.Restarts != 1 || state.Backoff != RESTART_BACKOFF_INITIAL*time.Second) {
+				t.Fatalf("expected one restart with the initial backoff, got %d and %v", state.Restarts, state.Backoff)
+			}
+			if !test.restarted && state.ExitCode != test.exitCode {
+				t.Fatalf("expected exit code %d to be kept, got %d", test.exitCode, state.ExitCode)
+			}
+		})
+	}
+}
+
+func TestReconcileInstanceBackoff(t *testing.T) {
+	fake := startFakeRuntime(t)
+	name := "reconcile-backoff"
+	defer forgetInstanceState(name)
+	app := StormfrontApplication{ID: name, Name: name, Image: "web:1"}
+	fake.Images[app.Image] = true
+
+	steps := []struct {
+		name     string
+		advance  bool
+		running  bool
+		restarts int
+		backoff  time.Duration
+	}{
+		{name: "deployed", running: true},
+		{name: "first crash restarts straight away", running: true, restarts: 1, backoff: 10 * time.Second},
+		{name: "second crash waits out the backoff", running: false, restarts: 1, backoff: 10 * time.Second},
+		{name: "restarted after the backoff", advance: true, running: true, restarts: 2, backoff: 20 * time.Second},
+	}
+
+	for idx, step := range steps {
+		if idx > 0 {
+			fake.Exit(name, 1)
+		}
+		if step.advance {
+			state := loadInstanceState(name, StormfrontApplicationStatus{})
+			state.NextRestart = time.Now().Add(-time.Second)
+			storeInstanceState(name, state)
+		}
+		reconcileInstance(app, name, false)
+
+		info, _ := fake.Inspect(name)
+		state := loadInstanceState(name, StormfrontApplicationStatus{})
+		if info.Running != step.running || state.Restarts != step.restarts || state.Backoff != step.backoff {
+			t.Fatalf("%s: expected running %v, %d restarts and %v backoff, got %v, %d and %v", step.name, step.running, step.restarts, step.backoff, info.Running, state.Restarts, state.Backoff)
+		}
+		if !step.running && getInstanceStatus(app, name).Status != CRASH_LOOP_BACKOFF_STATUS {
+			t.Fatalf("%s: expected instance to be reported as %s", step.name, CRASH_LOOP_BACKOFF_STATUS)
+		}
+	}
+}
+
+func TestReconcileInstancePullError(t *testing.T) {
+	fake := startFakeRuntime(t)
+	name := "reconcile-pull"
+	defer forgetInstanceState(name)
+	app := StormfrontApplication{ID: name, Name: name, Image: "missing:1", ImagePullPolicy: IMAGE_PULL_POLICY_ALWAYS}
+	fake.PullErrors[app.Image] = errors.New("manifest unknown")
+
+	reconcileInstance(app, name, false)
+	state := loadInstanceState(name, StormfrontApplicationStatus{})
+	if state.PullError == "" || state.PullBackoff != RESTART_BACKOFF_INITIAL*time.Second {
+		t.Fatalf("expected pull error to be recorded with a backoff, got %q and %v", state.PullError, state.PullBackoff)
+	}
+	if getInstanceStatus(app, name).Status != IMAGE_PULL_ERROR_STATUS {
+		t.Fatalf("expected instance to be reported as %s", IMAGE_PULL_ERROR_STATUS)
+	}
+
+	// The next pass waits out the backoff, a change to the application does not
+	delete(fake.PullErrors, app.Image)
+	reconcileInstance(app, name, false)
+	if _, err := fake.Inspect(name); err == nil {
+		t.Fatal("expected pull to wait for its backoff")
+	}
+	reconcileInstance(app, name, true)
+	if info, err := fake.Inspect(name); err != nil || !info.Running {
+		t.Fatalf("expected changed application to be deployed, got %+v (%v)", info, err)
+	}
+	if state := loadInstanceState(name, StormfrontApplicationStatus{}); state.PullError != "" {
+		t.Fatalf("expected pull error to be cleared, got %q", state.PullError)
+	}
+}
+
+func TestReconcileInstanceDeployError(t *testing.T) {
+	fake := startFakeRuntime(t)
+	name := "reconcile-deploy"
+	defer forgetInstanceState(name)
+	// The volume does not exist, which fails the deploy after the image
+	// has been pulled
+	app := StormfrontApplication{ID: name, Name: name, Image: "web:1", Volumes: map[string]string{"data": "/data"}}
+	fake.Images[app.Image] = true
+
+	steps := []struct {
+		name    string
+		advance bool
+		changed bool
+		backoff time.Duration
+	}{
+		{name: "first failure backs off", backoff: 10 * time.Second},
+		{name: "waits out the backoff", backoff: 10 * time.Second},
+		{name: "retries after the backoff", advance: true, backoff: 20 * time.Second},
+		{name: "a change retries straight away", changed: true, backoff: 40 * time.Second},
+	}
+
+	for _, step := range steps {
+		if step.advance {
+			state := loadInstanceState(name, StormfrontApplicationStatus{})
+			state.NextRestart = time.Now().Add(-time.Second)
+			storeInstanceState(name, state)
+		}
+		reconcileInstance(app, name, step.changed)
+
+		state := loadInstanceState(name, StormfrontApplicationStatus{})
+		if state.Backoff != step.backoff || state.Restarts != 0 || state.DeployError == "" || state.PullError != "" {
+			t.Fatalf("%s: expected %v backoff, no restarts and a deploy error, got %v, %d and %q", step.name, step.backoff, state.Backoff, state.Restarts, state.DeployError)
+		}
+		if _, err := fake.Inspect(name); err == nil {
+			t.Fatalf("%s: expected no container to be running", step.name)
+		}
+		if status := getInstanceStatus(app, name).Status; status != CRASH_LOOP_BACKOFF_STATUS {
+			t.Fatalf("%s: expected instance to be reported as %s, got %s", step.name, CRASH_LOOP_BACKOFF_STATUS, status)
+		}
+	}
+}
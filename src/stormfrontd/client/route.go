@@ -2,6 +2,7 @@ package client
 
 type StormfrontRoute struct {
 	ID        string `json:"id" yaml:"id"`
+	Name      string `json:"name" yaml:"name"`
 	Alias     string `json:"alias" yaml:"alias"`
 	Hostname  string `json:"hostname" yaml:"hostname"`
 	Port      int    `json:"port" yaml:"port"`
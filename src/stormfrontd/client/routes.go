@@ -1,48 +1,91 @@
 package client
 
 import (
+	"stormfrontd/client/auth"
 	"stormfrontd/middleware"
 )
 
 func InitializeRoutes(clientType string) {
 	Client.Router.Use(middleware.CORSMiddleware())
+	Client.Router.GET("/metrics", middleware.CheckTokenAuthentication(), GetMetrics)
 	apiRoutes := Client.Router.Group("/api")
 	{
 		apiRoutes.GET("/health", middleware.CheckTokenAuthentication(), GetHealth)
-		apiRoutes.GET("/state", middleware.CheckTokenAuthentication(), GetState)
-		apiRoutes.POST("/register", middleware.CheckTokenAuthentication(), RegisterFollower)
-		apiRoutes.DELETE("/register", middleware.CheckTokenAuthentication(), DeregisterFollower)
+		apiRoutes.GET("/state", middleware.CheckTokenAuthentication(auth.ROLE_ADMIN), GetState)
+		apiRoutes.POST("/register", middleware.CheckTokenAuthentication(auth.ROLE_ADMIN), RegisterFollower)
+		apiRoutes.POST("/ca", middleware.CheckTokenAuthentication(auth.ROLE_ADMIN), ReceiveCAKey)
+		apiRoutes.DELETE("/ca", middleware.CheckTokenAuthentication(auth.ROLE_ADMIN), DeleteCAKey)
+		apiRoutes.DELETE("/register", middleware.CheckTokenAuthentication(auth.ROLE_ADMIN), DeregisterFollower)
 		apiRoutes.GET("/application", middleware.CheckTokenAuthentication(), GetAllApplications)
-		apiRoutes.GET("/application/:id/logs", middleware.CheckTokenAuthentication(), GetApplicationLogs)
-		apiRoutes.GET("/application/:id/restart", middleware.CheckTokenAuthentication(), RestartApplication)
-		apiRoutes.GET("/application/:id", middleware.CheckTokenAuthentication(), GetApplication)
-		apiRoutes.POST("/application", middleware.CheckTokenAuthentication(), CreateApplication)
-		// apiRoutes.PATCH("/application/:id", middleware.CheckTokenAuthentication(), UpdateApplication)
-		apiRoutes.DELETE("/application/:id", middleware.CheckTokenAuthentication(), DeleteApplication)
-		apiRoutes.GET("/client", middleware.CheckTokenAuthentication(), GetAllClients)
-		apiRoutes.GET("/client/:id", middleware.CheckTokenAuthentication(), GetClient)
+		apiRoutes.GET("/application/:id/stats", middleware.CheckTokenAuthentication(), namespaceScope("application"), GetApplicationStats)
+		apiRoutes.GET("/application/:id/logs", middleware.CheckTokenAuthentication(), namespaceScope("application"), GetApplicationLogs)
+		apiRoutes.GET("/application/:id/exec", middleware.CheckTokenAuthentication(auth.ROLE_DEPLOYER), namespaceScope("application"), ExecApplication)
+		apiRoutes.GET("/application/:id/restart", middleware.CheckTokenAuthentication(auth.ROLE_DEPLOYER), namespaceScope("application"), RestartApplication)
+		apiRoutes.GET("/application/:id", middleware.CheckTokenAuthentication(), namespaceScope("application"), GetApplication)
+		apiRoutes.POST("/application", middleware.CheckTokenAuthentication(auth.ROLE_DEPLOYER), CreateApplication)
+		apiRoutes.PATCH("/application/:id", middleware.CheckTokenAuthentication(auth.ROLE_DEPLOYER), namespaceScope("application"), UpdateApplication)
+		apiRoutes.DELETE("/application/:id", middleware.CheckTokenAuthentication(auth.ROLE_DEPLOYER), namespaceScope("application"), DeleteApplication)
+		apiRoutes.GET("/client", middleware.CheckTokenAuthentication(auth.ROLE_ADMIN), GetAllClients)
+		apiRoutes.GET("/client/:id", middleware.CheckTokenAuthentication(auth.ROLE_ADMIN), GetClient)
 		apiRoutes.GET("/node", middleware.CheckTokenAuthentication(), GetAllNodes)
 		apiRoutes.GET("/node/:id", middleware.CheckTokenAuthentication(), GetNode)
+		apiRoutes.GET("/node/:id/stats", middleware.CheckTokenAuthentication(), GetNodeStats)
 		apiRoutes.GET("/route", middleware.CheckTokenAuthentication(), GetAllRoutes)
-		apiRoutes.GET("/route/:id", middleware.CheckTokenAuthentication(), GetRoute)
-		apiRoutes.POST("/route", middleware.CheckTokenAuthentication(), CreateRoute)
-		apiRoutes.DELETE("/route/:id", middleware.CheckTokenAuthentication(), DeleteRoute)
+		apiRoutes.GET("/route/:id", middleware.CheckTokenAuthentication(), namespaceScope("route"), GetRoute)
+		apiRoutes.POST("/route", middleware.CheckTokenAuthentication(auth.ROLE_DEPLOYER), CreateRoute)
+		apiRoutes.PATCH("/route/:id", middleware.CheckTokenAuthentication(auth.ROLE_DEPLOYER), namespaceScope("route"), UpdateRoute)
+		apiRoutes.DELETE("/route/:id", middleware.CheckTokenAuthentication(auth.ROLE_DEPLOYER), namespaceScope("route"), DeleteRoute)
+		apiRoutes.GET("/namespace", middleware.CheckTokenAuthentication(), GetAllNamespaces)
+		apiRoutes.GET("/namespace/:id", middleware.CheckTokenAuthentication(), GetNamespace)
+		apiRoutes.POST("/namespace", middleware.CheckTokenAuthentication(auth.ROLE_ADMIN), CreateNamespace)
+		apiRoutes.PATCH("/namespace/:id", middleware.CheckTokenAuthentication(auth.ROLE_ADMIN), UpdateNamespace)
+		apiRoutes.DELETE("/namespace/:id", middleware.CheckTokenAuthentication(auth.ROLE_ADMIN), DeleteNamespace)
+		apiRoutes.GET("/registry", middleware.CheckTokenAuthentication(), GetAllRegistries)
+		apiRoutes.POST("/registry", middleware.CheckTokenAuthentication(auth.ROLE_ADMIN), CreateRegistry)
+		apiRoutes.DELETE("/registry/:id", middleware.CheckTokenAuthentication(auth.ROLE_ADMIN), DeleteRegistry)
+		apiRoutes.GET("/secret", middleware.CheckTokenAuthentication(), GetAllSecrets)
+		apiRoutes.GET("/secret/:id", middleware.CheckTokenAuthentication(), namespaceScope("secret"), GetSecret)
+		apiRoutes.POST("/secret", middleware.CheckTokenAuthentication(auth.ROLE_DEPLOYER), CreateSecret)
+		apiRoutes.DELETE("/secret/:id", middleware.CheckTokenAuthentication(auth.ROLE_DEPLOYER), namespaceScope("secret"), DeleteSecret)
+		apiRoutes.GET("/config", middleware.CheckTokenAuthentication(), GetAllConfigs)
+		apiRoutes.GET("/config/:id", middleware.CheckTokenAuthentication(), namespaceScope("config"), GetConfig)
+		apiRoutes.POST("/config", middleware.CheckTokenAuthentication(auth.ROLE_DEPLOYER), CreateConfig)
+		apiRoutes.PATCH("/config/:id", middleware.CheckTokenAuthentication(auth.ROLE_DEPLOYER), namespaceScope("config"), UpdateConfig)
+		apiRoutes.DELETE("/config/:id", middleware.CheckTokenAuthentication(auth.ROLE_DEPLOYER), namespaceScope("config"), DeleteConfig)
+		apiRoutes.GET("/volume", middleware.CheckTokenAuthentication(), GetAllVolumes)
+		apiRoutes.GET("/volume/:id", middleware.CheckTokenAuthentication(), namespaceScope("volume"), GetVolume)
+		apiRoutes.POST("/volume", middleware.CheckTokenAuthentication(auth.ROLE_DEPLOYER), CreateVolume)
+		apiRoutes.PATCH("/volume/:id", middleware.CheckTokenAuthentication(auth.ROLE_DEPLOYER), namespaceScope("volume"), UpdateVolume)
+		apiRoutes.DELETE("/volume/:id", middleware.CheckTokenAuthentication(auth.ROLE_DEPLOYER), namespaceScope("volume"), DeleteVolume)
+		apiRoutes.GET("/volume/:id/manifest", middleware.CheckTokenAuthentication(auth.ROLE_ADMIN), GetVolumeManifest)
+		apiRoutes.POST("/volume/:id/data", middleware.CheckTokenAuthentication(auth.ROLE_ADMIN), ReceiveVolumeData)
+		apiRoutes.DELETE("/volume/:id/data", middleware.CheckTokenAuthentication(auth.ROLE_ADMIN), DeleteVolumeData)
+		apiRoutes.GET("/event", middleware.CheckTokenAuthentication(), GetAllEvents)
+		apiRoutes.POST("/event", middleware.CheckTokenAuthentication(auth.ROLE_ADMIN), PostEvent)
 	}
 	authRoutes := Client.Router.Group("/auth")
 	{
 		// authRoutes.GET("/check/access", CheckAccessToken)
 		// authRoutes.GET("/check/api", CheckAPIToken)
-		authRoutes.GET("/join/command", middleware.CheckTokenAuthentication(), GetJoinCommand)
-		authRoutes.GET("/join", middleware.CheckTokenAuthentication(), GetJoinToken)
-		authRoutes.DELETE("/join/:token", middleware.CheckTokenAuthentication(), RevokeJoinToken)
+		authRoutes.GET("/join/command", middleware.CheckTokenAuthentication(auth.ROLE_ADMIN), GetJoinCommand)
+		authRoutes.GET("/join", middleware.CheckTokenAuthentication(auth.ROLE_ADMIN), GetJoinToken)
+		authRoutes.GET("/join/list", middleware.CheckTokenAuthentication(auth.ROLE_ADMIN), GetAllJoinTokens)
+		authRoutes.DELETE("/join/:token", middleware.CheckTokenAuthentication(auth.ROLE_ADMIN), RevokeJoinToken)
 		authRoutes.GET("/token", GetAccessToken)
+		authRoutes.POST("/token", GetAccessToken)
 		authRoutes.GET("/refresh", RefreshAccessToken)
-		authRoutes.GET("/api", middleware.CheckTokenAuthentication(), GetAPIToken)
+		authRoutes.GET("/api", middleware.CheckTokenAuthentication(auth.ROLE_ADMIN), GetAPIToken)
+		authRoutes.GET("/api/list", middleware.CheckTokenAuthentication(auth.ROLE_ADMIN), GetAllAPITokens)
 		authRoutes.DELETE("/api", middleware.CheckTokenAuthentication(), RevokeAPIToken)
+		authRoutes.DELETE("/api/:name", middleware.CheckTokenAuthentication(auth.ROLE_ADMIN), RevokeNamedAPIToken)
 	}
 	lightningRoutes := Client.Router.Group("/lightning")
 	{
-		lightningRoutes.GET("/:id", middleware.CheckTokenAuthentication(), GetBolt)
-		lightningRoutes.POST("/", middleware.CheckTokenAuthentication(), PostBolt)
+		lightningRoutes.GET("", middleware.CheckTokenAuthentication(auth.ROLE_ADMIN), GetAllBolts)
+		lightningRoutes.GET("/:id", middleware.CheckTokenAuthentication(auth.ROLE_ADMIN), GetBolt)
+		lightningRoutes.POST("/", middleware.CheckTokenAuthentication(auth.ROLE_ADMIN), PostBolt)
+		lightningRoutes.POST("/:id/cancel", middleware.CheckTokenAuthentication(auth.ROLE_ADMIN), CancelBolt)
+		lightningRoutes.GET("/node/:id", middleware.CheckTokenAuthentication(auth.ROLE_ADMIN), GetNodeBolts)
+		lightningRoutes.POST("/run/:id", middleware.CheckTokenAuthentication(auth.ROLE_ADMIN), UpdateBoltRun)
 	}
 }
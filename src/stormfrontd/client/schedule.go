@@ -0,0 +1,172 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"stormfrontd/client/scheduler"
+	"strings"
+
+	"github.com/jfcarter2358/ceresdb-go/connection"
+)
+
+// scheduleInstance asks the scheduler for a node to run the named instance of
+// an application on. The instance itself is left out of the existing
+// placements so that it can be rescheduled without conflicting with its old
+// assignment. Replicas which bind host ports are kept on separate nodes.
+func scheduleInstance(app StormfrontApplication, name, pin string, nodes []StormfrontNode, applications []StormfrontApplication) (scheduler.Decision, error) {
+	request := scheduler.Request{
+		Name:         app.Name,
+		Namespace:    app.Namespace,
+		CPU:          app.CPU,
+		Memory:       app.Memory,
+		Node:         pin,
+		NodeSelector: app.NodeSelector,
+		Affinity:     app.Affinity,
+		AntiAffinity: app.AntiAffinity,
+	}
+	volumeNodes, err := volumeNodes(app)
+	if err != nil {
+		return scheduler.Decision{}, err
+	}
+	request.VolumeNodes = volumeNodes
+	if len(app.Ports) > 0 {
+		request.AntiAffinity = append(append([]string{}, app.AntiAffinity...), app.Name)
+	}
+
+	candidates := []scheduler.Node{}
+	for _, node := range nodes {
+		candidates = append(candidates, scheduler.Node{
+			ID:              node.ID,
+			Labels:          node.Labels,
+			Healthy:         node.Health == "Healthy",
+			CPUAvailable:    node.System.CPUAvailable,
+			CPUTotal:        float64(node.System.Cores),
+			MemoryAvailable: node.System.MemoryAvailable,
+			MemoryTotal:     node.System.TotalMemory,
+		})
+	}
+
+	placements := []scheduler.Placement{}
+	for _, placed := range applications {
+		if placed.ID == app.ID {
+			continue
+		}
+		for _, instance := range placed.Instances {
+			placements = append(placements, scheduler.Placement{Name: placed.Name, Namespace: placed.Namespace, Node: instance.Node})
+		}
+	}
+	for _, instance := range app.Instances {
+		if instance.Name == name {
+			continue
+		}
+		placements = append(placements, scheduler.Placement{Name: app.Name, Namespace: app.Namespace, Node: instance.Node})
+	}
+
+	return Scheduler.Schedule(request, candidates, placements)
+}
+
+// scaleApplication adds or removes instances until the application has as
+// many as its replica count. Nodes are updated in place with the resources
+// handed to each new instance since their system info will not reflect it
+// until the next health check.
+func scaleApplication(app *StormfrontApplication, nodes []StormfrontNode, applications []StormfrontApplication) ([]scheduler.Decision, error) {
+	decisions := []scheduler.Decision{}
+	replicas := app.replicaCount()
+
+	if len(app.Instances) > replicas {
+		app.Instances = app.Instances[:replicas]
+		return decisions, nil
+	}
+
+	for idx := len(app.Instances); idx < replicas; idx++ {
+		name := app.instanceName(idx)
+		decision, err := scheduleInstance(*app, name, "", nodes, applications)
+		decisions = append(decisions, decision)
+		if err != nil {
+			return decisions, err
+		}
+		app.Instances = append(app.Instances, StormfrontInstance{Name: name, Node: decision.Node})
+		reserveResources(nodes, decision.Node, *app)
+	}
+
+	return decisions, nil
+}
+
+// adoptLegacyNode moves an application created before replicas were
+// introduced, which carries only a node, over to instances. Its existing
+// container, which is named after the application alone, is adopted as the
+// first instance and the node is cleared so that
+// it does not pin the replicas added later. It reports whether the
+// application was changed.
+func adoptLegacyNode(app *StormfrontApplication) bool {
+	if app.Node == "" {
+		return false
+	}
+	if len(app.Instances) == 0 {
+		app.Instances = []StormfrontInstance{{Name: app.Name, Node: app.Node}}
+	}
+	app.Node = ""
+	return true
+}
+
+func reserveResources(nodes []StormfrontNode, nodeID string, app StormfrontApplication) {
+	for idx := range nodes {
+		if nodes[idx].ID == nodeID {
+			nodes[idx].System.CPUAvailable -= app.CPU
+			nodes[idx].System.MemoryAvailable -= app.Memory
+		}
+	}
+}
+
+// scaleApplications brings every application's instances in line with its
+// replica count, adopting applications created before replicas were
+// introduced along the way
+func scaleApplications() error {
+	nodes, err := getNodes()
+	if err != nil {
+		return err
+	}
+	applicationData, err := connection.Query("get record stormfront.application")
+	if err != nil {
+		return err
+	}
+
+	applications := []StormfrontApplication{}
+	applicationBytes, _ := json.Marshal(applicationData)
+	json.Unmarshal(applicationBytes, &applications)
+
+	for idx := range applications {
+		app := &applications[idx]
+
+		adopted := adoptLegacyNode(app)
+		if app.Replicas == 0 {
+			app.Replicas = 1
+		} else if len(app.Instances) == app.replicaCount() && !adopted {
+			continue
+		}
+
+		existing := len(app.Instances)
+		decisions, err := scaleApplication(app, nodes, applications)
+		if len(app.Instances) > existing {
+			for idx, instance := range app.Instances[existing:] {
+				recordEvent(applicationEvent(EVENT_APPLICATION_SCHEDULED, *app, "Scheduled instance %s on node %s by %s strategy", instance.Name, instance.Node, decisions[idx].Strategy))
+			}
+		}
+		if err != nil {
+			explanation := []string{}
+			if len(decisions) > 0 {
+				explanation = decisions[len(decisions)-1].Explanation
+			}
+			schedulerLog.Error("Unable to scale application", "application", app.Name, "namespace", app.Namespace, "replicas", app.replicaCount(), "explanation", explanation, "error", err)
+			recordRepeatingEvent(applicationEvent(EVENT_SCHEDULING_FAILED, *app, "Unable to scale to %v replicas: %v: %s", app.replicaCount(), err, strings.Join(explanation, "; ")))
+		}
+
+		instancesBytes, _ := json.Marshal(app.Instances)
+		_, err = connection.Query(fmt.Sprintf(`patch record stormfront.application '%s' {"node":"","replicas":%v,"instances":%s}`, applicationData[idx][".id"].(string), app.Replicas, instancesBytes))
+		if err != nil {
+			schedulerLog.Error("Unable to update application instances", "application", app.ID, "error", err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,135 @@
+package client
+
+import (
+	"stormfrontd/client/scheduler"
+	"testing"
+)
+
+func testNodes(ids ...string) []StormfrontNode {
+	nodes := []StormfrontNode{}
+	for _, id := range ids {
+		nodes = append(nodes, StormfrontNode{
+			ID:     id,
+			Health: "Healthy",
+			System: StormfrontSystemInfo{
+				Cores:           4,
+				CPUAvailable:    4,
+				TotalMemory:     4096,
+				MemoryAvailable: 4096,
+			},
+		})
+	}
+	return nodes
+}
+
+func TestScaleApplicationAcrossNodes(t *testing.T) {
+	var err error
+	Scheduler, err = scheduler.New(scheduler.FIRST_FIT_STRATEGY)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		app   StormfrontApplication
+		nodes []string
+	}{
+		{
+			name: "replicas binding ports are spread over nodes",
+			app: StormfrontApplication{
+				ID:        "web",
+				Name:      "web",
+				Namespace: DEFAULT_NAMESPACE,
+				CPU:       1,
+				Memory:    512,
+				Ports:     map[string]string{"8080": "80"},
+				Replicas:  3,
+				Instances: []StormfrontInstance{{Name: "web", Node: "node-a"}},
+			},
+			nodes: []string{"node-a", "node-b", "node-c"},
+		},
+		{
+			name: "legacy node does not pin new replicas",
+			app: StormfrontApplication{
+				ID:        "api",
+				Name:      "api",
+				Namespace: DEFAULT_NAMESPACE,
+				Node:      "node-b",
+				CPU:       1,
+				Memory:    512,
+				Ports:     map[string]string{"9090": "90"},
+				Replicas:  3,
+			},
+			nodes: []string{"node-b", "node-a", "node-c"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			app := test.app
+			adoptLegacyNode(&app)
+			if app.Node != "" {
+				t.Fatalf("expected legacy node to be cleared, got %s", app.Node)
+			}
+
+			decisions, err := scaleApplication(&app, testNodes("node-a", "node-b", "node-c"), []StormfrontApplication{app})
+			if err != nil {
+				t.Fatalf("unable to scale: %v: %v", err, decisions)
+			}
+			if len(app.Instances) != len(test.nodes) {
+				t.Fatalf("expected %d instances, got %d", len(test.nodes), len(app.Instances))
+			}
+			for idx, instance := range app.Instances {
+				// The first instance of both is an existing container named
+				// after the application alone
+				expected := app.instanceName(idx)
+				if idx == 0 {
+					expected = app.Name
+				}
+				if instance.Name != expected {
+					t.Errorf("expected instance %d to be named %s, got %s", idx, expected, instance.Name)
+				}
+				if instance.Node != test.nodes[idx] {
+					t.Errorf("expected instance %s on %s, got %s", instance.Name, test.nodes[idx], instance.Node)
+				}
+			}
+		})
+	}
+}
+
+func TestScaleApplicationDown(t *testing.T) {
+	app := StormfrontApplication{
+		Name:     "web",
+		Replicas: 1,
+		Instances: []StormfrontInstance{
+			{Name: "web", Node: "node-a"},
+			{Name: "web-1", Node: "node-b"},
+			{Name: "web-2", Node: "node-c"},
+		},
+	}
+	if _, err := scaleApplication(&app, testNodes("node-a", "node-b", "node-c"), nil); err != nil {
+		t.Fatal(err)
+	}
+	if len(app.Instances) != 1 || app.Instances[0].Name != "web" {
+		t.Fatalf("expected only the first instance to remain, got %v", app.Instances)
+	}
+}
+
+func TestInstanceNamesIncludeNamespace(t *testing.T) {
+	tests := []struct {
+		app      StormfrontApplication
+		idx      int
+		expected string
+	}{
+		{app: StormfrontApplication{Name: "web", Namespace: "team-a"}, idx: 0, expected: "team-a.web"},
+		{app: StormfrontApplication{Name: "web", Namespace: "team-b"}, idx: 0, expected: "team-b.web"},
+		{app: StormfrontApplication{Name: "web", Namespace: "team-a"}, idx: 2, expected: "team-a.web-2"},
+		{app: StormfrontApplication{Name: "web"}, idx: 1, expected: DEFAULT_NAMESPACE + ".web-1"},
+	}
+
+	for _, test := range tests {
+		if name := test.app.instanceName(test.idx); name != test.expected {
+			t.Errorf("expected %s, got %s", test.expected, name)
+		}
+	}
+}
@@ -0,0 +1,184 @@
+package scheduler
+
+import (
+	"fmt"
+)
+
+// Node is the view of a cluster node that the scheduler places applications
+// against
+type Node struct {
+	ID              string            `json:"id" yaml:"id"`
+	Labels          map[string]string `json:"labels" yaml:"labels"`
+	Healthy         bool              `json:"healthy" yaml:"healthy"`
+	CPUAvailable    float64           `json:"cpu_available" yaml:"cpu_available"`
+	CPUTotal        float64           `json:"cpu_total" yaml:"cpu_total"`
+	MemoryAvailable int               `json:"memory_available" yaml:"memory_available"`
+	MemoryTotal     int               `json:"memory_total" yaml:"memory_total"`
+}
+
+// Request describes the constraints of an application which needs a node
+type Request struct {
+	Name         string            `json:"name" yaml:"name"`
+	Namespace    string            `json:"namespace" yaml:"namespace"`
+	CPU          float64           `json:"cpu" yaml:"cpu"`
+	Memory       int               `json:"memory" yaml:"memory"`
+	Node         string            `json:"node" yaml:"node"` // pin to a specific node if set
+	NodeSelector map[string]string `json:"node_selector" yaml:"node_selector"`
+	Affinity     []string          `json:"affinity" yaml:"affinity"`           // applications which must share the node
+	AntiAffinity []string          `json:"anti_affinity" yaml:"anti_affinity"` // applications which must not share the node
+	VolumeNodes  []string          `json:"volume_nodes" yaml:"volume_nodes"`   // nodes holding the data of the application's volumes
+}
+
+// Placement records an application which is already assigned to a node
+type Placement struct {
+	Name      string `json:"name" yaml:"name"`
+	Namespace string `json:"namespace" yaml:"namespace"`
+	Node      string `json:"node" yaml:"node"`
+}
+
+// Decision is the outcome of a scheduling attempt. Explanation has one entry
+// per node describing why it was rejected or chosen.
+type Decision struct {
+	Node        string   `json:"node" yaml:"node"`
+	Strategy    string   `json:"strategy" yaml:"strategy"`
+	Explanation []string `json:"explanation" yaml:"explanation"`
+}
+
+type Scheduler struct {
+	Strategy Strategy
+}
+
+// predicate returns an empty string if the node can run the request and the
+// reason it cannot otherwise
+type predicate func(request Request, node Node, placements []Placement) string
+
+var predicates = []predicate{
+	checkPinned,
+	checkVolumes,
+	checkHealthy,
+	checkNodeSelector,
+	checkAffinity,
+	checkAntiAffinity,
+	checkResources,
+}
+
+func New(strategyName string) (*Scheduler, error) {
+	strategy, err := NewStrategy(strategyName)
+	if err != nil {
+		return nil, err
+	}
+	return &Scheduler{Strategy: strategy}, nil
+}
+
+// Schedule picks the node which should run the request. Nodes are first
+// filtered by the hard constraints and the remaining nodes are ordered by the
+// scheduler's strategy. An error is returned alongside the explanation if no
+// node is able to run the request.
+func (s *Scheduler) Schedule(request Request, nodes []Node, placements []Placement) (Decision, error) {
+	decision := Decision{Strategy: s.Strategy.Name(), Explanation: []string{}}
+
+	feasible := []Node{}
+	for _, node := range nodes {
+		reason := ""
+		for _, check := range predicates {
+			reason = check(request, node, placements)
+			if reason != "" {
+				break
+			}
+		}
+		if reason != "" {
+			decision.Explanation = append(decision.Explanation, fmt.Sprintf("node %s: %s", node.ID, reason))
+			continue
+		}
+		feasible = append(feasible, node)
+	}
+
+	if len(feasible) == 0 {
+		if len(nodes) == 0 {
+			decision.Explanation = append(decision.Explanation, "no nodes are registered")
+		}
+		return decision, fmt.Errorf("unable to schedule application %s on any of %v nodes", request.Name, len(nodes))
+	}
+
+	ranked := s.Strategy.Rank(request, feasible, placements)
+	decision.Node = ranked[0].ID
+	decision.Explanation = append(decision.Explanation, fmt.Sprintf("node %s: selected by %s strategy out of %v feasible nodes", decision.Node, decision.Strategy, len(feasible)))
+
+	return decision, nil
+}
+
+func checkPinned(request Request, node Node, placements []Placement) string {
+	if request.Node != "" && request.Node != node.ID {
+		return fmt.Sprintf("application is pinned to node %s", request.Node)
+	}
+	return ""
+}
+
+func checkVolumes(request Request, node Node, placements []Placement) string {
+	if request.VolumeNodes == nil {
+		return ""
+	}
+	for _, id := range request.VolumeNodes {
+		if id == node.ID {
+			return ""
+		}
+	}
+	return fmt.Sprintf("application volumes are only available on nodes %v", request.VolumeNodes)
+}
+
+func checkHealthy(request Request, node Node, placements []Placement) string {
+	if !node.Healthy {
+		return "node is not healthy"
+	}
+	return ""
+}
+
+func checkNodeSelector(request Request, node Node, placements []Placement) string {
+	for key, val := range request.NodeSelector {
+		actual, ok := node.Labels[key]
+		if !ok {
+			return fmt.Sprintf("node selector requires label %s=%s but node has no %s label", key, val, key)
+		}
+		if actual != val {
+			return fmt.Sprintf("node selector requires label %s=%s but node has %s=%s", key, val, key, actual)
+		}
+	}
+	return ""
+}
+
+func checkAffinity(request Request, node Node, placements []Placement) string {
+	for _, name := range request.Affinity {
+		if !isPlaced(name, request.Namespace, node.ID, placements) {
+			return fmt.Sprintf("affinity requires application %s which is not on this node", name)
+		}
+	}
+	return ""
+}
+
+func checkAntiAffinity(request Request, node Node, placements []Placement) string {
+	for _, name := range request.AntiAffinity {
+		if isPlaced(name, request.Namespace, node.ID, placements) {
+			return fmt.Sprintf("anti-affinity forbids sharing a node with application %s", name)
+		}
+	}
+	return ""
+}
+
+func checkResources(request Request, node Node, placements []Placement) string {
+	if node.CPUAvailable < request.CPU {
+		return fmt.Sprintf("insufficient cpu, requested %v but %v available", request.CPU, node.CPUAvailable)
+	}
+	if node.MemoryAvailable < request.Memory {
+		return fmt.Sprintf("insufficient memory, requested %v but %v available", request.Memory, node.MemoryAvailable)
+	}
+	return ""
+}
+
+func isPlaced(name, namespace, nodeID string, placements []Placement) bool {
+	for _, placement := range placements {
+		if placement.Name == name && placement.Namespace == namespace && placement.Node == nodeID {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,164 @@
+package scheduler
+
+import (
+	"strings"
+	"testing"
+)
+
+func testNode(id string, cpu float64, memory int) Node {
+	return Node{
+		ID:              id,
+		Healthy:         true,
+		Labels:          map[string]string{},
+		CPUAvailable:    cpu,
+		CPUTotal:        4,
+		MemoryAvailable: memory,
+		MemoryTotal:     4096,
+	}
+}
+
+func TestSchedulePredicates(t *testing.T) {
+	unhealthy := testNode("sick", 4, 4096)
+	unhealthy.Healthy = false
+	labelled := testNode("gpu", 4, 4096)
+	labelled.Labels["gpu"] = "true"
+
+	tests := []struct {
+		name       string
+		request    Request
+		nodes      []Node
+		placements []Placement
+		node       string
+		reason     string
+	}{
+		{
+			name:    "pinned node is chosen",
+			request: Request{Name: "web", Node: "b"},
+			nodes:   []Node{testNode("a", 4, 4096), testNode("b", 4, 4096)},
+			node:    "b",
+			reason:  "pinned to node b",
+		},
+		{
+			name:    "unhealthy nodes are skipped",
+			request: Request{Name: "web"},
+			nodes:   []Node{unhealthy, testNode("a", 4, 4096)},
+			node:    "a",
+			reason:  "node is not healthy",
+		},
+		{
+			name:    "node selector must match",
+			request: Request{Name: "web", NodeSelector: map[string]string{"gpu": "true"}},
+			nodes:   []Node{testNode("a", 4, 4096), labelled},
+			node:    "gpu",
+			reason:  "node has no gpu label",
+		},
+		{
+			name:    "volumes limit the nodes",
+			request: Request{Name: "db", VolumeNodes: []string{"b"}},
+			nodes:   []Node{testNode("a", 4, 4096), testNode("b", 4, 4096)},
+			node:    "b",
+			reason:  "volumes are only available",
+		},
+		{
+			name:       "affinity follows the other application",
+			request:    Request{Name: "web", Affinity: []string{"cache"}},
+			nodes:      []Node{testNode("a", 4, 4096), testNode("b", 4, 4096)},
+			placements: []Placement{{Name: "cache", Node: "b"}},
+			node:       "b",
+			reason:     "affinity requires application cache",
+		},
+		{
+			name:       "anti-affinity avoids the other application",
+			request:    Request{Name: "web", AntiAffinity: []string{"web"}},
+			nodes:      []Node{testNode("a", 4, 4096), testNode("b", 4, 4096)},
+			placements: []Placement{{Name: "web", Node: "a"}},
+			node:       "b",
+			reason:     "anti-affinity forbids",
+		},
+		{
+			name:    "nodes without enough cpu are skipped",
+			request: Request{Name: "web", CPU: 2},
+			nodes:   []Node{testNode("a", 1, 4096), testNode("b", 2, 4096)},
+			node:    "b",
+			reason:  "insufficient cpu",
+		},
+		{
+			name:    "nodes without enough memory are skipped",
+			request: Request{Name: "web", Memory: 1024},
+			nodes:   []Node{testNode("a", 4, 512), testNode("b", 4, 1024)},
+			node:    "b",
+			reason:  "insufficient memory",
+		},
+		{
+			name:    "no node fits",
+			request: Request{Name: "web", CPU: 8},
+			nodes:   []Node{testNode("a", 4, 4096)},
+			reason:  "insufficient cpu",
+		},
+		{
+			name:    "no nodes registered",
+			request: Request{Name: "web"},
+			reason:  "no nodes are registered",
+		},
+	}
+
+	scheduler, err := New(FIRST_FIT_STRATEGY)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			decision, err := scheduler.Schedule(test.request, test.nodes, test.placements)
+			if test.node == "" {
+				if err == nil {
+					t.Fatalf("expected scheduling to fail, got node %s", decision.Node)
+				}
+			} else if err != nil || decision.Node != test.node {
+				t.Fatalf("expected node %s, got %s: %v", test.node, decision.Node, err)
+			}
+			if !strings.Contains(strings.Join(decision.Explanation, "\n"), test.reason) {
+				t.Errorf("expected explanation to mention %q, got %v", test.reason, decision.Explanation)
+			}
+		})
+	}
+}
+
+func TestStrategies(t *testing.T) {
+	nodes := []Node{testNode("busy", 1, 1024), testNode("idle", 4, 4096), testNode("half", 2, 2048)}
+	placements := []Placement{{Name: "a", Node: "idle"}, {Name: "b", Node: "idle"}, {Name: "c", Node: "half"}}
+
+	tests := []struct {
+		strategy string
+		node     string
+	}{
+		{strategy: FIRST_FIT_STRATEGY, node: "busy"},
+		{strategy: LEAST_LOADED_STRATEGY, node: "idle"},
+		{strategy: SPREAD_STRATEGY, node: "busy"},
+		{strategy: BIN_PACK_STRATEGY, node: "busy"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.strategy, func(t *testing.T) {
+			scheduler, err := New(test.strategy)
+			if err != nil {
+				t.Fatal(err)
+			}
+			decision, err := scheduler.Schedule(Request{Name: "web"}, nodes, placements)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if decision.Node != test.node || decision.Strategy != test.strategy {
+				t.Fatalf("expected %s to choose %s, got %s with %s", test.strategy, test.node, decision.Node, decision.Strategy)
+			}
+		})
+	}
+}
+
+func TestNewStrategy(t *testing.T) {
+	if strategy, err := NewStrategy(""); err != nil || strategy.Name() != FIRST_FIT_STRATEGY {
+		t.Fatalf("expected first-fit to be the default strategy, got %v: %v", strategy, err)
+	}
+	if _, err := NewStrategy("random"); err == nil {
+		t.Fatal("expected unknown strategy to be rejected")
+	}
+}
@@ -0,0 +1,107 @@
+package scheduler
+
+import (
+	"fmt"
+	"sort"
+)
+
+const FIRST_FIT_STRATEGY = "first-fit"
+const LEAST_LOADED_STRATEGY = "least-loaded"
+const SPREAD_STRATEGY = "spread"
+const BIN_PACK_STRATEGY = "bin-pack"
+
+// Strategy orders the nodes which satisfy a request's constraints from most
+// to least preferred
+type Strategy interface {
+	Name() string
+	Rank(request Request, nodes []Node, placements []Placement) []Node
+}
+
+func NewStrategy(name string) (Strategy, error) {
+	switch name {
+	case FIRST_FIT_STRATEGY, "":
+		return FirstFitStrategy{}, nil
+	case LEAST_LOADED_STRATEGY:
+		return LeastLoadedStrategy{}, nil
+	case SPREAD_STRATEGY:
+		return SpreadStrategy{}, nil
+	case BIN_PACK_STRATEGY:
+		return BinPackStrategy{}, nil
+	}
+	return nil, fmt.Errorf("unsupported scheduling strategy '%s', allowed strategies are '%s', '%s', '%s' and '%s'", name, FIRST_FIT_STRATEGY, LEAST_LOADED_STRATEGY, SPREAD_STRATEGY, BIN_PACK_STRATEGY)
+}
+
+// FirstFitStrategy keeps nodes in registration order
+type FirstFitStrategy struct{}
+
+func (FirstFitStrategy) Name() string {
+	return FIRST_FIT_STRATEGY
+}
+
+func (FirstFitStrategy) Rank(request Request, nodes []Node, placements []Placement) []Node {
+	return nodes
+}
+
+// LeastLoadedStrategy prefers the node with the largest share of its
+// resources still free
+type LeastLoadedStrategy struct{}
+
+func (LeastLoadedStrategy) Name() string {
+	return LEAST_LOADED_STRATEGY
+}
+
+func (LeastLoadedStrategy) Rank(request Request, nodes []Node, placements []Placement) []Node {
+	ranked := append([]Node{}, nodes...)
+	sort.SliceStable(ranked, func(i, j int) bool {
+		return freeShare(ranked[i]) > freeShare(ranked[j])
+	})
+	return ranked
+}
+
+// SpreadStrategy prefers the node running the fewest applications
+type SpreadStrategy struct{}
+
+func (SpreadStrategy) Name() string {
+	return SPREAD_STRATEGY
+}
+
+func (SpreadStrategy) Rank(request Request, nodes []Node, placements []Placement) []Node {
+	counts := map[string]int{}
+	for _, placement := range placements {
+		counts[placement.Node]++
+	}
+	ranked := append([]Node{}, nodes...)
+	sort.SliceStable(ranked, func(i, j int) bool {
+		return counts[ranked[i].ID] < counts[ranked[j].ID]
+	})
+	return ranked
+}
+
+// BinPackStrategy prefers the node with the smallest share of its resources
+// still free so that other nodes are kept empty
+type BinPackStrategy struct{}
+
+func (BinPackStrategy) Name() string {
+	return BIN_PACK_STRATEGY
+}
+
+func (BinPackStrategy) Rank(request Request, nodes []Node, placements []Placement) []Node {
+	ranked := append([]Node{}, nodes...)
+	sort.SliceStable(ranked, func(i, j int) bool {
+		return freeShare(ranked[i]) < freeShare(ranked[j])
+	})
+	return ranked
+}
+
+// freeShare is the average fraction of cpu and memory which is unreserved on
+// a node
+func freeShare(node Node) float64 {
+	share := 0.0
+	if node.CPUTotal > 0 {
+		share += node.CPUAvailable / node.CPUTotal
+	}
+	if node.MemoryTotal > 0 {
+		share += float64(node.MemoryAvailable) / float64(node.MemoryTotal)
+	}
+	return share / 2
+}
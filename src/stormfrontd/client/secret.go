@@ -0,0 +1,315 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"stormfrontd/client/auth"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jfcarter2358/ceresdb-go/connection"
+)
+
+const SECRET_DIRECTORY = "/var/stormfront/secrets"
+
+// StormfrontSecret is a named set of values which applications can reference
+// through secret_env and secret_mounts. Values in Data are encrypted with the
+// cluster key before they are written to the database and are only decrypted
+// by the node which runs an instance referencing them.
+type StormfrontSecret struct {
+	ID        string            `json:"id" yaml:"id"`
+	Name      string            `json:"name" yaml:"name"`
+	Namespace string            `json:"namespace" yaml:"namespace"`
+	Data      map[string]string `json:"data" yaml:"data"`
+}
+
+func (secret StormfrontSecret) redact() StormfrontSecret {
+	redacted := map[string]string{}
+	for key := range secret.Data {
+		redacted[key] = REDACTED_VALUE
+	}
+	secret.Data = redacted
+	return secret
+}
+
+// parseSecretReference splits a secret_env value of the form <secret>/<key>
+func parseSecretReference(reference string) (string, string, error) {
+	parts := strings.SplitN(reference, "/", 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", "", fmt.Errorf("invalid secret reference '%s', references must be of the form <secret>/<key>", reference)
+	}
+	return parts[0], parts[1], nil
+}
+
+func getSecrets() ([]StormfrontSecret, error) {
+	secretData, err := connection.Query("get record stormfront.secret")
+	if err != nil {
+		return nil, err
+	}
+	secrets := []StormfrontSecret{}
+	secretBytes, err := json.Marshal(secretData)
+	if err != nil {
+		return nil, err
+	}
+	err = json.Unmarshal(secretBytes, &secrets)
+	if err != nil {
+		return nil, err
+	}
+
+	return secrets, nil
+}
+
+func getSecret(name, namespace string) (StormfrontSecret, bool, error) {
+	secrets, err := getSecrets()
+	if err != nil {
+		return StormfrontSecret{}, false, err
+	}
+	for _, secret := range secrets {
+		if secret.Name == name && secret.Namespace == namespace {
+			return secret, true, nil
+		}
+	}
+	return StormfrontSecret{}, false, nil
+}
+
+// validateSecretReferences makes sure every secret an application refers to
+// exists in its namespace so that mistakes are caught when the application is
+// applied rather than when a node tries to deploy it
+func validateSecretReferences(app StormfrontApplication) error {
+	if len(app.SecretEnv) == 0 && len(app.SecretMounts) == 0 {
+		return nil
+	}
+	secrets, err := getSecrets()
+	if err != nil {
+		return err
+	}
+	find := func(name string) (StormfrontSecret, bool) {
+		for _, secret := range secrets {
+			if secret.Name == name && secret.Namespace == app.Namespace {
+				return secret, true
+			}
+		}
+		return StormfrontSecret{}, false
+	}
+
+	for env, reference := range app.SecretEnv {
+		name, key, err := parseSecretReference(reference)
+		if err != nil {
+			return fmt.Errorf("secret_env %s: %v", env, err)
+		}
+		secret, found := find(name)
+		if !found {
+			return fmt.Errorf("secret_env %s: secret %s does not exist in namespace %s", env, name, app.Namespace)
+		}
+		if _, ok := secret.Data[key]; !ok {
+			return fmt.Errorf("secret_env %s: secret %s has no key %s", env, name, key)
+		}
+	}
+	for name := range app.SecretMounts {
+		if _, found := find(name); !found {
+			return fmt.Errorf("secret_mounts: secret %s does not exist in namespace %s", name, app.Namespace)
+		}
+	}
+	return nil
+}
+
+// resolveSecrets decrypts the secrets referenced by an instance, returning the
+// environment variables to add to the container and writing mounted secrets
+// to files under SECRET_DIRECTORY. The returned mounts map host directories
+// to container directories.
+func resolveSecrets(app StormfrontApplication, name string) (map[string]string, map[string]string, error) {
+	env := map[string]string{}
+	mounts := map[string]string{}
+	if len(app.SecretEnv) == 0 && len(app.SecretMounts) == 0 {
+		return env, mounts, nil
+	}
+
+	for variable, reference := range app.SecretEnv {
+		secretName, key, err := parseSecretReference(reference)
+		if err != nil {
+			return nil, nil, err
+		}
+		secret, found, err := getSecret(secretName, app.Namespace)
+		if err != nil {
+			return nil, nil, err
+		}
+		if !found {
+			return nil, nil, fmt.Errorf("secret %s does not exist in namespace %s", secretName, app.Namespace)
+		}
+		ciphertext, ok := secret.Data[key]
+		if !ok {
+			return nil, nil, fmt.Errorf("secret %s has no key %s", secretName, key)
+		}
+		value, err := auth.Decrypt(ciphertext)
+		if err != nil {
+			return nil, nil, fmt.Errorf("unable to decrypt secret %s: %v", secretName, err)
+		}
+		env[variable] = value
+	}
+
+	removeSecretFiles(name)
+	for secretName, dst := range app.SecretMounts {
+		secret, found, err := getSecret(secretName, app.Namespace)
+		if err != nil {
+			return nil, nil, err
+		}
+		if !found {
+			return nil, nil, fmt.Errorf("secret %s does not exist in namespace %s", secretName, app.Namespace)
+		}
+		src := filepath.Join(SECRET_DIRECTORY, name, secretName)
+		if err := os.MkdirAll(src, 0700); err != nil {
+			return nil, nil, err
+		}
+		for key, ciphertext := range secret.Data {
+			value, err := auth.Decrypt(ciphertext)
+			if err != nil {
+				return nil, nil, fmt.Errorf("unable to decrypt secret %s: %v", secretName, err)
+			}
+			if err := ioutil.WriteFile(filepath.Join(src, key), []byte(value), 0600); err != nil {
+				return nil, nil, err
+			}
+		}
+		mounts[src] = dst
+	}
+
+	return env, mounts, nil
+}
+
+func removeSecretFiles(name string) {
+	if err := os.RemoveAll(filepath.Join(SECRET_DIRECTORY, name)); err != nil {
+		reconcilerLog.Warn("Unable to remove secret files", "container", name, "error", err)
+	}
+}
+
+func CreateSecret(c *gin.Context) {
+	if Client.Type != "Leader" {
+		c.Redirect(http.StatusTemporaryRedirect, fmt.Sprintf("https://%s:%v/api/secret", Client.Leader.Host, Client.Leader.Port))
+		return
+	}
+
+	var secret StormfrontSecret
+	if err := c.BindJSON(&secret); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if !namespaceNameRegex.MatchString(secret.Name) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("invalid secret name '%s', names must be lowercase alphanumeric characters or '-'", secret.Name)})
+		return
+	}
+	if len(secret.Data) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "secret data must not be empty"})
+		return
+	}
+	if err := validateDataKeys(secret.Data); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("invalid secret: %v", err)})
+		return
+	}
+
+	if secret.Namespace == "" {
+		secret.Namespace = DEFAULT_NAMESPACE
+	}
+	if !authorizeNamespace(c, secret.Namespace) {
+		return
+	}
+	if _, found, err := getNamespace(secret.Namespace); err != nil || !found {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("namespace %s does not exist", secret.Namespace)})
+		return
+	}
+
+	_, found, err := getSecret(secret.Name, secret.Namespace)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if found {
+		c.JSON(http.StatusConflict, gin.H{"error": fmt.Sprintf("secret %s already exists in namespace %s", secret.Name, secret.Namespace)})
+		return
+	}
+
+	for key, value := range secret.Data {
+		ciphertext, err := auth.Encrypt(value)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("unable to encrypt secret: %v", err)})
+			return
+		}
+		secret.Data[key] = ciphertext
+	}
+
+	secret.ID = uuid.NewString()
+	secretData, _ := json.Marshal(secret)
+	_, err = connection.Query(fmt.Sprintf("post record stormfront.secret %s", secretData))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("unable to create secret: %v", err.Error())})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"id": secret.ID})
+}
+
+func GetAllSecrets(c *gin.Context) {
+	if Client.Type != "Leader" {
+		c.Redirect(http.StatusTemporaryRedirect, fmt.Sprintf("https://%s:%v/api/secret", Client.Leader.Host, Client.Leader.Port))
+		return
+	}
+
+	secrets, err := getSecrets()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	scoped := []StormfrontSecret{}
+	for _, secret := range secrets {
+		if namespaceAllowed(c, secret.Namespace) {
+			scoped = append(scoped, secret.redact())
+		}
+	}
+
+	c.JSON(http.StatusOK, scoped)
+}
+
+func GetSecret(c *gin.Context) {
+	id := c.Param("id")
+
+	if Client.Type != "Leader" {
+		c.Redirect(http.StatusTemporaryRedirect, fmt.Sprintf("https://%s:%v/api/secret/%s", Client.Leader.Host, Client.Leader.Port, id))
+		return
+	}
+
+	secrets, err := getSecrets()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	for _, secret := range secrets {
+		if secret.ID == id {
+			c.JSON(http.StatusOK, secret.redact())
+			return
+		}
+	}
+
+	c.Status(http.StatusNotFound)
+}
+
+func DeleteSecret(c *gin.Context) {
+	id := c.Param("id")
+
+	if Client.Type != "Leader" {
+		c.Redirect(http.StatusTemporaryRedirect, fmt.Sprintf("https://%s:%v/api/secret/%s", Client.Leader.Host, Client.Leader.Port, id))
+		return
+	}
+
+	_, err := connection.Query(fmt.Sprintf(`get record stormfront.secret .id | filter id = '%s' | delete record stormfront.secret -`, id))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
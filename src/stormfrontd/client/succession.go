@@ -1,9 +1,9 @@
 package client
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
-	"log"
 	"net/http"
 	"stormfrontd/client/auth"
 	"stormfrontd/client/communication"
@@ -17,7 +17,7 @@ func updateSuccession() error {
 
 	nodeData, err := connection.Query("get record stormfront.leader")
 	if len(nodeData) == 0 {
-		log.Println("No followers to update")
+		healthLog.Debug("No followers to update")
 		return nil
 	}
 	if err != nil {
@@ -27,6 +27,13 @@ func updateSuccession() error {
 	successionBytes, _ := json.Marshal(nodeData[0]["succession"])
 	json.Unmarshal(successionBytes, &succession)
 
+	// Keep probing unknown nodes so they rejoin the succession once they are
+	// reachable again
+	var unknown []StormfrontNode
+	unknownBytes, _ := json.Marshal(nodeData[0]["unknown"])
+	json.Unmarshal(unknownBytes, &unknown)
+	succession = append(succession, unknown...)
+
 	newSuccession := []StormfrontNode{}
 	newUnhealthy := []StormfrontNode{}
 	newUnknown := []StormfrontNode{}
@@ -34,15 +41,17 @@ func updateSuccession() error {
 	for _, successor := range succession {
 		foundSuccessor := false
 		for counter := 0; counter < UPDATE_MAX_TRIES; counter++ {
-			fmt.Printf("Trying to reach follower at %s:%v/api/health, %v of %v\n", successor.Host, successor.Port, counter+1, UPDATE_MAX_TRIES)
-			status, body, err := communication.Get(successor.Host, successor.Port, "api/health", AuthClient)
+			healthLog.Trace("Checking follower health", "node", successor.ID, "host", successor.Host, "port", successor.Port, "attempt", counter+1, "max_attempts", UPDATE_MAX_TRIES)
+			start := time.Now()
+			status, body, err := communication.Get(context.Background(), successor.Host, successor.Port, "api/health", AuthClient)
+			observeHealthCheck(successor, time.Since(start).Seconds(), err == nil && status == http.StatusOK)
 			if err != nil {
-				fmt.Printf("Encountered error: %v\n", err.Error())
+				healthLog.Warn("Unable to reach follower", "node", successor.ID, "host", successor.Host, "port", successor.Port, "attempt", counter+1, "max_attempts", UPDATE_MAX_TRIES, "error", err)
 				time.Sleep(UPDATE_RETRY_DELAY * time.Second)
 				continue
 			}
 			if status != http.StatusOK {
-				fmt.Printf("Encountered error: %v\n", err.Error())
+				healthLog.Warn("Follower is unhealthy", "node", successor.ID, "host", successor.Host, "port", successor.Port, "status", status, "attempt", counter+1, "max_attempts", UPDATE_MAX_TRIES)
 				time.Sleep(UPDATE_RETRY_DELAY * time.Second)
 				continue
 			}
@@ -52,8 +61,13 @@ func updateSuccession() error {
 		}
 		if foundSuccessor {
 			newSuccession = append(newSuccession, successor)
+			err = setNodeHealth(successor.ID, "Healthy")
 		} else {
 			newUnknown = append(newUnknown, successor)
+			err = setNodeHealth(successor.ID, "Unknown")
+		}
+		if err != nil {
+			healthLog.Error("Unable to record node health", "node", successor.ID, "error", err)
 		}
 	}
 
@@ -63,9 +77,36 @@ func updateSuccession() error {
 	newUnhealthy = dedupeNodes(newUnhealthy)
 	newUnknown = dedupeNodes(newUnknown)
 
+	distributeCAKey(newSuccession)
+
+	err = rescheduleApplications()
+	if err != nil {
+		schedulerLog.Error("Unable to reschedule applications", "error", err)
+	}
+	err = scaleApplications()
+	if err != nil {
+		schedulerLog.Error("Unable to scale applications", "error", err)
+	}
+	err = bindVolumes()
+	if err != nil {
+		volumeLog.Error("Unable to bind volumes", "error", err)
+	}
+	err = cleanupBolts()
+	if err != nil {
+		lightningLog.Error("Unable to clean up bolts", "error", err)
+	}
+	err = auth.DeleteExpiredTokens()
+	if err != nil {
+		healthLog.Error("Unable to delete expired tokens", "error", err)
+	}
+	err = cleanupEvents()
+	if err != nil {
+		eventLog.Error("Unable to clean up events", "error", err)
+	}
+
 	nodeData, err = connection.Query("get record stormfront.leader")
 	if err != nil {
-		log.Printf("Unable to contact database during node get, changes to node status not recorded: %v\n", err)
+		healthLog.Error("Unable to get leader record, changes to node status not recorded", "error", err)
 		return err
 	}
 	nodeData[0]["succession"] = newSuccession
@@ -76,7 +117,7 @@ func updateSuccession() error {
 
 	_, err = connection.Query(fmt.Sprintf("put record stormfront.leader %s", payload))
 	if err != nil {
-		log.Printf("Unable to contact database during node put, changes to node status not recorded: %v\n", err)
+		healthLog.Error("Unable to update leader record, changes to node status not recorded", "error", err)
 		return err
 	}
 
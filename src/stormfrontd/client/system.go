@@ -50,10 +50,9 @@ func updateSystemInfo() error {
 	memoryReserved := 0
 	cpuReserved := 0.0
 	for _, application := range Client.Applications {
-		if application.Node == Client.ID {
-			memoryReserved += application.Memory
-			cpuReserved += application.CPU
-		}
+		localInstances := len(application.instancesOn(Client.ID))
+		memoryReserved += application.Memory * localInstances
+		cpuReserved += application.CPU * float64(localInstances)
 	}
 
 	diskInfo := DiskUsage("/")
@@ -67,11 +66,12 @@ func updateSystemInfo() error {
 	systemInfo.CPUAvailable = float64(systemInfo.Cores) - cpuUsed
 
 	Client.System = systemInfo
+	recordNodeStats(systemInfo)
 
 	// update client information
 	clientIDs, err := connection.Query(fmt.Sprintf(`get record stormfront.client .id | filter id = "%s"`, Client.ID))
 	if err != nil {
-		fmt.Printf("database error: %v", err)
+		healthLog.Error("Unable to get client record", "error", err)
 		return err
 	}
 	clientData, _ := json.Marshal(Client)
@@ -81,7 +81,7 @@ func updateSystemInfo() error {
 	clientData, _ = json.Marshal(clientMap)
 	_, err = connection.Query(fmt.Sprintf(`put record stormfront.client %s`, clientData))
 	if err != nil {
-		fmt.Printf("database error: %v", err)
+		healthLog.Error("Unable to update client record", "error", err)
 		return err
 	}
 
@@ -90,6 +90,9 @@ func updateSystemInfo() error {
 	if err != nil {
 		return err
 	}
+	if len(nodeData) == 0 {
+		return fmt.Errorf("no node record found for %s", Client.ID)
+	}
 
 	nodeBytes, err := json.Marshal(nodeData[0])
 	if err != nil {
@@ -111,7 +114,7 @@ func updateSystemInfo() error {
 
 	_, err = connection.Query(fmt.Sprintf(`put record stormfront.node %s`, nodeMarshalled))
 	if err != nil {
-		fmt.Printf("database error: %v", err)
+		healthLog.Error("Unable to update node record", "node", Client.ID, "error", err)
 		return err
 	}
 
@@ -0,0 +1,221 @@
+package timeseries
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Point is a sample, or the average of the samples taken during one interval
+// of a downsampled tier
+type Point struct {
+	Time   string             `json:"time" yaml:"time"`
+	Values map[string]float64 `json:"values" yaml:"values"`
+}
+
+// Tier keeps Capacity points at Resolution, so it covers Resolution *
+// Capacity of history. Every sample is added to every tier, coarser tiers
+// average the samples falling in each of their intervals.
+type Tier struct {
+	Resolution time.Duration
+	Capacity   int
+}
+
+func (t Tier) Span() time.Duration {
+	return t.Resolution * time.Duration(t.Capacity)
+}
+
+type point struct {
+	time   time.Time
+	values map[string]float64
+}
+
+// ring is a fixed size buffer of points for one tier, along with the running
+// sums of the interval currently being filled
+type ring struct {
+	tier   Tier
+	points []point
+	start  int
+	count  int
+
+	bucket  time.Time
+	sums    map[string]float64
+	samples int
+}
+
+func newRing(tier Tier) *ring {
+	return &ring{tier: tier, points: make([]point, tier.Capacity)}
+}
+
+func (r *ring) push(p point) {
+	idx := (r.start + r.count) % len(r.points)
+	r.points[idx] = p
+	if r.count < len(r.points) {
+		r.count++
+	} else {
+		r.start = (r.start + 1) % len(r.points)
+	}
+}
+
+// current averages the samples of the interval being filled
+func (r *ring) current() (point, bool) {
+	if r.samples == 0 {
+		return point{}, false
+	}
+	values := make(map[string]float64, len(r.sums))
+	for key, sum := range r.sums {
+		values[key] = sum / float64(r.samples)
+	}
+	return point{time: r.bucket, values: values}, true
+}
+
+func (r *ring) add(t time.Time, values map[string]float64) {
+	bucket := t.Truncate(r.tier.Resolution)
+	if r.samples > 0 && !bucket.Equal(r.bucket) {
+		p, _ := r.current()
+		r.push(p)
+		r.samples = 0
+	}
+	if r.samples == 0 {
+		r.bucket = bucket
+		r.sums = map[string]float64{}
+	}
+	for key, value := range values {
+		r.sums[key] += value
+	}
+	r.samples++
+}
+
+func (r *ring) since(t time.Time) []Point {
+	points := []Point{}
+	for i := 0; i < r.count; i++ {
+		p := r.points[(r.start+i)%len(r.points)]
+		if !p.time.Before(t) {
+			points = append(points, Point{Time: p.time.Format(time.RFC3339), Values: p.values})
+		}
+	}
+	if p, ok := r.current(); ok && !p.time.Before(t) {
+		points = append(points, Point{Time: p.time.Format(time.RFC3339), Values: p.values})
+	}
+	return points
+}
+
+// Series is the history of one node or application instance
+type Series struct {
+	mutex   sync.Mutex
+	rings   []*ring
+	updated time.Time
+}
+
+func NewSeries(tiers []Tier) *Series {
+	series := &Series{}
+	for _, tier := range tiers {
+		series.rings = append(series.rings, newRing(tier))
+	}
+	return series
+}
+
+func (s *Series) Add(t time.Time, values map[string]float64) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	for _, r := range s.rings {
+		r.add(t, values)
+	}
+	s.updated = t
+}
+
+// Query returns the points since t from the finest tier which reaches back
+// that far, or from the coarsest tier if none do
+func (s *Series) Query(t time.Time) []Point {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if len(s.rings) == 0 {
+		return []Point{}
+	}
+	window := time.Since(t)
+	for _, r := range s.rings {
+		if r.tier.Span() >= window {
+			return r.since(t)
+		}
+	}
+	return s.rings[len(s.rings)-1].since(t)
+}
+
+// Store holds a bounded series per key, series which stop receiving samples
+// are dropped by Prune
+type Store struct {
+	mutex  sync.Mutex
+	tiers  []Tier
+	series map[string]*Series
+}
+
+func NewStore(tiers ...Tier) *Store {
+	return &Store{tiers: tiers, series: map[string]*Series{}}
+}
+
+func (s *Store) Add(key string, t time.Time, values map[string]float64) {
+	s.mutex.Lock()
+	series, ok := s.series[key]
+	if !ok {
+		series = NewSeries(s.tiers)
+		s.series[key] = series
+	}
+	s.mutex.Unlock()
+	series.Add(t, values)
+}
+
+// Query returns the points of key since t, and false if nothing has been
+// recorded for key
+func (s *Store) Query(key string, t time.Time) ([]Point, bool) {
+	s.mutex.Lock()
+	series, ok := s.series[key]
+	s.mutex.Unlock()
+	if !ok {
+		return []Point{}, false
+	}
+	return series.Query(t), true
+}
+
+// MaxRange is how far back the coarsest tier reaches
+func (s *Store) MaxRange() time.Duration {
+	max := time.Duration(0)
+	for _, tier := range s.tiers {
+		if tier.Span() > max {
+			max = tier.Span()
+		}
+	}
+	return max
+}
+
+// Prune drops the series which have not been updated since t
+func (s *Store) Prune(t time.Time) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	for key, series := range s.series {
+		series.mutex.Lock()
+		stale := series.updated.Before(t)
+		series.mutex.Unlock()
+		if stale {
+			delete(s.series, key)
+		}
+	}
+}
+
+// ParseRange reads a range such as 90s, 15m, 6h or 7d
+func ParseRange(value string) (time.Duration, error) {
+	var window time.Duration
+	var err error
+	if strings.HasSuffix(value, "d") {
+		var days int
+		days, err = strconv.Atoi(strings.TrimSuffix(value, "d"))
+		window = time.Duration(days) * 24 * time.Hour
+	} else {
+		window, err = time.ParseDuration(value)
+	}
+	if err != nil || window <= 0 {
+		return 0, fmt.Errorf("invalid range %s, must be a positive duration such as 15m, 6h or 7d", value)
+	}
+	return window, nil
+}
@@ -0,0 +1,117 @@
+package timeseries
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestRing(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		samples  []float64
+		expected []float64
+	}{
+		{name: "empty", samples: nil, expected: []float64{}},
+		{name: "open interval only", samples: []float64{1}, expected: []float64{1}},
+		{name: "under capacity", samples: []float64{1, 2, 3}, expected: []float64{1, 2, 3}},
+		// Three closed intervals plus the open one, so the oldest is dropped
+		{name: "wraps around", samples: []float64{1, 2, 3, 4, 5}, expected: []float64{2, 3, 4, 5}},
+		{name: "wraps twice", samples: []float64{1, 2, 3, 4, 5, 6, 7, 8}, expected: []float64{5, 6, 7, 8}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := newRing(Tier{Resolution: time.Minute, Capacity: 3})
+			for idx, value := range test.samples {
+				r.add(start.Add(time.Duration(idx)*time.Minute), map[string]float64{"cpu": value})
+			}
+
+			values := []float64{}
+			for _, p := range r.since(start) {
+				values = append(values, p.Values["cpu"])
+			}
+			if !reflect.DeepEqual(values, test.expected) {
+				t.Fatalf("expected %v, got %v", test.expected, values)
+			}
+		})
+	}
+}
+
+func TestRingAveragesIntervals(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	r := newRing(Tier{Resolution: time.Minute, Capacity: 10})
+	r.add(start, map[string]float64{"cpu": 10})
+	r.add(start.Add(20*time.Second), map[string]float64{"cpu": 20})
+	r.add(start.Add(40*time.Second), map[string]float64{"cpu": 60})
+	r.add(start.Add(time.Minute), map[string]float64{"cpu": 5})
+
+	points := r.since(start.Add(time.Minute))
+	if len(points) != 1 || points[0].Values["cpu"] != 5 {
+		t.Fatalf("expected only the open interval after the first minute, got %v", points)
+	}
+	points = r.since(start)
+	if len(points) != 2 || points[0].Values["cpu"] != 30 || points[0].Time != start.Format(time.RFC3339) {
+		t.Fatalf("expected the first minute to average to 30, got %v", points)
+	}
+}
+
+func TestSeriesQueryPicksTier(t *testing.T) {
+	now := time.Now()
+	series := NewSeries([]Tier{{Resolution: time.Second, Capacity: 60}, {Resolution: time.Minute, Capacity: 60}})
+	for idx := 0; idx < 120; idx++ {
+		series.Add(now.Add(time.Duration(idx-120)*time.Second), map[string]float64{"cpu": 1})
+	}
+
+	tests := []struct {
+		name   string
+		since  time.Duration
+		points int
+	}{
+		{name: "fine tier", since: 30 * time.Second, points: 30},
+		{name: "coarse tier", since: 5 * time.Minute, points: 3},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			points := series.Query(now.Add(-test.since))
+			if len(points) < test.points-1 || len(points) > test.points+1 {
+				t.Fatalf("expected about %d points, got %d", test.points, len(points))
+			}
+		})
+	}
+}
+
+func TestParseRange(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected time.Duration
+		err      bool
+	}{
+		{value: "90s", expected: 90 * time.Second},
+		{value: "15m", expected: 15 * time.Minute},
+		{value: "6h", expected: 6 * time.Hour},
+		{value: "7d", expected: 7 * 24 * time.Hour},
+		{value: "0s", err: true},
+		{value: "-1h", err: true},
+		{value: "xd", err: true},
+		{value: "week", err: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.value, func(t *testing.T) {
+			window, err := ParseRange(test.value)
+			if test.err {
+				if err == nil {
+					t.Fatalf("expected an error, got %v", window)
+				}
+				return
+			}
+			if err != nil || window != test.expected {
+				t.Fatalf("expected %v, got %v (%v)", test.expected, window, err)
+			}
+		})
+	}
+}
@@ -17,6 +17,41 @@ func contains(s []string, e string) bool {
 	return false
 }
 
+func stringMapsEqual(a, b map[string]string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for key, val := range a {
+		if other, ok := b[key]; !ok || other != val {
+			return false
+		}
+	}
+	return true
+}
+
+func stringSlicesEqual(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for idx := range a {
+		if a[idx] != b[idx] {
+			return false
+		}
+	}
+	return true
+}
+
+// validateDataKeys makes sure the keys of a secret or config can be written as
+// file names when the object is mounted into a container
+func validateDataKeys(data map[string]string) error {
+	for key := range data {
+		if key == "" || key == "." || key == ".." || strings.ContainsAny(key, "/\\") {
+			return fmt.Errorf("invalid key '%s'", key)
+		}
+	}
+	return nil
+}
+
 func dedupeNodes(nodes []StormfrontNode) []StormfrontNode {
 	out := []StormfrontNode{}
 
@@ -80,6 +115,38 @@ func getNodes() ([]StormfrontNode, error) {
 	return nodes, nil
 }
 
+func getNode(id string) (StormfrontNode, bool, error) {
+	nodes, err := getNodes()
+	if err != nil {
+		return StormfrontNode{}, false, err
+	}
+	for _, node := range nodes {
+		if node.ID == id {
+			return node, true, nil
+		}
+	}
+	return StormfrontNode{}, false, nil
+}
+
+// putNode creates or replaces the stormfront.node record for a node
+func putNode(node StormfrontNode) error {
+	nodeIDs, err := connection.Query(fmt.Sprintf(`get record stormfront.node .id | filter id = "%s"`, node.ID))
+	if err != nil {
+		return err
+	}
+	nodeData, _ := json.Marshal(node)
+	if len(nodeIDs) == 0 {
+		_, err = connection.Query(fmt.Sprintf(`post record stormfront.node %s`, nodeData))
+		return err
+	}
+	var nodeMap map[string]interface{}
+	json.Unmarshal(nodeData, &nodeMap)
+	nodeMap[".id"] = nodeIDs[0][".id"]
+	nodeData, _ = json.Marshal(nodeMap)
+	_, err = connection.Query(fmt.Sprintf(`put record stormfront.node %s`, nodeData))
+	return err
+}
+
 func getApplications() ([]StormfrontApplication, error) {
 	applicationData, err := connection.Query("get record stormfront.application")
 	if err != nil {
@@ -98,6 +165,40 @@ func getApplications() ([]StormfrontApplication, error) {
 	return applications, nil
 }
 
+func getNamespaces() ([]StormfrontNamespace, error) {
+	namespaceData, err := connection.Query("get record stormfront.namespace")
+	if err != nil {
+		return nil, err
+	}
+	namespaces := []StormfrontNamespace{}
+	namespaceBytes, err := json.Marshal(namespaceData)
+	if err != nil {
+		return nil, err
+	}
+	err = json.Unmarshal(namespaceBytes, &namespaces)
+	if err != nil {
+		return nil, err
+	}
+
+	return namespaces, nil
+}
+
+// getNamespace looks up a namespace by either its ID or its name
+func getNamespace(idOrName string) (StormfrontNamespace, bool, error) {
+	namespaces, err := getNamespaces()
+	if err != nil {
+		return StormfrontNamespace{}, false, err
+	}
+
+	for _, namespace := range namespaces {
+		if namespace.ID == idOrName || namespace.Name == idOrName {
+			return namespace, true, nil
+		}
+	}
+
+	return StormfrontNamespace{}, false, nil
+}
+
 func getClients() ([]StormfrontClient, error) {
 	clientData, err := connection.Query("get record stormfront.client")
 	if err != nil {
@@ -116,8 +217,19 @@ func getClients() ([]StormfrontClient, error) {
 	return clients, nil
 }
 
-func lookupFunc(domain string) (string, error) {
-	fmt.Printf("DNS request received for %s", domain)
+// dnsRotation offsets the order of the addresses returned for a hostname on
+// each lookup so that clients which take the first answer are spread across
+// replicas
+var dnsRotation = 0
+
+func lookupFunc(domain string) ([]string, error) {
+	hosts, err := lookupHosts(domain)
+	observeDNSQuery(err)
+	return hosts, err
+}
+
+func lookupHosts(domain string) ([]string, error) {
+	dnsLog.Debug("Received DNS request", "domain", domain)
 	parts := strings.Split(domain, ".")
 
 	length := len(parts)
@@ -126,27 +238,47 @@ func lookupFunc(domain string) (string, error) {
 
 	nodes, err := getNodes()
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 	applications, err := getApplications()
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
+	hosts := []string{}
 	for _, app := range applications {
-		if app.Hostname == hostname && app.Namespace == namespace {
-			scheduledNode := app.Node
+		if app.Hostname != hostname || app.Namespace != namespace {
+			continue
+		}
+		for _, instance := range app.Instances {
 			for _, node := range nodes {
-				if node.ID == scheduledNode {
-					if node.Health != "Healthy" {
-						return "", fmt.Errorf("node %s is unhealthy", node.ID)
-					}
-					fmt.Printf("Routing traffic from %s to %s", domain, node.Host)
-					return node.Host, nil
+				if node.ID != instance.Node {
+					continue
 				}
+				if node.Health != "Healthy" {
+					dnsLog.Debug("Skipping instance on unhealthy node", "domain", domain, "instance", instance.Name, "node", node.ID)
+					break
+				}
+				if app.ReadinessProbe != nil && !app.InstanceStatus[instance.Name].Ready {
+					dnsLog.Debug("Skipping instance which is not ready", "domain", domain, "instance", instance.Name)
+					break
+				}
+				if !contains(hosts, node.Host) {
+					hosts = append(hosts, node.Host)
+				}
+				break
 			}
 		}
 	}
 
-	return "", fmt.Errorf("no node is currently serving hostname '%s' with namespace '%s'", hostname, namespace)
+	if len(hosts) == 0 {
+		return nil, fmt.Errorf("no healthy node is currently serving hostname '%s' with namespace '%s'", hostname, namespace)
+	}
+
+	dnsRotation++
+	offset := dnsRotation % len(hosts)
+	hosts = append(hosts[offset:], hosts[:offset]...)
+
+	dnsLog.Debug("Routing traffic", "domain", domain, "hosts", hosts)
+	return hosts, nil
 }
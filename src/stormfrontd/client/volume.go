@@ -0,0 +1,875 @@
+package client
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"stormfrontd/client/communication"
+	"stormfrontd/config"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jfcarter2358/ceresdb-go/connection"
+)
+
+const VOLUME_DIRECTORY = "/var/stormfront/volumes"
+
+const VOLUME_POLICY_RETAIN = "retain"
+const VOLUME_POLICY_DELETE = "delete"
+
+const VOLUME_DEFAULT_REPLICATION_INTERVAL = 300
+
+var VolumePolicies = []string{VOLUME_POLICY_RETAIN, VOLUME_POLICY_DELETE}
+
+// StormfrontVolume is persistent storage which outlives the applications
+// mounting it. A volume is bound to the node its first instance runs on and,
+// if Replicate is set, that node periodically mirrors its contents to Replica
+// so the application can be rescheduled there along with its data. Policy
+// decides whether the data is removed from the nodes when the volume is
+// deleted.
+type StormfrontVolume struct {
+	ID                  string `json:"id" yaml:"id"`
+	Name                string `json:"name" yaml:"name"`
+	Namespace           string `json:"namespace" yaml:"namespace"`
+	Policy              string `json:"policy" yaml:"policy"`
+	Replicate           bool   `json:"replicate" yaml:"replicate"`
+	ReplicationInterval int    `json:"replication_interval" yaml:"replication_interval"`
+	Node                string `json:"node" yaml:"node"`
+	Replica             string `json:"replica" yaml:"replica"`
+	Replicated          string `json:"replicated" yaml:"replicated"`
+}
+
+func volumeDirectory(id string) string {
+	return filepath.Join(VOLUME_DIRECTORY, id)
+}
+
+func getVolumes() ([]StormfrontVolume, error) {
+	volumeData, err := connection.Query("get record stormfront.volume")
+	if err != nil {
+		return nil, err
+	}
+	volumes := []StormfrontVolume{}
+	volumeBytes, err := json.Marshal(volumeData)
+	if err != nil {
+		return nil, err
+	}
+	err = json.Unmarshal(volumeBytes, &volumes)
+	if err != nil {
+		return nil, err
+	}
+
+	return volumes, nil
+}
+
+func findVolume(volumes []StormfrontVolume, name, namespace string) (StormfrontVolume, bool) {
+	for _, volume := range volumes {
+		if volume.Name == name && volume.Namespace == namespace {
+			return volume, true
+		}
+	}
+	return StormfrontVolume{}, false
+}
+
+// validateVolumes makes sure the volumes an application mounts exist and are
+// not already mounted by another application. Volumes have a single writer so
+// applications mounting them can only run one replica.
+func validateVolumes(app StormfrontApplication, applications []StormfrontApplication) error {
+	if len(app.Volumes) == 0 {
+		return nil
+	}
+	if app.replicaCount() > 1 {
+		return fmt.Errorf("applications mounting volumes can only run a single replica")
+	}
+	volumes, err := getVolumes()
+	if err != nil {
+		return err
+	}
+	for name := range app.Volumes {
+		if _, found := findVolume(volumes, name, app.Namespace); !found {
+			return fmt.Errorf("volume %s does not exist in namespace %s", name, app.Namespace)
+		}
+		for _, other := range applications {
+			if other.ID == app.ID || other.Namespace != app.Namespace {
+				continue
+			}
+			if _, ok := other.Volumes[name]; ok {
+				return fmt.Errorf("volume %s is already mounted by application %s", name, other.Name)
+			}
+		}
+	}
+	return nil
+}
+
+// volumeNodes returns the nodes an application has to run on to reach the
+// data of its volumes, or nil if none of them are bound yet. A replica only
+// counts once it has received a copy of the data.
+func volumeNodes(app StormfrontApplication) ([]string, error) {
+	if len(app.Volumes) == 0 {
+		return nil, nil
+	}
+	volumes, err := getVolumes()
+	if err != nil {
+		return nil, err
+	}
+
+	var allowed []string
+	for name := range app.Volumes {
+		volume, found := findVolume(volumes, name, app.Namespace)
+		if !found || volume.Node == "" {
+			continue
+		}
+		holders := []string{volume.Node}
+		if volume.Replicate && volume.Replica != "" && volume.Replicated != "" {
+			holders = append(holders, volume.Replica)
+		}
+		if allowed == nil {
+			allowed = holders
+			continue
+		}
+		remaining := []string{}
+		for _, id := range allowed {
+			if contains(holders, id) {
+				remaining = append(remaining, id)
+			}
+		}
+		allowed = remaining
+	}
+	return allowed, nil
+}
+
+// bindVolumes is run by the leader to keep each volume bound to the node
+// running the application which mounts it. When the application has been
+// rescheduled onto the replica the two nodes swap roles so that replication
+// flows from the new node back to the old one once it returns.
+func bindVolumes() error {
+	volumeData, err := connection.Query("get record stormfront.volume")
+	if err != nil {
+		return err
+	}
+	volumes := []StormfrontVolume{}
+	volumeBytes, _ := json.Marshal(volumeData)
+	json.Unmarshal(volumeBytes, &volumes)
+
+	applications, err := getApplications()
+	if err != nil {
+		return err
+	}
+	nodes, err := getNodes()
+	if err != nil {
+		return err
+	}
+
+	for idx, volume := range volumes {
+		node := volume.Node
+		for _, app := range applications {
+			if _, ok := app.Volumes[volume.Name]; !ok || app.Namespace != volume.Namespace || len(app.Instances) == 0 {
+				continue
+			}
+			node = app.Instances[0].Node
+		}
+
+		replica := volume.Replica
+		replicated := volume.Replicated
+		if node != volume.Node && volume.Node != "" {
+			volumeLog.Info("Volume moved to another node", "volume", volume.Name, "namespace", volume.Namespace, "from", volume.Node, "to", node)
+			if node == volume.Replica {
+				replica = volume.Node
+			} else {
+				replica = ""
+			}
+			replicated = ""
+		}
+		if volume.Replicate && node != "" {
+			replica = pickReplica(node, replica, nodes)
+		} else if !volume.Replicate {
+			replica = ""
+		}
+
+		if node == volume.Node && replica == volume.Replica && replicated == volume.Replicated {
+			continue
+		}
+		_, err := connection.Query(fmt.Sprintf(`patch record stormfront.volume '%s' {"node":"%s","replica":"%s","replicated":"%s"}`, volumeData[idx][".id"].(string), node, replica, replicated))
+		if err != nil {
+			volumeLog.Error("Unable to update volume binding", "volume", volume.ID, "error", err)
+		}
+	}
+
+	return nil
+}
+
+// pickReplica keeps the current replica as long as it is still registered,
+// otherwise the first healthy node other than the volume's own is chosen
+func pickReplica(node, replica string, nodes []StormfrontNode) string {
+	for _, candidate := range nodes {
+		if candidate.ID == replica && replica != node {
+			return replica
+		}
+	}
+	for _, candidate := range nodes {
+		if candidate.ID != node && candidate.Health == "Healthy" {
+			return candidate.ID
+		}
+	}
+	return ""
+}
+
+// mountVolumes returns the host directories of the volumes an application
+// mounts mapped to their container directories
+func mountVolumes(app StormfrontApplication) (map[string]string, error) {
+	mounts := map[string]string{}
+	if len(app.Volumes) == 0 {
+		return mounts, nil
+	}
+	volumes, err := getVolumes()
+	if err != nil {
+		return nil, err
+	}
+	for name, dst := range app.Volumes {
+		volume, found := findVolume(volumes, name, app.Namespace)
+		if !found {
+			return nil, fmt.Errorf("volume %s does not exist in namespace %s", name, app.Namespace)
+		}
+		src := volumeDirectory(volume.ID)
+		if err := os.MkdirAll(src, os.ModePerm); err != nil {
+			return nil, err
+		}
+		mounts[src] = dst
+	}
+	return mounts, nil
+}
+
+// VOLUME_MANIFEST_ENTRY is the first entry of every replication archive and
+// lists all files on the sending node, so the replica knows what to remove
+const VOLUME_MANIFEST_ENTRY = ".stormfront-manifest.json"
+
+// volumeFile is compared between the two copies of a volume to decide which
+// files have to be sent again, using the size and modification time like
+// rsync does by default
+type volumeFile struct {
+	Dir     bool  `json:"dir"`
+	Size    int64 `json:"size"`
+	ModTime int64 `json:"mod_time"`
+}
+
+// volumeReplications tracks the transfers running in the background so that
+// the health check never waits on them. Finished transfers are recorded by
+// the next health check, which is the only place the records are written.
+var volumeReplications = struct {
+	sync.Mutex
+	running  map[string]bool
+	finished map[string]time.Time
+}{running: map[string]bool{}, finished: map[string]time.Time{}}
+
+// replicateVolumes starts sending the changes to every volume bound to this
+// node to its replica once the volume's replication interval has passed.
+// Files removed here are removed from the replica too.
+func replicateVolumes() {
+	connection.Host = Client.Leader.Host
+	defer func() { connection.Host = config.Config.CeresDBHost }()
+
+	volumeData, err := connection.Query(fmt.Sprintf(`get record stormfront.volume | filter node = '%s'`, Client.ID))
+	if err != nil {
+		volumeLog.Error("Unable to get volumes", "error", err)
+		return
+	}
+	volumes := []StormfrontVolume{}
+	volumeBytes, _ := json.Marshal(volumeData)
+	json.Unmarshal(volumeBytes, &volumes)
+
+	volumeReplications.Lock()
+	defer volumeReplications.Unlock()
+
+	for idx, volume := range volumes {
+		if finished, ok := volumeReplications.finished[volume.ID]; ok {
+			delete(volumeReplications.finished, volume.ID)
+			_, err = connection.Query(fmt.Sprintf(`patch record stormfront.volume '%s' {"replicated":"%s"}`, volumeData[idx][".id"].(string), finished.Format(time.RFC3339)))
+			if err != nil {
+				volumeLog.Error("Unable to record volume replication", "volume", volume.ID, "error", err)
+			}
+			continue
+		}
+		if volumeReplications.running[volume.ID] {
+			continue
+		}
+		if !volume.Replicate || volume.Replica == "" || volume.Replica == Client.ID {
+			continue
+		}
+		if replicated, err := time.Parse(time.RFC3339, volume.Replicated); err == nil && time.Since(replicated) < time.Duration(volume.ReplicationInterval)*time.Second {
+			continue
+		}
+
+		replica, found, err := getNode(volume.Replica)
+		if err != nil || !found || replica.Health != "Healthy" {
+			continue
+		}
+
+		volumeReplications.running[volume.ID] = true
+		go replicateVolume(volume, replica)
+	}
+}
+
+// replicateVolume asks the replica which files it already holds and streams
+// an archive of the ones that differ
+func replicateVolume(volume StormfrontVolume, replica StormfrontNode) {
+	defer func() {
+		volumeReplications.Lock()
+		delete(volumeReplications.running, volume.ID)
+		volumeReplications.Unlock()
+	}()
+
+	status, body, err := communication.Get(context.Background(), replica.Host, replica.Port, fmt.Sprintf("api/volume/%s/manifest", volume.ID), AuthClient)
+	if err != nil || status != http.StatusOK {
+		volumeLog.Error("Unable to get replica manifest", "volume", volume.Name, "node", replica.ID, "status", status, "body", body, "error", err)
+		return
+	}
+	remote := map[string]volumeFile{}
+	if err := json.Unmarshal([]byte(body), &remote); err != nil {
+		volumeLog.Error("Unable to read replica manifest", "volume", volume.Name, "node", replica.ID, "error", err)
+		return
+	}
+
+	dir := volumeDirectory(volume.ID)
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		volumeLog.Error("Unable to archive volume", "volume", volume.Name, "error", err)
+		return
+	}
+	local, err := volumeManifest(dir)
+	if err != nil {
+		volumeLog.Error("Unable to archive volume", "volume", volume.Name, "error", err)
+		return
+	}
+
+	reader, writer := io.Pipe()
+	go func() {
+		writer.CloseWithError(archiveDirectory(dir, local, remote, writer))
+	}()
+	status, body, err = communication.PostStream(context.Background(), replica.Host, replica.Port, fmt.Sprintf("api/volume/%s/data", volume.ID), AuthClient, reader)
+	// Unblocks the archive writer if the request ended before reading it all
+	reader.Close()
+	if err != nil || status != http.StatusOK {
+		volumeLog.Error("Unable to replicate volume", "volume", volume.Name, "node", replica.ID, "status", status, "body", body, "error", err)
+		return
+	}
+
+	volumeReplications.Lock()
+	volumeReplications.finished[volume.ID] = time.Now()
+	volumeReplications.Unlock()
+}
+
+// volumeManifest lists every file and directory below dir, an empty
+// manifest is returned if dir does not exist yet
+func volumeManifest(dir string) (map[string]volumeFile, error) {
+	manifest := map[string]volumeFile{}
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			if path == dir && os.IsNotExist(err) {
+				return filepath.SkipDir
+			}
+			return err
+		}
+		if path == dir || !(info.IsDir() || info.Mode().IsRegular()) {
+			return nil
+		}
+		relative, _ := filepath.Rel(dir, path)
+		file := volumeFile{Dir: info.IsDir()}
+		if !info.IsDir() {
+			file.Size = info.Size()
+			file.ModTime = info.ModTime().Unix()
+		}
+		manifest[filepath.ToSlash(relative)] = file
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return manifest, nil
+}
+
+// archiveDirectory writes the manifest of dir followed by the files which
+// are missing or differ in remote
+func archiveDirectory(dir string, local, remote map[string]volumeFile, out io.Writer) error {
+	gzipWriter := gzip.NewWriter(out)
+	tarWriter := tar.NewWriter(gzipWriter)
+
+	manifestBytes, err := json.Marshal(local)
+	if err != nil {
+		return err
+	}
+	err = tarWriter.WriteHeader(&tar.Header{Name: VOLUME_MANIFEST_ENTRY, Mode: 0600, Size: int64(len(manifestBytes)), ModTime: time.Now()})
+	if err != nil {
+		return err
+	}
+	if _, err := tarWriter.Write(manifestBytes); err != nil {
+		return err
+	}
+
+	err = filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if path == dir || !(info.IsDir() || info.Mode().IsRegular()) {
+			return nil
+		}
+		relative, _ := filepath.Rel(dir, path)
+		name := filepath.ToSlash(relative)
+		if existing, ok := remote[name]; ok && existing == local[name] {
+			return nil
+		}
+		header, err := tar.FileInfoHeader(info, "")
+		if err != nil {
+			return err
+		}
+		header.Name = name
+		// The archive only keeps whole seconds, truncating here keeps the
+		// replica's times equal to the ones in the manifest
+		header.ModTime = info.ModTime().Truncate(time.Second)
+		if err := tarWriter.WriteHeader(header); err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+		file, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		_, err = io.Copy(tarWriter, file)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := tarWriter.Close(); err != nil {
+		return err
+	}
+	return gzipWriter.Close()
+}
+
+// extractDirectory unpacks the changed files of an archive next to dir and
+// only applies them once the whole archive has arrived, so that a failed
+// transfer never leaves a half written replica. Anything in dir which is
+// not in the archive's manifest is removed.
+func extractDirectory(dir string, archive io.Reader) error {
+	incoming := dir + ".incoming"
+	os.RemoveAll(incoming)
+	if err := os.MkdirAll(incoming, os.ModePerm); err != nil {
+		return err
+	}
+	defer os.RemoveAll(incoming)
+
+	gzipReader, err := gzip.NewReader(archive)
+	if err != nil {
+		return err
+	}
+	tarReader := tar.NewReader(gzipReader)
+	var manifest map[string]volumeFile
+	headers := []*tar.Header{}
+	for {
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if header.Name == VOLUME_MANIFEST_ENTRY {
+			if err := json.NewDecoder(tarReader).Decode(&manifest); err != nil {
+				return err
+			}
+			continue
+		}
+		target := filepath.Join(incoming, filepath.FromSlash(header.Name))
+		if !strings.HasPrefix(target, incoming+string(os.PathSeparator)) {
+			return fmt.Errorf("archive entry %s escapes the volume directory", header.Name)
+		}
+		switch header.Typeflag {
+		case tar.TypeDir:
+			headers = append(headers, header)
+		case tar.TypeReg:
+			if err := os.MkdirAll(filepath.Dir(target), os.ModePerm); err != nil {
+				return err
+			}
+			file, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, os.FileMode(header.Mode))
+			if err != nil {
+				return err
+			}
+			_, err = io.Copy(file, tarReader)
+			file.Close()
+			if err != nil {
+				return err
+			}
+			headers = append(headers, header)
+		}
+	}
+	if manifest == nil {
+		return fmt.Errorf("archive is missing %s", VOLUME_MANIFEST_ENTRY)
+	}
+
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return err
+	}
+	err = filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if path == dir {
+			return nil
+		}
+		relative, _ := filepath.Rel(dir, path)
+		if file, ok := manifest[filepath.ToSlash(relative)]; ok && file.Dir == info.IsDir() {
+			return nil
+		}
+		if err := os.RemoveAll(path); err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return filepath.SkipDir
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, header := range headers {
+		staged := filepath.Join(incoming, filepath.FromSlash(header.Name))
+		target := filepath.Join(dir, filepath.FromSlash(header.Name))
+		if header.Typeflag == tar.TypeDir {
+			if err := os.MkdirAll(target, os.FileMode(header.Mode)); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(target), os.ModePerm); err != nil {
+			return err
+		}
+		if err := os.Rename(staged, target); err != nil {
+			return err
+		}
+		if err := os.Chtimes(target, header.ModTime, header.ModTime); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func CreateVolume(c *gin.Context) {
+	if Client.Type != "Leader" {
+		c.Redirect(http.StatusTemporaryRedirect, fmt.Sprintf("https://%s:%v/api/volume", Client.Leader.Host, Client.Leader.Port))
+		return
+	}
+
+	var volume StormfrontVolume
+	if err := c.BindJSON(&volume); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if !namespaceNameRegex.MatchString(volume.Name) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("invalid volume name '%s', names must be lowercase alphanumeric characters or '-'", volume.Name)})
+		return
+	}
+	if volume.Policy == "" {
+		volume.Policy = VOLUME_POLICY_RETAIN
+	}
+	if !contains(VolumePolicies, volume.Policy) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("invalid volume policy %s, allowed policies are %s", volume.Policy, strings.Join(VolumePolicies, ", "))})
+		return
+	}
+	if volume.ReplicationInterval < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "replication interval must not be negative"})
+		return
+	}
+	if volume.ReplicationInterval == 0 {
+		volume.ReplicationInterval = VOLUME_DEFAULT_REPLICATION_INTERVAL
+	}
+
+	if volume.Namespace == "" {
+		volume.Namespace = DEFAULT_NAMESPACE
+	}
+	if !authorizeNamespace(c, volume.Namespace) {
+		return
+	}
+	if _, found, err := getNamespace(volume.Namespace); err != nil || !found {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("namespace %s does not exist", volume.Namespace)})
+		return
+	}
+
+	volumes, err := getVolumes()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if _, found := findVolume(volumes, volume.Name, volume.Namespace); found {
+		c.JSON(http.StatusConflict, gin.H{"error": fmt.Sprintf("volume %s already exists in namespace %s", volume.Name, volume.Namespace)})
+		return
+	}
+
+	// Binding and replication are managed by the leader
+	volume.Node = ""
+	volume.Replica = ""
+	volume.Replicated = ""
+	volume.ID = uuid.NewString()
+	volumeData, _ := json.Marshal(volume)
+	_, err = connection.Query(fmt.Sprintf("post record stormfront.volume %s", volumeData))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("unable to create volume: %v", err.Error())})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"id": volume.ID})
+}
+
+func GetAllVolumes(c *gin.Context) {
+	if Client.Type != "Leader" {
+		c.Redirect(http.StatusTemporaryRedirect, fmt.Sprintf("https://%s:%v/api/volume", Client.Leader.Host, Client.Leader.Port))
+		return
+	}
+
+	volumes, err := getVolumes()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	scoped := []StormfrontVolume{}
+	for _, volume := range volumes {
+		if namespaceAllowed(c, volume.Namespace) {
+			scoped = append(scoped, volume)
+		}
+	}
+
+	c.JSON(http.StatusOK, scoped)
+}
+
+func GetVolume(c *gin.Context) {
+	id := c.Param("id")
+
+	if Client.Type != "Leader" {
+		c.Redirect(http.StatusTemporaryRedirect, fmt.Sprintf("https://%s:%v/api/volume/%s", Client.Leader.Host, Client.Leader.Port, id))
+		return
+	}
+
+	volumes, err := getVolumes()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	for _, volume := range volumes {
+		if volume.ID == id {
+			c.JSON(http.StatusOK, volume)
+			return
+		}
+	}
+
+	c.Status(http.StatusNotFound)
+}
+
+// UpdateVolume changes the policy and replication settings of a volume
+func UpdateVolume(c *gin.Context) {
+	id := c.Param("id")
+
+	if Client.Type != "Leader" {
+		c.Redirect(http.StatusTemporaryRedirect, fmt.Sprintf("https://%s:%v/api/volume/%s", Client.Leader.Host, Client.Leader.Port, id))
+		return
+	}
+
+	var desired map[string]interface{}
+	if err := c.BindJSON(&desired); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	data, err := connection.Query(fmt.Sprintf(`get record stormfront.volume | filter id = '%s'`, id))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if len(data) == 0 {
+		c.Status(http.StatusNotFound)
+		return
+	}
+
+	var volume StormfrontVolume
+	volumeBytes, _ := json.Marshal(data[0])
+	json.Unmarshal(volumeBytes, &volume)
+
+	changed := []string{}
+	if policy, ok := desired["policy"].(string); ok && policy != volume.Policy {
+		if !contains(VolumePolicies, policy) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("invalid volume policy %s, allowed policies are %s", policy, strings.Join(VolumePolicies, ", "))})
+			return
+		}
+		volume.Policy = policy
+		changed = append(changed, "policy")
+	}
+	if replicate, ok := desired["replicate"].(bool); ok && replicate != volume.Replicate {
+		volume.Replicate = replicate
+		changed = append(changed, "replicate")
+	}
+	if interval, ok := desired["replication_interval"].(float64); ok && int(interval) != volume.ReplicationInterval {
+		if interval <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "replication interval must be positive"})
+			return
+		}
+		volume.ReplicationInterval = int(interval)
+		changed = append(changed, "replication_interval")
+	}
+	if len(changed) == 0 {
+		c.JSON(http.StatusOK, gin.H{"id": volume.ID, "changed": changed})
+		return
+	}
+
+	_, err = connection.Query(fmt.Sprintf(`patch record stormfront.volume '%s' {"policy":"%s","replicate":%v,"replication_interval":%v}`, data[0][".id"].(string), volume.Policy, volume.Replicate, volume.ReplicationInterval))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("unable to update volume: %v", err.Error())})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"id": volume.ID, "changed": changed})
+}
+
+// DeleteVolume removes a volume which is no longer mounted. Volumes with the
+// delete policy also have their data removed from the nodes holding it.
+func DeleteVolume(c *gin.Context) {
+	id := c.Param("id")
+
+	if Client.Type != "Leader" {
+		c.Redirect(http.StatusTemporaryRedirect, fmt.Sprintf("https://%s:%v/api/volume/%s", Client.Leader.Host, Client.Leader.Port, id))
+		return
+	}
+
+	volumes, err := getVolumes()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	var volume StormfrontVolume
+	found := false
+	for _, existing := range volumes {
+		if existing.ID == id {
+			volume = existing
+			found = true
+		}
+	}
+	if !found {
+		c.Status(http.StatusNotFound)
+		return
+	}
+
+	applications, err := getApplications()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	for _, app := range applications {
+		if _, ok := app.Volumes[volume.Name]; ok && app.Namespace == volume.Namespace {
+			c.JSON(http.StatusConflict, gin.H{"error": fmt.Sprintf("volume %s is mounted by application %s", volume.Name, app.Name)})
+			return
+		}
+	}
+
+	_, err = connection.Query(fmt.Sprintf(`get record stormfront.volume .id | filter id = '%s' | delete record stormfront.volume -`, id))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	removeVolumeData(volume)
+
+	c.Status(http.StatusNoContent)
+}
+
+// removeVolumeData deletes the data of a volume whose record has been removed
+// from the node it is bound to and from its replica, unless the volume's
+// policy is to keep it
+func removeVolumeData(volume StormfrontVolume) {
+	if volume.Policy != VOLUME_POLICY_DELETE {
+		return
+	}
+	for _, nodeID := range []string{volume.Node, volume.Replica} {
+		if nodeID == "" {
+			continue
+		}
+		if nodeID == Client.ID {
+			os.RemoveAll(volumeDirectory(volume.ID))
+			continue
+		}
+		node, found, err := getNode(nodeID)
+		if err != nil || !found {
+			volumeLog.Error("Unable to find node to remove volume data", "volume", volume.Name, "node", nodeID, "error", err)
+			continue
+		}
+		status, _, err := communication.Delete(context.Background(), node.Host, node.Port, fmt.Sprintf("api/volume/%s/data", volume.ID), AuthClient)
+		if err != nil || status != http.StatusNoContent {
+			volumeLog.Error("Unable to remove volume data", "volume", volume.Name, "node", nodeID, "status", status, "error", err)
+		}
+	}
+}
+
+// ReceiveVolumeData is called on a replica by the node a volume is bound to
+// with an archive of the files which changed since the last replication
+func ReceiveVolumeData(c *gin.Context) {
+	id := c.Param("id")
+
+	if _, err := uuid.Parse(id); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid volume id"})
+		return
+	}
+
+	err := extractDirectory(volumeDirectory(id), c.Request.Body)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("unable to store volume data: %v", err)})
+		return
+	}
+
+	c.Status(http.StatusOK)
+}
+
+// GetVolumeManifest is called on a replica by the node a volume is bound to
+// to find out which files have to be sent
+func GetVolumeManifest(c *gin.Context) {
+	id := c.Param("id")
+
+	if _, err := uuid.Parse(id); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid volume id"})
+		return
+	}
+
+	manifest, err := volumeManifest(volumeDirectory(id))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, manifest)
+}
+
+// DeleteVolumeData removes this node's copy of a volume
+func DeleteVolumeData(c *gin.Context) {
+	id := c.Param("id")
+
+	if _, err := uuid.Parse(id); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid volume id"})
+		return
+	}
+
+	err := os.RemoveAll(volumeDirectory(id))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
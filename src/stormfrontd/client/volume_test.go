@@ -0,0 +1,67 @@
+package client
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func replicateDirectory(t *testing.T, src, dst string) int {
+	t.Helper()
+	local, err := volumeManifest(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	remote, err := volumeManifest(dst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var archive bytes.Buffer
+	if err := archiveDirectory(src, local, remote, &archive); err != nil {
+		t.Fatal(err)
+	}
+	if err := extractDirectory(dst, &archive); err != nil {
+		t.Fatal(err)
+	}
+	sent := 0
+	for name, file := range local {
+		if existing, ok := remote[name]; !ok || existing != file {
+			sent++
+		}
+	}
+	return sent
+}
+
+func TestReplicateDirectoryIncrementally(t *testing.T) {
+	src := t.TempDir()
+	dst := filepath.Join(t.TempDir(), "replica")
+
+	os.MkdirAll(filepath.Join(src, "data"), os.ModePerm)
+	os.WriteFile(filepath.Join(src, "data", "a.txt"), []byte("a"), 0644)
+	os.WriteFile(filepath.Join(src, "b.txt"), []byte("b"), 0644)
+
+	if sent := replicateDirectory(t, src, dst); sent != 3 {
+		t.Fatalf("expected the first replication to send 3 entries, sent %d", sent)
+	}
+	if sent := replicateDirectory(t, src, dst); sent != 0 {
+		t.Fatalf("expected an unchanged volume to send nothing, sent %d", sent)
+	}
+
+	os.WriteFile(filepath.Join(src, "data", "a.txt"), []byte("changed"), 0644)
+	os.Remove(filepath.Join(src, "b.txt"))
+	if sent := replicateDirectory(t, src, dst); sent != 1 {
+		t.Fatalf("expected only the changed file to be sent, sent %d", sent)
+	}
+
+	contents, err := os.ReadFile(filepath.Join(dst, "data", "a.txt"))
+	if err != nil || string(contents) != "changed" {
+		t.Fatalf("expected replica to hold the changed file, got %q: %v", contents, err)
+	}
+	if _, err := os.Stat(filepath.Join(dst, "b.txt")); !os.IsNotExist(err) {
+		t.Fatalf("expected removed file to be removed from the replica: %v", err)
+	}
+	if _, err := os.Stat(dst + ".incoming"); !os.IsNotExist(err) {
+		t.Fatalf("expected staging directory to be cleaned up: %v", err)
+	}
+}
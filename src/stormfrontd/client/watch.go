@@ -0,0 +1,469 @@
+package client
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jfcarter2358/ceresdb-go/connection"
+)
+
+const WATCH_ADDED = "ADDED"
+const WATCH_MODIFIED = "MODIFIED"
+const WATCH_DELETED = "DELETED"
+const WATCH_BOOKMARK = "BOOKMARK"
+
+// Watched collections are checked every WATCH_INTERVAL seconds and streams
+// send a comment every WATCH_KEEPALIVE seconds so idle ones are not dropped
+const WATCH_INTERVAL = 1
+const WATCH_KEEPALIVE = 15
+
+// WATCH_BUFFER is how many changes a watcher can fall behind by before its
+// stream is ended, WATCH_HISTORY how many each collection keeps for watches
+// which resume
+const WATCH_BUFFER = 256
+const WATCH_HISTORY = 1024
+
+// A collection is checked for WATCH_LINGER seconds after its last watcher
+// leaves so that clients which reconnect can still resume
+const WATCH_LINGER = 300
+
+// WatchEvent is sent for each change to a watched list. A bookmark follows
+// every batch of changes and carries the resource version to resume from.
+//
+// Resource versions are numbers which grow with every change the leader
+// sees, so a client which reconnects with the last one it received is only
+// sent what changed since. Versions the leader no longer holds the changes
+// after, such as ones from before a failover, are answered with 410 Gone and
+// the client has to start again from the full list.
+type WatchEvent struct {
+	Type            string      `json:"type" yaml:"type"`
+	ResourceVersion string      `json:"resource_version" yaml:"resource_version"`
+	Object          interface{} `json:"object,omitempty" yaml:"object,omitempty"`
+}
+
+// watchVersion is the last resource version handed out. It starts from the
+// time the daemon started so that versions from an earlier leader are always
+// older than anything this one can resume from.
+var watchVersion = uint64(time.Now().UnixNano())
+
+func nextWatchVersion() uint64 {
+	return atomic.AddUint64(&watchVersion, 1)
+}
+
+var errWatchExpired = errors.New("resource version is too old, reconnect without one to get the full list")
+
+// objectDigest changes whenever any of the object's fields do
+func objectDigest(object interface{}) string {
+	objectBytes, _ := json.Marshal(object)
+	sum := sha256.Sum256(objectBytes)
+	return hex.EncodeToString(sum[:8])
+}
+
+type watchChange struct {
+	version uint64
+	event   WatchEvent
+}
+
+// watchCollection lists one collection for all of its watchers and hands
+// each of them the changes, in the same way publishEvent does for events
+type watchCollection struct {
+	list func() (map[string]interface{}, error)
+
+	lock     sync.Mutex
+	running  bool
+	objects  map[string]interface{}
+	digests  map[string]string
+	versions map[string]uint64
+	// history holds the latest changes, oldest first, and since is the
+	// version after which it is complete
+	history  []watchChange
+	since    uint64
+	watchers map[chan watchChange]bool
+}
+
+var applicationWatch = &watchCollection{list: func() (map[string]interface{}, error) {
+	applications, err := getApplications()
+	if err != nil {
+		return nil, err
+	}
+	objects := map[string]interface{}{}
+	for _, app := range applications {
+		objects[app.ID] = app
+	}
+	return objects, nil
+}}
+
+var nodeWatch = &watchCollection{list: func() (map[string]interface{}, error) {
+	nodes, err := getNodes()
+	if err != nil {
+		return nil, err
+	}
+	objects := map[string]interface{}{}
+	for _, node := range nodes {
+		objects[node.ID] = node
+	}
+	return objects, nil
+}}
+
+var routeWatch = &watchCollection{list: func() (map[string]interface{}, error) {
+	data, err := connection.Query(`get record stormfront.route`)
+	if err != nil {
+		return nil, err
+	}
+	objects := map[string]interface{}{}
+	for _, datum := range data {
+		objects[fmt.Sprintf("%v", datum["id"])] = datum
+	}
+	return objects, nil
+}}
+
+// subscribe returns the changes a watcher resuming after version has missed,
+// or every object as added if version is 0, the version they bring it up to
+// and a channel carrying each change after them. The collection is listed
+// straight away if nobody was watching it.
+func (w *watchCollection) subscribe(version uint64) ([]watchChange, uint64, chan watchChange, error) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	if !w.running {
+		current, err := w.list()
+		if err != nil {
+			return nil, 0, nil, err
+		}
+		w.reset(current)
+		w.running = true
+		go w.run()
+	}
+
+	changes := []watchChange{}
+	if version == 0 {
+		ids := []string{}
+		for id := range w.objects {
+			ids = append(ids, id)
+		}
+		sort.Strings(ids)
+		for _, id := range ids {
+			changes = append(changes, watchChange{
+				version: w.versions[id],
+				event:   WatchEvent{Type: WATCH_ADDED, ResourceVersion: strconv.FormatUint(w.versions[id], 10), Object: w.objects[id]},
+			})
+		}
+	} else {
+		if version < w.since || version > atomic.LoadUint64(&watchVersion) {
+			return nil, 0, nil, errWatchExpired
+		}
+		for _, change := range w.history {
+			if change.version > version {
+				changes = append(changes, change)
+			}
+		}
+	}
+
+	latest := w.since
+	if len(w.history) > 0 {
+		latest = w.history[len(w.history)-1].version
+	}
+	if version > latest {
+		latest = version
+	}
+
+	updates := make(chan watchChange, WATCH_BUFFER)
+	w.watchers[updates] = true
+	return changes, latest, updates, nil
+}
+
+func (w *watchCollection) unsubscribe(updates chan watchChange) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	if w.watchers[updates] {
+		delete(w.watchers, updates)
+		close(updates)
+	}
+}
+
+// reset starts the collection over from current, which changes can only be
+// resumed after
+func (w *watchCollection) reset(current map[string]interface{}) {
+	w.since = nextWatchVersion()
+	w.objects = current
+	w.digests = map[string]string{}
+	w.versions = map[string]uint64{}
+	for id, object := range current {
+		w.digests[id] = objectDigest(object)
+		w.versions[id] = w.since
+	}
+	w.history = nil
+	w.watchers = map[chan watchChange]bool{}
+}
+
+// run lists the collection every WATCH_INTERVAL seconds while this node is
+// leader and it is being watched
+func (w *watchCollection) run() {
+	var idleSince time.Time
+	for {
+		time.Sleep(WATCH_INTERVAL * time.Second)
+
+		w.lock.Lock()
+		if len(w.watchers) > 0 {
+			idleSince = time.Time{}
+		} else if idleSince.IsZero() {
+			idleSince = time.Now()
+		}
+		if Client.Type != "Leader" || (!idleSince.IsZero() && time.Since(idleSince) > WATCH_LINGER*time.Second) {
+			for updates := range w.watchers {
+				delete(w.watchers, updates)
+				close(updates)
+			}
+			w.running = false
+			w.lock.Unlock()
+			return
+		}
+		w.lock.Unlock()
+
+		current, err := w.list()
+		if err != nil {
+			apiLog.Error("Unable to list watched collection", "error", err)
+			continue
+		}
+		w.update(current)
+	}
+}
+
+// update compares the collection with what was last listed and hands each
+// change to the watchers
+func (w *watchCollection) update(current map[string]interface{}) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	ids := []string{}
+	for id := range current {
+		ids = append(ids, id)
+	}
+	for id := range w.objects {
+		if _, ok := current[id]; !ok {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+
+	for _, id := range ids {
+		object, ok := current[id]
+		if !ok {
+			version := nextWatchVersion()
+			w.publish(watchChange{
+				version: version,
+				event:   WatchEvent{Type: WATCH_DELETED, ResourceVersion: strconv.FormatUint(version, 10), Object: w.objects[id]},
+			})
+			delete(w.digests, id)
+			delete(w.versions, id)
+			continue
+		}
+
+		digest := objectDigest(object)
+		eventType := WATCH_MODIFIED
+		if previous, ok := w.digests[id]; !ok {
+			eventType = WATCH_ADDED
+		} else if previous == digest {
+			continue
+		}
+		version := nextWatchVersion()
+		w.digests[id] = digest
+		w.versions[id] = version
+		w.publish(watchChange{
+			version: version,
+			event:   WatchEvent{Type: eventType, ResourceVersion: strconv.FormatUint(version, 10), Object: object},
+		})
+	}
+	w.objects = current
+}
+
+// publish never blocks the loop, a watcher whose buffer is full is closed
+// instead so that its client reconnects and resumes. Must be called with the
+// lock held.
+func (w *watchCollection) publish(change watchChange) {
+	w.history = append(w.history, change)
+	if len(w.history) > WATCH_HISTORY {
+		w.since = w.history[0].version
+		w.history = w.history[1:]
+	}
+	for updates := range w.watchers {
+		select {
+		case updates <- change:
+		default:
+			delete(w.watchers, updates)
+			close(updates)
+		}
+	}
+}
+
+// startStream prepares the response for server-sent events
+func startStream(c *gin.Context) io.Writer {
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Status(http.StatusOK)
+	return flushWriter{writer: c.Writer}
+}
+
+// writeStreamEvent sends an event, without an id field if id is empty so
+// that the client keeps the ID of the last event it received
+func writeStreamEvent(w io.Writer, id, name string, data interface{}) error {
+	dataBytes, _ := json.Marshal(data)
+	if id == "" {
+		_, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", name, dataBytes)
+		return err
+	}
+	_, err := fmt.Fprintf(w, "id: %s\nevent: %s\ndata: %s\n\n", id, name, dataBytes)
+	return err
+}
+
+// pollStream calls poll every WATCH_INTERVAL seconds until the client goes
+// away. poll reports whether it sent anything so that keepalives are only
+// sent on idle streams, an error from poll is sent on and ends the stream.
+func pollStream(c *gin.Context, w io.Writer, poll func() (bool, error)) {
+	idle := 0
+	for {
+		sent, err := poll()
+		if err != nil {
+			writeStreamEvent(w, "", "error", gin.H{"error": err.Error()})
+			return
+		}
+
+		idle++
+		if sent {
+			idle = 0
+		} else if idle >= WATCH_KEEPALIVE/WATCH_INTERVAL {
+			if _, err := fmt.Fprint(w, ": keepalive\n\n"); err != nil {
+				return
+			}
+			idle = 0
+		}
+
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case <-time.After(WATCH_INTERVAL * time.Second):
+		}
+	}
+}
+
+// watchList streams the changes to collection which visible allows. Clients
+// resume with the resource_version parameter or the Last-Event-ID header,
+// without either the stream starts with every object as added so that they
+// start from the full list and then only see what changes.
+func watchList(c *gin.Context, collection *watchCollection, visible func(object interface{}) bool) {
+	resume := c.Query("resource_version")
+	if resume == "" {
+		resume = c.GetHeader("Last-Event-ID")
+	}
+	var version uint64
+	if resume != "" {
+		parsed, err := strconv.ParseUint(resume, 10, 64)
+		if err != nil || parsed == 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("invalid resource version %s", resume)})
+			return
+		}
+		version = parsed
+	}
+
+	changes, latest, updates, err := collection.subscribe(version)
+	if err == errWatchExpired {
+		c.JSON(http.StatusGone, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer collection.unsubscribe(updates)
+
+	w := startStream(c)
+
+	// Bookmarks carry the latest version seen, so changes which were not
+	// visible to this watcher are not replayed when it resumes either. The
+	// objects of the full list are sent without an ID as their versions are
+	// not in order, clients resume from the bookmark which follows them.
+	send := func(changes []watchChange, withID bool) (bool, error) {
+		sent := false
+		for _, change := range changes {
+			if change.version > latest {
+				latest = change.version
+			}
+			if !visible(change.event.Object) {
+				continue
+			}
+			id := ""
+			if withID {
+				id = change.event.ResourceVersion
+			}
+			if err := writeStreamEvent(w, id, change.event.Type, change.event); err != nil {
+				return false, err
+			}
+			sent = true
+		}
+		return sent, nil
+	}
+
+	bookmark := func() error {
+		event := WatchEvent{Type: WATCH_BOOKMARK, ResourceVersion: strconv.FormatUint(latest, 10)}
+		return writeStreamEvent(w, event.ResourceVersion, event.Type, event)
+	}
+	// Clients redraw on bookmarks so one always follows what was missed
+	if _, err := send(changes, version != 0); err != nil {
+		return
+	}
+	if err := bookmark(); err != nil {
+		return
+	}
+
+	keepalive := time.NewTicker(WATCH_KEEPALIVE * time.Second)
+	defer keepalive.Stop()
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case <-keepalive.C:
+			if _, err := fmt.Fprint(w, ": keepalive\n\n"); err != nil {
+				return
+			}
+		case change, ok := <-updates:
+			if !ok {
+				writeStreamEvent(w, "", "error", gin.H{"error": fmt.Sprintf("watch fell more than %d changes behind or the leader changed, reconnect to resume", WATCH_BUFFER)})
+				return
+			}
+			// Pick up the rest of the batch so that one bookmark follows it
+			batch := []watchChange{change}
+		drain:
+			for {
+				select {
+				case change, ok := <-updates:
+					if !ok {
+						break drain
+					}
+					batch = append(batch, change)
+				default:
+					break drain
+				}
+			}
+			sent, err := send(batch, true)
+			if err != nil {
+				return
+			}
+			if sent {
+				if err := bookmark(); err != nil {
+					return
+				}
+			}
+		}
+	}
+}
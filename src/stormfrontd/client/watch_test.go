@@ -0,0 +1,166 @@
+package client
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// runningWatch returns a collection holding objects which is marked as
+// running without a loop listing it, so tests hand it changes themselves
+func runningWatch(objects map[string]interface{}) *watchCollection {
+	w := &watchCollection{}
+	w.reset(objects)
+	w.running = true
+	return w
+}
+
+func TestWatchCollectionStartsFromFullList(t *testing.T) {
+	w := runningWatch(map[string]interface{}{"b": "two", "a": "one"})
+
+	changes, latest, updates, err := w.subscribe(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.unsubscribe(updates)
+
+	if len(changes) != 2 || changes[0].event.Object != "one" || changes[1].event.Object != "two" {
+		t.Fatalf("expected every object in ID order, got %v", changes)
+	}
+	for _, change := range changes {
+		if change.event.Type != WATCH_ADDED || change.version != latest {
+			t.Errorf("expected %v to be added at version %v", change, latest)
+		}
+	}
+}
+
+func TestWatchCollectionPublishesChanges(t *testing.T) {
+	w := runningWatch(map[string]interface{}{"a": "one", "b": "two"})
+	_, latest, updates, err := w.subscribe(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.unsubscribe(updates)
+
+	w.update(map[string]interface{}{"a": "one", "b": "changed", "c": "three"})
+	w.update(map[string]interface{}{"a": "one", "c": "three"})
+
+	expected := []string{WATCH_MODIFIED, WATCH_ADDED, WATCH_DELETED}
+	for _, eventType := range expected {
+		select {
+		case change := <-updates:
+			if change.event.Type != eventType {
+				t.Fatalf("expected %s, got %v", eventType, change)
+			}
+			if change.version <= latest {
+				t.Fatalf("expected versions to grow, got %v after %v", change.version, latest)
+			}
+			if change.event.ResourceVersion != strconv.FormatUint(change.version, 10) {
+				t.Fatalf("expected resource version %v, got %s", change.version, change.event.ResourceVersion)
+			}
+			latest = change.version
+		default:
+			t.Fatalf("expected %s to be published", eventType)
+		}
+	}
+	select {
+	case change := <-updates:
+		t.Fatalf("expected unchanged objects to be skipped, got %v", change)
+	default:
+	}
+}
+
+func TestWatchCollectionResumes(t *testing.T) {
+	w := runningWatch(map[string]interface{}{"a": "one"})
+	_, start, updates, err := w.subscribe(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.unsubscribe(updates)
+
+	w.update(map[string]interface{}{"a": "changed"})
+	w.update(map[string]interface{}{"a": "changed", "b": "two"})
+
+	changes, latest, updates, err := w.subscribe(start)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.unsubscribe(updates)
+	if len(changes) != 2 || changes[0].event.Type != WATCH_MODIFIED || changes[1].event.Type != WATCH_ADDED {
+		t.Fatalf("expected the missed changes, got %v", changes)
+	}
+	if latest != changes[1].version {
+		t.Fatalf("expected to be brought up to %v, got %v", changes[1].version, latest)
+	}
+
+	changes, _, resumed, err := w.subscribe(latest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.unsubscribe(resumed)
+	if len(changes) != 0 {
+		t.Fatalf("expected nothing to replay, got %v", changes)
+	}
+}
+
+func TestWatchCollectionRejectsExpiredVersions(t *testing.T) {
+	w := runningWatch(map[string]interface{}{})
+	_, start, updates, _ := w.subscribe(0)
+	w.unsubscribe(updates)
+
+	for i := 0; i <= WATCH_HISTORY; i++ {
+		w.update(map[string]interface{}{"a": i})
+	}
+
+	for _, version := range []uint64{start - 1, start, nextWatchVersion() + 1000} {
+		if _, _, _, err := w.subscribe(version); err != errWatchExpired {
+			t.Errorf("expected version %v to have expired, got %v", version, err)
+		}
+	}
+}
+
+func TestWatchCollectionClosesSlowWatchers(t *testing.T) {
+	w := runningWatch(map[string]interface{}{})
+	_, _, updates, _ := w.subscribe(0)
+	defer w.unsubscribe(updates)
+
+	for i := 0; i <= WATCH_BUFFER; i++ {
+		w.update(map[string]interface{}{"a": i})
+	}
+	for i := 0; i < WATCH_BUFFER; i++ {
+		<-updates
+	}
+	if _, ok := <-updates; ok {
+		t.Fatal("expected watcher which fell behind to be closed")
+	}
+}
+
+func TestWatchListRejectsResourceVersions(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	w := runningWatch(map[string]interface{}{})
+
+	tests := []struct {
+		name    string
+		version string
+		status  int
+	}{
+		{name: "not a number", version: "abc", status: http.StatusBadRequest},
+		{name: "zero", version: "0", status: http.StatusBadRequest},
+		{name: "expired", version: "1", status: http.StatusGone},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(recorder)
+			c.Request = httptest.NewRequest(http.MethodGet, "/api/application?watch=true&resource_version="+test.version, nil)
+
+			watchList(c, w, func(interface{}) bool { return true })
+			if recorder.Code != test.status {
+				t.Fatalf("expected status %d, got %d: %s", test.status, recorder.Code, recorder.Body.String())
+			}
+		})
+	}
+}
@@ -4,9 +4,9 @@ import (
 	"encoding/json"
 	"errors"
 	"io/ioutil"
-	"log"
 	"os"
 	"reflect"
+	"stormfrontd/logging"
 	"strconv"
 )
 
@@ -14,20 +14,29 @@ const DEFAULT_CONFIG_PATH = "/var/stormfront/config.json"
 const ENV_PREFIX = "STORMFRONTD_"
 
 type ConfigObject struct {
-	DaemonHost               string   `json:"daemon_host" env:"DAEMON_HOST"`
-	DaemonPort               int      `json:"daemon_port" env:"DAEMON_PORT"`
-	AllowedIPs               []string `json:"allowed_ips" env:"ALLOWED_IPS"`
-	RestrictRequestHost      bool     `json:"restrict_request_host" env:"RESTRICT_REQUEST_HOST"`
-	ClientPort               int      `json:"client_port" env:"CLIENT_PORT"`
-	InterfaceName            string   `json:"interface_name" env:"INTERFACE_NAME"`
-	ReservedCPUPercentage    float64  `json:"reserved_cpu_percentage" env:"RESERVED_CPU_PERCENTAGE"`
-	ReservedMemoryPercentage float64  `json:"reserved_memory_percentage" env:"RESERVED_MEMORY_PERCENTAGE"`
-	CeresDBPassword          string   `json:"ceresdb_password" env:"CERESDB_PASSWORD"`
-	CeresDBImage             string   `json:"ceresdb_image" env:"CERESDB_IMAGE"`
-	CeresDBPort              int      `json:"ceresdb_port" env:"CERESDB_PORT"`
-	CeresDBHost              string   `json:"ceresdb_host" env:"CERESDB_HOST"`
-	CeresDBLogLevel          string   `json:"ceresdb_log_level" env:"CERESDB_LOG_LEVEL"`
-	ContainerEngine          string   `json:"container_engine" env:"CONTAINER_ENGINE"`
+	DaemonHost               string            `json:"daemon_host" env:"DAEMON_HOST"`
+	DaemonPort               int               `json:"daemon_port" env:"DAEMON_PORT"`
+	AllowedIPs               []string          `json:"allowed_ips" env:"ALLOWED_IPS"`
+	RestrictRequestHost      bool              `json:"restrict_request_host" env:"RESTRICT_REQUEST_HOST"`
+	ClientPort               int               `json:"client_port" env:"CLIENT_PORT"`
+	InterfaceName            string            `json:"interface_name" env:"INTERFACE_NAME"`
+	ReservedCPUPercentage    float64           `json:"reserved_cpu_percentage" env:"RESERVED_CPU_PERCENTAGE"`
+	ReservedMemoryPercentage float64           `json:"reserved_memory_percentage" env:"RESERVED_MEMORY_PERCENTAGE"`
+	CeresDBPassword          string            `json:"ceresdb_password" env:"CERESDB_PASSWORD"`
+	CeresDBImage             string            `json:"ceresdb_image" env:"CERESDB_IMAGE"`
+	CeresDBPort              int               `json:"ceresdb_port" env:"CERESDB_PORT"`
+	CeresDBHost              string            `json:"ceresdb_host" env:"CERESDB_HOST"`
+	CeresDBLogLevel          string            `json:"ceresdb_log_level" env:"CERESDB_LOG_LEVEL"`
+	ContainerEngine          string            `json:"container_engine" env:"CONTAINER_ENGINE"`
+	RescheduleGracePeriod    int               `json:"reschedule_grace_period" env:"RESCHEDULE_GRACE_PERIOD"`
+	SchedulingStrategy       string            `json:"scheduling_strategy" env:"SCHEDULING_STRATEGY"`
+	NodeLabels               map[string]string `json:"node_labels" env:"NODE_LABELS"`
+	BoltRetention            int               `json:"bolt_retention" env:"BOLT_RETENTION"`
+	EventRetention           int               `json:"event_retention" env:"EVENT_RETENTION"`
+	JoinTokenExpiration      int               `json:"join_token_expiration" env:"JOIN_TOKEN_EXPIRATION"`
+	MutualTLS                bool              `json:"mutual_tls" env:"MUTUAL_TLS"`
+	LogLevel                 string            `json:"log_level" env:"LOG_LEVEL"`
+	LogFormat                string            `json:"log_format" env:"LOG_FORMAT"`
 }
 
 var Config ConfigObject
@@ -53,6 +62,15 @@ func LoadConfig() {
 		CeresDBPort:              7437,
 		CeresDBLogLevel:          "INFO",
 		ContainerEngine:          "docker",
+		RescheduleGracePeriod:    60,
+		SchedulingStrategy:       "first-fit",
+		NodeLabels:               map[string]string{},
+		BoltRetention:            86400,
+		EventRetention:           86400,
+		JoinTokenExpiration:      86400,
+		MutualTLS:                false,
+		LogLevel:                 "info",
+		LogFormat:                "logfmt",
 	}
 
 	if _, err := os.Stat(configPath); errors.Is(err, os.ErrNotExist) {
@@ -63,11 +81,11 @@ func LoadConfig() {
 
 	jsonFile, err := os.Open(configPath)
 	if err != nil {
-		log.Println("Unable to read json file")
+		logging.Error("Unable to read config file", "path", configPath, "error", err)
 		panic(err)
 	}
 
-	log.Printf("Successfully Opened %v", configPath)
+	logging.Info("Loaded config file", "path", configPath)
 
 	byteValue, _ := ioutil.ReadAll(jsonFile)
 
@@ -135,7 +153,7 @@ func LoadConfig() {
 						if err == nil {
 							w.Set(reflect.Indirect(obj).Convert(field.Type))
 						} else {
-							log.Println(err)
+							logging.Warn("Unable to parse config value from environment", "field", field.Name, "error", err)
 						}
 					}
 				}
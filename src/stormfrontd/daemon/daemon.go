@@ -6,12 +6,14 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	"log"
 	"net/http"
 	"stormfrontd/client"
 	"stormfrontd/client/auth"
+	"stormfrontd/client/communication"
+	"stormfrontd/client/pki"
 	"stormfrontd/config"
 	"stormfrontd/database"
+	"stormfrontd/logging"
 	"stormfrontd/utils"
 	"time"
 
@@ -19,6 +21,8 @@ import (
 	"github.com/jfcarter2358/ceresdb-go/connection"
 )
 
+var daemonLog = logging.Subsystem("daemon")
+
 func Deploy() error {
 
 	if client.Running {
@@ -45,6 +49,15 @@ func Deploy() error {
 		Healthy:    true,
 	}
 
+	err = pki.CreateCA()
+	if err != nil {
+		return fmt.Errorf("unable to create cluster CA: %v", err)
+	}
+	err = pki.IssueNodeCertificate(hostname)
+	if err != nil {
+		return fmt.Errorf("unable to issue leader certificate: %v", err)
+	}
+
 	err = database.Deploy("")
 
 	if err != nil {
@@ -52,7 +65,7 @@ func Deploy() error {
 		return err
 	}
 
-	err = client.Initialize("")
+	err = client.Initialize("", "")
 
 	if err != nil {
 		Destroy()
@@ -71,7 +84,7 @@ func Destroy() error {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 	if err := client.Client.Server.Shutdown(ctx); err != nil {
-		log.Printf("Server forced to shutdown: %v", err)
+		daemonLog.Error("Server forced to shutdown", "error", err)
 		return err
 	}
 
@@ -85,8 +98,8 @@ func Destroy() error {
 
 		clientInfo := auth.ReadClientInformation()
 
-		requestURL := fmt.Sprintf("http://%s:%v/api/register", client.Client.Leader.Host, client.Client.Leader.Port)
-		httpClient := &http.Client{}
+		requestURL := fmt.Sprintf("https://%s:%v/api/register", client.Client.Leader.Host, client.Client.Leader.Port)
+		httpClient := &http.Client{Transport: &http.Transport{TLSClientConfig: communication.TLSConfig}}
 		req, _ := http.NewRequest("DELETE", requestURL, postBodyBuffer)
 		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", clientInfo.AccessToken))
 		resp, err := httpClient.Do(req)
@@ -100,19 +113,19 @@ func Destroy() error {
 
 	clientIDs, err := connection.Query(fmt.Sprintf(`get record stormfront.client .id | filter id = "%s"`, client.Client.ID))
 	if err != nil {
-		fmt.Printf("database error: %v", err)
+		daemonLog.Error("Unable to remove client record", "client_id", client.Client.ID, "error", err)
 		return err
 	}
 	_, err = connection.Query(fmt.Sprintf(`delete record stormfront.client %s`, clientIDs[0][".id"].(string)))
 	if err != nil {
-		fmt.Printf("database error: %v", err)
+		daemonLog.Error("Unable to remove client record", "client_id", client.Client.ID, "error", err)
 		return err
 	}
 
 	return nil
 }
 
-func Join(leaderHost string, leaderPort int, joinToken string) error {
+func Join(leaderHost string, leaderPort int, joinToken, caFingerprint string) error {
 	if client.Running {
 		return errors.New("client is already running")
 	}
@@ -143,7 +156,7 @@ func Join(leaderHost string, leaderPort int, joinToken string) error {
 		return err
 	}
 
-	err = client.Initialize(joinToken)
+	err = client.Initialize(joinToken, caFingerprint)
 
 	if err != nil {
 		Destroy()
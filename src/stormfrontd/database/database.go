@@ -5,14 +5,17 @@ import (
 	"os"
 	"os/exec"
 	"stormfrontd/config"
+	"stormfrontd/logging"
 )
 
+var databaseLog = logging.Subsystem("database")
+
 func Deploy(leader string) error {
 	os.MkdirAll("/var/stormfront/ceresdb/data", os.ModePerm)
 	os.MkdirAll("/var/stormfront/ceresdb/indices", os.ModePerm)
-	fmt.Println("Removing any existing ceresdb containers...")
+	databaseLog.Info("Removing any existing CeresDB containers")
 	exec.Command("/bin/sh", "-c", fmt.Sprintf("%s kill ceresdb || true; %s rm ceresdb || true", config.Config.ContainerEngine, config.Config.ContainerEngine)).Run()
-	fmt.Println("Deploying CeresDB...")
+	databaseLog.Info("Deploying CeresDB", "image", config.Config.CeresDBImage)
 	// dockerCommand := fmt.Sprintf("%s run --net host -d --rm ", config.Config.ContainerEngine)
 	dockerCommand := fmt.Sprintf("%s run --net host -d ", config.Config.ContainerEngine)
 	dockerCommand += "--name ceresdb "
@@ -23,12 +26,14 @@ func Deploy(leader string) error {
 		dockerCommand += fmt.Sprintf("-e CERESDB_FOLLOWER_AUTH='ceresdb:%s' ", config.Config.CeresDBPassword)
 	}
 	dockerCommand += fmt.Sprintf("-p %d:%d ", config.Config.CeresDBPort, config.Config.CeresDBPort)
+	// CeresDB starts out empty on every deploy, the leader recreates its
+	// records and restores tokens from its own backup (see auth.RestoreTokens)
 	// dockerCommand += "-v /var/stormfront/ceresdb/data:/home/ceresdb/.ceresdb/data "
 	// dockerCommand += "-v /var/stormfront/ceresdb/indices:/home/ceresdb/.ceresdb/indices "
 	dockerCommand += config.Config.CeresDBImage
 	err := exec.Command("/bin/sh", "-c", dockerCommand).Run()
 	if err != nil {
-		fmt.Printf("Encountered error deploying CeresDB instance: %v\n", err.Error())
+		databaseLog.Error("Unable to deploy CeresDB", "error", err)
 		return err
 	}
 	return nil
@@ -37,12 +42,12 @@ func Deploy(leader string) error {
 func Destroy() error {
 	err := exec.Command("/bin/sh", "-c", fmt.Sprintf("%s kill ceresdb", config.Config.ContainerEngine)).Run()
 	if err != nil {
-		fmt.Printf("Encountered error killing container: %v\n", err.Error())
+		databaseLog.Error("Unable to kill CeresDB container", "error", err)
 		return err
 	}
 	err = os.RemoveAll("/var/stormfront/ceresdb")
 	if err != nil {
-		fmt.Printf("Encountered error removing CeresDB data: %v\n", err.Error())
+		databaseLog.Error("Unable to remove CeresDB data", "error", err)
 		return err
 	}
 	return nil
@@ -0,0 +1,255 @@
+// logging.go
+
+package logging
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const TRACE_NAME = "trace"
+const DEBUG_NAME = "debug"
+const INFO_NAME = "info"
+const WARN_NAME = "warn"
+const ERROR_NAME = "error"
+const FATAL_NAME = "fatal"
+
+const TRACE_LEVEL = 0
+const DEBUG_LEVEL = 1
+const INFO_LEVEL = 2
+const WARN_LEVEL = 3
+const ERROR_LEVEL = 4
+const FATAL_LEVEL = 5
+
+const FORMAT_LOGFMT = "logfmt"
+const FORMAT_JSON = "json"
+
+// REQUEST_ID_HEADER carries the ID of a request to the nodes it is passed on
+// to so that their log lines can be matched up with the original request
+const REQUEST_ID_HEADER = "X-Request-ID"
+
+var levels = map[string]int{
+	TRACE_NAME: TRACE_LEVEL,
+	DEBUG_NAME: DEBUG_LEVEL,
+	INFO_NAME:  INFO_LEVEL,
+	WARN_NAME:  WARN_LEVEL,
+	ERROR_NAME: ERROR_LEVEL,
+	FATAL_NAME: FATAL_LEVEL,
+}
+
+var levelNames = []string{TRACE_NAME, DEBUG_NAME, INFO_NAME, WARN_NAME, ERROR_NAME, FATAL_NAME}
+
+var mutex sync.Mutex
+var level = INFO_LEVEL
+var format = FORMAT_LOGFMT
+var output io.Writer = os.Stdout
+
+// Configure sets the lowest level which is written and the format lines are
+// written in
+func Configure(levelName, formatName string) error {
+	newLevel, ok := levels[strings.ToLower(levelName)]
+	if !ok {
+		return fmt.Errorf("invalid log level %s, valid levels are %s", levelName, strings.Join(levelNames, ", "))
+	}
+	formatName = strings.ToLower(formatName)
+	if formatName != FORMAT_LOGFMT && formatName != FORMAT_JSON {
+		return fmt.Errorf("invalid log format %s, valid formats are %s and %s", formatName, FORMAT_LOGFMT, FORMAT_JSON)
+	}
+
+	mutex.Lock()
+	defer mutex.Unlock()
+	level = newLevel
+	format = formatName
+	return nil
+}
+
+// SetOutput replaces where log lines are written, stdout by default
+func SetOutput(w io.Writer) {
+	mutex.Lock()
+	defer mutex.Unlock()
+	output = w
+}
+
+// Logger writes lines carrying a fixed set of fields along with the fields
+// given for each line. Fields are passed as alternating keys and values.
+type Logger struct {
+	fields []interface{}
+}
+
+var root = &Logger{}
+
+// Subsystem returns a logger which tags its lines with the part of the
+// daemon they come from
+func Subsystem(name string) *Logger {
+	return root.With("subsystem", name)
+}
+
+func With(keyvals ...interface{}) *Logger {
+	return root.With(keyvals...)
+}
+
+// With returns a logger which adds keyvals to every line
+func (l *Logger) With(keyvals ...interface{}) *Logger {
+	fields := make([]interface{}, 0, len(l.fields)+len(keyvals))
+	fields = append(fields, l.fields...)
+	fields = append(fields, keyvals...)
+	return &Logger{fields: fields}
+}
+
+func (l *Logger) Trace(message string, keyvals ...interface{}) {
+	l.write(TRACE_NAME, message, keyvals)
+}
+
+func (l *Logger) Debug(message string, keyvals ...interface{}) {
+	l.write(DEBUG_NAME, message, keyvals)
+}
+
+func (l *Logger) Info(message string, keyvals ...interface{}) {
+	l.write(INFO_NAME, message, keyvals)
+}
+
+func (l *Logger) Warn(message string, keyvals ...interface{}) {
+	l.write(WARN_NAME, message, keyvals)
+}
+
+func (l *Logger) Error(message string, keyvals ...interface{}) {
+	l.write(ERROR_NAME, message, keyvals)
+}
+
+// Fatal writes the line and exits
+func (l *Logger) Fatal(message string, keyvals ...interface{}) {
+	l.write(FATAL_NAME, message, keyvals)
+	os.Exit(1)
+}
+
+func Trace(message string, keyvals ...interface{}) {
+	root.write(TRACE_NAME, message, keyvals)
+}
+
+func Debug(message string, keyvals ...interface{}) {
+	root.write(DEBUG_NAME, message, keyvals)
+}
+
+func Info(message string, keyvals ...interface{}) {
+	root.write(INFO_NAME, message, keyvals)
+}
+
+func Warn(message string, keyvals ...interface{}) {
+	root.write(WARN_NAME, message, keyvals)
+}
+
+func Error(message string, keyvals ...interface{}) {
+	root.write(ERROR_NAME, message, keyvals)
+}
+
+func Fatal(message string, keyvals ...interface{}) {
+	root.write(FATAL_NAME, message, keyvals)
+	os.Exit(1)
+}
+
+func (l *Logger) write(levelName, message string, keyvals []interface{}) {
+	mutex.Lock()
+	defer mutex.Unlock()
+	if levels[levelName] < level {
+		return
+	}
+
+	fields := []interface{}{"time", time.Now().Format(time.RFC3339Nano), "level", levelName, "msg", message}
+	fields = append(fields, l.fields...)
+	fields = append(fields, keyvals...)
+	if len(fields)%2 != 0 {
+		fields = append(fields, nil)
+	}
+
+	var line string
+	if format == FORMAT_JSON {
+		line = formatJSON(fields)
+	} else {
+		line = formatLogfmt(fields)
+	}
+	fmt.Fprintln(output, line)
+}
+
+// value converts errors and other types without a useful JSON form to their
+// string representation
+func value(v interface{}) interface{} {
+	switch typed := v.(type) {
+	case nil:
+		return nil
+	case error:
+		return typed.Error()
+	case fmt.Stringer:
+		return typed.String()
+	case string, bool, int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64:
+		return typed
+	}
+	return v
+}
+
+func formatJSON(fields []interface{}) string {
+	var line strings.Builder
+	line.WriteString("{")
+	for idx := 0; idx < len(fields); idx += 2 {
+		if idx > 0 {
+			line.WriteString(",")
+		}
+		keyBytes, _ := json.Marshal(fmt.Sprintf("%v", fields[idx]))
+		valueBytes, err := json.Marshal(value(fields[idx+1]))
+		if err != nil {
+			valueBytes, _ = json.Marshal(fmt.Sprintf("%v", fields[idx+1]))
+		}
+		line.Write(keyBytes)
+		line.WriteString(":")
+		line.Write(valueBytes)
+	}
+	line.WriteString("}")
+	return line.String()
+}
+
+func formatLogfmt(fields []interface{}) string {
+	pairs := make([]string, 0, len(fields)/2)
+	for idx := 0; idx < len(fields); idx += 2 {
+		pairs = append(pairs, fmt.Sprintf("%v=%s", fields[idx], quoteLogfmt(fields[idx+1])))
+	}
+	return strings.Join(pairs, " ")
+}
+
+func quoteLogfmt(v interface{}) string {
+	var text string
+	switch typed := value(v).(type) {
+	case nil:
+		return ""
+	case string:
+		text = typed
+	default:
+		if valueBytes, err := json.Marshal(typed); err == nil {
+			text = string(valueBytes)
+		} else {
+			text = fmt.Sprintf("%v", typed)
+		}
+	}
+	if text == "" || strings.ContainsAny(text, " =\"\t\r\n\\") {
+		quoted, _ := json.Marshal(text)
+		return string(quoted)
+	}
+	return text
+}
+
+type requestIDKey struct{}
+
+// WithRequestID returns a context carrying the ID of the request being served
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestID returns the ID of the request being served, if any
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
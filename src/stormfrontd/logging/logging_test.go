@@ -0,0 +1,143 @@
+package logging
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFormatLogfmt(t *testing.T) {
+	tests := []struct {
+		name     string
+		fields   []interface{}
+		expected string
+	}{
+		{name: "plain values", fields: []interface{}{"msg", "started", "port", 6674, "leader", true}, expected: "msg=started port=6674 leader=true"},
+		{name: "quoted string", fields: []interface{}{"msg", "Node joined the cluster"}, expected: `msg="Node joined the cluster"`},
+		{name: "empty string", fields: []interface{}{"node", ""}, expected: `node=""`},
+		{name: "nil value", fields: []interface{}{"error", nil}, expected: "error="},
+		{name: "error value", fields: []interface{}{"error", errors.New("connection refused")}, expected: `error="connection refused"`},
+		{name: "escaped characters", fields: []interface{}{"path", `C:\a "b"`}, expected: `path="C:\\a \"b\""`},
+		{name: "newline", fields: []interface{}{"output", "a\nb"}, expected: `output="a\nb"`},
+		{name: "duration", fields: []interface{}{"took", 1500 * time.Millisecond}, expected: "took=1.5s"},
+		{name: "slice", fields: []interface{}{"nodes", []string{"a", "b"}}, expected: `nodes="[\"a\",\"b\"]"`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if line := formatLogfmt(test.fields); line != test.expected {
+				t.Fatalf("expected %s, got %s", test.expected, line)
+			}
+		})
+	}
+}
+
+func TestFormatJSON(t *testing.T) {
+	tests := []struct {
+		name     string
+		fields   []interface{}
+		expected string
+	}{
+		{name: "plain values", fields: []interface{}{"msg", "started", "port", 6674, "leader", true}, expected: `{"msg":"started","port":6674,"leader":true}`},
+		{name: "nil value", fields: []interface{}{"error", nil}, expected: `{"error":null}`},
+		{name: "error value", fields: []interface{}{"error", errors.New("connection refused")}, expected: `{"error":"connection refused"}`},
+		{name: "non-string key", fields: []interface{}{1, "one"}, expected: `{"1":"one"}`},
+		{name: "unmarshalable value", fields: []interface{}{"callback", func() {}}, expected: `{"callback":"`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if line := formatJSON(test.fields); !strings.HasPrefix(line, test.expected) {
+				t.Fatalf("expected %s, got %s", test.expected, line)
+			}
+		})
+	}
+}
+
+func TestLoggerWrite(t *testing.T) {
+	defer Configure(INFO_NAME, FORMAT_LOGFMT)
+	defer SetOutput(output)
+
+	tests := []struct {
+		name     string
+		level    string
+		format   string
+		write    func(*Logger)
+		expected string
+	}{
+		{
+			name:     "subsystem and fields",
+			level:    INFO_NAME,
+			format:   FORMAT_LOGFMT,
+			write:    func(l *Logger) { l.With("node", "a").Info("Ready", "port", 6674) },
+			expected: "level=info msg=Ready subsystem=test node=a port=6674",
+		},
+		{
+			name:     "json",
+			level:    INFO_NAME,
+			format:   FORMAT_JSON,
+			write:    func(l *Logger) { l.Warn("Slow", "seconds", 2) },
+			expected: `"level":"warn","msg":"Slow","subsystem":"test","seconds":2}`,
+		},
+		{
+			name:     "odd number of fields",
+			level:    INFO_NAME,
+			format:   FORMAT_LOGFMT,
+			write:    func(l *Logger) { l.Error("Failed", "error") },
+			expected: "level=error msg=Failed subsystem=test error=",
+		},
+		{
+			name:     "below level",
+			level:    WARN_NAME,
+			format:   FORMAT_LOGFMT,
+			write:    func(l *Logger) { l.Info("Ignored") },
+			expected: "",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if err := Configure(test.level, test.format); err != nil {
+				t.Fatal(err)
+			}
+			var out strings.Builder
+			SetOutput(&out)
+			test.write(Subsystem("test"))
+
+			line := strings.TrimSuffix(out.String(), "\n")
+			if test.expected == "" {
+				if line != "" {
+					t.Fatalf("expected nothing to be written, got %s", line)
+				}
+				return
+			}
+			if !strings.HasSuffix(line, test.expected) {
+				t.Fatalf("expected line ending in %s, got %s", test.expected, line)
+			}
+		})
+	}
+}
+
+func TestConfigure(t *testing.T) {
+	defer Configure(INFO_NAME, FORMAT_LOGFMT)
+
+	tests := []struct {
+		level  string
+		format string
+		err    bool
+	}{
+		{level: "debug", format: "json"},
+		{level: "WARN", format: "LOGFMT"},
+		{level: "verbose", format: "logfmt", err: true},
+		{level: "info", format: "xml", err: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.level+"/"+test.format, func(t *testing.T) {
+			if err := Configure(test.level, test.format); (err != nil) != test.err {
+				t.Fatalf("expected error %v, got %v", test.err, err)
+			}
+		})
+	}
+}
@@ -3,9 +3,10 @@
 package main
 
 import (
-	"fmt"
 	"stormfrontd/api"
 	"stormfrontd/config"
+	"stormfrontd/logging"
+	"stormfrontd/middleware"
 	"stormfrontd/utils"
 	"strconv"
 
@@ -20,14 +21,20 @@ func main() {
 
 	config.LoadConfig()
 
+	if err := logging.Configure(config.Config.LogLevel, config.Config.LogFormat); err != nil {
+		logging.Fatal("Invalid logging configuration", "error", err)
+	}
+
 	routerPort := ":" + strconv.Itoa(config.Config.DaemonPort)
 
-	fmt.Printf("Running with port: %v\n", config.Config.DaemonPort)
+	logging.Info("Starting daemon", "port", config.Config.DaemonPort)
 
 	api.Healthy = true
 
-	// Set the router as the default one provided by Gin
-	router = gin.Default()
+	// Use our own logger and recovery in place of Gin's defaults so that
+	// request logs match the rest of the daemon
+	router = gin.New()
+	router.Use(middleware.Recovery(), middleware.RequestLogger())
 
 	// Initialize the routes
 	initializeRoutes()
@@ -35,7 +42,7 @@ func main() {
 	err := utils.EnsureDataDirectory()
 
 	if err != nil {
-		panic(err)
+		logging.Fatal("Unable to create data directory", "error", err)
 	}
 
 	// Start serving the application
@@ -3,24 +3,27 @@
 package middleware
 
 import (
-	"log"
+	"fmt"
 	"net/http"
 	"strings"
 
 	"stormfrontd/client/auth"
 	"stormfrontd/config"
+	"stormfrontd/logging"
 	"stormfrontd/utils"
 
 	"github.com/gin-gonic/gin"
 )
 
+var authLog = logging.Subsystem("auth")
+
 func EnsureLocalhost() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		if config.Config.RestrictRequestHost {
 			remoteAddr := c.Request.RemoteAddr
 			host := strings.Split(remoteAddr, ":")[0]
 			if !utils.Contains(config.Config.AllowedIPs, host) {
-				log.Printf("Invalid control request from IP %v", host)
+				authLog.Warn("Rejected control request", "request_id", logging.RequestID(c.Request.Context()), "client_ip", host)
 				c.AbortWithStatus(http.StatusUnauthorized)
 				return
 			}
@@ -28,24 +31,45 @@ func EnsureLocalhost() gin.HandlerFunc {
 	}
 }
 
-func CheckTokenAuthentication() gin.HandlerFunc {
+// CheckTokenAuthentication verifies the node access token or API token on the
+// request, API tokens must also hold one of roles when any are given
+func CheckTokenAuthentication(roles ...string) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		token := c.Request.Header.Get("Authorization")
 		splitToken := strings.Split(token, "Bearer ")
 		if len(splitToken) != 2 {
-			token := c.Request.Header.Get("X-Stormfront-API")
+			// The CLI sends API tokens as "Authorization: X-Stormfront-API
+			// <token>", older callers use the X-Stormfront-API header
+			if strings.HasPrefix(token, "X-Stormfront-API ") {
+				token = strings.TrimPrefix(token, "X-Stormfront-API ")
+			} else {
+				token = c.Request.Header.Get("X-Stormfront-API")
+			}
 
-			status := auth.VerifyAPIToken(token)
+			apiToken, status := auth.VerifyAPIToken(token)
 			if status != http.StatusOK {
-				c.Status(status)
+				c.AbortWithStatus(status)
+				return
+			}
+			if len(roles) > 0 && !apiToken.HasRole(roles...) {
+				authLog.Warn("API token denied", "request_id", logging.RequestID(c.Request.Context()), "token", apiToken.Name, "role", apiToken.Role, "method", c.Request.Method, "path", c.Request.URL.Path)
+				c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": fmt.Sprintf("API token %s with role %s is not allowed to %s %s", apiToken.Name, apiToken.Role, c.Request.Method, c.Request.URL.Path)})
 				return
 			}
+			c.Set(auth.API_TOKEN_CONTEXT_KEY, apiToken)
 		} else {
 			token = splitToken[1]
 
+			// With mutual TLS, node access tokens are only accepted alongside a
+			// certificate signed by the cluster CA
+			if config.Config.MutualTLS && (c.Request.TLS == nil || len(c.Request.TLS.VerifiedChains) == 0) {
+				c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "node requests require a client certificate"})
+				return
+			}
+
 			status := auth.VerifyAccessToken(token)
 			if status != http.StatusOK {
-				c.Status(status)
+				c.AbortWithStatus(status)
 				return
 			}
 		}
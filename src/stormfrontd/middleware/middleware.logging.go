@@ -0,0 +1,65 @@
+// middleware.logging.go
+
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"runtime/debug"
+	"time"
+
+	"stormfrontd/logging"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+var httpLog = logging.Subsystem("http")
+
+// RequestLogger gives each request an ID, keeping the one passed on by
+// another node if there is one, and logs the request once it is handled.
+// Health checks run every few seconds so they are only logged at debug.
+func RequestLogger() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+
+		id := c.GetHeader(logging.REQUEST_ID_HEADER)
+		if id == "" {
+			id = uuid.New().String()
+		}
+		c.Header(logging.REQUEST_ID_HEADER, id)
+		c.Request = c.Request.WithContext(logging.WithRequestID(c.Request.Context(), id))
+
+		c.Next()
+
+		fields := []interface{}{
+			"request_id", id,
+			"method", c.Request.Method,
+			"path", c.Request.URL.Path,
+			"status", c.Writer.Status(),
+			"duration_ms", time.Since(start).Milliseconds(),
+			"client_ip", c.ClientIP(),
+		}
+		if len(c.Errors) > 0 {
+			fields = append(fields, "error", c.Errors.String())
+		}
+
+		switch {
+		case c.Writer.Status() >= http.StatusInternalServerError:
+			httpLog.Error("Handled request", fields...)
+		case c.Request.URL.Path == "/api/health":
+			httpLog.Debug("Handled request", fields...)
+		default:
+			httpLog.Info("Handled request", fields...)
+		}
+	}
+}
+
+// Recovery logs a panic in a handler along with its stack and responds with
+// a 500 rather than dropping the connection
+func Recovery() gin.HandlerFunc {
+	return gin.CustomRecoveryWithWriter(nil, func(c *gin.Context, recovered interface{}) {
+		httpLog.Error("Recovered from panic", "request_id", logging.RequestID(c.Request.Context()), "method", c.Request.Method, "path", c.Request.URL.Path, "panic", fmt.Sprintf("%v", recovered), "stack", string(debug.Stack()))
+		c.AbortWithStatus(http.StatusInternalServerError)
+	})
+}
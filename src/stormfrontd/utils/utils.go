@@ -4,15 +4,15 @@ package utils
 
 import (
 	"fmt"
-	"log"
 	"net"
 	"os"
+	"stormfrontd/logging"
 
 	"github.com/gin-gonic/gin"
 )
 
 func Error(err error, c *gin.Context, statusCode int) {
-	log.Printf("Encountered error: %v", err)
+	logging.Error("Request failed", "request_id", logging.RequestID(c.Request.Context()), "method", c.Request.Method, "path", c.Request.URL.Path, "status", statusCode, "error", err)
 	c.JSON(statusCode, gin.H{"error": err.Error()})
 }
 